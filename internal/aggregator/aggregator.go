@@ -1,6 +1,7 @@
 package aggregator
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"log"
@@ -13,7 +14,9 @@ import (
 
 	"gorssag/internal/cache"
 	"gorssag/internal/config"
+	"gorssag/internal/events"
 	"gorssag/internal/models"
+	"gorssag/internal/notifier"
 	"gorssag/internal/odata"
 	"gorssag/internal/storage"
 
@@ -33,9 +36,10 @@ type Aggregator struct {
 	storage      storage.Storage
 	cacheManager *cache.Manager
 	feedStatus   map[string]*models.FeedStatus // Track feed status
-	// mu           sync.RWMutex -- sqlite should handle mutex - or storage ny itself
+	mu           sync.RWMutex                  // Guards the feed configuration, status and caches below
 	parser       *gofeed.Parser
 	filterParser *odata.FilterParser
+	searchParser *odata.SearchParser
 
 	// New fields for centralized feed management
 	allArticles  map[string]models.Article // Map of article ID to article (all articles from all feeds)
@@ -44,6 +48,9 @@ type Aggregator struct {
 
 	// HTTP caching fields
 	feedCache map[string]*FeedCacheEntry // Cache ETags and Last-Modified for each feed
+
+	events   *events.Bus        // Newly stored articles, published after each poll
+	notifier *notifier.Notifier // Chat channels told about newly stored articles, nil when unset
 }
 
 // FeedCacheEntry stores HTTP caching information for a feed
@@ -60,15 +67,30 @@ func New(cacheManager *cache.Manager, storage storage.Storage, feeds map[string]
 		feeds:        feeds,
 		parser:       gofeed.NewParser(),
 		filterParser: odata.NewFilterParser(),
+		searchParser: odata.NewSearchParser(),
 		feedStatus:   make(map[string]*models.FeedStatus),
 		allArticles:  make(map[string]models.Article),
 		feedArticles: make(map[string][]string),
 		lastFeedPoll: make(map[string]time.Time),
 		feedCache:    make(map[string]*FeedCacheEntry),
+		events:       events.NewBus(events.DefaultReplaySize),
 	}
 }
 
+// SetNotifier sets the chat notifier queued with the newly stored articles of each topic
+func (a *Aggregator) SetNotifier(n *notifier.Notifier) {
+	a.notifier = n
+}
+
+// Events returns the bus publishing the articles newly stored by feed polls
+func (a *Aggregator) Events() *events.Bus {
+	return a.events
+}
+
 func (a *Aggregator) GetAvailableTopics() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	var topics []string
 	for topic := range a.feeds {
 		topics = append(topics, topic)
@@ -80,19 +102,137 @@ func (a *Aggregator) GetAvailableTopics() []string {
 	return topics
 }
 
-// GetConfig returns the current feed configuration
+// GetConfig returns a copy of the current feed configuration
 func (a *Aggregator) GetConfig() map[string]config.TopicConfig {
-	return a.feeds
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	feeds := make(map[string]config.TopicConfig, len(a.feeds))
+	for topic, topicConfig := range a.feeds {
+		feeds[topic] = topicConfig
+	}
+	return feeds
+}
+
+// getTopicConfig returns the configuration of a topic
+func (a *Aggregator) getTopicConfig(topic string) (config.TopicConfig, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	topicConfig, ok := a.feeds[topic]
+	return topicConfig, ok
 }
 
 // GetAllArticles returns all articles from storage without topic filtering
-func (a *Aggregator) GetAllArticles(query *models.ODataQuery) ([]models.Article, int, error) {
-	return a.storage.GetAllArticles(query)
+func (a *Aggregator) GetAllArticles(ctx context.Context, query *models.ODataQuery) ([]models.Article, int, error) {
+	return a.storage.GetAllArticles(ctx, query)
+}
+
+// GetRelatedArticles returns articles similar to the given one across all topics
+func (a *Aggregator) GetRelatedArticles(ctx context.Context, articleID string, limit int) ([]models.Article, error) {
+	return a.storage.GetRelatedArticles(ctx, articleID, limit)
+}
+
+// GetArticle returns an article with its topics and source feed URLs
+func (a *Aggregator) GetArticle(ctx context.Context, articleID string) (*models.ArticleDetail, error) {
+	details, err := a.GetArticles(ctx, []string{articleID})
+	if err != nil {
+		return nil, err
+	}
+	if len(details) == 0 {
+		return nil, storage.ErrNotFound
+	}
+	return &details[0], nil
+}
+
+// GetArticles returns the articles with the given IDs, in the requested order,
+// with their topics. Unknown IDs are skipped.
+func (a *Aggregator) GetArticles(ctx context.Context, articleIDs []string) ([]models.ArticleDetail, error) {
+	articles, err := a.storage.GetArticles(ctx, articleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	details := make([]models.ArticleDetail, 0, len(articles))
+	for _, article := range articles {
+		topics, err := a.storage.GetArticleTopics(ctx, article.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get topics of article %s: %v", article.ID, err)
+		}
+		if topics == nil {
+			topics = []string{}
+		}
+		details = append(details, models.ArticleDetail{Article: article, Topics: topics})
+	}
+	return details, nil
+}
+
+// GetArticleRevisions returns the earlier versions of an article
+func (a *Aggregator) GetArticleRevisions(ctx context.Context, articleID string) ([]models.ArticleRevision, error) {
+	return a.storage.GetArticleRevisions(ctx, articleID)
+}
+
+// SetArticlePinned pins or unpins an article and drops the cached feeds of its topics
+func (a *Aggregator) SetArticlePinned(ctx context.Context, articleID string, pinned bool) error {
+	if err := a.storage.SetArticlePinned(ctx, articleID, pinned); err != nil {
+		return err
+	}
+
+	topics, err := a.storage.GetArticleTopics(ctx, articleID)
+	if err != nil {
+		log.Printf("Warning: failed to get topics of pinned article %s: %v", articleID, err)
+		return nil
+	}
+	for _, topic := range topics {
+		a.cacheManager.Delete(fmt.Sprintf("feed:%s", topic))
+	}
+	return nil
+}
+
+// DeleteSourceFeed deletes a polled feed, purging its articles when asked, and
+// unsubscribes the topics still configured with its URL so it is not polled again
+func (a *Aggregator) DeleteSourceFeed(ctx context.Context, feedID int64, purge bool) (*models.FeedDeletion, error) {
+	deletion, err := a.storage.DeleteSourceFeed(ctx, feedID, purge)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	for _, topic := range a.topicsForFeed(deletion.URL) {
+		topicConfig := a.feeds[topic]
+		urls := make([]string, 0, len(topicConfig.URLs))
+		for _, url := range topicConfig.URLs {
+			if url != deletion.URL {
+				urls = append(urls, url)
+			}
+		}
+		topicConfig.URLs = urls
+		a.feeds[topic] = topicConfig
+		deletion.UnsubscribedTopics = append(deletion.UnsubscribedTopics, topic)
+	}
+
+	delete(a.feedStatus, deletion.URL)
+	delete(a.feedArticles, deletion.URL)
+	delete(a.lastFeedPoll, deletion.URL)
+	delete(a.feedCache, deletion.URL)
+	for _, articleID := range deletion.DeletedArticleIDs {
+		delete(a.allArticles, articleID)
+	}
+	a.mu.Unlock()
+
+	// Purged articles may belong to any topic
+	if deletion.DeletedArticles > 0 {
+		a.cacheManager.Flush()
+	}
+	return deletion, nil
 }
 
 // GetCombinedFilters combines all topic filters for a feed
 func (a *Aggregator) GetCombinedFilters(feedURL string) ([]string, bool) {
-	topics := a.GetTopicsForFeed(feedURL)
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	topics := a.topicsForFeed(feedURL)
 	if len(topics) == 0 {
 		return nil, false
 	}
@@ -131,6 +271,9 @@ func (a *Aggregator) GetCombinedFilters(feedURL string) ([]string, bool) {
 
 // GetAllUniqueFeedURLs returns all unique RSS feed URLs across all topics
 func (a *Aggregator) GetAllUniqueFeedURLs() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	urlSet := make(map[string]bool)
 	for _, topicConfig := range a.feeds {
 		for _, url := range topicConfig.URLs {
@@ -151,6 +294,14 @@ func (a *Aggregator) GetAllUniqueFeedURLs() []string {
 
 // GetTopicsForFeed returns all topics that use a specific feed URL
 func (a *Aggregator) GetTopicsForFeed(feedURL string) []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.topicsForFeed(feedURL)
+}
+
+// topicsForFeed returns the topics using a feed URL, with the lock held
+func (a *Aggregator) topicsForFeed(feedURL string) []string {
 	var topics []string
 	for topic, topicConfig := range a.feeds {
 		for _, url := range topicConfig.URLs {
@@ -168,13 +319,10 @@ func (a *Aggregator) GetTopicsForFeed(feedURL string) []string {
 }
 
 // PollFeed polls a single feed and stores articles using the new architecture
-func (a *Aggregator) PollFeed(feedURL string) error {
+func (a *Aggregator) PollFeed(ctx context.Context, feedURL string) error {
 	// Check if we should retry this feed
-	if !a.ShouldRetryFeed(feedURL) {
-		status, exists := a.feedStatus[feedURL]
-		if exists && status.IsDisabled {
-			return fmt.Errorf("feed is disabled: %s", status.DisabledReason)
-		}
+	if err := a.checkFeedEnabled(feedURL); err != nil {
+		return err
 	}
 
 	// Get combined filters for this feed from all topics that use it
@@ -182,18 +330,14 @@ func (a *Aggregator) PollFeed(feedURL string) error {
 	log.Printf("PollFeed %s: Combined filters: %v, noFilter: %v", feedURL, combinedFilters, noFilter)
 
 	// Get stored User-Agent for this feed
-	status, exists := a.feedStatus[feedURL]
-	var userAgent string
-	if exists && status.UserAgent != "" {
-		userAgent = status.UserAgent
-	}
+	userAgent := a.feedUserAgent(feedURL)
 
 	// Try to fetch with stored User-Agent first
 	var feed *gofeed.Feed
 	var err error
 
 	if userAgent != "" {
-		feed, err = a.testFeedWithUserAgent(feedURL, userAgent)
+		feed, err = a.testFeedWithUserAgent(ctx, feedURL, userAgent)
 		if err != nil {
 			log.Printf("Failed to fetch %s with stored User-Agent: %v", feedURL, err)
 		}
@@ -202,7 +346,7 @@ func (a *Aggregator) PollFeed(feedURL string) error {
 	// If no stored User-Agent or it failed, try to find a working one
 	if feed == nil || err != nil {
 		log.Printf("Testing User-Agents for %s", feedURL)
-		workingUserAgent, uaErr := a.TestUserAgentForFeed(feedURL)
+		workingUserAgent, uaErr := a.TestUserAgentForFeed(ctx, feedURL)
 		if uaErr != nil {
 			// Update status with error
 			a.UpdateFeedStatus(feedURL, "", 0, uaErr)
@@ -214,7 +358,7 @@ func (a *Aggregator) PollFeed(feedURL string) error {
 		userAgent = workingUserAgent
 
 		// Fetch with the working User-Agent
-		feed, err = a.testFeedWithUserAgent(feedURL, userAgent)
+		feed, err = a.testFeedWithUserAgent(ctx, feedURL, userAgent)
 		if err != nil {
 			// Check if this is a "not modified" error (which is not really an error)
 			if strings.Contains(err.Error(), "feed not modified") {
@@ -255,7 +399,7 @@ func (a *Aggregator) PollFeed(feedURL string) error {
 		}
 
 		// Generate consistent article ID
-		articleID := generateArticleID(item.Title, item.Link, item.PublishedParsed)
+		articleID := generateArticleID(item.GUID, item.Link, item.Title)
 
 		// Create article
 		article := models.Article{
@@ -286,9 +430,10 @@ func (a *Aggregator) PollFeed(feedURL string) error {
 
 	log.Printf("PollFeed %s: %d total articles, %d after filtering", feedURL, len(allArticles), len(filteredArticles))
 
-	// Store articles centrally in memory for immediate access
-	for _, article := range filteredArticles {
-		a.allArticles[article.ID] = article
+	// Articles stored under an earlier ID keep it
+	if err := a.ReuseStoredArticleIDs(ctx, filteredArticles); err != nil {
+		a.UpdateFeedStatus(feedURL, "", 0, err)
+		return err
 	}
 
 	// Update feed articles mapping
@@ -296,21 +441,50 @@ func (a *Aggregator) PollFeed(feedURL string) error {
 	for _, article := range filteredArticles {
 		articleIDs = append(articleIDs, article.ID)
 	}
+
+	// Store articles centrally in memory for immediate access
+	a.mu.Lock()
+	for _, article := range filteredArticles {
+		a.allArticles[article.ID] = article
+	}
 	a.feedArticles[feedURL] = articleIDs
+	a.mu.Unlock()
 
 	// 🎯 NEW ARCHITECTURE: Save articles first, then assign topic memberships
 	if len(filteredArticles) > 0 {
+		// Articles already stored are not announced again. Without the lookup
+		// nothing is announced, rather than the whole feed again.
+		known := make(map[string]bool)
+		announce := true
+		if existing, err := a.storage.GetArticles(ctx, articleIDs); err != nil {
+			log.Printf("Warning: failed to look up known articles of feed %s, not announcing this poll: %v", feedURL, err)
+			announce = false
+		} else {
+			for _, article := range existing {
+				known[article.ID] = true
+			}
+		}
+
 		// Step 1: Save all articles to storage WITHOUT topic assignment
-		err := a.storage.SaveArticles(filteredArticles)
+		err := a.storage.SaveArticles(ctx, filteredArticles)
 		if err != nil {
 			log.Printf("Error saving articles from feed %s: %v", feedURL, err)
 			// Don't return error - continue with topic assignment for articles that might have been saved
 		}
 
+		// Record the feed URL the articles came from
+		if err := a.storage.AssignArticlesToFeed(ctx, articleIDs, feedURL, feed.Title); err != nil {
+			log.Printf("Warning: failed to record feed %s for articles: %v", feedURL, err)
+		}
+
 		// Step 2: THEN assign articles to topics based on their individual filters
+		articleTopics := make(map[string][]string)
 		topics := a.GetTopicsForFeed(feedURL)
 		for _, topic := range topics {
-			topicConfig := a.feeds[topic]
+			topicConfig, ok := a.getTopicConfig(topic)
+			if !ok {
+				continue // Unsubscribed meanwhile
+			}
 			var topicArticleIDs []string
 
 			for _, article := range filteredArticles {
@@ -321,19 +495,48 @@ func (a *Aggregator) PollFeed(feedURL string) error {
 			}
 
 			if len(topicArticleIDs) > 0 {
-				err := a.storage.AssignArticlesToTopic(topicArticleIDs, topic)
+				err := a.storage.AssignArticlesToTopic(ctx, topicArticleIDs, topic)
 				if err != nil {
 					log.Printf("Error assigning articles to topic %s: %v", topic, err)
 				} else {
 					log.Printf("Assigned %d articles to topic %s", len(topicArticleIDs), topic)
+					for _, articleID := range topicArticleIDs {
+						articleTopics[articleID] = append(articleTopics[articleID], topic)
+					}
 				}
 			}
 		}
+
+		// Step 3: announce the newly stored articles as stored
+		var newIDs []string
+		for _, article := range filteredArticles {
+			if announce && !known[article.ID] {
+				newIDs = append(newIDs, article.ID)
+			}
+		}
+		if len(newIDs) > 0 {
+			stored, err := a.storage.GetArticles(ctx, newIDs)
+			if err != nil {
+				log.Printf("Warning: failed to load new articles of feed %s: %v", feedURL, err)
+			}
+			topicArticles := make(map[string][]models.Article)
+			for _, article := range stored {
+				a.events.Publish(article, articleTopics[article.ID])
+				for _, topic := range articleTopics[article.ID] {
+					topicArticles[topic] = append(topicArticles[topic], article)
+				}
+			}
+			for topic, articles := range topicArticles {
+				a.notifier.Notify(topic, articles)
+			}
+		}
 	}
 
 	// Update feed status
 	a.UpdateFeedStatus(feedURL, "", len(filteredArticles), nil)
+	a.mu.Lock()
 	a.lastFeedPoll[feedURL] = time.Now()
+	a.mu.Unlock()
 
 	topics := a.GetTopicsForFeed(feedURL)
 	log.Printf("Polled feed %s: %d articles, %d topics affected", feedURL, len(filteredArticles), len(topics))
@@ -341,7 +544,7 @@ func (a *Aggregator) PollFeed(feedURL string) error {
 }
 
 // PollAllFeeds polls all unique feeds with improved parallelism
-func (a *Aggregator) PollAllFeeds() error {
+func (a *Aggregator) PollAllFeeds(ctx context.Context) error {
 	urls := a.GetAllUniqueFeedURLs()
 	log.Printf("DEBUG: PollAllFeeds called with %d unique feeds: %v", len(urls), urls)
 
@@ -364,7 +567,7 @@ func (a *Aggregator) PollAllFeeds() error {
 			log.Printf("DEBUG: Worker %d started", workerID)
 			for url := range urlChan {
 				log.Printf("DEBUG: Worker %d processing URL: %s", workerID, url)
-				err := a.PollFeed(url)
+				err := a.PollFeed(ctx, url)
 				log.Printf("DEBUG: Worker %d completed URL %s with error: %v", workerID, url, err)
 				resultChan <- err
 			}
@@ -408,6 +611,9 @@ func (a *Aggregator) PollAllFeeds() error {
 			} else {
 				successCount++
 			}
+		case <-ctx.Done():
+			log.Printf("Feed polling cancelled - %d feeds completed, %d pending", successCount+len(errors), len(urls)-successCount-len(errors))
+			return ctx.Err()
 		case <-timeoutChan:
 			log.Printf("Timeout after %v - %d feeds completed, %d pending", timeout, successCount+len(errors), len(urls)-successCount-len(errors))
 			return fmt.Errorf("timeout polling feeds after %v", timeout)
@@ -516,18 +722,36 @@ func convertHTMLToMarkdown(html string) string {
 	return html
 }
 
-func generateArticleID(title, link string, publishedAt *time.Time) string {
-	// Generate a deterministic ID based on content to enable deduplication
-	// Use title + link as the primary key for deduplication
-	// This ensures the same article from multiple feeds gets the same ID
+func generateArticleID(guid, link, title string) string {
+	// Generate a deterministic ID based on the article identity to enable deduplication
+	// Use the link as the primary key, so the same article from multiple feeds gets
+	// the same ID and a corrected headline updates the article instead of adding one
+	// Feeds without links fall back to the item GUID, then to the title
+
+	// Normalize the key for consistent hashing
+	var key string
+	switch {
+	case strings.TrimSpace(link) != "":
+		key = strings.TrimSpace(strings.ToLower(link))
+	case strings.TrimSpace(guid) != "":
+		key = "guid|" + strings.TrimSpace(guid)
+	default:
+		key = "title|" + strings.TrimSpace(strings.ToLower(title))
+	}
+	return articleIDFromKey(key)
+}
 
-	// Normalize the title and link for consistent hashing
-	normalizedTitle := strings.TrimSpace(strings.ToLower(title))
-	normalizedLink := strings.TrimSpace(strings.ToLower(link))
+// legacyArticleID is the ID articles were stored under before IDs followed
+// the link: a hash of the title and the link
+func legacyArticleID(title, link string) string {
+	return articleIDFromKey(strings.TrimSpace(strings.ToLower(title)) + "|" + strings.TrimSpace(strings.ToLower(link)))
+}
 
-	// Create a hash of the normalized content
+// articleIDFromKey hashes a normalized article key into a UUID formatted ID
+func articleIDFromKey(key string) string {
+	// Create a hash of the normalized key
 	hasher := sha256.New()
-	hasher.Write([]byte(normalizedTitle + "|" + normalizedLink))
+	hasher.Write([]byte(key))
 	hash := fmt.Sprintf("%x", hasher.Sum(nil))
 
 	// Convert to UUID format for consistency with existing code
@@ -559,18 +783,95 @@ func getPublishedTime(item *gofeed.Item) time.Time {
 	return time.Now()
 }
 
+// ReuseStoredArticleIDs gives polled articles the ID they are already stored
+// under: the one of the stored article with the same link, or for articles
+// without link their legacy title based ID. Articles stored before IDs
+// followed the link are then updated instead of being added and announced
+// again, and keep their pins, states and revisions.
+func (a *Aggregator) ReuseStoredArticleIDs(ctx context.Context, articles []models.Article) error {
+	var links, legacyIDs []string
+	for _, article := range articles {
+		if article.Link != "" {
+			links = append(links, article.Link)
+		} else {
+			legacyIDs = append(legacyIDs, legacyArticleID(article.Title, ""))
+		}
+	}
+
+	storedIDs := make(map[string]string)
+	if len(links) > 0 {
+		byLink, err := a.storage.GetArticleIDsByLink(ctx, links)
+		if err != nil {
+			return fmt.Errorf("failed to look up stored articles: %v", err)
+		}
+		storedIDs = byLink
+	}
+	legacy := make(map[string]bool)
+	if len(legacyIDs) > 0 {
+		stored, err := a.storage.GetArticles(ctx, legacyIDs)
+		if err != nil {
+			return fmt.Errorf("failed to look up stored articles: %v", err)
+		}
+		for _, article := range stored {
+			legacy[article.ID] = true
+		}
+	}
+
+	for i := range articles {
+		if articles[i].Link != "" {
+			if id, ok := storedIDs[articles[i].Link]; ok {
+				articles[i].ID = id
+			}
+		} else if id := legacyArticleID(articles[i].Title, ""); legacy[id] {
+			articles[i].ID = id
+		}
+	}
+	return nil
+}
+
 // GetFeedStatus returns the status of all feeds
 func (a *Aggregator) GetFeedStatus() map[string]*models.FeedStatus {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	// Create a copy to avoid race conditions
 	status := make(map[string]*models.FeedStatus)
 	for url, feedStatus := range a.feedStatus {
-		status[url] = feedStatus
+		feedStatus := *feedStatus
+		feedStatus.TestedUserAgents = append([]string(nil), feedStatus.TestedUserAgents...)
+		status[url] = &feedStatus
 	}
 	return status
 }
 
+// checkFeedEnabled returns an error when a feed is disabled and not due for a retry
+func (a *Aggregator) checkFeedEnabled(url string) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if !a.shouldRetryFeed(url) {
+		status, exists := a.feedStatus[url]
+		if exists && status.IsDisabled {
+			return fmt.Errorf("feed is disabled: %s", status.DisabledReason)
+		}
+	}
+	return nil
+}
+
+// feedUserAgent returns the stored working User-Agent of a feed
+func (a *Aggregator) feedUserAgent(url string) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if status, exists := a.feedStatus[url]; exists {
+		return status.UserAgent
+	}
+	return ""
+}
+
 // TestUserAgentForFeed tests different User-Agents to find one that works
-func (a *Aggregator) TestUserAgentForFeed(url string) (string, error) {
+func (a *Aggregator) TestUserAgentForFeed(ctx context.Context, url string) (string, error) {
+	a.mu.Lock()
 	status, exists := a.feedStatus[url]
 	if !exists {
 		status = &models.FeedStatus{
@@ -579,18 +880,26 @@ func (a *Aggregator) TestUserAgentForFeed(url string) (string, error) {
 		}
 		a.feedStatus[url] = status
 	}
+	a.mu.Unlock()
 
 	// Test each User-Agent
 	for _, userAgent := range userAgentsToTest {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
 		// Skip if already tested
-		if a.isUserAgentTested(status, userAgent) {
+		a.mu.RLock()
+		tested := a.isUserAgentTested(status, userAgent)
+		a.mu.RUnlock()
+		if tested {
 			continue
 		}
 
 		log.Printf("Testing User-Agent for %s: %s", url, userAgent)
 
 		// Test the User-Agent
-		feed, err := a.testFeedWithUserAgent(url, userAgent)
+		feed, err := a.testFeedWithUserAgent(ctx, url, userAgent)
 		if err == nil && feed != nil && len(feed.Items) > 0 {
 			// Check content quality
 			hasValidContent := false
@@ -608,23 +917,30 @@ func (a *Aggregator) TestUserAgentForFeed(url string) (string, error) {
 		}
 
 		// Mark as tested
+		a.mu.Lock()
 		a.markUserAgentTested(status, userAgent)
+		a.mu.Unlock()
 	}
 
 	return "", fmt.Errorf("no working User-Agent found for %s", url)
 }
 
 // testFeedWithUserAgent tests a feed with a specific User-Agent
-func (a *Aggregator) testFeedWithUserAgent(url, userAgent string) (*gofeed.Feed, error) {
-
+func (a *Aggregator) testFeedWithUserAgent(ctx context.Context, url, userAgent string) (*gofeed.Feed, error) {
+	a.mu.RLock()
 	cacheEntry, hasCache := a.feedCache[url]
+	if hasCache {
+		entry := *cacheEntry
+		cacheEntry = &entry
+	}
+	a.mu.RUnlock()
 
 	// Create a custom HTTP client with the User-Agent
 	client := &http.Client{
 		Timeout: 5 * time.Second, // Reduced timeout for faster testing
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -658,6 +974,7 @@ func (a *Aggregator) testFeedWithUserAgent(url, userAgent string) (*gofeed.Feed,
 	}
 
 	// Store caching headers for next request
+	a.mu.Lock()
 	if a.feedCache[url] == nil {
 		a.feedCache[url] = &FeedCacheEntry{}
 	}
@@ -670,6 +987,7 @@ func (a *Aggregator) testFeedWithUserAgent(url, userAgent string) (*gofeed.Feed,
 		a.feedCache[url].LastModified = lastModified
 	}
 	a.feedCache[url].LastChecked = time.Now()
+	a.mu.Unlock()
 
 	// Parse the feed
 	parser := gofeed.NewParser()
@@ -700,6 +1018,8 @@ func (a *Aggregator) markUserAgentTested(status *models.FeedStatus, userAgent st
 
 // UpdateFeedStatus updates the status of a specific feed
 func (a *Aggregator) UpdateFeedStatus(url, topic string, articlesCount int, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
 	status, exists := a.feedStatus[url]
 	if !exists {
@@ -759,6 +1079,8 @@ func (a *Aggregator) UpdateFeedStatus(url, topic string, articlesCount int, err
 
 // SetUserAgentForFeed sets the working User-Agent for a feed
 func (a *Aggregator) SetUserAgentForFeed(url, userAgent string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
 	status, exists := a.feedStatus[url]
 	if !exists {
@@ -788,7 +1110,14 @@ func (a *Aggregator) calculateBackoff(consecutiveErrors int) int {
 
 // ShouldRetryFeed checks if a disabled feed should be retried
 func (a *Aggregator) ShouldRetryFeed(url string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 
+	return a.shouldRetryFeed(url)
+}
+
+// shouldRetryFeed checks if a feed should be retried, with the lock held
+func (a *Aggregator) shouldRetryFeed(url string) bool {
 	status, exists := a.feedStatus[url]
 	if !exists {
 		return true // New feed, should try
@@ -807,11 +1136,11 @@ func (a *Aggregator) ShouldRetryFeed(url string) bool {
 	return !status.IsDisabled
 }
 
-func (a *Aggregator) GetAggregatedFeed(topic string, query *models.ODataQuery) (*models.AggregatedFeed, error) {
+func (a *Aggregator) GetAggregatedFeed(ctx context.Context, topic string, query *models.ODataQuery) (*models.AggregatedFeed, error) {
 	log.Printf("DEBUG: GetAggregatedFeed called for topic '%s'", topic)
 
 	// Check if topic exists
-	_, exists := a.feeds[topic]
+	_, exists := a.getTopicConfig(topic)
 	if !exists {
 		log.Printf("DEBUG: Topic '%s' not found in feeds config", topic)
 		return nil, fmt.Errorf("topic '%s' not found", topic)
@@ -831,12 +1160,14 @@ func (a *Aggregator) GetAggregatedFeed(topic string, query *models.ODataQuery) (
 
 	// Try to load from storage
 	log.Printf("DEBUG: About to call storage.LoadFeed for topic '%s'", topic)
-	if feed, err := a.storage.LoadFeed(topic); err == nil {
+	if feed, err := a.storage.LoadFeed(ctx, topic); err == nil {
 		log.Printf("DEBUG: Successfully loaded feed from storage for topic '%s'", topic)
 		// Cache the loaded feed
 		a.cacheManager.Set(cacheKey, feed, 0)
 		// Apply OData query
 		return a.applyODataQuery(feed, query)
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
 	} else {
 		log.Printf("DEBUG: Failed to load feed from storage for topic '%s': %v", topic, err)
 	}
@@ -902,7 +1233,7 @@ func (a *Aggregator) articleMatchesFilters(article models.Article, filters []str
 	return false
 }
 
-func (a *Aggregator) fetchFeedsParallel(feedURLs []string, topic string) ([]models.Article, error) {
+func (a *Aggregator) fetchFeedsParallel(ctx context.Context, feedURLs []string, topic string) ([]models.Article, error) {
 	var wg sync.WaitGroup
 	results := make(chan FeedResult, len(feedURLs))
 
@@ -911,7 +1242,7 @@ func (a *Aggregator) fetchFeedsParallel(feedURLs []string, topic string) ([]mode
 		wg.Add(1)
 		go func(feedURL string) {
 			defer wg.Done()
-			articles, err := a.fetchFeed(feedURL, topic)
+			articles, err := a.fetchFeed(ctx, feedURL, topic)
 			results <- FeedResult{
 				URL:      feedURL,
 				Articles: articles,
@@ -941,6 +1272,8 @@ func (a *Aggregator) fetchFeedsParallel(feedURLs []string, topic string) ([]mode
 			} else {
 				allArticles = append(allArticles, result.Articles...)
 			}
+		case <-ctx.Done():
+			return allArticles, ctx.Err()
 		case <-timeout:
 			log.Printf("Timeout waiting for feed results")
 			return allArticles, nil
@@ -948,27 +1281,20 @@ func (a *Aggregator) fetchFeedsParallel(feedURLs []string, topic string) ([]mode
 	}
 }
 
-func (a *Aggregator) fetchFeed(url string, topic string) ([]models.Article, error) {
+func (a *Aggregator) fetchFeed(ctx context.Context, url string, topic string) ([]models.Article, error) {
 	// Check if feed should be retried
-	if !a.ShouldRetryFeed(url) {
-		status, exists := a.feedStatus[url]
-		if exists && status.IsDisabled {
-			return nil, fmt.Errorf("feed is disabled: %s", status.DisabledReason)
-		}
+	if err := a.checkFeedEnabled(url); err != nil {
+		return nil, err
 	}
 
-	status, exists := a.feedStatus[url]
-	var userAgent string
-	if exists && status.UserAgent != "" {
-		userAgent = status.UserAgent
-	}
+	userAgent := a.feedUserAgent(url)
 
 	// Try to fetch with stored User-Agent first
 	var feed *gofeed.Feed
 	var err error
 
 	if userAgent != "" {
-		feed, err = a.testFeedWithUserAgent(url, userAgent)
+		feed, err = a.testFeedWithUserAgent(ctx, url, userAgent)
 		if err != nil {
 			log.Printf("Failed to fetch %s with stored User-Agent: %v", url, err)
 		}
@@ -977,7 +1303,7 @@ func (a *Aggregator) fetchFeed(url string, topic string) ([]models.Article, erro
 	// If no stored User-Agent or it failed, try to find a working one
 	if feed == nil || err != nil {
 		log.Printf("Testing User-Agents for %s", url)
-		workingUserAgent, uaErr := a.TestUserAgentForFeed(url)
+		workingUserAgent, uaErr := a.TestUserAgentForFeed(ctx, url)
 		if uaErr != nil {
 			// Update status with error
 			a.UpdateFeedStatus(url, topic, 0, uaErr)
@@ -989,7 +1315,7 @@ func (a *Aggregator) fetchFeed(url string, topic string) ([]models.Article, erro
 		userAgent = workingUserAgent
 
 		// Fetch with the working User-Agent
-		feed, err = a.testFeedWithUserAgent(url, userAgent)
+		feed, err = a.testFeedWithUserAgent(ctx, url, userAgent)
 		if err != nil {
 			// Check if this is a "not modified" error (which is not really an error)
 			if strings.Contains(err.Error(), "feed not modified") {
@@ -1068,9 +1394,37 @@ func (a *Aggregator) applyODataQuery(feed *models.AggregatedFeed, query *models.
 
 	// Apply search if specified
 	if len(query.Search) > 0 {
+		if _, err := a.searchParser.ParseTerms(query.Search); err != nil {
+			return nil, fmt.Errorf("invalid search expression: %v", err)
+		}
 		articles = a.searchArticles(articles, query.Search)
 	}
 
+	// Apply pin filter if specified
+	if query.Pinned != nil {
+		var pinnedArticles []models.Article
+		for _, article := range articles {
+			if article.Pinned == *query.Pinned {
+				pinnedArticles = append(pinnedArticles, article)
+			}
+		}
+		articles = pinnedArticles
+	}
+
+	// Apply feed URL filter if specified
+	if query.FeedURL != "" {
+		var feedArticles []models.Article
+		for _, article := range articles {
+			for _, url := range article.FeedURLs {
+				if url == query.FeedURL {
+					feedArticles = append(feedArticles, article)
+					break
+				}
+			}
+		}
+		articles = feedArticles
+	}
+
 	// Apply filter if specified
 	if query.Filter != "" {
 		filterExpr, err := a.filterParser.Parse(query.Filter)
@@ -1091,13 +1445,32 @@ func (a *Aggregator) applyODataQuery(feed *models.AggregatedFeed, query *models.
 		articles = filteredArticles
 	}
 
-	// Apply sorting
-	if query.OrderBy != "" {
+	// Apply sorting; the default order is the keyset order $skiptoken relies on
+	keyset := odata.IsKeysetOrder(query.OrderBy)
+	if keyset {
+		articles = sortByKeyset(articles)
+	} else {
 		articles = a.sortArticles(articles, query.OrderBy)
 	}
 
-	// Apply pagination
-	if query.Skip > 0 {
+	// Compute facets over the full result set, before pagination
+	var facets models.Facets
+	if len(query.Facets) > 0 {
+		facets = computeFacets(articles, query.Facets, feed.Topic)
+	}
+
+	// Keep one representative per story cluster
+	if query.Cluster {
+		articles = collapseClusters(articles)
+	}
+
+	// Apply pagination, either from a $skiptoken cursor or by offset
+	if query.SkipToken != nil {
+		start := sort.Search(len(articles), func(i int) bool {
+			return odata.AfterSkipToken(articles[i], query.SkipToken)
+		})
+		articles = articles[start:]
+	} else if query.Skip > 0 {
 		if query.Skip >= len(articles) {
 			articles = []models.Article{}
 		} else {
@@ -1105,8 +1478,12 @@ func (a *Aggregator) applyODataQuery(feed *models.AggregatedFeed, query *models.
 		}
 	}
 
+	var nextSkipToken string
 	if query.Top > 0 && query.Top < len(articles) {
 		articles = articles[:query.Top]
+		if keyset {
+			nextSkipToken = odata.EncodeSkipToken(articles[len(articles)-1])
+		}
 	}
 
 	// Apply field selection
@@ -1115,22 +1492,39 @@ func (a *Aggregator) applyODataQuery(feed *models.AggregatedFeed, query *models.
 	}
 
 	return &models.AggregatedFeed{
-		Topic:    feed.Topic,
-		Articles: articles,
-		Count:    len(articles),
-		Updated:  feed.Updated,
+		Topic:         feed.Topic,
+		Articles:      articles,
+		Count:         len(articles),
+		Updated:       feed.Updated,
+		Facets:        facets,
+		NextSkipToken: nextSkipToken,
 	}, nil
 }
 
+// sortByKeyset returns a copy of the articles ordered by published_at, then ID, descending
+func sortByKeyset(articles []models.Article) []models.Article {
+	sorted := make([]models.Article, len(articles))
+	copy(sorted, articles)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].PublishedAt.Equal(sorted[j].PublishedAt) {
+			return sorted[i].PublishedAt.After(sorted[j].PublishedAt)
+		}
+		return sorted[i].ID > sorted[j].ID
+	})
+	return sorted
+}
+
 func (a *Aggregator) searchArticles(articles []models.Article, searchTerms []string) []models.Article {
-	var results []models.Article
+	searchExpr, err := a.searchParser.ParseTerms(searchTerms)
+	if err != nil {
+		log.Printf("Warning: invalid search expression %v: %v", searchTerms, err)
+		return nil
+	}
 
+	var results []models.Article
 	for _, article := range articles {
-		for _, term := range searchTerms {
-			if a.articleContains(article, term) {
-				results = append(results, article)
-				break
-			}
+		if a.searchParser.Evaluate(searchExpr, article) {
+			results = append(results, article)
 		}
 	}
 
@@ -1213,18 +1607,18 @@ func (a *Aggregator) applySelectFields(articles []models.Article, selectedFields
 	return result
 }
 
-func (a *Aggregator) RefreshFeed(topic string) error {
+func (a *Aggregator) RefreshFeed(ctx context.Context, topic string) error {
 	// Remove from cache to force refresh
 	cacheKey := fmt.Sprintf("feed:%s", topic)
 	a.cacheManager.Delete(cacheKey)
 
 	// Fetch fresh data
-	_, err := a.GetAggregatedFeed(topic, nil)
+	_, err := a.GetAggregatedFeed(ctx, topic, nil)
 	return err
 }
 
-func (a *Aggregator) GetFeedInfo(topic string) (*models.FeedInfo, error) {
-	return a.storage.GetFeedInfo(topic)
+func (a *Aggregator) GetFeedInfo(ctx context.Context, topic string) (*models.FeedInfo, error) {
+	return a.storage.GetFeedInfo(ctx, topic)
 }
 
 type FeedResult struct {
@@ -1234,17 +1628,17 @@ type FeedResult struct {
 }
 
 // InitializeFeeds performs initial polling of all feeds to establish their status
-func (a *Aggregator) InitializeFeeds() {
+func (a *Aggregator) InitializeFeeds(ctx context.Context) {
 	log.Printf("Starting initial feed polling...")
 
-	for topic, topicConfig := range a.feeds {
+	for topic, topicConfig := range a.GetConfig() {
 		log.Printf("Initializing feeds for topic: %s", topic)
 
 		for _, url := range topicConfig.URLs {
 			log.Printf("Testing feed: %s", url)
 
 			// Test the feed to establish initial status
-			articles, err := a.fetchFeed(url, topic)
+			articles, err := a.fetchFeed(ctx, url, topic)
 			if err != nil {
 				log.Printf("Initial test failed for %s: %v", url, err)
 			} else {
@@ -1268,6 +1662,8 @@ type FeedHealth struct {
 
 // GetFeedHealth returns health status for all feeds
 func (a *Aggregator) GetFeedHealth() map[string][]FeedHealth {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 
 	health := make(map[string][]FeedHealth)
 
@@ -1327,13 +1723,29 @@ func (a *Aggregator) GetFeedHealth() map[string][]FeedHealth {
 }
 
 // GetStorageStats returns database statistics
-func (a *Aggregator) GetStorageStats() (map[string]interface{}, error) {
-	return a.storage.GetDatabaseStats()
+func (a *Aggregator) GetStorageStats(ctx context.Context) (map[string]interface{}, error) {
+	return a.storage.GetDatabaseStats(ctx)
+}
+
+// GetStorageHistory returns the stats snapshots taken since a time with a
+// linear forecast of disk usage horizon ahead
+func (a *Aggregator) GetStorageHistory(ctx context.Context, since time.Time, horizon time.Duration, capacity int64) ([]models.StatsSnapshot, *models.StorageForecast, error) {
+	snapshots, err := a.storage.GetStatsSnapshots(ctx, since)
+	if err != nil {
+		return nil, nil, err
+	}
+	return snapshots, storage.ForecastDiskUsage(snapshots, horizon, capacity), nil
 }
 
 // GetFeedStats returns detailed feed statistics
-func (a *Aggregator) GetFeedStats() (map[string]interface{}, error) {
-	return a.storage.GetFeedStats()
+func (a *Aggregator) GetFeedStats(ctx context.Context) (map[string]interface{}, error) {
+	return a.storage.GetFeedStats(ctx)
+}
+
+// SnapshotStorage writes a consistent snapshot of the storage to a temporary
+// file, returning its path and a function removing it
+func (a *Aggregator) SnapshotStorage(ctx context.Context) (string, func(), error) {
+	return storage.Snapshot(ctx, a.storage)
 }
 
 // getSpecificErrorReason provides detailed error explanations
@@ -1,14 +1,23 @@
 package aggregator
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"gorssag/internal/cache"
 	"gorssag/internal/config"
+	"gorssag/internal/events"
 	"gorssag/internal/models"
+	"gorssag/internal/notifier"
+	"gorssag/internal/odata"
 	"gorssag/internal/storage"
 
 	"github.com/mmcdole/gofeed"
@@ -34,7 +43,7 @@ func TestAggregator_GetAvailableTopics(t *testing.T) {
 		EnableDuplicateRemoval:   true,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -78,12 +87,12 @@ func TestAggregator_GetAggregatedFeed_InvalidTopic(t *testing.T) {
 		EnableDuplicateRemoval:   true,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
 
-	_, err := agg.GetAggregatedFeed("invalid-topic", nil)
+	_, err := agg.GetAggregatedFeed(context.Background(), "invalid-topic", nil)
 	if err == nil {
 		t.Error("Expected error for invalid topic, got nil")
 	}
@@ -105,7 +114,7 @@ func TestAggregator_ApplyODataQuery(t *testing.T) {
 		EnableDuplicateRemoval:   true,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -130,6 +139,136 @@ func TestAggregator_ApplyODataQuery(t *testing.T) {
 	}
 }
 
+func TestAggregator_ApplyODataQueryFacets(t *testing.T) {
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{MaxContentLength: 10000}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+
+	agg := New(cacheManager, storageManager, map[string]config.TopicConfig{})
+
+	day := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	feed := &models.AggregatedFeed{
+		Topic: "tech",
+		Articles: []models.Article{
+			{Title: "Go release", Source: "Go Blog", Categories: []string{"go", "go"}, PublishedAt: day},
+			{Title: "Go generics", Source: "Go Blog", Categories: []string{"go"}, Language: "fr", PublishedAt: day},
+			{Title: "Rust release", Source: "Rust Blog", Categories: []string{"rust"}, PublishedAt: day.Add(-24 * time.Hour)},
+		},
+	}
+
+	result, err := agg.applyODataQuery(feed, &models.ODataQuery{
+		Top:    1,
+		Facets: []string{"source", "category", "language", "topic", "published"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(result.Articles) != 1 {
+		t.Errorf("Expected 1 article on the page, got %d", len(result.Articles))
+	}
+
+	// Facets are computed before pagination
+	expected := models.Facets{
+		"source":    {{Value: "Go Blog", Count: 2}, {Value: "Rust Blog", Count: 1}},
+		"category":  {{Value: "go", Count: 2}, {Value: "rust", Count: 1}},
+		"language":  {{Value: "en", Count: 2}, {Value: "fr", Count: 1}},
+		"topic":     {{Value: "tech", Count: 3}},
+		"published": {{Value: "2024-03-09", Count: 1}, {Value: "2024-03-10", Count: 2}},
+	}
+	for facet, want := range expected {
+		if fmt.Sprint(result.Facets[facet]) != fmt.Sprint(want) {
+			t.Errorf("facet %s = %v, want %v", facet, result.Facets[facet], want)
+		}
+	}
+}
+
+func TestAggregator_ApplyODataQuerySkipToken(t *testing.T) {
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{MaxContentLength: 10000}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+
+	agg := New(cacheManager, storageManager, map[string]config.TopicConfig{})
+
+	now := time.Now()
+	feed := &models.AggregatedFeed{
+		Topic: "tech",
+		Articles: []models.Article{
+			{ID: "c", PublishedAt: now.Add(-2 * time.Hour)},
+			{ID: "a", PublishedAt: now},
+			{ID: "b", PublishedAt: now},
+			{ID: "d", PublishedAt: now.Add(-3 * time.Hour)},
+		},
+	}
+
+	var seen []string
+	query := &models.ODataQuery{Top: 3}
+	for {
+		result, err := agg.applyODataQuery(feed, query)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, article := range result.Articles {
+			seen = append(seen, article.ID)
+		}
+		if result.NextSkipToken == "" {
+			break
+		}
+
+		token, err := odata.DecodeSkipToken(result.NextSkipToken)
+		if err != nil {
+			t.Fatalf("Invalid next skip token: %v", err)
+		}
+		query = &models.ODataQuery{Top: 3, SkipToken: token}
+	}
+
+	if fmt.Sprint(seen) != "[b a c d]" {
+		t.Errorf("Expected keyset order [b a c d], got %v", seen)
+	}
+}
+
+func TestAggregator_ApplyODataQueryCluster(t *testing.T) {
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{MaxContentLength: 10000}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+
+	agg := New(cacheManager, storageManager, map[string]config.TopicConfig{})
+
+	now := time.Now()
+	feed := &models.AggregatedFeed{
+		Topic: "news",
+		Articles: []models.Article{
+			{ID: "npr", Source: "NPR", ClusterID: "bbc", PublishedAt: now},
+			{ID: "sports", Source: "Sports", ClusterID: "sports", PublishedAt: now.Add(-time.Hour)},
+			{ID: "bbc", Source: "BBC", ClusterID: "bbc", PublishedAt: now.Add(-2 * time.Hour)},
+			{ID: "legacy", Source: "Old", PublishedAt: now.Add(-3 * time.Hour)},
+		},
+	}
+
+	result, err := agg.applyODataQuery(feed, &models.ODataQuery{Cluster: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.Count != 3 {
+		t.Fatalf("Expected 3 clusters, got %d", result.Count)
+	}
+	if result.Articles[0].ID != "npr" || len(result.Articles[0].ClusterSources) != 1 || result.Articles[0].ClusterSources[0].Source != "BBC" {
+		t.Errorf("Expected npr with BBC attached, got %+v", result.Articles[0])
+	}
+
+	// The cached feed is left untouched
+	if len(feed.Articles) != 4 || len(feed.Articles[0].ClusterSources) != 0 {
+		t.Errorf("Expected source feed to be unchanged")
+	}
+}
+
 func TestAggregator_FetchFeedsParallel(t *testing.T) {
 	feeds := map[string]config.TopicConfig{
 		"tech": {
@@ -146,12 +285,12 @@ func TestAggregator_FetchFeedsParallel(t *testing.T) {
 		EnableDuplicateRemoval:   true,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
 
-	articles, err := agg.fetchFeedsParallel([]string{"http://example.com/tech1", "http://example.com/tech2"}, "tech")
+	articles, err := agg.fetchFeedsParallel(context.Background(), []string{"http://example.com/tech1", "http://example.com/tech2"}, "tech")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -178,7 +317,7 @@ func TestAggregator_SearchArticles(t *testing.T) {
 		EnableDuplicateRemoval:   true,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -231,7 +370,7 @@ func TestAggregator_ApplySelectFields(t *testing.T) {
 		EnableDuplicateRemoval:   true,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -284,7 +423,7 @@ func TestAggregator_ArticleMatchesFilters(t *testing.T) {
 		EnableDuplicateRemoval:   true,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -332,7 +471,7 @@ func TestAggregator_GetFeedHealth(t *testing.T) {
 		EnableDuplicateRemoval:   true,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -363,7 +502,7 @@ func TestAggregator_GetConfig(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -396,7 +535,7 @@ func TestAggregator_GetAllUniqueFeedURLs(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -439,7 +578,7 @@ func TestAggregator_GetTopicsForFeed(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -480,7 +619,7 @@ func TestAggregator_GetFeedStatus(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -513,7 +652,7 @@ func TestAggregator_UpdateFeedStatus(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -547,7 +686,7 @@ func TestAggregator_ShouldRetryFeed(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -587,19 +726,19 @@ func TestAggregator_RefreshFeed(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
 
 	// Test refresh with valid topic
-	err := agg.RefreshFeed("tech")
+	err := agg.RefreshFeed(context.Background(), "tech")
 	if err != nil {
 		t.Errorf("Expected no error for valid topic refresh, got %v", err)
 	}
 
 	// Test refresh with invalid topic
-	err = agg.RefreshFeed("invalid-topic")
+	err = agg.RefreshFeed(context.Background(), "invalid-topic")
 	if err == nil {
 		t.Error("Expected error for invalid topic refresh, got nil")
 	}
@@ -619,19 +758,19 @@ func TestAggregator_GetFeedInfo(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
 
 	// Test with valid topic - should return error since no feed data exists yet
-	_, err := agg.GetFeedInfo("tech")
+	_, err := agg.GetFeedInfo(context.Background(), "tech")
 	if err == nil {
 		t.Error("Expected error for topic with no feed data, got nil")
 	}
 
 	// Test with invalid topic
-	_, err = agg.GetFeedInfo("invalid-topic")
+	_, err = agg.GetFeedInfo(context.Background(), "invalid-topic")
 	if err == nil {
 		t.Error("Expected error for invalid topic, got nil")
 	}
@@ -651,13 +790,13 @@ func TestAggregator_InitializeFeeds(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
 
 	// This should not panic
-	agg.InitializeFeeds()
+	agg.InitializeFeeds(context.Background())
 }
 
 func TestAggregator_GetStorageStats(t *testing.T) {
@@ -674,12 +813,12 @@ func TestAggregator_GetStorageStats(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
 
-	stats, err := agg.GetStorageStats()
+	stats, err := agg.GetStorageStats(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error getting storage stats, got %v", err)
 	}
@@ -702,12 +841,12 @@ func TestAggregator_GetFeedStats(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
 
-	stats, err := agg.GetFeedStats()
+	stats, err := agg.GetFeedStats(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error getting feed stats, got %v", err)
 	}
@@ -730,7 +869,7 @@ func TestAggregator_SortArticles(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -789,7 +928,7 @@ func TestAggregator_ArticleContains(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -866,12 +1005,12 @@ func TestConvertHTMLToMarkdown(t *testing.T) {
 }
 
 func TestGenerateArticleID(t *testing.T) {
+	guid := "urn:example:test"
 	title := "Test Article"
 	link := "http://example.com/test"
-	publishedAt := time.Now()
 
-	id1 := generateArticleID(title, link, &publishedAt)
-	id2 := generateArticleID(title, link, &publishedAt)
+	id1 := generateArticleID(guid, link, title)
+	id2 := generateArticleID(guid, link, title)
 
 	// Should generate same deterministic IDs for same input
 	if id1 != id2 {
@@ -883,28 +1022,34 @@ func TestGenerateArticleID(t *testing.T) {
 		t.Errorf("Expected UUID format length 36, got %d", len(id1))
 	}
 
-	// Test with different input produces different ID
-	id3 := generateArticleID("Different Title", link, &publishedAt)
-	if id1 == id3 {
-		t.Error("Expected different IDs for different input")
+	// A corrected headline keeps the article identity
+	id3 := generateArticleID(guid, link, "Corrected Title")
+	if id1 != id3 {
+		t.Error("Expected the same ID for a changed title")
 	}
 
-	// Test with nil publishedAt
-	id4 := generateArticleID(title, link, nil)
-	if len(id4) != 36 {
-		t.Errorf("Expected UUID format length 36 for nil publishedAt, got %d", len(id4))
+	// The same link from another feed is the same article
+	id4 := generateArticleID("urn:other:test", link, title)
+	if id1 != id4 {
+		t.Error("Expected the same ID for the same link with another GUID")
 	}
 
 	// Test with different link produces different ID
-	id5 := generateArticleID(title, "http://different.com/test", &publishedAt)
+	id5 := generateArticleID(guid, "http://different.com/test", title)
 	if id1 == id5 {
 		t.Error("Expected different IDs for different link")
 	}
 
-	// Test deterministic behavior - same inputs should always produce same result
-	id6 := generateArticleID(title, link, &publishedAt)
-	if id1 != id6 {
-		t.Error("Expected deterministic behavior - same inputs should produce same result")
+	// Items without links are identified by their GUID, then their title
+	id6 := generateArticleID(guid, "", title)
+	if id6 != generateArticleID(guid, "", "Corrected Title") {
+		t.Error("Expected the same ID for a changed title of an item with a GUID")
+	}
+	if id6 == generateArticleID("urn:example:other", "", title) {
+		t.Error("Expected different IDs for different GUIDs")
+	}
+	if generateArticleID("", "", title) == generateArticleID("", "", "Different Title") {
+		t.Error("Expected different IDs for different titles without link and GUID")
 	}
 }
 
@@ -985,13 +1130,13 @@ func TestAggregator_TestUserAgentForFeed(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
 
 	// Test with invalid URL
-	_, err := agg.TestUserAgentForFeed("http://invalid-url-that-does-not-exist.com/feed")
+	_, err := agg.TestUserAgentForFeed(context.Background(), "http://invalid-url-that-does-not-exist.com/feed")
 	if err == nil {
 		t.Error("Expected error for invalid URL")
 	}
@@ -1011,7 +1156,7 @@ func TestAggregator_SetUserAgentForFeed(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -1040,7 +1185,7 @@ func TestAggregator_CalculateBackoff(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -1082,7 +1227,7 @@ func TestAggregator_GetSpecificErrorReason(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -1135,7 +1280,7 @@ func TestAggregator_FilterArticlesForTopic(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -1186,18 +1331,471 @@ func TestAggregator_PollAllFeeds(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
 
 	// Test polling all feeds (should handle errors gracefully)
-	err := agg.PollAllFeeds()
+	err := agg.PollAllFeeds(context.Background())
 	if err == nil {
 		t.Error("Expected error when polling invalid feeds")
 	}
 }
 
+func TestAggregator_DeleteSourceFeedWhilePolling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Example</title><item><title>Story %s</title><link>https://example.com%s</link><description>Description</description></item></channel></rss>`, r.URL.Path, r.URL.Path)
+	}))
+	defer server.Close()
+
+	storageManager := storage.NewMemoryStorage(&config.Config{MaxContentLength: 10000})
+	defer storageManager.Close()
+
+	ctx := context.Background()
+	var urls []string
+	for i := 0; i < 20; i++ {
+		url := fmt.Sprintf("%s/%d", server.URL, i)
+		urls = append(urls, url)
+		if err := storageManager.AssignArticlesToFeed(ctx, nil, url, "Example"); err != nil {
+			t.Fatalf("AssignArticlesToFeed() error = %v", err)
+		}
+	}
+	feeds := map[string]config.TopicConfig{
+		"tech": {URLs: urls},
+		"news": {URLs: urls[:10]},
+	}
+	agg := New(cache.NewManager(5*time.Minute), storageManager, feeds)
+
+	done := make(chan error)
+	go func() {
+		done <- agg.PollAllFeeds(ctx)
+	}()
+	for feedID := int64(1); feedID <= int64(len(urls)); feedID++ {
+		if _, err := agg.DeleteSourceFeed(ctx, feedID, true); err != nil {
+			t.Errorf("DeleteSourceFeed(%d) error = %v", feedID, err)
+		}
+		agg.GetConfig()
+		agg.GetFeedHealth()
+		agg.GetFeedStatus()
+	}
+	if err := <-done; err != nil {
+		t.Errorf("PollAllFeeds() error = %v", err)
+	}
+
+	if remaining := agg.GetAllUniqueFeedURLs(); len(remaining) != 0 {
+		t.Errorf("Expected every feed to be unsubscribed, got %v", remaining)
+	}
+}
+
+func TestAggregator_PollAllFeeds_Cancelled(t *testing.T) {
+	// The feed never answers, so only cancellation ends the poll
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	feeds := map[string]config.TopicConfig{
+		"tech": {URLs: []string{server.URL}},
+	}
+
+	cacheManager := cache.NewManager(5 * time.Minute)
+	storageManager := storage.NewMemoryStorage(&config.Config{})
+	defer storageManager.Close()
+
+	agg := New(cacheManager, storageManager, feeds)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := agg.PollAllFeeds(ctx)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected polling to stop on cancellation, took %v", elapsed)
+	}
+}
+
+// lookupFailingStorage fails the next GetArticles call
+type lookupFailingStorage struct {
+	storage.Storage
+	fail bool
+}
+
+func (s *lookupFailingStorage) GetArticles(ctx context.Context, ids []string) ([]models.Article, error) {
+	if s.fail {
+		s.fail = false
+		return nil, fmt.Errorf("database is locked")
+	}
+	return s.Storage.GetArticles(ctx, ids)
+}
+
+func TestAggregator_PollFeed_SkipsAnnouncingWhenLookupFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Example</title><item><title>First story</title><link>https://example.com/1</link><description>First description</description><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item></channel></rss>`)
+	}))
+	defer server.Close()
+
+	memoryStorage := storage.NewMemoryStorage(&config.Config{MaxContentLength: 10000})
+	defer memoryStorage.Close()
+	store := &lookupFailingStorage{Storage: memoryStorage}
+
+	agg := New(cache.NewManager(5*time.Minute), store, map[string]config.TopicConfig{"tech": {URLs: []string{server.URL}}})
+	sub, _ := agg.Events().Subscribe(0, false)
+	defer sub.Close()
+
+	if err := agg.PollFeed(context.Background(), server.URL); err != nil {
+		t.Fatalf("PollFeed() error = %v", err)
+	}
+	if len(sub.C) != 1 {
+		t.Fatalf("Expected the new article to be announced once, got %d events", len(sub.C))
+	}
+	<-sub.C
+
+	// A failed lookup must not announce the stored articles again
+	store.fail = true
+	if err := agg.PollFeed(context.Background(), server.URL); err != nil {
+		t.Fatalf("PollFeed() error = %v", err)
+	}
+	if len(sub.C) != 0 {
+		t.Errorf("Expected no events after a failed lookup, got %d", len(sub.C))
+	}
+
+	articles, _, err := memoryStorage.GetAllArticles(context.Background(), &models.ODataQuery{})
+	if err != nil || len(articles) != 1 {
+		t.Errorf("Expected the article to stay stored, got %d articles (err %v)", len(articles), err)
+	}
+}
+
+func TestAggregator_PollFeed_PublishesNewArticles(t *testing.T) {
+	items := `<item><title>First story</title><link>https://example.com/1</link><description>First description</description><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Example</title>%s</channel></rss>`, items)
+	}))
+	defer server.Close()
+
+	feeds := map[string]config.TopicConfig{
+		"tech": {URLs: []string{server.URL}},
+		"news": {URLs: []string{server.URL}, Filters: []string{"second"}},
+	}
+	storageManager := storage.NewMemoryStorage(&config.Config{MaxContentLength: 10000})
+	defer storageManager.Close()
+
+	var messages []string
+	chat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		messages = append(messages, string(body))
+	}))
+	defer chat.Close()
+
+	agg := New(cache.NewManager(5*time.Minute), storageManager, feeds)
+	sub, _ := agg.Events().Subscribe(0, false)
+	defer sub.Close()
+	chatNotifier := notifier.New(&config.Config{Notifiers: map[string][]config.NotifierTarget{
+		"tech": {{Kind: "slack", URL: chat.URL}},
+	}})
+	agg.SetNotifier(chatNotifier)
+
+	if err := agg.PollFeed(context.Background(), server.URL); err != nil {
+		t.Fatalf("PollFeed() error = %v", err)
+	}
+	items += `<item><title>Second story</title><link>https://example.com/2</link><description>Second description</description><pubDate>Tue, 03 Jan 2006 15:04:05 GMT</pubDate></item>`
+	if err := agg.PollFeed(context.Background(), server.URL); err != nil {
+		t.Fatalf("PollFeed() error = %v", err)
+	}
+
+	// Known articles are not announced again
+	var published []events.Event
+	for len(sub.C) > 0 {
+		published = append(published, <-sub.C)
+	}
+	if len(published) != 2 || published[0].Article.Title != "First story" || published[1].Article.Title != "Second story" {
+		t.Fatalf("Expected the first then the second story, got %+v", published)
+	}
+	if strings.Join(published[0].Topics, ",") != "tech" || strings.Join(published[1].Topics, ",") != "news,tech" {
+		t.Errorf("Unexpected topics %v and %v", published[0].Topics, published[1].Topics)
+	}
+	if len(published[1].Article.FeedURLs) != 1 || published[1].Article.FeedURLs[0] != server.URL {
+		t.Errorf("Expected the source feed URL, got %v", published[1].Article.FeedURLs)
+	}
+
+	// Both polls are batched in one message to the tech channel
+	if sent := chatNotifier.Flush(context.Background()); sent != 1 || len(messages) != 1 {
+		t.Fatalf("Expected 1 chat message, got %d: %v", sent, messages)
+	}
+	if !strings.Contains(messages[0], "2 new articles in tech") || !strings.Contains(messages[0], "Second story") {
+		t.Errorf("Unexpected chat message %s", messages[0])
+	}
+}
+
+func TestAggregator_PollFeed_RecordsRevisions(t *testing.T) {
+	cfg := &config.Config{MaxContentLength: 10000}
+	stores := map[string]func(t *testing.T) storage.Storage{
+		"memory": func(t *testing.T) storage.Storage { return storage.NewMemoryStorage(cfg) },
+		"sqlite": func(t *testing.T) storage.Storage {
+			store, err := storage.NewSQLiteStorage(t.TempDir(), cfg)
+			if err != nil {
+				t.Fatalf("NewSQLiteStorage() error = %v", err)
+			}
+			return store
+		},
+	}
+
+	for name, newStorage := range stores {
+		t.Run(name, func(t *testing.T) {
+			title := "Storm heads north"
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/rss+xml")
+				fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Wire</title><item><title>%s</title><link>https://example.com/story</link><description>Forecast</description></item></channel></rss>`, title)
+			}))
+			defer server.Close()
+
+			storageManager := newStorage(t)
+			defer storageManager.Close()
+			agg := New(cache.NewManager(5*time.Minute), storageManager, map[string]config.TopicConfig{
+				"news": {URLs: []string{server.URL}},
+			})
+
+			ctx := context.Background()
+			if err := agg.PollFeed(ctx, server.URL); err != nil {
+				t.Fatalf("PollFeed() error = %v", err)
+			}
+			title = "Storm heads south"
+			if err := agg.PollFeed(ctx, server.URL); err != nil {
+				t.Fatalf("PollFeed() error = %v", err)
+			}
+
+			// The corrected headline updates the article and keeps the replaced one
+			articles, _, err := agg.GetAllArticles(ctx, &models.ODataQuery{})
+			if err != nil || len(articles) != 1 {
+				t.Fatalf("GetAllArticles() = %v, %v, want 1 article", articles, err)
+			}
+			if articles[0].Title != "Storm heads south" {
+				t.Errorf("Expected the corrected title, got %q", articles[0].Title)
+			}
+			revisions, err := agg.GetArticleRevisions(ctx, articles[0].ID)
+			if err != nil {
+				t.Fatalf("GetArticleRevisions() error = %v", err)
+			}
+			if len(revisions) != 1 || revisions[0].Title != "Storm heads north" {
+				t.Errorf("Expected one revision of the original title, got %+v", revisions)
+			}
+		})
+	}
+}
+
+func TestAggregator_PollFeed_KeepsLegacyArticleIDs(t *testing.T) {
+	cfg := &config.Config{MaxContentLength: 10000}
+	stores := map[string]func(t *testing.T) storage.Storage{
+		"memory": func(t *testing.T) storage.Storage { return storage.NewMemoryStorage(cfg) },
+		"sqlite": func(t *testing.T) storage.Storage {
+			store, err := storage.NewSQLiteStorage(t.TempDir(), cfg)
+			if err != nil {
+				t.Fatalf("NewSQLiteStorage() error = %v", err)
+			}
+			return store
+		},
+	}
+
+	for name, newStorage := range stores {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/rss+xml")
+				fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Wire</title>`+
+					`<item><title>Storm heads north</title><link>https://example.com/story</link><guid>story-1</guid><description>Forecast</description></item>`+
+					`<item><title>Tides at noon</title><description>Harbour notice</description></item>`+
+					`</channel></rss>`)
+			}))
+			defer server.Close()
+
+			storageManager := newStorage(t)
+			defer storageManager.Close()
+
+			// Articles stored before IDs followed the link hashed title and link
+			ctx := context.Background()
+			linkedID := legacyArticleID("Storm heads north", "https://example.com/story")
+			linklessID := legacyArticleID("Tides at noon", "")
+			if err := storageManager.SaveArticles(ctx, []models.Article{
+				{ID: linkedID, Title: "Storm heads north", Link: "https://example.com/story", Description: "Forecast", Source: "Wire", PublishedAt: time.Now()},
+				{ID: linklessID, Title: "Tides at noon", Description: "Harbour notice", Source: "Wire", PublishedAt: time.Now()},
+			}); err != nil {
+				t.Fatalf("SaveArticles() error = %v", err)
+			}
+			if err := storageManager.AssignArticlesToTopic(ctx, []string{linkedID, linklessID}, "news"); err != nil {
+				t.Fatalf("AssignArticlesToTopic() error = %v", err)
+			}
+			if err := storageManager.SetArticlePinned(ctx, linkedID, true); err != nil {
+				t.Fatalf("SetArticlePinned() error = %v", err)
+			}
+
+			agg := New(cache.NewManager(5*time.Minute), storageManager, map[string]config.TopicConfig{
+				"news": {URLs: []string{server.URL}},
+			})
+			sub, _ := agg.Events().Subscribe(0, false)
+			defer sub.Close()
+			if err := agg.PollFeed(ctx, server.URL); err != nil {
+				t.Fatalf("PollFeed() error = %v", err)
+			}
+
+			// The polled items update the stored articles instead of adding new ones
+			if len(sub.C) != 0 {
+				t.Errorf("Expected no events for stored articles, got %d", len(sub.C))
+			}
+			articles, _, err := agg.GetAllArticles(ctx, &models.ODataQuery{})
+			if err != nil || len(articles) != 2 {
+				t.Fatalf("GetAllArticles() = %v, %v, want 2 articles", articles, err)
+			}
+			byID := make(map[string]models.Article)
+			for _, article := range articles {
+				byID[article.ID] = article
+			}
+			if article, ok := byID[linkedID]; !ok || !article.Pinned {
+				t.Errorf("Expected the linked article to keep its ID and pin, got %+v", articles)
+			}
+			if _, ok := byID[linklessID]; !ok {
+				t.Errorf("Expected the article without link to keep its ID, got %+v", articles)
+			}
+		})
+	}
+}
+
+func TestAggregator_ExportImportArticles(t *testing.T) {
+	ctx := context.Background()
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{MaxContentLength: 10000}
+
+	source := storage.NewMemoryStorage(cfg)
+	defer source.Close()
+
+	now := time.Now().Truncate(time.Second)
+	articles := []models.Article{
+		{ID: "a1", Title: "Newest", Link: "https://example.com/a1", Source: "Example", Language: "fr", PublishedAt: now},
+		{ID: "a2", Title: "Middle", Link: "https://example.com/a2", Source: "Example", PublishedAt: now.Add(-time.Hour)},
+		{ID: "a3", Title: "Oldest", Link: "https://example.com/a3", Source: "Example", PublishedAt: now.Add(-48 * time.Hour)},
+	}
+	if err := source.SaveArticles(ctx, articles); err != nil {
+		t.Fatalf("SaveArticles() error = %v", err)
+	}
+	if err := source.AssignArticlesToTopic(ctx, []string{"a1", "a2", "a3"}, "tech"); err != nil {
+		t.Fatalf("AssignArticlesToTopic() error = %v", err)
+	}
+	if err := source.AssignArticlesToTopic(ctx, []string{"a1"}, "news"); err != nil {
+		t.Fatalf("AssignArticlesToTopic() error = %v", err)
+	}
+
+	exporter := New(cacheManager, source, nil)
+	var exported []models.ExportedArticle
+	collect := func(article models.ExportedArticle) error {
+		exported = append(exported, article)
+		return nil
+	}
+
+	since := now.Add(-24 * time.Hour)
+	if err := exporter.ExportArticles(ctx, "tech", &since, nil, collect); err != nil {
+		t.Fatalf("ExportArticles() error = %v", err)
+	}
+	if len(exported) != 2 || exported[0].ID != "a1" || exported[1].ID != "a2" {
+		t.Fatalf("Expected a1 and a2 since yesterday, got %v", exported)
+	}
+	if strings.Join(exported[0].Topics, ",") != "news,tech" || exported[0].Language != "fr" {
+		t.Errorf("Expected topics and language to be exported, got %v %q", exported[0].Topics, exported[0].Language)
+	}
+
+	// Resuming after the first line skips it
+	first := exported[0]
+	exported = nil
+	cursor, err := odata.DecodeSkipToken(first.Cursor)
+	if err != nil {
+		t.Fatalf("DecodeSkipToken() error = %v", err)
+	}
+	if err := exporter.ExportArticles(ctx, "", nil, cursor, collect); err != nil {
+		t.Fatalf("ExportArticles() error = %v", err)
+	}
+	if len(exported) != 2 || exported[0].ID != "a2" || exported[1].ID != "a3" {
+		t.Fatalf("Expected a2 and a3 after the cursor, got %v", exported)
+	}
+
+	// Import the full export with a repeated line into another storage
+	var ndjson strings.Builder
+	exported = append([]models.ExportedArticle{first}, exported...)
+	for _, article := range append(exported, first) {
+		line, _ := json.Marshal(article)
+		ndjson.Write(append(line, '\n'))
+	}
+
+	target := storage.NewMemoryStorage(cfg)
+	defer target.Close()
+	importer := New(cacheManager, target, nil)
+	if err := target.CreateWebhook(ctx, &models.Webhook{URL: "https://hooks.example.com/tech", Topic: "tech", Secret: "s1"}); err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+
+	result, err := importer.ImportArticles(ctx, strings.NewReader(ndjson.String()))
+	if err != nil {
+		t.Fatalf("ImportArticles() error = %v", err)
+	}
+	if result.Lines != 4 || result.Imported != 3 || result.Duplicates != 1 {
+		t.Errorf("Unexpected import result: %+v", result)
+	}
+
+	topics, err := target.GetArticleTopics(ctx, "a1")
+	if err != nil {
+		t.Fatalf("GetArticleTopics() error = %v", err)
+	}
+	if strings.Join(topics, ",") != "news,tech" {
+		t.Errorf("Expected imported memberships news,tech, got %v", topics)
+	}
+	imported, _, err := target.GetAllArticles(ctx, &models.ODataQuery{})
+	if err != nil {
+		t.Fatalf("GetAllArticles() error = %v", err)
+	}
+	if len(imported) != 3 || imported[0].Language != "fr" {
+		t.Errorf("Expected 3 imported articles keeping their language, got %v", imported)
+	}
+
+	// Re-importing a changed export leaves the stored articles alone
+	var changed strings.Builder
+	for _, article := range exported {
+		article.Title = "Changed " + article.Title
+		line, _ := json.Marshal(article)
+		changed.Write(append(line, '\n'))
+	}
+	result, err = importer.ImportArticles(ctx, strings.NewReader(changed.String()))
+	if err != nil {
+		t.Fatalf("ImportArticles() error = %v", err)
+	}
+	if result.Lines != 3 || result.Imported != 0 || result.Existing != 3 {
+		t.Errorf("Unexpected re-import result: %+v", result)
+	}
+	stored, err := target.GetArticles(ctx, []string{"a1"})
+	if err != nil || len(stored) != 1 || stored[0].Title != "Newest" {
+		t.Errorf("Expected a1 to keep its title, got %v, %v", stored, err)
+	}
+	if revisions, err := target.GetArticleRevisions(ctx, "a1"); err != nil || len(revisions) != 0 {
+		t.Errorf("Expected no revisions after re-import, got %v, %v", revisions, err)
+	}
+
+	// Imports queue no webhook deliveries
+	if due, err := target.GetDueWebhookDeliveries(ctx, time.Now().Add(time.Minute), 100); err != nil || len(due) != 0 {
+		t.Errorf("Expected no webhook deliveries, got %d, %v", len(due), err)
+	}
+
+	// Invalid lines stop the import and report the committed lines
+	result, err = importer.ImportArticles(ctx, strings.NewReader(ndjson.String()+"{\"title\":\"no id\"}\n"))
+	if !errors.Is(err, ErrInvalidImport) {
+		t.Errorf("Expected ErrInvalidImport, got %v", err)
+	}
+	if result.Lines != 0 {
+		t.Errorf("Expected no committed lines, got %d", result.Lines)
+	}
+}
+
 func TestAggregator_ApplyODataQueryWithFilter(t *testing.T) {
 	feeds := map[string]config.TopicConfig{
 		"tech": {
@@ -1212,7 +1810,7 @@ func TestAggregator_ApplyODataQueryWithFilter(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -1251,7 +1849,7 @@ func TestAggregator_ApplyODataQueryWithPagination(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -1318,7 +1916,7 @@ func TestAggregator_ApplyODataQueryWithSearch(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -1360,7 +1958,7 @@ func TestAggregator_GetFeedHealthWithErrors(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -1395,7 +1993,7 @@ func TestAggregator_UpdateFeedStatusWithContentIssue(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -1427,7 +2025,7 @@ func TestAggregator_UpdateFeedStatusWithManyErrors(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -1461,7 +2059,7 @@ func TestAggregator_UpdateFeedStatusSuccess(t *testing.T) {
 		MaxContentLength:         10000,
 		EnableDuplicateRemoval:   true,
 	}
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := New(cacheManager, storageManager, feeds)
@@ -0,0 +1,34 @@
+package aggregator
+
+import "gorssag/internal/models"
+
+// collapseClusters keeps the first article of each story cluster, in the
+// current order, and attaches the other articles of the cluster to it
+func collapseClusters(articles []models.Article) []models.Article {
+	var collapsed []models.Article
+	representatives := make(map[string]int)
+
+	for _, article := range articles {
+		clusterID := article.ClusterID
+		if clusterID == "" {
+			clusterID = article.ID
+		}
+
+		if idx, ok := representatives[clusterID]; ok {
+			collapsed[idx].ClusterSources = append(collapsed[idx].ClusterSources, models.ClusterSource{
+				ID:          article.ID,
+				Title:       article.Title,
+				Link:        article.Link,
+				Source:      article.Source,
+				PublishedAt: article.PublishedAt,
+			})
+			continue
+		}
+
+		article.ClusterSources = nil
+		representatives[clusterID] = len(collapsed)
+		collapsed = append(collapsed, article)
+	}
+
+	return collapsed
+}
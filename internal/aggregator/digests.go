@@ -0,0 +1,93 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"gorssag/internal/digest"
+	"gorssag/internal/models"
+)
+
+// Articles listed per digest
+const (
+	defaultDigestArticles = 20
+	maxDigestArticles     = 200
+)
+
+// ErrInvalidDigest is returned when a digest subscription is incomplete or malformed
+var ErrInvalidDigest = errors.New("invalid digest")
+
+// CreateDigest validates and stores a digest subscription. Its first digest
+// covers the articles assigned to the topic from now on.
+func (a *Aggregator) CreateDigest(ctx context.Context, d *models.Digest) error {
+	if _, err := mail.ParseAddress(d.Recipient); err != nil {
+		return fmt.Errorf("%w: invalid recipient: %v", ErrInvalidDigest, err)
+	}
+	if _, ok := a.getTopicConfig(d.Topic); !ok {
+		return fmt.Errorf("%w: unknown topic %q", ErrInvalidDigest, d.Topic)
+	}
+	if d.Filter != "" {
+		if _, err := a.filterParser.Parse(d.Filter); err != nil {
+			return fmt.Errorf("%w: invalid filter expression: %v", ErrInvalidDigest, err)
+		}
+	}
+	schedule, err := digest.ParseSchedule(d.Schedule)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDigest, err)
+	}
+	if _, err := digest.ParseTemplates(d); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDigest, err)
+	}
+	if d.MaxArticles == 0 {
+		d.MaxArticles = defaultDigestArticles
+	}
+	if d.MaxArticles < 0 || d.MaxArticles > maxDigestArticles {
+		return fmt.Errorf("%w: max_articles must be between 1 and %d", ErrInvalidDigest, maxDigestArticles)
+	}
+
+	_, cursor, err := a.storage.GetTopicArrivals(ctx, d.Topic, -1)
+	if err != nil {
+		return err
+	}
+	d.Cursor = cursor
+	d.NextSendAt = schedule.Next(time.Now())
+	d.LastSentAt = nil
+	d.LastError = ""
+	return a.storage.CreateDigest(ctx, d)
+}
+
+// ListDigests returns all digest subscriptions
+func (a *Aggregator) ListDigests(ctx context.Context) ([]models.Digest, error) {
+	return a.storage.ListDigests(ctx)
+}
+
+// GetDigest returns a digest subscription by ID
+func (a *Aggregator) GetDigest(ctx context.Context, id int64) (*models.Digest, error) {
+	return a.storage.GetDigest(ctx, id)
+}
+
+// DeleteDigest deletes a digest subscription
+func (a *Aggregator) DeleteDigest(ctx context.Context, id int64) error {
+	return a.storage.DeleteDigest(ctx, id)
+}
+
+// PreviewDigest renders the digest that would be sent now, without sending
+// it or moving its cursor
+func (a *Aggregator) PreviewDigest(ctx context.Context, id int64) (subject, text, html string, err error) {
+	d, err := a.storage.GetDigest(ctx, id)
+	if err != nil {
+		return "", "", "", err
+	}
+	templates, err := digest.ParseTemplates(d)
+	if err != nil {
+		return "", "", "", err
+	}
+	data, _, err := digest.Compose(ctx, a.storage, d, time.Now().UTC())
+	if err != nil {
+		return "", "", "", err
+	}
+	return templates.Render(data)
+}
@@ -0,0 +1,190 @@
+package aggregator
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+)
+
+// exportBatchSize is the number of articles read from storage at once by
+// exports and written to it at once by imports
+const exportBatchSize = 500
+
+// ErrInvalidImport is returned when an import line is not an exported article
+var ErrInvalidImport = errors.New("invalid import")
+
+// maxImportLineSize bounds a single NDJSON line, content included
+const maxImportLineSize = 16 << 20
+
+// ExportArticles calls fn for every article of topic (every article when
+// topic is empty) published at or after since, newest first, reading storage
+// in batches. The export resumes after cursor when it is set.
+func (a *Aggregator) ExportArticles(ctx context.Context, topic string, since *time.Time, cursor *models.SkipToken, fn func(models.ExportedArticle) error) error {
+	for {
+		query := &models.ODataQuery{Top: exportBatchSize, DateFrom: since, SkipToken: cursor}
+
+		var articles []models.Article
+		var err error
+		if topic != "" {
+			articles, _, err = a.storage.GetTopicArticles(ctx, topic, query)
+		} else {
+			articles, _, err = a.storage.GetAllArticles(ctx, query)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read articles: %v", err)
+		}
+
+		for _, article := range articles {
+			topics, err := a.storage.GetArticleTopics(ctx, article.ID)
+			if err != nil {
+				return fmt.Errorf("failed to read topics of article %s: %v", article.ID, err)
+			}
+
+			// Memberships are carried by Topics
+			article.Topic = ""
+			if err := fn(models.ExportedArticle{
+				Article: article,
+				Topics:  topics,
+				Cursor:  odata.EncodeSkipToken(article),
+			}); err != nil {
+				return err
+			}
+		}
+
+		if len(articles) < exportBatchSize {
+			return nil
+		}
+		last := articles[len(articles)-1]
+		cursor = &models.SkipToken{PublishedAt: last.PublishedAt, ID: last.ID}
+	}
+}
+
+// ImportArticles reads an NDJSON export from r and stores it in batches
+// through SaveArticles and RestoreArticlesToTopic, so imports queue no webhook
+// deliveries. Articles repeated in the stream are imported once and articles
+// already stored are left untouched, so an interrupted import resumes by
+// sending the lines after result.Lines again.
+func (a *Aggregator) ImportArticles(ctx context.Context, r io.Reader) (*models.ImportResult, error) {
+	result := &models.ImportResult{}
+	seen := make(map[string]bool)
+	touched := make(map[string]bool)
+
+	// Cached feeds of the imported topics are stale, even after a partial import
+	defer func() {
+		for topic := range touched {
+			a.cacheManager.Delete(fmt.Sprintf("feed:%s", topic))
+		}
+	}()
+
+	var batch []models.Article
+	memberships := make(map[string][]string)
+	pending := 0
+
+	flush := func() error {
+		// Stored articles are neither overwritten nor given new topics
+		existing := make(map[string]bool)
+		if len(batch) > 0 {
+			articleIDs := make([]string, len(batch))
+			for i, article := range batch {
+				articleIDs[i] = article.ID
+			}
+			stored, err := a.storage.GetArticles(ctx, articleIDs)
+			if err != nil {
+				return fmt.Errorf("failed to look up stored articles: %v", err)
+			}
+			for _, article := range stored {
+				existing[article.ID] = true
+			}
+		}
+
+		var articles []models.Article
+		for _, article := range batch {
+			if !existing[article.ID] {
+				articles = append(articles, article)
+			}
+		}
+		if len(articles) > 0 {
+			if err := a.storage.SaveArticles(ctx, articles); err != nil {
+				return fmt.Errorf("failed to save articles: %v", err)
+			}
+		}
+		for topic, ids := range memberships {
+			var newIDs []string
+			for _, id := range ids {
+				if !existing[id] {
+					newIDs = append(newIDs, id)
+				}
+			}
+			if len(newIDs) == 0 {
+				continue
+			}
+			if err := a.storage.RestoreArticlesToTopic(ctx, newIDs, topic); err != nil {
+				return fmt.Errorf("failed to assign articles to topic %s: %v", topic, err)
+			}
+			touched[topic] = true
+		}
+
+		result.Lines += pending
+		result.Imported += len(articles)
+		result.Existing += len(existing)
+		batch = nil
+		memberships = make(map[string][]string)
+		pending = 0
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		pending++
+		if len(line) == 0 {
+			continue
+		}
+
+		var record models.ExportedArticle
+		if err := json.Unmarshal(line, &record); err != nil {
+			return result, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, result.Lines+pending, err)
+		}
+		if record.ID == "" {
+			return result, fmt.Errorf("%w: line %d: missing article id", ErrInvalidImport, result.Lines+pending)
+		}
+		if seen[record.ID] {
+			result.Duplicates++
+			continue
+		}
+		seen[record.ID] = true
+
+		article := record.Article
+		article.Topic = ""
+		article.ClusterID = ""
+		article.ClusterSources = nil
+		batch = append(batch, article)
+		for _, topic := range record.Topics {
+			memberships[topic] = append(memberships[topic], article.ID)
+		}
+
+		if len(batch) >= exportBatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, result.Lines+pending+1, err)
+	}
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	log.Printf("Imported %d articles from %d lines (%d duplicates, %d already stored)", result.Imported, result.Lines, result.Duplicates, result.Existing)
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package aggregator
+
+import (
+	"context"
+	"sort"
+
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+)
+
+// GetArticleFacets returns facet counts over all stored articles matching the query
+func (a *Aggregator) GetArticleFacets(ctx context.Context, query *models.ODataQuery) (models.Facets, error) {
+	return a.storage.GetArticleFacets(ctx, query)
+}
+
+// computeFacets counts facet values over an in-memory result set; articles
+// without a topic are counted under the feed's topic
+func computeFacets(articles []models.Article, facets []string, topic string) models.Facets {
+	result := make(models.Facets)
+
+	for _, facet := range facets {
+		counts := make(map[string]int)
+		for _, article := range articles {
+			for _, value := range facetValues(facet, article, topic) {
+				if value != "" {
+					counts[value]++
+				}
+			}
+		}
+
+		values := make([]models.FacetCount, 0, len(counts))
+		for value, count := range counts {
+			values = append(values, models.FacetCount{Value: value, Count: count})
+		}
+
+		// The date histogram is returned in full and in chronological order
+		if facet == "published" {
+			sort.Slice(values, func(i, j int) bool { return values[i].Value < values[j].Value })
+		} else {
+			sort.Slice(values, func(i, j int) bool {
+				if values[i].Count != values[j].Count {
+					return values[i].Count > values[j].Count
+				}
+				return values[i].Value < values[j].Value
+			})
+			if len(values) > odata.FacetLimit {
+				values = values[:odata.FacetLimit]
+			}
+		}
+
+		result[facet] = values
+	}
+
+	return result
+}
+
+func facetValues(facet string, article models.Article, topic string) []string {
+	switch facet {
+	case "source":
+		return []string{article.Source}
+	case "author":
+		return []string{article.Author}
+	case "language":
+		if article.Language == "" {
+			return []string{"en"}
+		}
+		return []string{article.Language}
+	case "category":
+		// Count each category once per article
+		seen := make(map[string]bool)
+		var values []string
+		for _, category := range article.Categories {
+			if !seen[category] {
+				seen[category] = true
+				values = append(values, category)
+			}
+		}
+		return values
+	case "topic":
+		if article.Topic != "" {
+			return []string{article.Topic}
+		}
+		return []string{topic}
+	case "published":
+		if article.PublishedAt.IsZero() {
+			return nil
+		}
+		return []string{article.PublishedAt.Format("2006-01-02")}
+	default:
+		return nil
+	}
+}
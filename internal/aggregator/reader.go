@@ -0,0 +1,79 @@
+package aggregator
+
+import (
+	"context"
+
+	"gorssag/internal/models"
+)
+
+// markReadBatch bounds the items marked read per storage call
+const markReadBatch = 500
+
+// GetReaderSubscriptions returns the configured feeds with their topics and
+// the title of their last poll, by URL
+func (a *Aggregator) GetReaderSubscriptions(ctx context.Context) ([]models.ReaderSubscription, error) {
+	stats, err := a.storage.GetFeedStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[string]string)
+	if feeds, ok := stats["feed_urls"].([]map[string]interface{}); ok {
+		for _, feed := range feeds {
+			url, _ := feed["url"].(string)
+			title, _ := feed["title"].(string)
+			titles[url] = title
+		}
+	}
+
+	urls := a.GetAllUniqueFeedURLs()
+	subscriptions := make([]models.ReaderSubscription, 0, len(urls))
+	for _, url := range urls {
+		title := titles[url]
+		if title == "" {
+			title = url
+		}
+		subscriptions = append(subscriptions, models.ReaderSubscription{URL: url, Title: title, Topics: a.GetTopicsForFeed(url)})
+	}
+	return subscriptions, nil
+}
+
+// GetReaderItemRefs returns the items of a Google Reader stream
+func (a *Aggregator) GetReaderItemRefs(ctx context.Context, query *models.ReaderQuery) ([]models.ReaderItemRef, error) {
+	return a.storage.GetReaderItemRefs(ctx, query)
+}
+
+// GetReaderItems returns Google Reader items by ID
+func (a *Aggregator) GetReaderItems(ctx context.Context, ids []int64) ([]models.ReaderItem, error) {
+	return a.storage.GetReaderItems(ctx, ids)
+}
+
+// SetReaderItemState sets or clears the read or starred state of items
+func (a *Aggregator) SetReaderItemState(ctx context.Context, ids []int64, state string, on bool) error {
+	return a.storage.SetReaderItemState(ctx, ids, state, on)
+}
+
+// MarkReaderStreamRead marks the unread items of a stream read and returns
+// how many there were
+func (a *Aggregator) MarkReaderStreamRead(ctx context.Context, query models.ReaderQuery) (int, error) {
+	query.Unread = true
+	query.Limit, query.Offset = markReadBatch, 0
+
+	marked := 0
+	for {
+		refs, err := a.storage.GetReaderItemRefs(ctx, &query)
+		if err != nil {
+			return marked, err
+		}
+		ids := make([]int64, len(refs))
+		for i, ref := range refs {
+			ids[i] = ref.ID
+		}
+		if err := a.storage.SetReaderItemState(ctx, ids, models.ReaderStateRead, true); err != nil {
+			return marked, err
+		}
+		marked += len(ids)
+		if len(refs) < markReadBatch {
+			return marked, nil
+		}
+	}
+}
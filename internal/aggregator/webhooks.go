@@ -0,0 +1,90 @@
+package aggregator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"gorssag/internal/models"
+	"gorssag/internal/storage"
+)
+
+// ErrInvalidWebhook is returned when a webhook subscription is incomplete or malformed
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// CreateWebhook validates and stores a webhook subscription. A secret is
+// generated when none is given.
+func (a *Aggregator) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	target, err := url.Parse(webhook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if webhook.Topic == "" {
+		return fmt.Errorf("%w: topic is required", ErrInvalidWebhook)
+	}
+	if _, ok := a.getTopicConfig(webhook.Topic); !ok {
+		return fmt.Errorf("%w: unknown topic %s", ErrInvalidWebhook, webhook.Topic)
+	}
+	if webhook.Filter != "" {
+		if _, err := a.filterParser.Parse(webhook.Filter); err != nil {
+			return fmt.Errorf("%w: invalid filter expression: %v", ErrInvalidWebhook, err)
+		}
+	}
+
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return fmt.Errorf("failed to generate webhook secret: %v", err)
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+
+	return a.storage.CreateWebhook(ctx, webhook)
+}
+
+// ListWebhooks returns all webhook subscriptions, oldest first
+func (a *Aggregator) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	return a.storage.ListWebhooks(ctx)
+}
+
+// GetWebhook returns a webhook subscription by ID
+func (a *Aggregator) GetWebhook(ctx context.Context, id int64) (*models.Webhook, error) {
+	return a.storage.GetWebhook(ctx, id)
+}
+
+// DeleteWebhook deletes a webhook subscription with its delivery log
+func (a *Aggregator) DeleteWebhook(ctx context.Context, id int64) error {
+	return a.storage.DeleteWebhook(ctx, id)
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook, newest first
+func (a *Aggregator) GetWebhookDeliveries(ctx context.Context, webhookID int64, status string, limit int) ([]models.WebhookDelivery, error) {
+	return a.storage.GetWebhookDeliveries(ctx, webhookID, status, limit)
+}
+
+// RetryWebhookDelivery queues a delivery of a webhook again with a fresh
+// set of attempts, typically to revive a dead one
+func (a *Aggregator) RetryWebhookDelivery(ctx context.Context, webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	delivery, err := a.storage.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.WebhookID != webhookID {
+		return nil, storage.ErrNotFound
+	}
+	if delivery.Status == models.WebhookDeliveryDelivered {
+		return nil, fmt.Errorf("%w: delivery %d was already delivered", ErrInvalidWebhook, deliveryID)
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now().UTC()
+	if err := a.storage.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorssag/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireAdmin only lets requests carrying the configured ADMIN_TOKEN as a
+// bearer token through. Admin endpoints are disabled without a token.
+func (s *Server) requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.config.AdminToken == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin API is disabled, set ADMIN_TOKEN to enable it"})
+			return
+		}
+
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// createBackup streams a gzip compressed snapshot of the database
+func (s *Server) createBackup(c *gin.Context) {
+	path, cleanup, err := s.aggregator.SnapshotStorage(c.Request.Context())
+	if errors.Is(err, storage.ErrBackupUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer cleanup()
+
+	filename := fmt.Sprintf("rss_aggregator-%s.db.gz", time.Now().UTC().Format("20060102T150405Z"))
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// Headers are sent by now, so failures can only be logged
+	if err := storage.CompressFile(path, c.Writer); err != nil {
+		log.Printf("Warning: failed to stream backup: %v", err)
+	}
+}
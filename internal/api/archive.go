@@ -0,0 +1,50 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+	"gorssag/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getArchivedArticles answers an $archive=true article query from the cold
+// archive tier instead of the database
+func (s *Server) getArchivedArticles(c *gin.Context, query *models.ODataQuery, topic string) {
+	archive := storage.NewArchive(storage.ArchiveDir(s.config.DataDir))
+	articles, totalCount, err := archive.Query(c.Request.Context(), topic, query)
+	if errors.Is(err, storage.ErrInvalidArchiveQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying archived articles: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve archived articles"})
+		return
+	}
+
+	hasMore := (query.Skip + len(articles)) < totalCount
+	if query.SkipToken != nil {
+		// Offsets do not apply to cursor pages, a full page may have a successor
+		hasMore = query.Top > 0 && len(articles) == query.Top
+	}
+
+	response := gin.H{
+		"articles":    articles,
+		"count":       len(articles),
+		"total_count": totalCount,
+		"skip":        query.Skip,
+		"top":         query.Top,
+		"has_more":    hasMore,
+		"archive":     true,
+	}
+	if hasMore && len(articles) > 0 {
+		response["next_skiptoken"] = odata.EncodeSkipToken(articles[len(articles)-1])
+	}
+
+	c.JSON(http.StatusOK, response)
+}
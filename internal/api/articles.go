@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"gorssag/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBatchArticles bounds the number of IDs of a batch article request
+const maxBatchArticles = 100
+
+// getArticle returns a single article with its full content, topics and source feed
+func (s *Server) getArticle(c *gin.Context) {
+	articleID := c.Param("id")
+
+	article, err := s.aggregator.GetArticle(c.Request.Context(), articleID)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("article '%s' not found", articleID)})
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting article %s: %v", articleID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve article"})
+		return
+	}
+
+	c.JSON(http.StatusOK, article)
+}
+
+// getArticlesBatch returns the articles listed in a JSON body {"ids": [...]},
+// in the requested order, and the IDs that were not found
+func (s *Server) getArticlesBatch(c *gin.Context) {
+	var request struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	if len(request.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must list at least one article ID"})
+		return
+	}
+	if len(request.IDs) > maxBatchArticles {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d article IDs per request", maxBatchArticles)})
+		return
+	}
+	for _, id := range request.IDs {
+		if strings.TrimSpace(id) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "article IDs must not be empty"})
+			return
+		}
+	}
+
+	articles, err := s.aggregator.GetArticles(c.Request.Context(), request.IDs)
+	if err != nil {
+		log.Printf("Error getting article batch: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve articles"})
+		return
+	}
+
+	found := make(map[string]bool, len(articles))
+	for _, article := range articles {
+		found[article.ID] = true
+	}
+	missing := []string{}
+	for _, id := range request.IDs {
+		if !found[id] {
+			missing = append(missing, id)
+			found[id] = true // Report repeated IDs once
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"articles": articles,
+		"count":    len(articles),
+		"missing":  missing,
+	})
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"gorssag/internal/aggregator"
+	"gorssag/internal/models"
+	"gorssag/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createDigest subscribes a recipient to scheduled emails of a topic
+func (s *Server) createDigest(c *gin.Context) {
+	var request struct {
+		Recipient    string `json:"recipient"`
+		Topic        string `json:"topic"`
+		Filter       string `json:"filter"`
+		Schedule     string `json:"schedule"`
+		Subject      string `json:"subject"`
+		HTMLTemplate string `json:"html_template"`
+		TextTemplate string `json:"text_template"`
+		MaxArticles  int    `json:"max_articles"`
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	digest := &models.Digest{
+		Recipient:    request.Recipient,
+		Topic:        request.Topic,
+		Filter:       request.Filter,
+		Schedule:     request.Schedule,
+		Subject:      request.Subject,
+		HTMLTemplate: request.HTMLTemplate,
+		TextTemplate: request.TextTemplate,
+		MaxArticles:  request.MaxArticles,
+	}
+	if err := s.aggregator.CreateDigest(c.Request.Context(), digest); err != nil {
+		if errors.Is(err, aggregator.ErrInvalidDigest) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error creating digest: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create digest"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, digest)
+}
+
+// listDigests returns the digest subscriptions
+func (s *Server) listDigests(c *gin.Context) {
+	digests, err := s.aggregator.ListDigests(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing digests: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list digests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"digests": digests, "count": len(digests)})
+}
+
+// getDigest returns a digest subscription with its sending state
+func (s *Server) getDigest(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	digest, err := s.aggregator.GetDigest(c.Request.Context(), id)
+	if !digestFound(c, id, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, digest)
+}
+
+// deleteDigest unsubscribes a digest
+func (s *Server) deleteDigest(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	err := s.aggregator.DeleteDigest(c.Request.Context(), id)
+	if !digestFound(c, id, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("digest %d deleted", id)})
+}
+
+// previewDigest renders the digest that would be sent now. format=html or
+// format=text returns that body alone, otherwise all parts are returned as JSON.
+func (s *Server) previewDigest(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	subject, text, html, err := s.aggregator.PreviewDigest(c.Request.Context(), id)
+	if !digestFound(c, id, err) {
+		return
+	}
+
+	switch c.Query("format") {
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(text))
+	case "":
+		c.JSON(http.StatusOK, gin.H{"subject": subject, "text": text, "html": html})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be html or text"})
+	}
+}
+
+// digestFound answers 404 or 500 for a failed digest lookup and reports
+// whether the handler should go on
+func digestFound(c *gin.Context, id int64, err error) bool {
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("digest %d not found", id)})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
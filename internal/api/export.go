@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"gorssag/internal/aggregator"
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportArticles streams articles as newline-delimited JSON, optionally
+// limited to a topic and to articles published since a date. Every line
+// carries a cursor; passing the last one received resumes the export.
+func (s *Server) exportArticles(c *gin.Context) {
+	var since *time.Time
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		parsed, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			parsed, err = time.Parse("2006-01-02", sinceStr)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp or a YYYY-MM-DD date"})
+			return
+		}
+		since = &parsed
+	}
+
+	var cursor *models.SkipToken
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		token, err := odata.DecodeSkipToken(cursorStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor: " + err.Error()})
+			return
+		}
+		cursor = token
+	}
+
+	// Headers are only sent with the first line so early failures can still
+	// be reported as JSON errors
+	encoder := json.NewEncoder(c.Writer)
+	started := false
+	err := s.aggregator.ExportArticles(c.Request.Context(), c.Query("topic"), since, cursor, func(article models.ExportedArticle) error {
+		if !started {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+			started = true
+		}
+		return encoder.Encode(article)
+	})
+
+	if err != nil && started {
+		log.Printf("Warning: article export interrupted: %v", err)
+		return
+	}
+	if err != nil {
+		log.Printf("Error exporting articles: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export articles"})
+		return
+	}
+	if !started {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+	}
+}
+
+// importArticles stores the articles of an NDJSON export sent as request body
+func (s *Server) importArticles(c *gin.Context) {
+	result, err := s.aggregator.ImportArticles(c.Request.Context(), c.Request.Body)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, aggregator.ErrInvalidImport) {
+			status = http.StatusBadRequest
+		}
+		// Lines tells the client where to resume from
+		c.JSON(status, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,544 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorssag/internal/config"
+	"gorssag/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Google Reader stream and tag IDs
+const (
+	readerReadingList = "user/-/state/com.google/reading-list"
+	readerRead        = "user/-/state/com.google/read"
+	readerStarred     = "user/-/state/com.google/starred"
+	readerKeptUnread  = "user/-/state/com.google/kept-unread"
+	readerLabelPrefix = "user/-/label/"
+	readerFeedPrefix  = "feed/"
+	readerItemPrefix  = "tag:google.com,2005:reader/item/"
+)
+
+// Items per Google Reader request
+const (
+	defaultReaderItems  = 20
+	maxReaderContents   = 1000  // Articles per stream/contents or stream/items/contents request
+	maxReaderItemIDs    = 10000 // IDs per stream/items/ids request
+	maxReaderUnreadSize = 1000  // Unread counts stop there, like Google Reader did
+)
+
+// readerUserStream matches stream IDs with a user ID, which clients either
+// send as "-" or as the ID user-info returned
+var readerUserStream = regexp.MustCompile(`^user/[^/]+/(state/com\.google/|label/)`)
+
+// newReaderKey returns the key signing the Google Reader tokens: the configured
+// secret, or a random one when the tokens may die with the process
+func newReaderKey(cfg *config.Config) []byte {
+	if cfg.ReaderSecret != "" {
+		return []byte(cfg.ReaderSecret)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatalf("Failed to generate the Google Reader signing key: %v", err)
+	}
+	return key
+}
+
+// readerSignature signs a purpose and the credentials with the server key, so
+// the tokens derived from it cannot be computed from the username alone and
+// changing the password revokes them
+func (s *Server) readerSignature(purpose string) string {
+	mac := hmac.New(sha256.New, s.readerKey)
+	mac.Write([]byte(purpose + ":" + s.config.ReaderUsername + ":" + s.config.ReaderPassword))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// readerAuthToken returns the token ClientLogin hands out
+func (s *Server) readerAuthToken() string {
+	return s.config.ReaderUsername + "/" + s.readerSignature("auth")
+}
+
+// readerLogin implements ClientLogin: valid credentials get the auth token
+// clients send as "Authorization: GoogleLogin auth=<token>"
+func (s *Server) readerLogin(c *gin.Context) {
+	if !s.config.ReaderEnabled() {
+		c.String(http.StatusForbidden, "Error=ServiceDisabled\n")
+		return
+	}
+
+	// Credentials are only read from the POST body, URLs end up in access logs
+	email, password := c.PostForm("Email"), c.PostForm("Passwd")
+	validUser := subtle.ConstantTimeCompare([]byte(email), []byte(s.config.ReaderUsername)) == 1
+	validPassword := subtle.ConstantTimeCompare([]byte(password), []byte(s.config.ReaderPassword)) == 1
+	if !validUser || !validPassword {
+		c.String(http.StatusUnauthorized, "Error=BadAuthentication\n")
+		return
+	}
+
+	token := s.readerAuthToken()
+	c.String(http.StatusOK, "SID=%s\nLSID=null\nAuth=%s\n", token, token)
+}
+
+// requireReaderAuth guards the Google Reader API with the ClientLogin token
+func (s *Server) requireReaderAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.config.ReaderEnabled() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Google Reader API is disabled, set GREADER_USERNAME and GREADER_PASSWORD to enable it"})
+			return
+		}
+
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "GoogleLogin auth=")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.readerAuthToken())) != 1 {
+			c.Header("Google-Bad-Token", "true")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid auth token"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// readerEditToken returns the token clients send as T with their edits. The
+// auth header already proves the client is logged in, so it is not checked.
+func (s *Server) readerEditToken(c *gin.Context) {
+	c.String(http.StatusOK, s.readerSignature("edit")[:57])
+}
+
+// readerUserInfo returns the single user of the API
+func (s *Server) readerUserInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"userId":        "1",
+		"userName":      s.config.ReaderUsername,
+		"userProfileId": "1",
+		"userEmail":     s.config.ReaderUsername,
+	})
+}
+
+// readerSubscriptions lists the configured feeds, in the folders of their topics
+func (s *Server) readerSubscriptions(c *gin.Context) {
+	subscriptions, err := s.aggregator.GetReaderSubscriptions(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing reader subscriptions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list subscriptions"})
+		return
+	}
+
+	list := make([]gin.H, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		categories := make([]gin.H, 0, len(subscription.Topics))
+		for _, topic := range subscription.Topics {
+			categories = append(categories, gin.H{"id": readerLabelPrefix + topic, "label": topic})
+		}
+		list = append(list, gin.H{
+			"id":         readerFeedPrefix + subscription.URL,
+			"title":      subscription.Title,
+			"categories": categories,
+			"url":        subscription.URL,
+			"htmlUrl":    subscription.URL,
+			"iconUrl":    "",
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscriptions": list})
+}
+
+// readerTags lists the starred state and the topics as folders
+func (s *Server) readerTags(c *gin.Context) {
+	tags := []gin.H{{"id": readerStarred}}
+	for _, topic := range s.aggregator.GetAvailableTopics() {
+		tags = append(tags, gin.H{"id": readerLabelPrefix + topic, "type": "folder"})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// readerUnreadCounts counts the unread items of every feed and topic and of
+// the reading list, up to maxReaderUnreadSize each
+func (s *Server) readerUnreadCounts(c *gin.Context) {
+	subscriptions, err := s.aggregator.GetReaderSubscriptions(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing reader subscriptions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unread items"})
+		return
+	}
+
+	streams := map[string]models.ReaderQuery{readerReadingList: {}}
+	for _, topic := range s.aggregator.GetAvailableTopics() {
+		streams[readerLabelPrefix+topic] = models.ReaderQuery{Topic: topic}
+	}
+	for _, subscription := range subscriptions {
+		streams[readerFeedPrefix+subscription.URL] = models.ReaderQuery{FeedURL: subscription.URL}
+	}
+
+	ids := make([]string, 0, len(streams))
+	for id := range streams {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	counts := make([]gin.H, 0, len(streams))
+	for _, id := range ids {
+		query := streams[id]
+		query.Unread, query.Limit = true, maxReaderUnreadSize
+		refs, err := s.aggregator.GetReaderItemRefs(c.Request.Context(), &query)
+		if err != nil {
+			log.Printf("Error counting unread items of %s: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unread items"})
+			return
+		}
+		if len(refs) == 0 {
+			continue
+		}
+		counts = append(counts, gin.H{
+			"id":                      id,
+			"count":                   len(refs),
+			"newestItemTimestampUsec": strconv.FormatInt(refs[0].CrawledAt.UnixMicro(), 10),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"max": maxReaderUnreadSize, "unreadcounts": counts})
+}
+
+// readerStreamQuery parses a stream ID and the paging and filtering
+// parameters shared by the stream endpoints
+func readerStreamQuery(c *gin.Context, streamID string, maxItems int) (models.ReaderQuery, bool) {
+	var query models.ReaderQuery
+	if !parseReaderStream(streamID, &query) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported stream %q", streamID)})
+		return query, false
+	}
+
+	query.Limit = defaultReaderItems
+	if n := readerParam(c, "n"); n != "" {
+		count, err := strconv.Atoi(n)
+		if err != nil || count <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "n must be a positive integer"})
+			return query, false
+		}
+		query.Limit = count
+		if query.Limit > maxItems {
+			query.Limit = maxItems
+		}
+	}
+	if continuation := readerParam(c, "c"); continuation != "" {
+		offset, err := strconv.Atoi(continuation)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid continuation"})
+			return query, false
+		}
+		query.Offset = offset
+	}
+	for name, bound := range map[string]*time.Time{"ot": &query.Since, "nt": &query.Until} {
+		if value := readerParam(c, name); value != "" {
+			seconds, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a Unix timestamp"})
+				return query, false
+			}
+			*bound = time.Unix(seconds, 0)
+		}
+	}
+	query.Oldest = readerParam(c, "r") == "o"
+
+	// Only the read and starred states can be excluded or included
+	for _, excluded := range readerParams(c, "xt") {
+		if normalizeReaderStream(excluded) == readerRead {
+			query.Unread = true
+		}
+	}
+	for _, included := range readerParams(c, "it") {
+		if normalizeReaderStream(included) == readerStarred {
+			query.Starred = true
+		}
+	}
+	return query, true
+}
+
+// parseReaderStream sets the stream of a query from its Google Reader ID
+func parseReaderStream(streamID string, query *models.ReaderQuery) bool {
+	streamID = normalizeReaderStream(streamID)
+	switch {
+	case streamID == readerReadingList:
+	case streamID == readerStarred:
+		query.Starred = true
+	case streamID == readerRead:
+		query.Read = true
+	case strings.HasPrefix(streamID, readerLabelPrefix) && len(streamID) > len(readerLabelPrefix):
+		query.Topic = strings.TrimPrefix(streamID, readerLabelPrefix)
+	case strings.HasPrefix(streamID, readerFeedPrefix) && len(streamID) > len(readerFeedPrefix):
+		query.FeedURL = strings.TrimPrefix(streamID, readerFeedPrefix)
+	default:
+		return false
+	}
+	return true
+}
+
+// normalizeReaderStream replaces the user ID of a stream ID with "-"
+func normalizeReaderStream(streamID string) string {
+	if loc := readerUserStream.FindStringSubmatchIndex(streamID); loc != nil {
+		return "user/-/" + streamID[loc[2]:]
+	}
+	return streamID
+}
+
+// readerParam returns a query or form parameter, as clients send either
+func readerParam(c *gin.Context, name string) string {
+	if value, ok := c.GetQuery(name); ok {
+		return value
+	}
+	return c.PostForm(name)
+}
+
+// readerParams returns the values of a repeated query or form parameter
+func readerParams(c *gin.Context, name string) []string {
+	return append(c.QueryArray(name), c.PostFormArray(name)...)
+}
+
+// readerStreamItemIDs lists the item IDs of a stream
+func (s *Server) readerStreamItemIDs(c *gin.Context) {
+	query, ok := readerStreamQuery(c, readerParam(c, "s"), maxReaderItemIDs)
+	if !ok {
+		return
+	}
+
+	refs, err := s.aggregator.GetReaderItemRefs(c.Request.Context(), &query)
+	if err != nil {
+		log.Printf("Error listing reader items: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list items"})
+		return
+	}
+
+	itemRefs := make([]gin.H, 0, len(refs))
+	for _, ref := range refs {
+		itemRefs = append(itemRefs, gin.H{
+			"id":              strconv.FormatInt(ref.ID, 10),
+			"directStreamIds": []string{},
+			"timestampUsec":   strconv.FormatInt(ref.CrawledAt.UnixMicro(), 10),
+		})
+	}
+	response := gin.H{"itemRefs": itemRefs}
+	if len(refs) == query.Limit {
+		response["continuation"] = strconv.Itoa(query.Offset + len(refs))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// readerStreamContents returns the articles of a stream. The stream ID is
+// the path after stream/contents, the reading list when missing.
+func (s *Server) readerStreamContents(c *gin.Context) {
+	streamID := strings.TrimPrefix(c.Param("streamId"), "/")
+	if streamID == "" {
+		streamID = readerParam(c, "s")
+	}
+	if streamID == "" {
+		streamID = readerReadingList
+	}
+	query, ok := readerStreamQuery(c, streamID, maxReaderContents)
+	if !ok {
+		return
+	}
+
+	refs, err := s.aggregator.GetReaderItemRefs(c.Request.Context(), &query)
+	if err != nil {
+		log.Printf("Error listing reader items: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list items"})
+		return
+	}
+	ids := make([]int64, len(refs))
+	for i, ref := range refs {
+		ids[i] = ref.ID
+	}
+	items, err := s.aggregator.GetReaderItems(c.Request.Context(), ids)
+	if err != nil {
+		log.Printf("Error loading reader items: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load items"})
+		return
+	}
+
+	response := readerContents(streamID, items)
+	if len(refs) == query.Limit {
+		response["continuation"] = strconv.Itoa(query.Offset + len(refs))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// readerItemContents returns the articles of the items listed as i
+func (s *Server) readerItemContents(c *gin.Context) {
+	ids, ok := readerItemIDs(c, maxReaderContents)
+	if !ok {
+		return
+	}
+
+	items, err := s.aggregator.GetReaderItems(c.Request.Context(), ids)
+	if err != nil {
+		log.Printf("Error loading reader items: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load items"})
+		return
+	}
+
+	c.JSON(http.StatusOK, readerContents(readerReadingList, items))
+}
+
+// readerItemIDs parses the item IDs listed as i, either decimal or in the
+// long tag:google.com form
+func readerItemIDs(c *gin.Context, maxItems int) ([]int64, bool) {
+	values := readerParams(c, "i")
+	if len(values) == 0 || len(values) > maxItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("between 1 and %d items must be given as i", maxItems)})
+		return nil, false
+	}
+
+	ids := make([]int64, 0, len(values))
+	for _, value := range values {
+		id, err := parseReaderItemID(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid item ID %q", value)})
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// parseReaderItemID parses an item ID in its decimal or long hexadecimal form
+func parseReaderItemID(value string) (int64, error) {
+	if hexID, ok := strings.CutPrefix(value, readerItemPrefix); ok {
+		id, err := strconv.ParseUint(hexID, 16, 64)
+		return int64(id), err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// readerContents formats items the way stream/contents returns them
+func readerContents(streamID string, items []models.ReaderItem) gin.H {
+	list := make([]gin.H, 0, len(items))
+	for _, item := range items {
+		list = append(list, readerItem(item))
+	}
+	return gin.H{
+		"direction": "ltr",
+		"id":        streamID,
+		"updated":   time.Now().Unix(),
+		"items":     list,
+	}
+}
+
+// readerItem formats an article as a Google Reader item
+func readerItem(item models.ReaderItem) gin.H {
+	article := item.Article
+
+	categories := []string{readerReadingList}
+	for _, topic := range item.Topics {
+		categories = append(categories, readerLabelPrefix+topic)
+	}
+	if item.Read {
+		categories = append(categories, readerRead)
+	}
+	if item.Starred {
+		categories = append(categories, readerStarred)
+	}
+
+	origin := gin.H{"title": article.Source}
+	if len(article.FeedURLs) > 0 {
+		origin["streamId"] = readerFeedPrefix + article.FeedURLs[0]
+		origin["htmlUrl"] = article.FeedURLs[0]
+	}
+
+	content := article.Content
+	if content == "" {
+		content = article.Description
+	}
+	updated := article.PublishedAt
+	if article.UpdatedAt != nil {
+		updated = *article.UpdatedAt
+	}
+
+	return gin.H{
+		"id":            fmt.Sprintf("%s%016x", readerItemPrefix, uint64(item.ID)),
+		"crawlTimeMsec": strconv.FormatInt(item.CrawledAt.UnixMilli(), 10),
+		"timestampUsec": strconv.FormatInt(item.CrawledAt.UnixMicro(), 10),
+		"published":     article.PublishedAt.Unix(),
+		"updated":       updated.Unix(),
+		"title":         article.Title,
+		"author":        article.Author,
+		"canonical":     []gin.H{{"href": article.Link}},
+		"alternate":     []gin.H{{"href": article.Link, "type": "text/html"}},
+		"categories":    categories,
+		"origin":        origin,
+		"summary":       gin.H{"direction": "ltr", "content": content},
+	}
+}
+
+// readerEditTag adds or removes the read and starred tags of items. Other
+// tags are ignored, topics being set by the feed configuration.
+func (s *Server) readerEditTag(c *gin.Context) {
+	ids, ok := readerItemIDs(c, maxReaderContents)
+	if !ok {
+		return
+	}
+
+	for _, edit := range []struct {
+		param string
+		on    bool
+	}{{"a", true}, {"r", false}} {
+		for _, tag := range readerParams(c, edit.param) {
+			state, on := "", edit.on
+			switch normalizeReaderStream(tag) {
+			case readerRead:
+				state = models.ReaderStateRead
+			case readerKeptUnread:
+				state, on = models.ReaderStateRead, !on
+			case readerStarred:
+				state = models.ReaderStateStarred
+			default:
+				continue
+			}
+			if err := s.aggregator.SetReaderItemState(c.Request.Context(), ids, state, on); err != nil {
+				log.Printf("Error editing reader tags: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit tags"})
+				return
+			}
+		}
+	}
+
+	c.String(http.StatusOK, "OK")
+}
+
+// readerMarkAllAsRead marks the items of a stream read, up to the ts
+// timestamp in microseconds when given
+func (s *Server) readerMarkAllAsRead(c *gin.Context) {
+	var query models.ReaderQuery
+	if streamID := readerParam(c, "s"); !parseReaderStream(streamID, &query) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported stream %q", streamID)})
+		return
+	}
+	if ts := readerParam(c, "ts"); ts != "" {
+		micros, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ts must be a timestamp in microseconds"})
+			return
+		}
+		query.Until = time.UnixMicro(micros + 1) // Including the item the client saw last
+	}
+
+	if _, err := s.aggregator.MarkReaderStreamRead(c.Request.Context(), query); err != nil {
+		log.Printf("Error marking reader stream read: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark items read"})
+		return
+	}
+
+	c.String(http.StatusOK, "OK")
+}
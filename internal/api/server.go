@@ -2,12 +2,14 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime/debug"
 	"strconv"
 	"strings"
@@ -16,8 +18,10 @@ import (
 	"gorssag/internal/aggregator"
 	"gorssag/internal/config"
 	"gorssag/internal/models"
+	"gorssag/internal/odata"
 	"gorssag/internal/poller"
 	"gorssag/internal/security"
+	"gorssag/internal/storage"
 	"gorssag/internal/web"
 
 	"github.com/gin-gonic/gin"
@@ -31,6 +35,7 @@ type Server struct {
 	spaServer     *web.SPAServer
 	swaggerServer *web.SwaggerServer
 	config        *config.Config
+	readerKey     []byte // Signs the Google Reader tokens
 }
 
 func NewServer(agg *aggregator.Aggregator, poller *poller.Poller, cfg *config.Config) *Server {
@@ -75,6 +80,7 @@ func NewServer(agg *aggregator.Aggregator, poller *poller.Poller, cfg *config.Co
 		spaServer:     spaServer,
 		swaggerServer: swaggerServer,
 		config:        cfg,
+		readerKey:     newReaderKey(cfg),
 	}
 
 	server.setupRoutes()
@@ -93,6 +99,12 @@ func (s *Server) setupRoutes() {
 	{
 		api.GET("/topics", s.getTopics)
 		api.GET("/articles", s.getAllArticles)
+		api.GET("/articles/:id", s.getArticle)
+		api.POST("/articles/batch", s.getArticlesBatch)
+		api.GET("/articles/:id/related", s.getRelatedArticles)
+		api.GET("/articles/:id/revisions", s.getArticleRevisions)
+		api.POST("/articles/:id/pin", s.pinArticle)
+		api.DELETE("/articles/:id/pin", s.unpinArticle)
 		api.GET("/test", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"message": "test route working"})
 		})
@@ -108,10 +120,58 @@ func (s *Server) setupRoutes() {
 
 		// Storage optimization endpoints
 		api.GET("/storage/stats", s.getStorageStats)
+		api.GET("/storage/stats/history", s.getStorageHistory)
 		api.POST("/storage/optimize", s.optimizeStorage)
 
 		// Feed statistics endpoint
 		api.GET("/feeds/stats", s.getFeedStats)
+		api.DELETE("/feeds/:feedId", s.requireAdmin(), s.deleteSourceFeed)
+
+		// Bulk article export as newline-delimited JSON
+		api.GET("/export", s.exportArticles)
+
+		// Newly stored articles as Server-Sent Events
+		api.GET("/stream", s.streamArticles)
+
+		// Admin endpoints, protected by ADMIN_TOKEN
+		admin := api.Group("/admin", s.requireAdmin())
+		admin.POST("/backup", s.createBackup)
+		admin.POST("/import", s.importArticles)
+
+		// Webhook subscriptions, protected by ADMIN_TOKEN
+		webhooks := api.Group("/webhooks", s.requireAdmin())
+		webhooks.GET("", s.listWebhooks)
+		webhooks.POST("", s.createWebhook)
+		webhooks.GET("/:id", s.getWebhook)
+		webhooks.DELETE("/:id", s.deleteWebhook)
+		webhooks.GET("/:id/deliveries", s.getWebhookDeliveries)
+		webhooks.POST("/:id/deliveries/:deliveryId/retry", s.retryWebhookDelivery)
+
+		// Email digest subscriptions, protected by ADMIN_TOKEN
+		digests := api.Group("/digests", s.requireAdmin())
+		digests.GET("", s.listDigests)
+		digests.POST("", s.createDigest)
+		digests.GET("/:id", s.getDigest)
+		digests.DELETE("/:id", s.deleteDigest)
+		digests.GET("/:id/preview", s.previewDigest)
+	}
+
+	// Google Reader API for mobile RSS clients
+	s.router.POST("/accounts/ClientLogin", s.readerLogin)
+	reader := s.router.Group("/reader/api/0", s.requireReaderAuth())
+	{
+		reader.GET("/token", s.readerEditToken)
+		reader.GET("/user-info", s.readerUserInfo)
+		reader.GET("/subscription/list", s.readerSubscriptions)
+		reader.GET("/tag/list", s.readerTags)
+		reader.GET("/unread-count", s.readerUnreadCounts)
+		reader.GET("/stream/items/ids", s.readerStreamItemIDs)
+		reader.GET("/stream/contents", s.readerStreamContents)
+		reader.GET("/stream/contents/*streamId", s.readerStreamContents)
+		reader.POST("/stream/items/contents", s.readerItemContents)
+		reader.GET("/stream/items/contents", s.readerItemContents)
+		reader.POST("/edit-tag", s.readerEditTag)
+		reader.POST("/mark-all-as-read", s.readerMarkAllAsRead)
 	}
 
 	// Register web interfaces
@@ -275,14 +335,21 @@ func (s *Server) getAggregatedFeed(c *gin.Context) {
 		Select:  parseSelectFields(c.Query("$select")),
 	}
 
-	// Parse search terms (comma-separated)
-	if searchStr := c.Query("$search"); searchStr != "" {
-		searchTerms := strings.Split(searchStr, ",")
-		for i, term := range searchTerms {
-			searchTerms[i] = strings.TrimSpace(term)
-		}
-		query.Search = searchTerms
+	// Parse search expression
+	search, err := parseSearchParam(c.Query("$search"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.Search = search
+
+	// Parse facet fields
+	facets, err := parseFacetsParam(c.Query("$facets"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	query.Facets = facets
 
 	if topStr := c.Query("$top"); topStr != "" {
 		if top, err := strconv.Atoi(topStr); err == nil {
@@ -296,7 +363,33 @@ func (s *Server) getAggregatedFeed(c *gin.Context) {
 		}
 	}
 
-	feed, err := s.aggregator.GetAggregatedFeed(topic, query)
+	// Parse keyset cursor
+	if err := parseSkipTokenParam(c.Query("$skiptoken"), query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Parse story clustering mode
+	cluster, err := parseClusterParam(c.Query("$cluster"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.Cluster = cluster
+
+	// Parse pin filter; a pinned $filter is also understood by the feed filter parser
+	pinned, _, err := parsePinnedParam(c.Query("$pinned"), "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.Pinned = pinned
+
+	// Parse feed URL filter; a feed_url $filter is also understood by the feed filter parser
+	feedURL, _ := parseFeedURLParam(c.Query("$feed_url"), "")
+	query.FeedURL = feedURL
+
+	feed, err := s.aggregator.GetAggregatedFeed(c.Request.Context(), topic, query)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
@@ -337,8 +430,18 @@ func (s *Server) getAllArticles(c *gin.Context) {
 		}
 	}
 
+	if query.Archive {
+		if targetTopic != "" {
+			query.Filter = "" // Archived articles are matched on their recorded topics
+		}
+		s.getArchivedArticles(c, query, targetTopic)
+		return
+	}
+
 	var allArticles []models.Article
 	var totalCount int
+	var facets models.Facets
+	var nextSkipToken string
 
 	if targetTopic != "" {
 		// If topic filter is specified, only get articles from that topic
@@ -346,14 +449,19 @@ func (s *Server) getAllArticles(c *gin.Context) {
 
 		// Create a topic-specific query without the topic filter (since we're already filtering by topic)
 		topicQuery := &models.ODataQuery{
-			OrderBy: query.OrderBy,
-			Select:  query.Select,
-			Search:  query.Search,
-			Top:     query.Top,
-			Skip:    query.Skip,
+			OrderBy:   query.OrderBy,
+			Select:    query.Select,
+			Search:    query.Search,
+			Top:       query.Top,
+			Skip:      query.Skip,
+			Facets:    query.Facets,
+			SkipToken: query.SkipToken,
+			Cluster:   query.Cluster,
+			Pinned:    query.Pinned,
+			FeedURL:   query.FeedURL,
 		}
 
-		feed, err := s.aggregator.GetAggregatedFeed(targetTopic, topicQuery)
+		feed, err := s.aggregator.GetAggregatedFeed(c.Request.Context(), targetTopic, topicQuery)
 		if err != nil {
 			log.Printf("Warning: failed to get feed for topic '%s': %v", targetTopic, err)
 			// Return empty result instead of error
@@ -387,6 +495,8 @@ func (s *Server) getAllArticles(c *gin.Context) {
 
 		allArticles = feed.Articles
 		totalCount = len(feed.Articles)
+		facets = feed.Facets
+		nextSkipToken = feed.NextSkipToken
 
 		log.Printf("DEBUG: Got %d articles for topic %s", len(allArticles), targetTopic)
 	} else {
@@ -394,7 +504,7 @@ func (s *Server) getAllArticles(c *gin.Context) {
 		log.Printf("DEBUG: Getting ALL articles from storage (no topic filtering)")
 
 		var err error
-		allArticles, totalCount, err = s.aggregator.GetAllArticles(query)
+		allArticles, totalCount, err = s.aggregator.GetAllArticles(c.Request.Context(), query)
 		if err != nil {
 			log.Printf("Error getting all articles: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve articles"})
@@ -402,21 +512,134 @@ func (s *Server) getAllArticles(c *gin.Context) {
 		}
 
 		log.Printf("DEBUG: Got %d articles total from storage", totalCount)
+
+		if len(query.Facets) > 0 {
+			facets, err = s.aggregator.GetArticleFacets(c.Request.Context(), query)
+			if err != nil {
+				log.Printf("Error computing facets: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute facets"})
+				return
+			}
+		}
 	}
 
 	// Calculate has_more correctly: true if there are more articles beyond the current page
 	hasMore := (query.Skip + len(allArticles)) < totalCount
+	if targetTopic != "" {
+		// The topic feed knows whether articles remain beyond the page
+		hasMore = hasMore || nextSkipToken != ""
+	} else {
+		if query.SkipToken != nil {
+			// Offsets do not apply to cursor pages, a full page may have a successor
+			hasMore = query.Top > 0 && len(allArticles) == query.Top
+		}
+		if hasMore && len(allArticles) > 0 && odata.IsKeysetOrder(query.OrderBy) {
+			nextSkipToken = odata.EncodeSkipToken(allArticles[len(allArticles)-1])
+		}
+	}
 
 	log.Printf("DEBUG: Final result: %d articles, totalCount: %d, skip: %d, top: %d, hasMore: %v",
 		len(allArticles), totalCount, query.Skip, query.Top, hasMore)
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"articles":    allArticles,
 		"count":       len(allArticles),
 		"total_count": totalCount,
 		"skip":        query.Skip,
 		"top":         query.Top,
 		"has_more":    hasMore,
+	}
+	if len(query.Facets) > 0 {
+		response["facets"] = facets
+	}
+	if nextSkipToken != "" {
+		response["next_skiptoken"] = nextSkipToken
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// getRelatedArticles returns articles similar to the given one across all topics
+func (s *Server) getRelatedArticles(c *gin.Context) {
+	articleID := c.Param("id")
+
+	top := 10
+	if topStr := c.Query("$top"); topStr != "" {
+		parsed, err := strconv.Atoi(topStr)
+		if err != nil || parsed < 1 || parsed > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "$top must be between 1 and 50"})
+			return
+		}
+		top = parsed
+	}
+
+	related, err := s.aggregator.GetRelatedArticles(c.Request.Context(), articleID, top)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("article '%s' not found", articleID)})
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting related articles for %s: %v", articleID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve related articles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"article_id": articleID,
+		"articles":   related,
+		"count":      len(related),
+	})
+}
+
+// getArticleRevisions returns the earlier versions of an article, oldest first
+func (s *Server) getArticleRevisions(c *gin.Context) {
+	articleID := c.Param("id")
+
+	revisions, err := s.aggregator.GetArticleRevisions(c.Request.Context(), articleID)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("article '%s' not found", articleID)})
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting revisions of article %s: %v", articleID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve article revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"article_id": articleID,
+		"revisions":  revisions,
+		"count":      len(revisions),
+	})
+}
+
+// pinArticle pins an article, exempting it from retention and deduplication
+func (s *Server) pinArticle(c *gin.Context) {
+	s.setArticlePinned(c, true)
+}
+
+// unpinArticle removes the pin of an article
+func (s *Server) unpinArticle(c *gin.Context) {
+	s.setArticlePinned(c, false)
+}
+
+func (s *Server) setArticlePinned(c *gin.Context, pinned bool) {
+	articleID := c.Param("id")
+
+	err := s.aggregator.SetArticlePinned(c.Request.Context(), articleID, pinned)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("article '%s' not found", articleID)})
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating pin of article %s: %v", articleID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update article pin"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"article_id": articleID,
+		"pinned":     pinned,
 	})
 }
 
@@ -505,6 +728,112 @@ func parseSelectFields(selectStr string) []string {
 	return result
 }
 
+// parseSearchParam validates a $search expression (quoted phrases, AND/OR/NOT,
+// parentheses, field:term and prefix* wildcards) and returns it as query terms
+func parseSearchParam(searchStr string) ([]string, error) {
+	searchStr = strings.TrimSpace(searchStr)
+	if searchStr == "" {
+		return nil, nil
+	}
+
+	if _, err := odata.NewSearchParser().Parse(searchStr); err != nil {
+		return nil, fmt.Errorf("invalid $search expression: %v", err)
+	}
+
+	return []string{searchStr}, nil
+}
+
+// parseFacetsParam parses the $facets parameter (e.g. "source,category,language")
+func parseFacetsParam(facetsStr string) ([]string, error) {
+	facets, err := odata.ParseFacets(facetsStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $facets parameter: %v", err)
+	}
+	return facets, nil
+}
+
+// parseSkipTokenParam decodes the $skiptoken cursor into the query. Cursors
+// follow the default published_at desc order and replace $skip.
+func parseSkipTokenParam(token string, query *models.ODataQuery) error {
+	if token == "" {
+		return nil
+	}
+
+	skipToken, err := odata.DecodeSkipToken(token)
+	if err != nil {
+		return fmt.Errorf("invalid $skiptoken: %v", err)
+	}
+	if query.Skip > 0 {
+		return fmt.Errorf("$skiptoken cannot be combined with $skip")
+	}
+	if !odata.IsKeysetOrder(query.OrderBy) {
+		return fmt.Errorf("$skiptoken requires the default published_at desc ordering")
+	}
+
+	query.SkipToken = skipToken
+	return nil
+}
+
+// parseClusterParam parses $cluster, which collapses near-duplicate articles
+// into one representative per story cluster
+func parseClusterParam(clusterStr string) (bool, error) {
+	if clusterStr == "" {
+		return false, nil
+	}
+	cluster, err := strconv.ParseBool(clusterStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid $cluster parameter: must be true or false")
+	}
+	return cluster, nil
+}
+
+// parseArchiveParam parses $archive, which searches the cold archive of
+// expired articles instead of the database
+func parseArchiveParam(archiveStr string) (bool, error) {
+	if archiveStr == "" {
+		return false, nil
+	}
+	archive, err := strconv.ParseBool(archiveStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid $archive parameter: must be true or false")
+	}
+	return archive, nil
+}
+
+// pinnedFilterPattern matches a $filter selecting articles on their pin flag
+var pinnedFilterPattern = regexp.MustCompile(`(?i)^\s*pinned\s+eq\s+(true|false)\s*$`)
+
+// parsePinnedParam parses $pinned, or a "pinned eq true|false" $filter, which
+// restrict results to pinned or unpinned articles. It reports whether the
+// filter was consumed.
+func parsePinnedParam(pinnedStr, filter string) (*bool, bool, error) {
+	if match := pinnedFilterPattern.FindStringSubmatch(filter); match != nil {
+		pinned := strings.EqualFold(match[1], "true")
+		return &pinned, true, nil
+	}
+	if pinnedStr == "" {
+		return nil, false, nil
+	}
+	pinned, err := strconv.ParseBool(pinnedStr)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid $pinned parameter: must be true or false")
+	}
+	return &pinned, false, nil
+}
+
+// feedURLFilterPattern matches a $filter selecting articles on a source feed URL
+var feedURLFilterPattern = regexp.MustCompile(`(?i)^\s*feed_url\s+eq\s+'([^']*)'\s*$`)
+
+// parseFeedURLParam parses $feed_url, or a "feed_url eq '<url>'" $filter, which
+// restrict results to articles polled from that feed URL. It reports whether
+// the filter was consumed.
+func parseFeedURLParam(feedURLStr, filter string) (string, bool) {
+	if match := feedURLFilterPattern.FindStringSubmatch(filter); match != nil {
+		return match[1], true
+	}
+	return feedURLStr, false
+}
+
 // parseODataQuery parses OData query parameters from the request
 func (s *Server) parseODataQuery(c *gin.Context) (*models.ODataQuery, error) {
 	query := &models.ODataQuery{
@@ -513,14 +842,19 @@ func (s *Server) parseODataQuery(c *gin.Context) (*models.ODataQuery, error) {
 		Select:  parseSelectFields(c.Query("$select")),
 	}
 
-	// Parse search terms (comma-separated)
-	if searchStr := c.Query("$search"); searchStr != "" {
-		searchTerms := strings.Split(searchStr, ",")
-		for i, term := range searchTerms {
-			searchTerms[i] = strings.TrimSpace(term)
-		}
-		query.Search = searchTerms
+	// Parse search expression
+	search, err := parseSearchParam(c.Query("$search"))
+	if err != nil {
+		return nil, err
 	}
+	query.Search = search
+
+	// Parse facet fields
+	facets, err := parseFacetsParam(c.Query("$facets"))
+	if err != nil {
+		return nil, err
+	}
+	query.Facets = facets
 
 	// Set default pagination if not specified
 	if topStr := c.Query("$top"); topStr != "" {
@@ -537,6 +871,18 @@ func (s *Server) parseODataQuery(c *gin.Context) (*models.ODataQuery, error) {
 		}
 	}
 
+	// Parse keyset cursor
+	if err := parseSkipTokenParam(c.Query("$skiptoken"), query); err != nil {
+		return nil, err
+	}
+
+	// Parse story clustering mode
+	cluster, err := parseClusterParam(c.Query("$cluster"))
+	if err != nil {
+		return nil, err
+	}
+	query.Cluster = cluster
+
 	// Parse advanced filter options
 	if filterStr := c.Query("$filter"); filterStr != "" {
 		query.Filter = filterStr
@@ -570,13 +916,40 @@ func (s *Server) parseODataQuery(c *gin.Context) (*models.ODataQuery, error) {
 		query.Category = categoryStr
 	}
 
+	// Parse pin filter, the storage text filter cannot evaluate it
+	pinned, consumed, err := parsePinnedParam(c.Query("$pinned"), query.Filter)
+	if err != nil {
+		return nil, err
+	}
+	query.Pinned = pinned
+	if consumed {
+		query.Filter = ""
+	}
+
+	// Parse feed URL filter, the storage text filter cannot evaluate it either
+	feedURL, consumed := parseFeedURLParam(c.Query("$feed_url"), query.Filter)
+	query.FeedURL = feedURL
+	if consumed {
+		query.Filter = ""
+	}
+
+	// Parse archive search mode
+	archive, err := parseArchiveParam(c.Query("$archive"))
+	if err != nil {
+		return nil, err
+	}
+	if archive && (len(query.Facets) > 0 || query.Cluster) {
+		return nil, fmt.Errorf("$archive cannot be combined with $facets or $cluster")
+	}
+	query.Archive = archive
+
 	return query, nil
 }
 
 func (s *Server) getFeedInfo(c *gin.Context) {
 	topic := c.Param("topic")
 
-	info, err := s.aggregator.GetFeedInfo(topic)
+	info, err := s.aggregator.GetFeedInfo(c.Request.Context(), topic)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
@@ -590,7 +963,7 @@ func (s *Server) getFeedInfo(c *gin.Context) {
 func (s *Server) refreshFeed(c *gin.Context) {
 	topic := c.Param("topic")
 
-	if err := s.aggregator.RefreshFeed(topic); err != nil {
+	if err := s.aggregator.RefreshFeed(c.Request.Context(), topic); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
@@ -618,7 +991,7 @@ func (s *Server) forcePollTopic(c *gin.Context) {
 		return
 	}
 
-	err := s.poller.ForcePoll(topic)
+	err := s.poller.ForcePoll(c.Request.Context(), topic)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -720,7 +1093,7 @@ func (s *Server) getFeeds(c *gin.Context) {
 
 // getStorageStats returns database statistics
 func (s *Server) getStorageStats(c *gin.Context) {
-	stats, err := s.aggregator.GetStorageStats()
+	stats, err := s.aggregator.GetStorageStats(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -731,6 +1104,56 @@ func (s *Server) getStorageStats(c *gin.Context) {
 	})
 }
 
+// defaultForecastHorizon is how far ahead storage growth is forecast by default, in days
+const defaultForecastHorizon = 30
+
+// getStorageHistory returns the storage stats snapshots with a disk usage forecast
+func (s *Server) getStorageHistory(c *gin.Context) {
+	since := time.Now().AddDate(0, 0, -90)
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		parsed, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			parsed, err = time.Parse("2006-01-02", sinceStr)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp or a YYYY-MM-DD date"})
+			return
+		}
+		since = parsed
+	}
+
+	horizon := defaultForecastHorizon
+	if horizonStr := c.Query("horizon"); horizonStr != "" {
+		parsed, err := strconv.Atoi(horizonStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "horizon must be a positive number of days"})
+			return
+		}
+		horizon = parsed
+	}
+
+	var capacity int64
+	if capacityStr := c.Query("capacity"); capacityStr != "" {
+		parsed, err := strconv.ParseInt(capacityStr, 10, 64)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "capacity must be a positive number of bytes"})
+			return
+		}
+		capacity = parsed
+	}
+
+	snapshots, forecast, err := s.aggregator.GetStorageHistory(c.Request.Context(), since, time.Duration(horizon)*24*time.Hour, capacity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshots": snapshots,
+		"forecast":  forecast,
+	})
+}
+
 // optimizeStorage triggers storage optimization
 func (s *Server) optimizeStorage(c *gin.Context) {
 	// Get storage from aggregator (we need to access it directly)
@@ -743,7 +1166,7 @@ func (s *Server) optimizeStorage(c *gin.Context) {
 
 // getFeedStats returns detailed feed statistics
 func (s *Server) getFeedStats(c *gin.Context) {
-	stats, err := s.aggregator.GetFeedStats()
+	stats, err := s.aggregator.GetFeedStats(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -753,3 +1176,33 @@ func (s *Server) getFeedStats(c *gin.Context) {
 		"stats": stats,
 	})
 }
+
+// deleteSourceFeed deletes a polled feed by the id reported in the feed stats.
+// Its articles are kept unless purge=true.
+func (s *Server) deleteSourceFeed(c *gin.Context) {
+	feedID, err := strconv.ParseInt(c.Param("feedId"), 10, 64)
+	if err != nil || feedID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid feed id"})
+		return
+	}
+
+	purge := false
+	if purgeStr := c.Query("purge"); purgeStr != "" {
+		if purge, err = strconv.ParseBool(purgeStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid purge parameter: must be true or false"})
+			return
+		}
+	}
+
+	deletion, err := s.aggregator.DeleteSourceFeed(c.Request.Context(), feedID, purge)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("feed %d not found", feedID)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deletion)
+}
@@ -1,9 +1,18 @@
 package api
 
 import (
+	"bufio"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,6 +20,7 @@ import (
 	"gorssag/internal/cache"
 	"gorssag/internal/config"
 	"gorssag/internal/models"
+	"gorssag/internal/odata"
 	"gorssag/internal/poller"
 	"gorssag/internal/storage"
 
@@ -33,7 +43,7 @@ func TestServer_New(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
@@ -66,7 +76,7 @@ func TestServer_SetupRoutes(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
@@ -107,7 +117,7 @@ func TestServer_GetTopics(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
@@ -154,7 +164,7 @@ func TestServer_GetFeeds(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -197,7 +207,7 @@ func TestServer_GetAggregatedFeed(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
@@ -240,7 +250,7 @@ func TestServer_GetAggregatedFeedWithQueryParams(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -292,7 +302,7 @@ func TestServer_GetAggregatedFeedInvalidTopic(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -334,7 +344,7 @@ func TestServer_GetFeedInfo(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -377,7 +387,7 @@ func TestServer_RefreshFeed(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -428,7 +438,7 @@ func TestServer_RefreshFeedInvalidTopic(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -470,7 +480,7 @@ func TestServer_GetPollerStatus(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -522,7 +532,7 @@ func TestServer_ForcePollTopic(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -573,7 +583,7 @@ func TestServer_ForcePollTopicInvalid(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -615,7 +625,7 @@ func TestServer_GetLastPolledTimes(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -667,7 +677,7 @@ func TestServer_GetStorageStats(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -719,7 +729,7 @@ func TestServer_OptimizeStorage(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -770,7 +780,7 @@ func TestServer_GetFeedStats(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -822,7 +832,7 @@ func TestServer_ParseODataQuery(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -864,7 +874,7 @@ func TestServer_ParseODataQueryInvalid(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -898,7 +908,7 @@ func TestServer_GetAllArticles(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
@@ -933,7 +943,7 @@ func TestServer_GetAllArticlesWithQueryParams(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
@@ -952,6 +962,768 @@ func TestServer_GetAllArticlesWithQueryParams(t *testing.T) {
 	}
 }
 
+func TestServer_GetAllArticlesInvalidSearch(t *testing.T) {
+	// Create test dependencies
+	cacheManager := cache.NewManager(5 * time.Minute)
+
+	cfg := &config.Config{
+		EnableContentCompression: false,
+		MaxContentLength:         10000,
+		EnableDuplicateRemoval:   true,
+		Feeds: map[string]config.TopicConfig{
+			"tech": {
+				URLs:    []string{"http://example.com/tech"},
+				Filters: []string{"AI"},
+			},
+		},
+	}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+
+	server := NewServer(agg, p, cfg)
+	gin.SetMode(gin.TestMode)
+
+	// Boolean and phrase syntax is accepted
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", `/api/v1/articles?$search=`+url.QueryEscape(`"zero day" OR (rust NOT game) title:kube*`), nil)
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for valid search expression, got %d", w.Code)
+	}
+
+	// Malformed expressions are rejected on both article endpoints
+	for _, path := range []string{"/api/v1/articles", "/api/v1/feeds/tech"} {
+		for _, search := range []string{`(rust OR "zero day`, `title:`} {
+			w = httptest.NewRecorder()
+			req, _ = http.NewRequest("GET", path+"?$search="+url.QueryEscape(search), nil)
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for invalid search %q on %s, got %d", search, path, w.Code)
+			}
+		}
+	}
+}
+
+func TestServer_Facets(t *testing.T) {
+	cacheManager := cache.NewManager(5 * time.Minute)
+
+	cfg := &config.Config{
+		MaxContentLength: 10000,
+		Feeds: map[string]config.TopicConfig{
+			"tech": {
+				URLs: []string{"http://example.com/tech"},
+			},
+		},
+	}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+
+	server := NewServer(agg, p, cfg)
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/articles?$facets=source,category,published", nil)
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	facets, ok := response["facets"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected facets in response, got %v", response["facets"])
+	}
+	for _, facet := range []string{"source", "category", "published"} {
+		if _, ok := facets[facet]; !ok {
+			t.Errorf("Expected %s facet in response", facet)
+		}
+	}
+
+	// Unknown facets are rejected on both article endpoints
+	for _, path := range []string{"/api/v1/articles", "/api/v1/feeds/tech"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", path+"?$facets=source,color", nil)
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for unknown facet on %s, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestServer_QueryParamValidation(t *testing.T) {
+	cacheManager := cache.NewManager(5 * time.Minute)
+
+	cfg := &config.Config{
+		MaxContentLength: 10000,
+		Feeds: map[string]config.TopicConfig{
+			"tech": {
+				URLs: []string{"http://example.com/tech"},
+			},
+		},
+	}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+
+	server := NewServer(agg, p, cfg)
+	gin.SetMode(gin.TestMode)
+
+	token := odata.EncodeSkipToken(models.Article{ID: "abc", PublishedAt: time.Now()})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/articles?$skiptoken="+token, nil)
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for valid skip token, got %d", w.Code)
+	}
+
+	invalid := []string{
+		"$skiptoken=garbage",
+		"$skiptoken=" + token + "&$skip=10",
+		"$skiptoken=" + token + "&$orderby=" + url.QueryEscape("title asc"),
+		"$cluster=maybe",
+	}
+
+	for _, path := range []string{"/api/v1/articles", "/api/v1/feeds/tech"} {
+		for _, params := range invalid {
+			w = httptest.NewRecorder()
+			req, _ = http.NewRequest("GET", path+"?"+params, nil)
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for %s?%s, got %d", path, params, w.Code)
+			}
+		}
+	}
+}
+
+func TestServer_GetRelatedArticles(t *testing.T) {
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{MaxContentLength: 10000}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+
+	server := NewServer(agg, p, cfg)
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/api/v1/articles/does-not-exist/related", http.StatusNotFound},
+		{"/api/v1/articles/does-not-exist/related?$top=0", http.StatusBadRequest},
+		{"/api/v1/articles/does-not-exist/related?$top=abc", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("GET %s: expected status %d, got %d", tt.path, tt.status, w.Code)
+		}
+	}
+}
+
+func TestServer_PinArticle(t *testing.T) {
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{MaxContentLength: 10000}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+
+	ctx := context.Background()
+	articles := []models.Article{
+		{ID: "kept", Title: "Kept article", Link: "https://example.com/kept", Source: "Test", PublishedAt: time.Now()},
+		{ID: "other", Title: "Other article", Link: "https://example.com/other", Source: "Test", PublishedAt: time.Now().Add(-time.Hour)},
+	}
+	if err := storageManager.SaveArticles(ctx, articles); err != nil {
+		t.Fatalf("SaveArticles() error = %v", err)
+	}
+
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+
+	server := NewServer(agg, p, cfg)
+	gin.SetMode(gin.TestMode)
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	pinnedIDs := func(path string) []string {
+		w := request("GET", path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status 200, got %d", path, w.Code)
+		}
+		var response struct {
+			Articles []models.Article `json:"articles"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		var ids []string
+		for _, article := range response.Articles {
+			if !article.Pinned {
+				t.Errorf("GET %s returned unpinned article %s", path, article.ID)
+			}
+			ids = append(ids, article.ID)
+		}
+		return ids
+	}
+
+	if w := request("POST", "/api/v1/articles/missing/pin"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 pinning an unknown article, got %d", w.Code)
+	}
+	if w := request("POST", "/api/v1/articles/kept/pin"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 pinning an article, got %d", w.Code)
+	}
+
+	for _, path := range []string{"/api/v1/articles?$pinned=true", "/api/v1/articles?$filter=pinned%20eq%20true"} {
+		if ids := pinnedIDs(path); len(ids) != 1 || ids[0] != "kept" {
+			t.Errorf("GET %s returned %v, want [kept]", path, ids)
+		}
+	}
+	if w := request("GET", "/api/v1/articles?$pinned=maybe"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid $pinned, got %d", w.Code)
+	}
+
+	if w := request("DELETE", "/api/v1/articles/kept/pin"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 unpinning an article, got %d", w.Code)
+	}
+	if ids := pinnedIDs("/api/v1/articles?$pinned=true"); len(ids) != 0 {
+		t.Errorf("Expected no pinned articles after unpinning, got %v", ids)
+	}
+}
+
+func TestServer_AdminBackup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cacheManager := cache.NewManager(5 * time.Minute)
+
+	newServer := func(cfg *config.Config, store storage.Storage) *Server {
+		agg := aggregator.New(cacheManager, store, cfg.Feeds)
+		p := poller.New(agg, cacheManager, store, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+		return NewServer(agg, p, cfg)
+	}
+	backup := func(server *Server, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/admin/backup", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	sqliteStorage, err := storage.NewSQLiteStorage(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	// Disabled without a configured token
+	if w := backup(newServer(&config.Config{}, sqliteStorage), "secret"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 without ADMIN_TOKEN, got %d", w.Code)
+	}
+
+	cfg := &config.Config{AdminToken: "secret"}
+	server := newServer(cfg, sqliteStorage)
+
+	for _, token := range []string{"", "wrong"} {
+		if w := backup(server, token); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for token %q, got %d", token, w.Code)
+		}
+	}
+
+	w := backup(server, "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/gzip" {
+		t.Errorf("Expected gzip content type, got %q", contentType)
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Backup is not gzip compressed: %v", err)
+	}
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(gz, header); err != nil || string(header) != "SQLite format 3\x00" {
+		t.Errorf("Backup is not a SQLite database: %q (%v)", header, err)
+	}
+
+	// Backends without snapshots report it
+	memoryStorage := storage.NewMemoryStorage(cfg)
+	defer memoryStorage.Close()
+	if w := backup(newServer(cfg, memoryStorage), "secret"); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 for the memory driver, got %d", w.Code)
+	}
+}
+
+func TestServer_DeleteSourceFeed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cacheManager := cache.NewManager(5 * time.Minute)
+	feedURL := "https://example.com/rss"
+	cfg := &config.Config{
+		MaxContentLength: 10000,
+		AdminToken:       "secret",
+		Feeds: map[string]config.TopicConfig{
+			"tech": {URLs: []string{feedURL, "https://example.com/other"}},
+		},
+	}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+	ctx := context.Background()
+	articles := []models.Article{
+		{ID: "a1", Title: "Only here", Link: "https://example.com/a1", Content: "First", Source: "Example", PublishedAt: time.Now()},
+		{ID: "a2", Title: "Pinned", Link: "https://example.com/a2", Content: "Second", Source: "Example", PublishedAt: time.Now()},
+	}
+	if err := storageManager.SaveArticles(ctx, articles); err != nil {
+		t.Fatalf("SaveArticles() error = %v", err)
+	}
+	if err := storageManager.AssignArticlesToFeed(ctx, []string{"a1", "a2"}, feedURL, "Example"); err != nil {
+		t.Fatalf("AssignArticlesToFeed() error = %v", err)
+	}
+	if err := storageManager.SetArticlePinned(ctx, "a2", true); err != nil {
+		t.Fatalf("SetArticlePinned() error = %v", err)
+	}
+
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+	server := NewServer(agg, p, cfg)
+
+	request := func(path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	for path, status := range map[string]int{
+		"/api/v1/feeds/abc":             http.StatusBadRequest,
+		"/api/v1/feeds/1?purge=perhaps": http.StatusBadRequest,
+		"/api/v1/feeds/42":              http.StatusNotFound,
+	} {
+		if w := request(path, "secret"); w.Code != status {
+			t.Errorf("DELETE %s: expected status %d, got %d", path, status, w.Code)
+		}
+	}
+	if w := request("/api/v1/feeds/1?purge=true", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without token, got %d", w.Code)
+	}
+
+	w := request("/api/v1/feeds/1?purge=true", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var deletion models.FeedDeletion
+	if err := json.Unmarshal(w.Body.Bytes(), &deletion); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if deletion.DeletedArticles != 1 || deletion.KeptArticles != 1 || !reflect.DeepEqual(deletion.UnsubscribedTopics, []string{"tech"}) {
+		t.Errorf("Unexpected deletion %+v, want a1 deleted, a2 kept and tech unsubscribed", deletion)
+	}
+	if urls := agg.GetAllUniqueFeedURLs(); !reflect.DeepEqual(urls, []string{"https://example.com/other"}) {
+		t.Errorf("Feed URLs after deletion = %v, want only the other feed", urls)
+	}
+	if remaining, err := storageManager.GetArticles(ctx, []string{"a1", "a2"}); err != nil || len(remaining) != 1 || remaining[0].ID != "a2" {
+		t.Errorf("Articles after purge = %v, %v, want only the pinned a2", remaining, err)
+	}
+}
+
+func TestServer_GetStorageHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{MaxContentLength: 10000}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := storageManager.RecordStatsSnapshot(context.Background()); err != nil {
+			t.Fatalf("RecordStatsSnapshot() error = %v", err)
+		}
+	}
+
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+	server := NewServer(agg, p, cfg)
+
+	for _, path := range []string{
+		"/api/v1/storage/stats/history?since=yesterday",
+		"/api/v1/storage/stats/history?horizon=0",
+		"/api/v1/storage/stats/history?capacity=lots",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected status 400, got %d", path, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/storage/stats/history?horizon=7&capacity=1000000", nil)
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Snapshots []models.StatsSnapshot `json:"snapshots"`
+		Forecast  models.StorageForecast `json:"forecast"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Snapshots) != 2 || response.Forecast.Samples != 2 || response.Forecast.HorizonDays != 7 || response.Forecast.CapacityBytes != 1000000 {
+		t.Errorf("Unexpected history %+v", response)
+	}
+}
+
+func TestServer_StreamArticles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{MaxContentLength: 10000}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+	server := NewServer(agg, p, cfg)
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/stream?$filter=title%20zz%20'x'", nil)
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid filter, got %d", w.Code)
+	}
+
+	bus := agg.Events()
+	bus.Publish(models.Article{ID: "missed-1", Title: "Go release"}, []string{"tech"})
+	bus.Publish(models.Article{ID: "missed-2", Title: "Go conference"}, []string{"tech"})
+	bus.Publish(models.Article{ID: "other-topic", Title: "Go elections"}, []string{"news"})
+	bus.Publish(models.Article{ID: "filtered", Title: "Rust release"}, []string{"tech"})
+
+	req, _ = http.NewRequest("GET", httpServer.URL+"/api/v1/stream?topic=tech&$filter="+url.QueryEscape("startswith(title, 'Go')"), nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Stream request error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// The replayed event is followed by live ones once subscribed
+	go func() {
+		for bus.Subscribers() == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		bus.Publish(models.Article{ID: "live", Title: "Go live"}, []string{"tech", "news"})
+	}()
+
+	reader := bufio.NewReader(resp.Body)
+	var ids []string
+	for len(ids) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Stream read error = %v", err)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var article models.ArticleDetail
+			if err := json.Unmarshal([]byte(data), &article); err != nil {
+				t.Fatalf("Invalid event data %q: %v", data, err)
+			}
+			ids = append(ids, article.ID)
+		}
+	}
+	if !reflect.DeepEqual(ids, []string{"missed-2", "live"}) {
+		t.Errorf("Streamed %v, want [missed-2 live]", ids)
+	}
+}
+
+func TestServer_GetArticleRevisions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{MaxContentLength: 10000}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+
+	article := models.Article{ID: "story", Title: "Draft headline", Link: "https://example.com/story", Content: "Body", Source: "Wire", PublishedAt: time.Now()}
+	corrected := article
+	corrected.Title = "Final headline"
+	for _, version := range []models.Article{article, corrected} {
+		if err := storageManager.SaveArticles(context.Background(), []models.Article{version}); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+	}
+
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+	server := NewServer(agg, p, cfg)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/articles/missing/revisions", nil)
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown article, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/articles/story/revisions", nil)
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		Revisions []models.ArticleRevision `json:"revisions"`
+		Count     int                      `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Count != 1 || response.Revisions[0].Title != "Draft headline" {
+		t.Errorf("Unexpected revisions: %+v", response)
+	}
+}
+
+func TestServer_GetArticle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{MaxContentLength: 10000, Security: config.SecurityConfig{MaxRequestSize: 1 << 20}}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+
+	articles := []models.Article{
+		{ID: "first", Title: "First story", Link: "https://example.com/first", Content: "Full body", Source: "Wire", PublishedAt: time.Now()},
+		{ID: "second", Title: "Second story", Link: "https://example.com/second", Source: "Wire", PublishedAt: time.Now()},
+	}
+	if err := storageManager.SaveArticles(context.Background(), articles); err != nil {
+		t.Fatalf("SaveArticles() error = %v", err)
+	}
+	if err := storageManager.AssignArticlesToTopic(context.Background(), []string{"first"}, "tech"); err != nil {
+		t.Fatalf("AssignArticlesToTopic() error = %v", err)
+	}
+
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+	server := NewServer(agg, p, cfg)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/articles/missing", nil)
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown article, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/articles/first", nil)
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var article models.ArticleDetail
+	if err := json.Unmarshal(w.Body.Bytes(), &article); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if article.Content != "Full body" || len(article.Topics) != 1 || article.Topics[0] != "tech" {
+		t.Errorf("Unexpected article: %+v", article)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/articles/batch", strings.NewReader(`{"ids": ["second", "missing", "first"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var batch struct {
+		Articles []models.ArticleDetail `json:"articles"`
+		Count    int                    `json:"count"`
+		Missing  []string               `json:"missing"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &batch); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if batch.Count != 2 || batch.Articles[0].ID != "second" || batch.Articles[1].ID != "first" || len(batch.Missing) != 1 || batch.Missing[0] != "missing" {
+		t.Errorf("Unexpected batch response: %+v", batch)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/articles/batch", strings.NewReader(`{"ids": []}`))
+	req.Header.Set("Content-Type", "application/json")
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an empty batch, got %d", w.Code)
+	}
+}
+
+func TestServer_ArchivedArticles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{MaxContentLength: 10000, DataDir: t.TempDir()}
+
+	archived := []models.ExportedArticle{
+		{Article: models.Article{ID: "old-1", Title: "Archived one", Link: "https://example.com/old-1", Source: "Example", PublishedAt: time.Date(2023, 3, 10, 0, 0, 0, 0, time.UTC)}, Topics: []string{"tech"}},
+		{Article: models.Article{ID: "old-2", Title: "Archived two", Link: "https://example.com/old-2", Source: "Example", PublishedAt: time.Date(2023, 1, 5, 0, 0, 0, 0, time.UTC)}, Topics: []string{"news"}},
+	}
+	if err := storage.NewArchive(storage.ArchiveDir(cfg.DataDir)).Store(context.Background(), archived); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+	server := NewServer(agg, p, cfg)
+
+	tests := []struct {
+		path   string
+		status int
+		ids    string
+	}{
+		{"/api/v1/articles?$archive=true", http.StatusOK, "old-1,old-2"},
+		{"/api/v1/articles?$archive=true&$filter=topic%20eq%20'news'", http.StatusOK, "old-2"},
+		{"/api/v1/articles?$archive=true&$search=one", http.StatusOK, "old-1"},
+		{"/api/v1/articles", http.StatusOK, ""},
+		{"/api/v1/articles?$archive=maybe", http.StatusBadRequest, ""},
+		{"/api/v1/articles?$archive=true&$cluster=true", http.StatusBadRequest, ""},
+		{"/api/v1/articles?$archive=true&$orderby=title", http.StatusBadRequest, ""},
+		{"/api/v1/articles?$archive=true&$feed_url=https://example.com/feed", http.StatusBadRequest, ""},
+		{"/api/v1/articles?$archive=true&$filter=feed_url%20eq%20'https://example.com/feed'", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		server.router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("GET %s: expected status %d, got %d", tt.path, tt.status, w.Code)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+
+		var response struct {
+			Articles []models.Article `json:"articles"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("GET %s: failed to decode response: %v", tt.path, err)
+		}
+		var ids []string
+		for _, article := range response.Articles {
+			ids = append(ids, article.ID)
+		}
+		if got := strings.Join(ids, ","); got != tt.ids {
+			t.Errorf("GET %s returned %s, want %s", tt.path, got, tt.ids)
+		}
+	}
+}
+
+func TestServer_ExportImportArticles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{MaxContentLength: 10000, AdminToken: "secret", Security: config.SecurityConfig{MaxRequestSize: 10 << 20}}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+	article := models.Article{ID: "a1", Title: "Exported", Link: "https://example.com/a1", Source: "Example", PublishedAt: time.Now()}
+	if err := storageManager.SaveArticles(context.Background(), []models.Article{article}); err != nil {
+		t.Fatalf("SaveArticles() error = %v", err)
+	}
+	if err := storageManager.AssignArticlesToTopic(context.Background(), []string{"a1"}, "tech"); err != nil {
+		t.Fatalf("AssignArticlesToTopic() error = %v", err)
+	}
+
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+	server := NewServer(agg, p, cfg)
+
+	for path, status := range map[string]int{
+		"/api/v1/export?since=yesterday": http.StatusBadRequest,
+		"/api/v1/export?cursor=%21":      http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		server.router.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("GET %s: expected status %d, got %d", path, status, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/export?topic=tech&since=2000-01-01", nil)
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("Expected NDJSON export, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	export := w.Body.String()
+	var line models.ExportedArticle
+	if err := json.Unmarshal([]byte(export), &line); err != nil || line.ID != "a1" || len(line.Topics) != 1 || line.Cursor == "" {
+		t.Errorf("Unexpected export line %q: %v", export, err)
+	}
+
+	// Imports require the admin token
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/admin/import", strings.NewReader(export))
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without token, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/admin/import", strings.NewReader(export+"not json\n"))
+	req.Header.Set("Authorization", "Bearer secret")
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid line, got %d", w.Code)
+	}
+
+	// The exported article is already stored, so it is left alone
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/admin/import", strings.NewReader(export))
+	req.Header.Set("Authorization", "Bearer secret")
+	server.router.ServeHTTP(w, req)
+	var result models.ImportResult
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &result) != nil || result.Imported != 0 || result.Existing != 1 {
+		t.Errorf("Expected 1 existing article, got %d %s", w.Code, w.Body.String())
+	}
+}
+
 // Test helper functions
 func TestSearchArticles(t *testing.T) {
 	articles := []models.Article{
@@ -1160,7 +1932,7 @@ func TestApplyAdvancedFilters(t *testing.T) {
 		},
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -1196,3 +1968,464 @@ func TestApplyAdvancedFilters(t *testing.T) {
 		t.Errorf("Expected 2 articles with no filters, got %d", len(filtered))
 	}
 }
+
+func TestServer_Webhooks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{
+		MaxContentLength: 10000,
+		AdminToken:       "secret",
+		Security:         config.SecurityConfig{MaxRequestSize: 1 << 20},
+		Feeds: map[string]config.TopicConfig{
+			"tech": {URLs: []string{"https://example.com/rss"}},
+		},
+	}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+	server := NewServer(agg, p, cfg)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/json")
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	for body, status := range map[string]int{
+		`{"url": "ftp://example.com", "topic": "tech"}`:                              http.StatusBadRequest,
+		`{"url": "https://hooks.example.com", "topic": "sports"}`:                    http.StatusBadRequest,
+		`{"url": "https://hooks.example.com", "topic": "tech", "filter": "title ~"}`: http.StatusBadRequest,
+		`not json`: http.StatusBadRequest,
+	} {
+		if w := request("POST", "/api/v1/webhooks", body); w.Code != status {
+			t.Errorf("POST %s: expected status %d, got %d", body, status, w.Code)
+		}
+	}
+
+	w := request("POST", "/api/v1/webhooks", `{"url": "https://hooks.example.com/in", "topic": "tech", "filter": "contains(title, 'Go')"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.Webhook
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to unmarshal webhook: %v", err)
+	}
+	if created.ID == 0 || len(created.Secret) != 64 {
+		t.Errorf("Expected an ID and a generated secret, got %+v", created)
+	}
+
+	w = request("GET", "/api/v1/webhooks", "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), created.Secret) || !strings.Contains(w.Body.String(), `"count":1`) {
+		t.Errorf("Expected one listed webhook without its secret, got %d: %s", w.Code, w.Body.String())
+	}
+
+	ctx := context.Background()
+	articles := []models.Article{
+		{ID: "a1", Title: "Go release", Link: "https://example.com/a1", Content: "First", Source: "Example", PublishedAt: time.Now()},
+		{ID: "a2", Title: "Rust release", Link: "https://example.com/a2", Content: "Second", Source: "Example", PublishedAt: time.Now()},
+	}
+	if err := storageManager.SaveArticles(ctx, articles); err != nil {
+		t.Fatalf("SaveArticles() error = %v", err)
+	}
+	if err := storageManager.AssignArticlesToTopic(ctx, []string{"a1", "a2"}, "tech"); err != nil {
+		t.Fatalf("AssignArticlesToTopic() error = %v", err)
+	}
+
+	path := fmt.Sprintf("/api/v1/webhooks/%d/deliveries", created.ID)
+	w = request("GET", path+"?status=pending", "")
+	var deliveryLog struct {
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+		Count      int                      `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &deliveryLog); err != nil || deliveryLog.Count != 1 || deliveryLog.Deliveries[0].ArticleID != "a1" {
+		t.Fatalf("Expected the Go article pending, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request("GET", path+"?status=lost", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown status, got %d", w.Code)
+	}
+
+	// A dead delivery can be queued again
+	dead := deliveryLog.Deliveries[0]
+	dead.Status = models.WebhookDeliveryDead
+	dead.Attempts = 6
+	if err := storageManager.UpdateWebhookDelivery(ctx, &dead); err != nil {
+		t.Fatalf("UpdateWebhookDelivery() error = %v", err)
+	}
+	w = request("POST", fmt.Sprintf("%s/%d/retry", path, dead.ID), "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"pending"`) || !strings.Contains(w.Body.String(), `"attempts":0`) {
+		t.Errorf("Expected the delivery pending again, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request("POST", fmt.Sprintf("/api/v1/webhooks/%d/deliveries/%d/retry", created.ID+1, dead.ID), ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another webhook's delivery, got %d", w.Code)
+	}
+
+	if w := request("DELETE", fmt.Sprintf("/api/v1/webhooks/%d", created.ID), ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if w := request("GET", fmt.Sprintf("/api/v1/webhooks/%d", created.ID), ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after delete, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/webhooks", nil)
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without token, got %d", w.Code)
+	}
+}
+
+func TestServer_Digests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{
+		MaxContentLength: 10000,
+		AdminToken:       "secret",
+		Security:         config.SecurityConfig{MaxRequestSize: 1 << 20},
+		Feeds: map[string]config.TopicConfig{
+			"tech": {URLs: []string{"https://example.com/rss"}},
+		},
+	}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+	server := NewServer(agg, p, cfg)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/json")
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	for body, status := range map[string]int{
+		`{"recipient": "not an address", "topic": "tech", "schedule": "daily"}`:                       http.StatusBadRequest,
+		`{"recipient": "team@example.com", "topic": "sports", "schedule": "daily"}`:                   http.StatusBadRequest,
+		`{"recipient": "team@example.com", "topic": "tech", "schedule": "hourly"}`:                    http.StatusBadRequest,
+		`{"recipient": "team@example.com", "topic": "tech", "schedule": "daily", "filter": "x ~"}`:    http.StatusBadRequest,
+		`{"recipient": "team@example.com", "topic": "tech", "schedule": "daily", "subject": "{{"}`:    http.StatusBadRequest,
+		`{"recipient": "team@example.com", "topic": "tech", "schedule": "daily", "max_articles": -1}`: http.StatusBadRequest,
+		`not json`: http.StatusBadRequest,
+	} {
+		if w := request("POST", "/api/v1/digests", body); w.Code != status {
+			t.Errorf("POST %s: expected status %d, got %d", body, status, w.Code)
+		}
+	}
+
+	w := request("POST", "/api/v1/digests", `{"recipient": "team@example.com", "topic": "tech", "schedule": "weekly fri 09:00", "filter": "contains(title, 'Go')"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.Digest
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to unmarshal digest: %v", err)
+	}
+	if created.ID == 0 || created.MaxArticles != 20 || created.NextSendAt.Weekday() != time.Friday || created.NextSendAt.Hour() != 9 {
+		t.Errorf("Expected defaults and the next Friday 09:00, got %+v", created)
+	}
+
+	if w := request("GET", "/api/v1/digests", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"count":1`) {
+		t.Errorf("Expected one listed digest, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Only articles assigned after the subscription are previewed
+	ctx := context.Background()
+	articles := []models.Article{
+		{ID: "a1", Title: "Go release", Link: "https://example.com/a1", Content: "First", Source: "Example", PublishedAt: time.Now()},
+		{ID: "a2", Title: "Rust release", Link: "https://example.com/a2", Content: "Second", Source: "Example", PublishedAt: time.Now()},
+	}
+	if err := storageManager.SaveArticles(ctx, articles); err != nil {
+		t.Fatalf("SaveArticles() error = %v", err)
+	}
+	if err := storageManager.AssignArticlesToTopic(ctx, []string{"a1", "a2"}, "tech"); err != nil {
+		t.Fatalf("AssignArticlesToTopic() error = %v", err)
+	}
+
+	path := fmt.Sprintf("/api/v1/digests/%d", created.ID)
+	w = request("GET", path+"/preview", "")
+	var preview struct {
+		Subject string `json:"subject"`
+		Text    string `json:"text"`
+		HTML    string `json:"html"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil || preview.Subject != "1 new article in tech" {
+		t.Fatalf("Expected a one article preview, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(preview.Text, "Go release") || strings.Contains(preview.Text, "Rust release") {
+		t.Errorf("Expected the filtered article only, got:\n%s", preview.Text)
+	}
+	w = request("GET", path+"/preview?format=html", "")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") || !strings.Contains(w.Body.String(), "https://example.com/a1") {
+		t.Errorf("Expected the HTML body, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request("GET", path+"/preview?format=pdf", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown format, got %d", w.Code)
+	}
+
+	if w := request("DELETE", path, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if w := request("GET", path, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after delete, got %d", w.Code)
+	}
+	if w := request("GET", "/api/v1/digests/abc", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid id, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/digests", nil)
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without token, got %d", w.Code)
+	}
+}
+
+func TestServer_GoogleReader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cacheManager := cache.NewManager(5 * time.Minute)
+	cfg := &config.Config{
+		MaxContentLength: 10000,
+		ReaderUsername:   "reader@example.com",
+		ReaderPassword:   "hunter2",
+		Security:         config.SecurityConfig{MaxRequestSize: 1 << 20},
+		Feeds: map[string]config.TopicConfig{
+			"tech":     {URLs: []string{"https://example.com/rss"}},
+			"security": {URLs: []string{"https://example.com/rss", "https://sec.example.com/feed"}},
+		},
+	}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+	p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+	server := NewServer(agg, p, cfg)
+
+	ctx := context.Background()
+	published := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	articles := []models.Article{
+		{ID: "a1", Title: "Go release", Link: "https://example.com/a1", Content: "<p>First</p>", Source: "Example", PublishedAt: published},
+		{ID: "a2", Title: "Zero day", Link: "https://sec.example.com/a2", Description: "Second", Source: "Sec", PublishedAt: published.Add(time.Hour)},
+	}
+	if err := storageManager.SaveArticles(ctx, articles); err != nil {
+		t.Fatalf("SaveArticles() error = %v", err)
+	}
+	if err := storageManager.AssignArticlesToFeed(ctx, []string{"a1"}, "https://example.com/rss", "Example"); err != nil {
+		t.Fatalf("AssignArticlesToFeed() error = %v", err)
+	}
+	if err := storageManager.AssignArticlesToTopic(ctx, []string{"a1"}, "tech"); err != nil {
+		t.Fatalf("AssignArticlesToTopic() error = %v", err)
+	}
+	if err := storageManager.AssignArticlesToTopic(ctx, []string{"a2"}, "security"); err != nil {
+		t.Fatalf("AssignArticlesToTopic() error = %v", err)
+	}
+
+	request := func(method, path string, form url.Values, auth string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if auth != "" {
+			req.Header.Set("Authorization", "GoogleLogin auth="+auth)
+		}
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// ClientLogin hands out the token the other endpoints require
+	if w := request("POST", "/accounts/ClientLogin", url.Values{"Email": {"reader@example.com"}, "Passwd": {"wrong"}}, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected bad credentials to be refused, got %d", w.Code)
+	}
+	if w := request("POST", "/accounts/ClientLogin?Email=reader%40example.com&Passwd=hunter2", nil, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected credentials in the query string to be refused, got %d", w.Code)
+	}
+	if w := request("GET", "/accounts/ClientLogin?Email=reader%40example.com&Passwd=hunter2", nil, ""); w.Code == http.StatusOK {
+		t.Errorf("Expected GET logins to be refused, got %d", w.Code)
+	}
+	w := request("POST", "/accounts/ClientLogin", url.Values{"Email": {"reader@example.com"}, "Passwd": {"hunter2"}}, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var auth string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, "Auth="); ok {
+			auth = value
+		}
+	}
+	if auth == "" {
+		t.Fatalf("Expected an Auth line, got %q", w.Body.String())
+	}
+	if w := request("GET", "/reader/api/0/tag/list", nil, "forged"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a forged token to be refused, got %d", w.Code)
+	}
+
+	readJSON := func(w *httptest.ResponseRecorder, v interface{}) {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("Failed to unmarshal %s: %v", w.Body.String(), err)
+		}
+	}
+
+	var tags struct {
+		Tags []struct{ ID, Type string }
+	}
+	readJSON(request("GET", "/reader/api/0/tag/list?output=json", nil, auth), &tags)
+	if len(tags.Tags) != 3 || tags.Tags[0].ID != "user/-/state/com.google/starred" || tags.Tags[1].ID != "user/-/label/security" || tags.Tags[1].Type != "folder" {
+		t.Errorf("Expected the starred state and the topics as folders, got %+v", tags.Tags)
+	}
+
+	var subscriptions struct {
+		Subscriptions []struct {
+			ID, Title  string
+			Categories []struct{ ID, Label string }
+		}
+	}
+	readJSON(request("GET", "/reader/api/0/subscription/list?output=json", nil, auth), &subscriptions)
+	if len(subscriptions.Subscriptions) != 2 {
+		t.Fatalf("Expected 2 subscriptions, got %+v", subscriptions)
+	}
+	if first := subscriptions.Subscriptions[0]; first.ID != "feed/https://example.com/rss" || first.Title != "Example" || len(first.Categories) != 2 || first.Categories[0].ID != "user/-/label/security" {
+		t.Errorf("Expected the polled feed in both topics, got %+v", first)
+	}
+	if second := subscriptions.Subscriptions[1]; second.Title != "https://sec.example.com/feed" {
+		t.Errorf("Expected an unpolled feed to be titled by its URL, got %+v", second)
+	}
+
+	type itemRefs struct {
+		ItemRefs     []struct{ ID string }
+		Continuation string
+	}
+	var refs itemRefs
+	readJSON(request("GET", "/reader/api/0/stream/items/ids?output=json&s=user/-/state/com.google/reading-list&n=1", nil, auth), &refs)
+	if len(refs.ItemRefs) != 1 || refs.Continuation != "1" {
+		t.Fatalf("Expected the first page of the reading list, got %+v", refs)
+	}
+	newest := refs.ItemRefs[0].ID
+	readJSON(request("GET", "/reader/api/0/stream/items/ids?output=json&s=user/-/state/com.google/reading-list&n=1&c=1", nil, auth), &refs)
+	if len(refs.ItemRefs) != 1 || refs.ItemRefs[0].ID == newest {
+		t.Fatalf("Expected the second page to hold the other item, got %+v", refs)
+	}
+	oldest := refs.ItemRefs[0].ID
+
+	type contents struct {
+		Items []struct {
+			ID, Title  string
+			Categories []string
+			Origin     struct{ StreamID string }
+			Summary    struct{ Content string }
+		}
+		Continuation string
+	}
+	var stream contents
+	readJSON(request("GET", "/reader/api/0/stream/contents/"+url.PathEscape("user/-/label/tech")+"?output=json", nil, auth), &stream)
+	if len(stream.Items) != 1 || stream.Items[0].Title != "Go release" || stream.Items[0].Summary.Content != "<p>First</p>" || stream.Items[0].Origin.StreamID != "feed/https://example.com/rss" {
+		t.Fatalf("Expected the article of the tech topic, got %+v", stream)
+	}
+	longID := stream.Items[0].ID
+	if id, err := parseReaderItemID(longID); err != nil || strconv.FormatInt(id, 10) != oldest {
+		t.Errorf("Expected long ID %s to be item %s, got %d, %v", longID, oldest, id, err)
+	}
+	if !reflect.DeepEqual(stream.Items[0].Categories, []string{"user/-/state/com.google/reading-list", "user/-/label/tech"}) {
+		t.Errorf("Expected the reading list and topic categories, got %v", stream.Items[0].Categories)
+	}
+
+	// Read and starred tags, by long and short item IDs
+	if w := request("POST", "/reader/api/0/edit-tag", url.Values{"i": {longID, newest}, "a": {"user/-/state/com.google/read"}, "T": {"token"}}, auth); w.Code != http.StatusOK || w.Body.String() != "OK" {
+		t.Fatalf("Expected OK, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request("POST", "/reader/api/0/edit-tag", url.Values{"i": {newest}, "a": {"user/-/state/com.google/starred"}, "r": {"user/-/state/com.google/read"}}, auth); w.Code != http.StatusOK {
+		t.Fatalf("Expected OK, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request("POST", "/reader/api/0/edit-tag", url.Values{"i": {"not-an-id"}, "a": {"user/-/state/com.google/read"}}, auth); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid item ID to be refused, got %d", w.Code)
+	}
+	refs = itemRefs{}
+	readJSON(request("GET", "/reader/api/0/stream/items/ids?output=json&s=user/-/state/com.google/reading-list&xt=user/-/state/com.google/read", nil, auth), &refs)
+	if len(refs.ItemRefs) != 1 || refs.ItemRefs[0].ID != newest || refs.Continuation != "" {
+		t.Errorf("Expected only the item marked unread again, got %+v", refs)
+	}
+
+	readJSON(request("POST", "/reader/api/0/stream/items/contents?output=json", url.Values{"i": {newest}}, auth), &stream)
+	if len(stream.Items) != 1 || stream.Items[0].Summary.Content != "Second" ||
+		!reflect.DeepEqual(stream.Items[0].Categories, []string{"user/-/state/com.google/reading-list", "user/-/label/security", "user/-/state/com.google/starred"}) {
+		t.Errorf("Expected the starred unread article, got %+v", stream.Items)
+	}
+	readJSON(request("GET", "/reader/api/0/stream/contents/user/1/state/com.google/starred?output=json", nil, auth), &stream)
+	if len(stream.Items) != 1 || stream.Items[0].Title != "Zero day" {
+		t.Errorf("Expected the starred stream with a numeric user ID, got %+v", stream.Items)
+	}
+
+	var counts struct {
+		UnreadCounts []struct {
+			ID    string
+			Count int
+		}
+	}
+	readJSON(request("GET", "/reader/api/0/unread-count?output=json", nil, auth), &counts)
+	if len(counts.UnreadCounts) != 2 || counts.UnreadCounts[1].ID != "user/-/state/com.google/reading-list" || counts.UnreadCounts[1].Count != 1 {
+		t.Errorf("Expected one unread item in security and the reading list, got %+v", counts.UnreadCounts)
+	}
+
+	if w := request("POST", "/reader/api/0/mark-all-as-read", url.Values{"s": {"user/-/label/security"}, "ts": {strconv.FormatInt(time.Now().UnixMicro(), 10)}}, auth); w.Code != http.StatusOK {
+		t.Fatalf("Expected OK, got %d: %s", w.Code, w.Body.String())
+	}
+	refs = itemRefs{}
+	readJSON(request("GET", "/reader/api/0/stream/items/ids?output=json&s=user/-/state/com.google/reading-list&xt=user/-/state/com.google/read", nil, auth), &refs)
+	if len(refs.ItemRefs) != 0 {
+		t.Errorf("Expected no unread items left, got %+v", refs)
+	}
+
+	if w := request("GET", "/reader/api/0/stream/items/ids?s=user/-/state/com.google/broadcast", nil, auth); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unsupported stream to be refused, got %d", w.Code)
+	}
+	if w := request("GET", "/reader/api/0/token", nil, auth); w.Code != http.StatusOK || len(w.Body.String()) != 57 {
+		t.Errorf("Expected a 57 character edit token, got %d: %q", w.Code, w.Body.String())
+	}
+}
+
+func TestServer_GoogleReaderCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	login := func(cfg *config.Config, password string) *httptest.ResponseRecorder {
+		cfg.MaxContentLength = 10000
+		cfg.Security = config.SecurityConfig{MaxRequestSize: 1 << 20}
+		storageManager := storage.NewMemoryStorage(cfg)
+		t.Cleanup(func() { storageManager.Close() })
+		cacheManager := cache.NewManager(5 * time.Minute)
+		agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
+		p := poller.New(agg, cacheManager, storageManager, cfg.Feeds, 1*time.Minute, 1*time.Minute, cfg)
+		server := NewServer(agg, p, cfg)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/accounts/ClientLogin", strings.NewReader(url.Values{"Email": {"reader@example.com"}, "Passwd": {password}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A username without a password leaves the API disabled
+	if w := login(&config.Config{ReaderUsername: "reader@example.com"}, ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected an empty password to be refused with 403, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Tokens are signed with the server secret, not derived from the credentials alone
+	tokens := make(map[string]bool)
+	for _, secret := range []string{"first-secret", "second-secret", "second-secret"} {
+		w := login(&config.Config{ReaderUsername: "reader@example.com", ReaderPassword: "hunter2", ReaderSecret: secret}, "hunter2")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		tokens[w.Body.String()] = true
+	}
+	if len(tokens) != 2 {
+		t.Errorf("Expected one token per secret, got %d distinct tokens", len(tokens))
+	}
+}
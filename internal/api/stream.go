@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorssag/internal/events"
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamKeepAlive is how often an idle stream sends a comment so proxies keep it open
+const streamKeepAlive = 30 * time.Second
+
+// streamArticles pushes newly stored articles as Server-Sent Events, limited
+// to a topic and an OData $filter. A reconnecting client sending Last-Event-ID
+// first receives the buffered events it missed.
+func (s *Server) streamArticles(c *gin.Context) {
+	topic := c.Query("topic")
+
+	filterParser := odata.NewFilterParser()
+	var filter *odata.FilterExpression
+	if filterStr := c.Query("$filter"); filterStr != "" {
+		expr, err := filterParser.Parse(filterStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid filter expression: %v", err)})
+			return
+		}
+		filter = expr
+	}
+
+	lastEventID, err := strconv.ParseUint(c.GetHeader("Last-Event-ID"), 10, 64)
+	resume := err == nil
+
+	sub, missed := s.aggregator.Events().Subscribe(lastEventID, resume)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	send := func(event events.Event) bool {
+		if topic != "" && !containsTopic(event.Topics, topic) {
+			return true
+		}
+		if filter != nil {
+			matches, err := filterParser.Evaluate(filter, event.Article)
+			if err != nil || !matches {
+				return true
+			}
+		}
+
+		data, err := json.Marshal(models.ArticleDetail{Article: event.Article, Topics: event.Topics})
+		if err != nil {
+			log.Printf("Warning: failed to encode stream event %d: %v", event.ID, err)
+			return true
+		}
+		if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: article\ndata: %s\n\n", event.ID, data); err != nil {
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	}
+
+	for _, event := range missed {
+		if !send(event) {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			// Dropped for falling behind, the client resumes with Last-Event-ID
+			if !ok || !send(event) {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// containsTopic reports whether topics holds topic
+func containsTopic(topics []string, topic string) bool {
+	for _, t := range topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
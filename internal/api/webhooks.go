@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"gorssag/internal/aggregator"
+	"gorssag/internal/models"
+	"gorssag/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Delivery log page sizes
+const (
+	defaultWebhookDeliveries = 50
+	maxWebhookDeliveries     = 500
+)
+
+// createWebhook subscribes a URL to the articles newly assigned to a topic.
+// The secret, generated when omitted, is only returned here.
+func (s *Server) createWebhook(c *gin.Context) {
+	var request struct {
+		URL    string `json:"url"`
+		Topic  string `json:"topic"`
+		Filter string `json:"filter"`
+		Secret string `json:"secret"`
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	webhook := &models.Webhook{URL: request.URL, Topic: request.Topic, Filter: request.Filter, Secret: request.Secret}
+	if err := s.aggregator.CreateWebhook(c.Request.Context(), webhook); err != nil {
+		if errors.Is(err, aggregator.ErrInvalidWebhook) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error creating webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// listWebhooks returns the webhook subscriptions without their secrets
+func (s *Server) listWebhooks(c *gin.Context) {
+	webhooks, err := s.aggregator.ListWebhooks(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing webhooks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhooks"})
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks, "count": len(webhooks)})
+}
+
+// getWebhook returns a webhook subscription without its secret
+func (s *Server) getWebhook(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	webhook, err := s.aggregator.GetWebhook(c.Request.Context(), id)
+	if !webhookFound(c, id, err) {
+		return
+	}
+	webhook.Secret = ""
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// deleteWebhook unsubscribes a webhook and drops its delivery log
+func (s *Server) deleteWebhook(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	err := s.aggregator.DeleteWebhook(c.Request.Context(), id)
+	if !webhookFound(c, id, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("webhook %d deleted", id)})
+}
+
+// getWebhookDeliveries returns the delivery log of a webhook, newest first,
+// optionally limited to a status (pending, delivered or dead)
+func (s *Server) getWebhookDeliveries(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, delivered or dead"})
+		return
+	}
+
+	limit := defaultWebhookDeliveries
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 || parsed > maxWebhookDeliveries {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxWebhookDeliveries)})
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries, err := s.aggregator.GetWebhookDeliveries(c.Request.Context(), id, status, limit)
+	if !webhookFound(c, id, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries, "count": len(deliveries)})
+}
+
+// retryWebhookDelivery queues a pending or dead delivery again
+func (s *Server) retryWebhookDelivery(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := idParam(c, "deliveryId")
+	if !ok {
+		return
+	}
+
+	delivery, err := s.aggregator.RetryWebhookDelivery(c.Request.Context(), id, deliveryID)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("delivery %d of webhook %d not found", deliveryID, id)})
+		return
+	}
+	if errors.Is(err, aggregator.ErrInvalidWebhook) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// idParam parses a positive ID path parameter, answering 400 otherwise
+func idParam(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
+
+// webhookFound answers 404 or 500 for a failed webhook lookup and reports
+// whether the handler should go on
+func webhookFound(c *gin.Context, id int64, err error) bool {
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("webhook %d not found", id)})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
@@ -13,6 +13,12 @@ type TopicConfig struct {
 	Filters []string // Full-text terms to filter articles
 }
 
+// TopicRetention overrides how long and how many articles a topic keeps
+type TopicRetention struct {
+	MaxAge   time.Duration // 0 uses ArticleRetention
+	MaxCount int           // 0 keeps any number of articles
+}
+
 // SecurityConfig represents security configuration
 type SecurityConfig struct {
 	EnableRateLimit       bool
@@ -29,6 +35,8 @@ type Config struct {
 	Port             int
 	CacheTTL         time.Duration
 	DataDir          string
+	StorageDriver    string // "sqlite" (default), "postgres" or "memory"
+	DatabaseURL      string // PostgreSQL connection string
 	Feeds            map[string]TopicConfig
 	LogLevel         string
 	PollInterval     time.Duration
@@ -37,29 +45,105 @@ type Config struct {
 	Security         SecurityConfig
 	ArticleRetention time.Duration // How long to keep articles in storage
 
+	// Retention settings
+	TopicRetention         map[string]TopicRetention // Per-topic overrides of ArticleRetention
+	MaxArticlesPerFeed     int                       // Newest articles kept per feed source, 0 for no limit
+	RetentionByIngestion   bool                      // Age articles by when they were stored rather than published
+	ArchiveExpiredArticles bool                      // Move expired articles to DataDir/archive instead of deleting them
+
 	// Storage optimization settings
 	EnableContentCompression bool
-	MaxContentLength         int // Maximum content length to store
+	ContentCompressionCodec  string // Codec for new compressed content: gzip or zstd
+	MaxContentLength         int    // Maximum content length to store
 	EnableDuplicateRemoval   bool
 	DatabaseOptimizeInterval time.Duration // How often to run database optimization
+
+	// Backup settings
+	AdminToken     string        // Bearer token for /api/v1/admin endpoints, disabled when empty
+	BackupInterval time.Duration // How often to write a backup under DataDir/backups, disabled when 0
+	BackupKeep     int           // Number of scheduled backups to keep
+
+	// Webhook settings
+	WebhookMaxAttempts int           // Delivery attempts before a webhook delivery is dead
+	WebhookTimeout     time.Duration // Timeout of a single webhook delivery request
+
+	// Email digest settings
+	SMTP SMTPConfig
+
+	// Chat notifier settings
+	Notifiers         map[string][]NotifierTarget // Chat channels notified of the new articles of each topic
+	NotifyInterval    time.Duration               // Minimum time between two messages to a channel, articles are batched meanwhile
+	NotifyMaxArticles int                         // Articles listed per message, the others are only counted
+
+	// Google Reader API settings
+	ReaderUsername string // Login of the Google Reader API for mobile clients, disabled unless a password is set too
+	ReaderPassword string
+	ReaderSecret   string // Key signing the Google Reader tokens, random at each start when empty
+}
+
+// NotifierTarget is a chat channel notified of new articles
+type NotifierTarget struct {
+	Kind string // slack, mattermost, matrix or discord
+	URL  string // Incoming webhook URL, or the room URL for matrix
+}
+
+// SMTPConfig is the mail server email digests are sent through
+type SMTPConfig struct {
+	Host     string // Digests are disabled when empty
+	Port     int
+	Username string // No authentication when empty
+	Password string
+	From     string
+	StartTLS bool // Require STARTTLS, refusing servers that do not offer it
 }
 
 func Load() *Config {
 	port := getEnvAsInt("PORT", 8080)
 	cacheTTL := getEnvAsDuration("CACHE_TTL", 15*time.Minute)
 	dataDir := getEnv("DATA_DIR", "./data")
+	storageDriver := strings.ToLower(getEnv("STORAGE_DRIVER", "sqlite"))
+	databaseURL := getEnv("DATABASE_URL", "")
 	logLevel := getEnv("LOG_LEVEL", "info")
 	pollInterval := getEnvAsDuration("POLL_INTERVAL", 15*time.Minute)
 	enableSPA := getEnvAsBool("ENABLE_SPA", true)
 	enableSwagger := getEnvAsBool("ENABLE_SWAGGER", true)
 	articleRetention := getEnvAsDuration("ARTICLE_RETENTION", 30*24*time.Hour) // 30 days default
 
+	// Retention settings
+	topicRetention := loadTopicRetentionFromEnv()
+	maxArticlesPerFeed := getEnvAsInt("MAX_ARTICLES_PER_FEED", 0)
+	retentionByIngestion := getEnvAsBool("RETENTION_BY_INGESTION", false)
+	archiveExpiredArticles := getEnvAsBool("ARCHIVE_EXPIRED_ARTICLES", false)
+
 	// Storage optimization settings
 	enableContentCompression := getEnvAsBool("ENABLE_CONTENT_COMPRESSION", true)
+	contentCompressionCodec := strings.ToLower(getEnv("CONTENT_COMPRESSION_CODEC", "gzip"))
 	maxContentLength := getEnvAsInt("MAX_CONTENT_LENGTH", 50000) // 50KB default
 	enableDuplicateRemoval := getEnvAsBool("ENABLE_DUPLICATE_REMOVAL", true)
 	databaseOptimizeInterval := getEnvAsDuration("DATABASE_OPTIMIZE_INTERVAL", 24*time.Hour) // Daily
 
+	// Backup settings
+	adminToken := getEnv("ADMIN_TOKEN", "")
+	backupInterval := getEnvAsDuration("BACKUP_INTERVAL", 0)
+	backupKeep := getEnvAsInt("BACKUP_KEEP", 7)
+
+	// Webhook settings
+	webhookMaxAttempts := getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 6)
+	webhookTimeout := getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+
+	// Email digest settings
+	smtp := loadSMTPConfig()
+
+	// Chat notifier settings
+	notifiers := loadNotifiersFromEnv()
+	notifyInterval := getEnvAsDuration("NOTIFY_INTERVAL", time.Minute)
+	notifyMaxArticles := getEnvAsInt("NOTIFY_MAX_ARTICLES", 10)
+
+	// Google Reader API settings
+	readerUsername := getEnv("GREADER_USERNAME", "")
+	readerPassword := getEnv("GREADER_PASSWORD", "")
+	readerSecret := getEnv("GREADER_SECRET", "")
+
 	// Load security configuration
 	security := loadSecurityConfig()
 
@@ -75,6 +159,8 @@ func Load() *Config {
 		Port:                     port,
 		CacheTTL:                 cacheTTL,
 		DataDir:                  dataDir,
+		StorageDriver:            storageDriver,
+		DatabaseURL:              databaseURL,
 		Feeds:                    feeds,
 		LogLevel:                 logLevel,
 		PollInterval:             pollInterval,
@@ -82,13 +168,36 @@ func Load() *Config {
 		EnableSwagger:            enableSwagger,
 		Security:                 security,
 		ArticleRetention:         articleRetention,
+		TopicRetention:           topicRetention,
+		MaxArticlesPerFeed:       maxArticlesPerFeed,
+		RetentionByIngestion:     retentionByIngestion,
+		ArchiveExpiredArticles:   archiveExpiredArticles,
 		EnableContentCompression: enableContentCompression,
+		ContentCompressionCodec:  contentCompressionCodec,
 		MaxContentLength:         maxContentLength,
 		EnableDuplicateRemoval:   enableDuplicateRemoval,
 		DatabaseOptimizeInterval: databaseOptimizeInterval,
+		AdminToken:               adminToken,
+		BackupInterval:           backupInterval,
+		BackupKeep:               backupKeep,
+		WebhookMaxAttempts:       webhookMaxAttempts,
+		WebhookTimeout:           webhookTimeout,
+		SMTP:                     smtp,
+		Notifiers:                notifiers,
+		NotifyInterval:           notifyInterval,
+		NotifyMaxArticles:        notifyMaxArticles,
+		ReaderUsername:           readerUsername,
+		ReaderPassword:           readerPassword,
+		ReaderSecret:             readerSecret,
 	}
 }
 
+// ReaderEnabled reports whether the Google Reader API is on, which takes both
+// a username and a password
+func (c *Config) ReaderEnabled() bool {
+	return c.ReaderUsername != "" && c.ReaderPassword != ""
+}
+
 func loadSecurityConfig() SecurityConfig {
 	return SecurityConfig{
 		EnableRateLimit:       getEnvAsBool("ENABLE_RATE_LIMIT", true),
@@ -102,6 +211,17 @@ func loadSecurityConfig() SecurityConfig {
 	}
 }
 
+func loadSMTPConfig() SMTPConfig {
+	return SMTPConfig{
+		Host:     getEnv("SMTP_HOST", ""),
+		Port:     getEnvAsInt("SMTP_PORT", 587),
+		Username: getEnv("SMTP_USERNAME", ""),
+		Password: getEnv("SMTP_PASSWORD", ""),
+		From:     getEnv("SMTP_FROM", ""),
+		StartTLS: getEnvAsBool("SMTP_STARTTLS", true),
+	}
+}
+
 func loadFeedsFromEnv() map[string]TopicConfig {
 	feeds := make(map[string]TopicConfig)
 
@@ -131,6 +251,83 @@ func loadFeedsFromEnv() map[string]TopicConfig {
 	return feeds
 }
 
+func loadTopicRetentionFromEnv() map[string]TopicRetention {
+	retention := make(map[string]TopicRetention)
+
+	// Look for RETENTION_TOPIC_* environment variables
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "RETENTION_TOPIC_") {
+			continue
+		}
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		topicName := strings.ToLower(strings.TrimPrefix(parts[0], "RETENTION_TOPIC_"))
+		retention[topicName] = parseTopicRetention(parts[1])
+	}
+
+	return retention
+}
+
+func parseTopicRetention(value string) TopicRetention {
+	// Format: "max_age|max_count", either part may be empty: "8760h", "|500", "168h|1000"
+	var retention TopicRetention
+
+	parts := strings.SplitN(value, "|", 2)
+	if age := strings.TrimSpace(parts[0]); age != "" {
+		if duration, err := time.ParseDuration(age); err == nil {
+			retention.MaxAge = duration
+		}
+	}
+	if len(parts) > 1 {
+		if count, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil {
+			retention.MaxCount = count
+		}
+	}
+
+	return retention
+}
+
+func loadNotifiersFromEnv() map[string][]NotifierTarget {
+	notifiers := make(map[string][]NotifierTarget)
+
+	// Look for NOTIFY_TOPIC_* environment variables
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "NOTIFY_TOPIC_") {
+			continue
+		}
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		topicName := strings.ToLower(strings.TrimPrefix(parts[0], "NOTIFY_TOPIC_"))
+		if targets := parseNotifierTargets(parts[1]); len(targets) > 0 {
+			notifiers[topicName] = targets
+		}
+	}
+
+	return notifiers
+}
+
+func parseNotifierTargets(value string) []NotifierTarget {
+	// Format: "kind:url,kind:url", e.g. "slack:https://hooks.slack.com/services/...,discord:https://discord.com/api/webhooks/..."
+	var targets []NotifierTarget
+	for _, entry := range strings.Split(value, ",") {
+		kind, url, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || strings.TrimSpace(url) == "" {
+			continue
+		}
+		targets = append(targets, NotifierTarget{
+			Kind: strings.ToLower(strings.TrimSpace(kind)),
+			URL:  strings.TrimSpace(url),
+		})
+	}
+	return targets
+}
+
 func parseTopicValue(value string) ([]string, []string) {
 	// Format: "url1,url2,url3|filter1,filter2,filter3"
 	// If no filters specified, just URLs: "url1,url2,url3"
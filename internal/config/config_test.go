@@ -236,3 +236,89 @@ func TestParseFeedTopicConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadConfig_TopicRetention(t *testing.T) {
+	os.Setenv("RETENTION_TOPIC_SECURITY", "8760h")
+	os.Setenv("RETENTION_TOPIC_NEWS", "168h|500")
+	os.Setenv("RETENTION_TOPIC_BLOGS", "|100")
+	os.Setenv("MAX_ARTICLES_PER_FEED", "200")
+	os.Setenv("RETENTION_BY_INGESTION", "true")
+
+	defer func() {
+		os.Unsetenv("RETENTION_TOPIC_SECURITY")
+		os.Unsetenv("RETENTION_TOPIC_NEWS")
+		os.Unsetenv("RETENTION_TOPIC_BLOGS")
+		os.Unsetenv("MAX_ARTICLES_PER_FEED")
+		os.Unsetenv("RETENTION_BY_INGESTION")
+	}()
+
+	cfg := Load()
+
+	expected := map[string]TopicRetention{
+		"security": {MaxAge: 365 * 24 * time.Hour},
+		"news":     {MaxAge: 7 * 24 * time.Hour, MaxCount: 500},
+		"blogs":    {MaxCount: 100},
+	}
+	if len(cfg.TopicRetention) != len(expected) {
+		t.Errorf("Expected %d topic retention policies, got %v", len(expected), cfg.TopicRetention)
+	}
+	for topic, retention := range expected {
+		if cfg.TopicRetention[topic] != retention {
+			t.Errorf("Expected %s retention %+v, got %+v", topic, retention, cfg.TopicRetention[topic])
+		}
+	}
+
+	if cfg.MaxArticlesPerFeed != 200 {
+		t.Errorf("Expected MaxArticlesPerFeed 200, got %d", cfg.MaxArticlesPerFeed)
+	}
+	if !cfg.RetentionByIngestion {
+		t.Error("Expected RetentionByIngestion to be enabled")
+	}
+}
+
+func TestLoadConfig_Notifiers(t *testing.T) {
+	os.Setenv("NOTIFY_TOPIC_SECURITY", "slack:https://hooks.slack.com/services/T0/B0/x, Discord:https://discord.com/api/webhooks/1/y")
+	os.Setenv("NOTIFY_TOPIC_TECH", "matrix:https://matrix.example.org/!room:example.org?access_token=t,broken")
+	os.Setenv("NOTIFY_TOPIC_EMPTY", "")
+	os.Setenv("NOTIFY_INTERVAL", "5m")
+
+	defer func() {
+		os.Unsetenv("NOTIFY_TOPIC_SECURITY")
+		os.Unsetenv("NOTIFY_TOPIC_TECH")
+		os.Unsetenv("NOTIFY_TOPIC_EMPTY")
+		os.Unsetenv("NOTIFY_INTERVAL")
+	}()
+
+	cfg := Load()
+
+	expected := map[string][]NotifierTarget{
+		"security": {
+			{Kind: "slack", URL: "https://hooks.slack.com/services/T0/B0/x"},
+			{Kind: "discord", URL: "https://discord.com/api/webhooks/1/y"},
+		},
+		"tech": {
+			{Kind: "matrix", URL: "https://matrix.example.org/!room:example.org?access_token=t"},
+		},
+	}
+	if len(cfg.Notifiers) != len(expected) {
+		t.Errorf("Expected notifiers for %d topics, got %v", len(expected), cfg.Notifiers)
+	}
+	for topic, targets := range expected {
+		if len(cfg.Notifiers[topic]) != len(targets) {
+			t.Errorf("Expected %s notifiers %+v, got %+v", topic, targets, cfg.Notifiers[topic])
+			continue
+		}
+		for i, target := range targets {
+			if cfg.Notifiers[topic][i] != target {
+				t.Errorf("Expected %s notifier %+v, got %+v", topic, target, cfg.Notifiers[topic][i])
+			}
+		}
+	}
+
+	if cfg.NotifyInterval != 5*time.Minute {
+		t.Errorf("Expected NotifyInterval 5m, got %v", cfg.NotifyInterval)
+	}
+	if cfg.NotifyMaxArticles != 10 {
+		t.Errorf("Expected NotifyMaxArticles 10, got %d", cfg.NotifyMaxArticles)
+	}
+}
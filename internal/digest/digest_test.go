@@ -0,0 +1,400 @@
+package digest
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorssag/internal/config"
+	"gorssag/internal/models"
+	"gorssag/internal/storage"
+)
+
+func TestParseSchedule(t *testing.T) {
+	for value, want := range map[string]Schedule{
+		"daily":               {Weekday: time.Monday, Hour: 8},
+		"Daily 07:30":         {Weekday: time.Monday, Hour: 7, Minute: 30},
+		"weekly":              {Weekly: true, Weekday: time.Monday, Hour: 8},
+		"weekly fri":          {Weekly: true, Weekday: time.Friday, Hour: 8},
+		"weekly sunday 18:45": {Weekly: true, Weekday: time.Sunday, Hour: 18, Minute: 45},
+		"weekly 06:00":        {Weekly: true, Weekday: time.Monday, Hour: 6},
+	} {
+		got, err := ParseSchedule(value)
+		if err != nil || got != want {
+			t.Errorf("ParseSchedule(%q) = %+v, %v, want %+v", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"", "hourly", "daily 25:00", "weekly someday 08:00", "daily 08:00 extra"} {
+		if _, err := ParseSchedule(value); err == nil {
+			t.Errorf("ParseSchedule(%q) expected an error", value)
+		}
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// Wednesday
+	at := time.Date(2024, 1, 17, 9, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Time{
+		"daily 08:00":      time.Date(2024, 1, 18, 8, 0, 0, 0, time.UTC),
+		"daily 09:00":      time.Date(2024, 1, 18, 9, 0, 0, 0, time.UTC),
+		"daily 10:00":      time.Date(2024, 1, 17, 10, 0, 0, 0, time.UTC),
+		"weekly mon 08:00": time.Date(2024, 1, 22, 8, 0, 0, 0, time.UTC),
+		"weekly wed 10:00": time.Date(2024, 1, 17, 10, 0, 0, 0, time.UTC),
+		"weekly wed 08:00": time.Date(2024, 1, 24, 8, 0, 0, 0, time.UTC),
+	} {
+		schedule, _ := ParseSchedule(value)
+		if got := schedule.Next(at); !got.Equal(want) {
+			t.Errorf("%q.Next(%v) = %v, want %v", value, at, got, want)
+		}
+	}
+}
+
+// smtpSink is a local SMTP server offering STARTTLS and recording the
+// messages it accepts
+type smtpSink struct {
+	listener net.Listener
+	tls      *tls.Config
+	mu       sync.Mutex
+	messages []sinkMessage
+	reject   bool // Answer DATA with a temporary failure
+}
+
+type sinkMessage struct {
+	from, to string
+	tls      bool
+	auth     string
+	data     string
+}
+
+func newSMTPSink(t *testing.T) (*smtpSink, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	sink := &smtpSink{
+		listener: listener,
+		tls:      &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+	}
+	go sink.serve()
+	t.Cleanup(func() { listener.Close() })
+	return sink, roots
+}
+
+func (s *smtpSink) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpSink) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpSink) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	var msg sinkMessage
+	reply("220 sink ready")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			if msg.tls {
+				reply("250-sink\r\n250 AUTH PLAIN")
+			} else {
+				reply("250-sink\r\n250 STARTTLS")
+			}
+		case "STARTTLS":
+			reply("220 go ahead")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			reader = bufio.NewReader(conn)
+			msg.tls = true
+		case "AUTH":
+			msg.auth = line
+			reply("235 authenticated")
+		case "MAIL":
+			msg.from = line
+			reply("250 ok")
+		case "RCPT":
+			msg.to = line
+			reply("250 ok")
+		case "DATA":
+			reply("354 send it")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			if s.reject {
+				reply("451 try again later")
+				continue
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown command")
+		}
+	}
+}
+
+func (s *smtpSink) received() []sinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sinkMessage(nil), s.messages...)
+}
+
+// parseDigest returns the subject and the text and HTML parts of a message
+func parseDigest(t *testing.T, data string) (string, string, string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("ParseMediaType() error = %v", err)
+	}
+
+	parts := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[mediaType] = string(body)
+	}
+	return subject, parts["text/plain"], parts["text/html"]
+}
+
+func TestScheduler_SendsDigestOverSTARTTLS(t *testing.T) {
+	sink, roots := newSMTPSink(t)
+	mailer := NewMailer(config.SMTPConfig{Host: "127.0.0.1", Port: sink.port(), Username: "digest", Password: "pw", From: "Gorssag <news@example.com>", StartTLS: true})
+	mailer.tlsConfig = &tls.Config{ServerName: "127.0.0.1", RootCAs: roots}
+
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(&config.Config{MaxContentLength: 10000})
+	created := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	digest := &models.Digest{Recipient: "team@example.com", Topic: "security", Filter: "source eq 'Wire'", Schedule: "daily 08:00", MaxArticles: 2, NextSendAt: time.Date(2024, 1, 16, 8, 0, 0, 0, time.UTC)}
+	_, digest.Cursor, _ = store.GetTopicArrivals(ctx, "security", -1)
+	if err := store.CreateDigest(ctx, digest); err != nil {
+		t.Fatalf("CreateDigest() error = %v", err)
+	}
+
+	articles := []models.Article{
+		{ID: "a1", Title: "Patch Tuesday", Link: "https://example.com/a1", Source: "Wire", PublishedAt: created.Add(time.Hour)},
+		{ID: "a2", Title: "Zero day <exploited>", Link: "https://example.com/a2", Source: "Wire", PublishedAt: created.Add(3 * time.Hour)},
+		{ID: "a3", Title: "Old advisory", Link: "https://example.com/a3", Source: "Wire", PublishedAt: created.Add(-time.Hour)},
+		{ID: "a4", Title: "Blog post", Link: "https://example.com/a4", Source: "Blog", PublishedAt: created.Add(2 * time.Hour)},
+	}
+	if err := store.SaveArticles(ctx, articles); err != nil {
+		t.Fatalf("SaveArticles() error = %v", err)
+	}
+	if err := store.AssignArticlesToTopic(ctx, []string{"a1", "a2", "a3", "a4"}, "security"); err != nil {
+		t.Fatalf("AssignArticlesToTopic() error = %v", err)
+	}
+
+	scheduler := NewScheduler(store, mailer)
+	clock := time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC)
+	scheduler.now = func() time.Time { return clock }
+	if sent, err := scheduler.SendDue(ctx); err != nil || sent != 0 {
+		t.Fatalf("SendDue() before the schedule = %d, %v, want nothing sent", sent, err)
+	}
+
+	// The server was down at 08:00 on the 16th and the 17th, the digest catches up
+	clock = time.Date(2024, 1, 17, 12, 0, 0, 0, time.UTC)
+	if sent, err := scheduler.SendDue(ctx); err != nil || sent != 1 {
+		t.Fatalf("SendDue() = %d, %v, want 1 digest", sent, err)
+	}
+
+	messages := sink.received()
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	msg := messages[0]
+	if !msg.tls || !strings.HasPrefix(msg.auth, "AUTH PLAIN") {
+		t.Errorf("Expected authentication after STARTTLS, got tls=%v auth=%q", msg.tls, msg.auth)
+	}
+	if msg.from != "MAIL FROM:<news@example.com>" || msg.to != "RCPT TO:<team@example.com>" {
+		t.Errorf("Unexpected envelope %q %q", msg.from, msg.to)
+	}
+
+	subject, text, html := parseDigest(t, msg.data)
+	if subject != "3 new articles in security" {
+		t.Errorf("Subject = %q", subject)
+	}
+	// Newest first, limited to MaxArticles, other sources filtered out
+	if !strings.Contains(text, "Zero day <exploited>") || !strings.Contains(text, "Patch Tuesday") || strings.Contains(text, "Old advisory") || strings.Contains(text, "Blog post") {
+		t.Errorf("Unexpected text body:\n%s", text)
+	}
+	if strings.Index(text, "Zero day") > strings.Index(text, "Patch Tuesday") || !strings.Contains(text, "and 1 more.") {
+		t.Errorf("Expected the newest articles first and a remainder, got:\n%s", text)
+	}
+	if !strings.Contains(html, "Zero day &lt;exploited&gt;") || !strings.Contains(html, `href="https://example.com/a1"`) {
+		t.Errorf("Expected escaped HTML with links, got:\n%s", html)
+	}
+
+	stored, err := store.GetDigest(ctx, digest.ID)
+	if err != nil {
+		t.Fatalf("GetDigest() error = %v", err)
+	}
+	if stored.LastSentAt == nil || !stored.LastSentAt.Equal(clock) || !stored.NextSendAt.Equal(time.Date(2024, 1, 18, 8, 0, 0, 0, time.UTC)) || stored.LastError != "" {
+		t.Errorf("Unexpected state after sending %+v", stored)
+	}
+
+	// Only articles assigned since the last digest are sent
+	clock = time.Date(2024, 1, 18, 8, 0, 0, 0, time.UTC)
+	if sent, err := scheduler.SendDue(ctx); err != nil || sent != 0 {
+		t.Errorf("SendDue() without new articles = %d, %v, want nothing sent", sent, err)
+	}
+	if len(sink.received()) != 1 {
+		t.Errorf("Expected no new message, got %d", len(sink.received()))
+	}
+}
+
+func TestScheduler_RetriesFailedSend(t *testing.T) {
+	sink, roots := newSMTPSink(t)
+	sink.reject = true
+	mailer := NewMailer(config.SMTPConfig{Host: "127.0.0.1", Port: sink.port(), From: "news@example.com", StartTLS: true})
+	mailer.tlsConfig = &tls.Config{ServerName: "127.0.0.1", RootCAs: roots}
+
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(&config.Config{MaxContentLength: 10000})
+	digest := &models.Digest{Recipient: "team@example.com", Topic: "tech", Schedule: "weekly", MaxArticles: 10}
+	if err := store.CreateDigest(ctx, digest); err != nil {
+		t.Fatalf("CreateDigest() error = %v", err)
+	}
+	if err := store.SaveArticles(ctx, []models.Article{{ID: "a1", Title: "Go release", Link: "https://example.com/a1", Source: "Go", PublishedAt: time.Now()}}); err != nil {
+		t.Fatalf("SaveArticles() error = %v", err)
+	}
+	if err := store.AssignArticlesToTopic(ctx, []string{"a1"}, "tech"); err != nil {
+		t.Fatalf("AssignArticlesToTopic() error = %v", err)
+	}
+
+	scheduler := NewScheduler(store, mailer)
+	clock := time.Now().UTC()
+	scheduler.now = func() time.Time { return clock }
+	if sent, err := scheduler.SendDue(ctx); err != nil || sent != 0 {
+		t.Fatalf("SendDue() = %d, %v, want the send to fail", sent, err)
+	}
+	failed, _ := store.GetDigest(ctx, digest.ID)
+	if !strings.Contains(failed.LastError, "451") || failed.Cursor != digest.Cursor || !failed.NextSendAt.Equal(clock.Add(retryDelay)) {
+		t.Errorf("Expected a retry keeping the cursor, got %+v", failed)
+	}
+
+	sink.reject = false
+	clock = clock.Add(retryDelay)
+	if sent, err := scheduler.SendDue(ctx); err != nil || sent != 1 {
+		t.Fatalf("SendDue() retry = %d, %v, want 1 digest", sent, err)
+	}
+	if subject, _, _ := parseDigest(t, sink.received()[0].data); subject != "1 new article in tech" {
+		t.Errorf("Subject = %q", subject)
+	}
+}
+
+func TestMailer_RequiresSTARTTLS(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		io.WriteString(conn, "220 plain\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if strings.HasPrefix(strings.ToUpper(line), "EHLO") {
+				io.WriteString(conn, "250 plain\r\n")
+			} else {
+				io.WriteString(conn, "221 bye\r\n")
+			}
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	mailer := NewMailer(config.SMTPConfig{Host: "127.0.0.1", Port: port, From: "news@example.com", StartTLS: true})
+	err = mailer.Send(context.Background(), "team@example.com", "subject", "text", "<p>html</p>")
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Send() error = %v, want a missing STARTTLS error on port %s", err, strconv.Itoa(port))
+	}
+}
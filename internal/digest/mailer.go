@@ -0,0 +1,163 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorssag/internal/config"
+)
+
+// smtpTimeout bounds a whole SMTP session when the context has no deadline
+const smtpTimeout = time.Minute
+
+// Sender sends a digest email with text and HTML alternatives
+type Sender interface {
+	Send(ctx context.Context, to, subject, text, html string) error
+}
+
+// Mailer sends emails through an SMTP server, upgrading the connection with
+// STARTTLS before authenticating
+type Mailer struct {
+	cfg       config.SMTPConfig
+	tlsConfig *tls.Config // Nil verifies the server certificate against the system roots
+}
+
+// NewMailer creates a mailer for an SMTP server
+func NewMailer(cfg config.SMTPConfig) *Mailer {
+	return &Mailer{cfg: cfg}
+}
+
+// Send sends a multipart/alternative email
+func (m *Mailer) Send(ctx context.Context, to, subject, text, html string) error {
+	message, err := buildMessage(m.cfg.From, to, subject, text, html, time.Now())
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", addr, err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %v", err)
+	}
+	defer client.Close()
+
+	if m.cfg.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s does not offer STARTTLS", addr)
+		}
+		tlsConfig := m.tlsConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: m.cfg.Host}
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %v", err)
+		}
+	}
+
+	if m.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+
+	if err := client.Mail(addressOf(m.cfg.From)); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %v", err)
+	}
+	if err := client.Rcpt(addressOf(to)); err != nil {
+		return fmt.Errorf("SMTP RCPT TO failed: %v", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %v", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write message: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected the message: %v", err)
+	}
+	return client.Quit()
+}
+
+// buildMessage builds a multipart/alternative message with a text and an
+// HTML part, both quoted-printable
+func buildMessage(from, to, subject, text, html string, date time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create message part: %v", err)
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, fmt.Errorf("failed to encode message part: %v", err)
+		}
+		qp.Close()
+	}
+	parts.Close()
+
+	id := make([]byte, 12)
+	rand.Read(id)
+	domain := "localhost"
+	if at := strings.LastIndex(addressOf(from), "@"); at >= 0 {
+		domain = addressOf(from)[at+1:]
+	}
+
+	var message bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", from},
+		{"To", to},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", hex.EncodeToString(id), domain)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	} {
+		fmt.Fprintf(&message, "%s: %s\r\n", header[0], header[1])
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+// addressOf returns the bare address of "Name <address>", or the value as is
+func addressOf(value string) string {
+	if addr, err := mail.ParseAddress(value); err == nil {
+		return addr.Address
+	}
+	return value
+}
@@ -0,0 +1,111 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"gorssag/internal/models"
+)
+
+// Data is what digest templates are rendered with
+type Data struct {
+	Recipient string
+	Topic     string
+	Since     time.Time        // Previous digest, or the subscription for the first one
+	Until     time.Time        // When this digest was composed
+	Articles  []models.Article // Newest first, at most MaxArticles
+	Total     int              // New articles matching the digest, listed or not
+}
+
+// Default templates, used when a digest leaves them empty
+const (
+	DefaultSubject = `{{.Total}} new {{if eq .Total 1}}article{{else}}articles{{end}} in {{.Topic}}`
+
+	DefaultTextTemplate = `{{.Total}} new {{if eq .Total 1}}article{{else}}articles{{end}} in {{.Topic}} since {{date .Since}}
+{{range .Articles}}
+* {{.Title}}
+  {{.Source}}, {{date .PublishedAt}}
+  {{.Link}}
+{{end}}{{if gt .Total (len .Articles)}}
+and {{sub .Total (len .Articles)}} more.
+{{end}}`
+
+	DefaultHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; max-width: 640px;">
+<h2>{{.Total}} new {{if eq .Total 1}}article{{else}}articles{{end}} in {{.Topic}}</h2>
+<p style="color: #666;">Since {{date .Since}}</p>
+{{range .Articles}}
+<div style="margin-bottom: 1em;">
+<a href="{{.Link}}" style="font-size: 1.1em;">{{.Title}}</a><br>
+<span style="color: #666;">{{.Source}}, {{date .PublishedAt}}</span>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+</div>
+{{end}}
+{{if gt .Total (len .Articles)}}<p>and {{sub .Total (len .Articles)}} more.</p>{{end}}
+</body>
+</html>`
+)
+
+// templateFuncs are available to every digest template
+var templateFuncs = map[string]interface{}{
+	"date": func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 UTC") },
+	"sub":  func(a, b int) int { return a - b },
+}
+
+// Templates are the parsed templates of a digest
+type Templates struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// ParseTemplates parses the templates of a digest, using the defaults for empty ones
+func ParseTemplates(digest *models.Digest) (*Templates, error) {
+	subject, err := texttemplate.New("subject").Funcs(templateFuncs).Parse(orDefault(digest.Subject, DefaultSubject))
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %v", err)
+	}
+	text, err := texttemplate.New("text").Funcs(templateFuncs).Parse(orDefault(digest.TextTemplate, DefaultTextTemplate))
+	if err != nil {
+		return nil, fmt.Errorf("invalid text template: %v", err)
+	}
+	html, err := htmltemplate.New("html").Funcs(templateFuncs).Parse(orDefault(digest.HTMLTemplate, DefaultHTMLTemplate))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTML template: %v", err)
+	}
+	return &Templates{subject: subject, text: text, html: html}, nil
+}
+
+// Render renders the subject and the text and HTML bodies of a digest
+func (t *Templates) Render(data Data) (subject, text, html string, err error) {
+	var buf bytes.Buffer
+	if err := t.subject.Execute(&buf, data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render subject: %v", err)
+	}
+	// Headers cannot span lines
+	subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	buf.Reset()
+	if err := t.text.Execute(&buf, data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render text body: %v", err)
+	}
+	text = buf.String()
+
+	buf.Reset()
+	if err := t.html.Execute(&buf, data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render HTML body: %v", err)
+	}
+	return subject, text, buf.String(), nil
+}
+
+func orDefault(value, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
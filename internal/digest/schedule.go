@@ -0,0 +1,87 @@
+// Package digest sends scheduled emails of the articles newly assigned to a topic.
+package digest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule is when a digest is sent, in UTC
+type Schedule struct {
+	Weekly  bool
+	Weekday time.Weekday // Day of weekly digests
+	Hour    int
+	Minute  int
+}
+
+// Defaults of schedules leaving out the day or time
+const (
+	defaultWeekday = time.Monday
+	defaultHour    = 8
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// ParseSchedule parses "daily [HH:MM]" or "weekly [day] [HH:MM]", for
+// example "daily", "daily 07:30" or "weekly fri 16:00". Times are UTC and
+// default to 08:00; weekly digests default to Monday.
+func ParseSchedule(value string) (Schedule, error) {
+	fields := strings.Fields(strings.ToLower(value))
+	if len(fields) == 0 {
+		return Schedule{}, fmt.Errorf("schedule is required")
+	}
+
+	schedule := Schedule{Weekday: defaultWeekday, Hour: defaultHour}
+	switch fields[0] {
+	case "daily":
+	case "weekly":
+		schedule.Weekly = true
+		if len(fields) > 1 {
+			if day, ok := weekdays[fields[1]]; ok {
+				schedule.Weekday = day
+				fields = append(fields[:1], fields[2:]...)
+			}
+		}
+	default:
+		return Schedule{}, fmt.Errorf("schedule must start with daily or weekly, got %q", fields[0])
+	}
+
+	switch len(fields) {
+	case 1:
+	case 2:
+		at, err := time.Parse("15:04", fields[1])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("invalid schedule time %q, expected HH:MM", fields[1])
+		}
+		schedule.Hour, schedule.Minute = at.Hour(), at.Minute()
+	default:
+		return Schedule{}, fmt.Errorf("invalid schedule %q", value)
+	}
+	return schedule, nil
+}
+
+// Next returns the first send time strictly after a time
+func (s Schedule) Next(after time.Time) time.Time {
+	after = after.UTC()
+	next := time.Date(after.Year(), after.Month(), after.Day(), s.Hour, s.Minute, 0, 0, time.UTC)
+	if s.Weekly {
+		next = next.AddDate(0, 0, (int(s.Weekday)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(after) {
+		if s.Weekly {
+			next = next.AddDate(0, 0, 7)
+		} else {
+			next = next.AddDate(0, 0, 1)
+		}
+	}
+	return next
+}
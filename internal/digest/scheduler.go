@@ -0,0 +1,198 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+	"gorssag/internal/storage"
+)
+
+const (
+	checkInterval = time.Minute      // How often due digests are looked up
+	retryDelay    = 15 * time.Minute // Delay before retrying a digest that failed to send
+)
+
+// Scheduler sends the due digests in the background. A digest covers the
+// articles assigned to its topic since the previous one, so a digest missed
+// while the server was down is caught up by the next one sent.
+type Scheduler struct {
+	storage storage.Storage
+	sender  Sender
+	now     func() time.Time
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewScheduler creates a scheduler sending the digests stored in storage
+func NewScheduler(store storage.Storage, sender Sender) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		storage: store,
+		sender:  sender,
+		now:     time.Now,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start sends the due digests every checkInterval until Stop
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	log.Printf("Starting email digest scheduler")
+
+	s.wg.Add(1)
+	go s.scheduleLoop()
+}
+
+// Stop waits for the digest being sent and stops the scheduler
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	s.mu.Unlock()
+
+	s.cancel()
+	s.wg.Wait()
+	log.Println("Email digest scheduler stopped")
+}
+
+func (s *Scheduler) scheduleLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		// Digests due while the server was down are sent on start
+		if _, err := s.SendDue(s.ctx); err != nil && s.ctx.Err() == nil {
+			log.Printf("Warning: digest scheduling failed: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// SendDue sends every digest due now and returns how many emails were sent.
+// Digests without new matching articles are skipped until their next send.
+func (s *Scheduler) SendDue(ctx context.Context) (int, error) {
+	now := s.now().UTC()
+	due, err := s.storage.GetDueDigests(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read due digests: %v", err)
+	}
+
+	sent := 0
+	for i := range due {
+		digest := &due[i]
+		schedule, err := ParseSchedule(digest.Schedule)
+		if err != nil {
+			// Validated on creation, only a hand-edited row gets here
+			log.Printf("Warning: digest %d has an invalid schedule: %v", digest.ID, err)
+			continue
+		}
+
+		data, cursor, err := Compose(ctx, s.storage, digest, now)
+		if err == nil && data.Total > 0 {
+			err = s.send(ctx, digest, data)
+		}
+		if err != nil {
+			log.Printf("Warning: failed to send digest %d to %s: %v", digest.ID, digest.Recipient, err)
+			digest.LastError = err.Error()
+			digest.NextSendAt = now.Add(retryDelay)
+		} else {
+			if data.Total > 0 {
+				digest.LastSentAt = &now
+				sent++
+			}
+			digest.Cursor = cursor
+			digest.LastError = ""
+			digest.NextSendAt = schedule.Next(now)
+		}
+
+		if err := s.storage.UpdateDigestState(ctx, digest); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// send renders a digest and sends it
+func (s *Scheduler) send(ctx context.Context, digest *models.Digest, data Data) error {
+	templates, err := ParseTemplates(digest)
+	if err != nil {
+		return err
+	}
+	subject, text, html, err := templates.Render(data)
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(ctx, digest.Recipient, subject, text, html)
+}
+
+// Compose collects the articles of a digest: those assigned to its topic
+// since the previous digest and matching its filter, newest first. It also
+// returns the cursor to store once the digest is sent.
+func Compose(ctx context.Context, store storage.Storage, digest *models.Digest, until time.Time) (Data, int64, error) {
+	data := Data{Recipient: digest.Recipient, Topic: digest.Topic, Since: digest.CreatedAt, Until: until}
+	if digest.LastSentAt != nil {
+		data.Since = *digest.LastSentAt
+	}
+
+	ids, cursor, err := store.GetTopicArrivals(ctx, digest.Topic, digest.Cursor)
+	if err != nil {
+		return data, 0, err
+	}
+	articles, err := store.GetArticles(ctx, ids)
+	if err != nil {
+		return data, 0, err
+	}
+
+	if digest.Filter != "" {
+		filterParser := odata.NewFilterParser()
+		filter, err := filterParser.Parse(digest.Filter)
+		if err != nil {
+			return data, 0, fmt.Errorf("invalid filter expression: %v", err)
+		}
+		matching := articles[:0]
+		for _, article := range articles {
+			if ok, err := filterParser.Evaluate(filter, article); err == nil && ok {
+				matching = append(matching, article)
+			}
+		}
+		articles = matching
+	}
+
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].PublishedAt.After(articles[j].PublishedAt)
+	})
+	data.Total = len(articles)
+	if digest.MaxArticles > 0 && len(articles) > digest.MaxArticles {
+		articles = articles[:digest.MaxArticles]
+	}
+	data.Articles = articles
+	return data, cursor, nil
+}
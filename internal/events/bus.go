@@ -0,0 +1,120 @@
+package events
+
+import (
+	"sync"
+
+	"gorssag/internal/models"
+)
+
+// DefaultReplaySize is the number of recent events kept for resuming subscribers
+const DefaultReplaySize = 256
+
+// subscriberBuffer is the number of events a subscriber may lag behind
+// before it is dropped
+const subscriberBuffer = 64
+
+// Event is an article newly stored by a feed poll, with the topics it was assigned to
+type Event struct {
+	ID      uint64         `json:"id"`
+	Article models.Article `json:"article"`
+	Topics  []string       `json:"topics"`
+}
+
+// Bus fans out article events to in-process subscribers. Event IDs increase
+// from 1 and restart with the process; the most recent events are kept so
+// subscribers can resume after a disconnect.
+type Bus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	replay      []Event // Ring buffer of the most recent events
+	next        int     // Replay slot the next event goes to
+	replaySize  int
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events published after it was created. C is
+// closed when the subscription is closed or dropped for falling behind.
+type Subscription struct {
+	C   <-chan Event
+	ch  chan Event
+	bus *Bus
+}
+
+// NewBus creates a bus keeping the last replaySize events
+func NewBus(replaySize int) *Bus {
+	if replaySize <= 0 {
+		replaySize = DefaultReplaySize
+	}
+	return &Bus{
+		replay:      make([]Event, 0, replaySize),
+		replaySize:  replaySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish sends an article to every subscriber and returns its event
+func (b *Bus) Publish(article models.Article, topics []string) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := Event{ID: b.lastID, Article: article, Topics: append([]string(nil), topics...)}
+	if len(b.replay) < b.replaySize {
+		b.replay = append(b.replay, event)
+	} else {
+		b.replay[b.next] = event
+	}
+	b.next = (b.next + 1) % b.replaySize
+
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			// Slow subscribers reconnect and resume from the replay buffer
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+	return event
+}
+
+// Subscribe registers a subscriber. When resuming, it also returns the
+// buffered events published after lastEventID, oldest first; older ones are lost.
+func (b *Bus) Subscribe(lastEventID uint64, resume bool) (*Subscription, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []Event
+	// A last ID ahead of the bus comes from before a restart
+	if resume && lastEventID <= b.lastID {
+		for i := 0; i < len(b.replay); i++ {
+			event := b.replay[(b.next-len(b.replay)+i+b.replaySize)%b.replaySize]
+			if event.ID > lastEventID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, bus: b}
+	b.subscribers[sub] = struct{}{}
+	return sub, missed
+}
+
+// Close unregisters the subscription
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	if _, ok := s.bus.subscribers[s]; ok {
+		delete(s.bus.subscribers, s)
+		close(s.ch)
+	}
+}
+
+// Subscribers returns the number of active subscriptions
+func (b *Bus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
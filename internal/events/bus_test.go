@@ -0,0 +1,83 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+
+	"gorssag/internal/models"
+)
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	bus := NewBus(3)
+	sub, missed := bus.Subscribe(0, false)
+	defer sub.Close()
+	if len(missed) != 0 {
+		t.Errorf("Expected no replay for a new subscriber, got %v", missed)
+	}
+
+	event := bus.Publish(models.Article{ID: "a1"}, []string{"tech"})
+	if event.ID != 1 {
+		t.Errorf("Expected first event ID 1, got %d", event.ID)
+	}
+	received := <-sub.C
+	if received.Article.ID != "a1" || len(received.Topics) != 1 || received.Topics[0] != "tech" {
+		t.Errorf("Unexpected event %+v", received)
+	}
+
+	sub.Close()
+	if _, ok := <-sub.C; ok {
+		t.Error("Expected the channel to be closed")
+	}
+	if bus.Subscribers() != 0 {
+		t.Errorf("Expected no subscribers after Close, got %d", bus.Subscribers())
+	}
+}
+
+func TestBus_Replay(t *testing.T) {
+	bus := NewBus(3)
+	for _, id := range []string{"a1", "a2", "a3", "a4", "a5"} {
+		bus.Publish(models.Article{ID: id}, nil)
+	}
+
+	ids := func(events []Event) []string {
+		var ids []string
+		for _, event := range events {
+			ids = append(ids, event.Article.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		lastEventID uint64
+		want        []string
+	}{
+		{0, []string{"a3", "a4", "a5"}}, // Older events left the buffer
+		{3, []string{"a4", "a5"}},
+		{5, nil},
+		{42, nil}, // ID from before a restart
+	}
+	for _, tt := range tests {
+		sub, missed := bus.Subscribe(tt.lastEventID, true)
+		sub.Close()
+		if got := ids(missed); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Subscribe(%d) replayed %v, want %v", tt.lastEventID, got, tt.want)
+		}
+	}
+}
+
+func TestBus_DropsSlowSubscriber(t *testing.T) {
+	bus := NewBus(0)
+	sub, _ := bus.Subscribe(0, false)
+	for i := 0; i <= subscriberBuffer; i++ {
+		bus.Publish(models.Article{ID: "a"}, nil)
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	if received != subscriberBuffer || bus.Subscribers() != 0 {
+		t.Errorf("Expected the subscriber to be dropped after %d events, got %d (%d subscribers)", subscriberBuffer, received, bus.Subscribers())
+	}
+	sub.Close()
+}
@@ -6,27 +6,63 @@ import (
 
 // Article represents a single RSS article
 type Article struct {
-	ID          string    `json:"id"` // Unique identifier for the article
+	ID          string     `json:"id"` // Unique identifier for the article
+	Title       string     `json:"title"`
+	Link        string     `json:"link"`
+	Description string     `json:"description"`
+	Content     string     `json:"content"`
+	Author      string     `json:"author"`
+	PublishedAt time.Time  `json:"published_at"`
+	Source      string     `json:"source"`
+	Categories  []string   `json:"categories"`
+	Topic       string     `json:"topic,omitempty"`      // Topic this article belongs to
+	Language    string     `json:"language"`             // New field for article language
+	ClusterID   string     `json:"cluster_id,omitempty"` // Story cluster shared by near-duplicate articles
+	Pinned      bool       `json:"pinned"`               // Pinned articles are exempt from retention and deduplication
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"` // Last change of the text by the publisher, nil for unchanged articles
+	FeedURLs    []string   `json:"feed_urls,omitempty"`  // URLs of the feeds the article was polled from, oldest first
+
+	ClusterSources []ClusterSource `json:"cluster_sources,omitempty"` // Other coverage of the story ($cluster=true)
+}
+
+// ArticleRevision is an earlier version of an article, replaced when the
+// publisher changed its title, description or content
+type ArticleRevision struct {
+	Revision    int       `json:"revision"`     // 1 for the original version
+	ContentHash string    `json:"content_hash"` // SHA-256 of the replaced title, description and content
+	Title       string    `json:"title"`        // Title of the replaced version
+	Diff        string    `json:"diff"`         // Line diff to the next version, per changed field
+	ChangedAt   time.Time `json:"changed_at"`   // When the next version was stored
+}
+
+// ClusterSource is another article of the same story cluster
+type ClusterSource struct {
+	ID          string    `json:"id"`
 	Title       string    `json:"title"`
 	Link        string    `json:"link"`
-	Description string    `json:"description"`
-	Content     string    `json:"content"`
-	Author      string    `json:"author"`
-	PublishedAt time.Time `json:"published_at"`
 	Source      string    `json:"source"`
-	Categories  []string  `json:"categories"`
-	Topic       string    `json:"topic,omitempty"` // Topic this article belongs to
-	Language    string    `json:"language"`        // New field for article language
+	PublishedAt time.Time `json:"published_at"`
 }
 
 // AggregatedFeed represents an aggregated RSS feed for a topic
 type AggregatedFeed struct {
-	Topic    string    `json:"topic"`
-	Articles []Article `json:"articles"`
-	Count    int       `json:"count"`
-	Updated  time.Time `json:"updated"`
+	Topic         string    `json:"topic"`
+	Articles      []Article `json:"articles"`
+	Count         int       `json:"count"`
+	Updated       time.Time `json:"updated"`
+	Facets        Facets    `json:"facets,omitempty"`         // Only set when $facets is requested
+	NextSkipToken string    `json:"next_skiptoken,omitempty"` // Cursor for the next page, if any
+}
+
+// FacetCount is the number of articles sharing a facet value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
 }
 
+// Facets maps a facet field (source, category, ...) to its value counts
+type Facets map[string][]FacetCount
+
 // FeedInfo represents metadata about a stored feed
 type FeedInfo struct {
 	Topic        string    `json:"topic"`
@@ -35,6 +71,110 @@ type FeedInfo struct {
 	ArticleCount int       `json:"article_count"`
 }
 
+// ArticleDetail is a single article with its topic memberships
+type ArticleDetail struct {
+	Article
+	Topics []string `json:"topics"`
+}
+
+// FeedDeletion reports what deleting a feed removed. Purged articles are only
+// deleted when no other feed or pin references them, the others are kept.
+type FeedDeletion struct {
+	ID                 int64    `json:"id"`
+	URL                string   `json:"url"`
+	Title              string   `json:"title"`
+	Purged             bool     `json:"purged"`
+	LinkedArticles     int      `json:"linked_articles"`
+	DeletedArticles    int      `json:"deleted_articles"`
+	KeptArticles       int      `json:"kept_articles"`
+	DeletedCompressed  int      `json:"deleted_compressed_content"`
+	DeletedSearchTerms int      `json:"deleted_search_terms"`
+	DeletedArticleIDs  []string `json:"deleted_article_ids"`
+	UnsubscribedTopics []string `json:"unsubscribed_topics"`
+}
+
+// ExportedArticle is one line of an NDJSON article export: the article with
+// its topic memberships and the cursor resuming the export after it
+type ExportedArticle struct {
+	Article
+	Topics []string `json:"topics"`
+	Cursor string   `json:"cursor,omitempty"`
+}
+
+// ImportResult summarizes an NDJSON article import
+type ImportResult struct {
+	Lines      int `json:"lines"`      // Lines read and committed to storage
+	Imported   int `json:"imported"`   // Articles saved
+	Duplicates int `json:"duplicates"` // Lines repeating an article ID already imported
+	Existing   int `json:"existing"`   // Articles already stored, left untouched
+}
+
+// StatsSnapshot is the storage statistics recorded after an optimization cycle
+type StatsSnapshot struct {
+	TakenAt           time.Time        `json:"taken_at"`
+	TableRows         map[string]int64 `json:"table_rows"`
+	DatabaseSizeBytes int64            `json:"database_size_bytes"`
+	WALSizeBytes      int64            `json:"wal_size_bytes"`
+	CompressionRatio  float64          `json:"compression_ratio"` // Original over compressed content size, 0 without compressed content
+	IndexSizeBytes    map[string]int64 `json:"index_size_bytes"`  // Empty when the backend cannot measure indexes
+}
+
+// StorageForecast extrapolates disk usage (database and WAL) linearly from
+// the stats snapshots
+type StorageForecast struct {
+	Samples           int        `json:"samples"`
+	CurrentBytes      int64      `json:"current_bytes"`
+	GrowthBytesPerDay float64    `json:"growth_bytes_per_day"`
+	HorizonDays       int        `json:"horizon_days"`
+	ForecastBytes     int64      `json:"forecast_bytes"` // Disk usage expected after HorizonDays
+	CapacityBytes     int64      `json:"capacity_bytes,omitempty"`
+	FullAt            *time.Time `json:"full_at,omitempty"` // When usage reaches CapacityBytes at the current growth
+}
+
+// Webhook delivery states
+const (
+	WebhookDeliveryPending   = "pending"   // Waiting for its next attempt
+	WebhookDeliveryDelivered = "delivered" // Acknowledged with a 2xx response
+	WebhookDeliveryDead      = "dead"      // Gave up after the last attempt
+)
+
+// Webhook posts the articles newly assigned to a topic, and matching its
+// optional OData filter, to a URL
+type Webhook struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Topic     string    `json:"topic"`
+	Filter    string    `json:"filter,omitempty"`
+	Secret    string    `json:"secret,omitempty"` // HMAC-SHA256 key signing the payloads
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one article payload queued for a webhook, with the
+// outcome of its attempts
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int64      `json:"webhook_id"`
+	ArticleID      string     `json:"article_id"`
+	Topic          string     `json:"topic"`
+	Payload        []byte     `json:"-"` // JSON body posted to the webhook
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastError      string     `json:"last_error,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"` // HTTP status of the last attempt
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookPayload is the JSON body posted for an article newly assigned to a topic
+type WebhookPayload struct {
+	Event     string    `json:"event"` // Always "article.assigned"
+	WebhookID int64     `json:"webhook_id"`
+	Topic     string    `json:"topic"`
+	Article   Article   `json:"article"`
+	CreatedAt time.Time `json:"created_at"` // When the delivery was queued
+}
+
 // FeedStatus represents the status of a feed
 type FeedStatus struct {
 	URL               string    `json:"url"`
@@ -56,17 +196,30 @@ type FeedStatus struct {
 
 // ODataQuery represents OData query parameters
 type ODataQuery struct {
-	Filter   string     `json:"filter"`
-	OrderBy  string     `json:"orderby"`
-	Select   []string   `json:"select"`
-	Search   []string   `json:"search"` // Global search terms (OR logic)
-	Top      int        `json:"top"`
-	Skip     int        `json:"skip"`
-	DateFrom *time.Time `json:"date_from,omitempty"`
-	DateTo   *time.Time `json:"date_to,omitempty"`
-	Source   string     `json:"source,omitempty"`
-	Author   string     `json:"author,omitempty"`
-	Category string     `json:"category,omitempty"`
+	Filter    string     `json:"filter"`
+	OrderBy   string     `json:"orderby"`
+	Select    []string   `json:"select"`
+	Search    []string   `json:"search"` // Global search terms (OR logic)
+	Top       int        `json:"top"`
+	Skip      int        `json:"skip"`
+	DateFrom  *time.Time `json:"date_from,omitempty"`
+	DateTo    *time.Time `json:"date_to,omitempty"`
+	Source    string     `json:"source,omitempty"`
+	Author    string     `json:"author,omitempty"`
+	Category  string     `json:"category,omitempty"`
+	Facets    []string   `json:"facets,omitempty"`   // Facet fields to count over the full result set
+	SkipToken *SkipToken `json:"-"`                  // Keyset cursor, replaces Skip when set
+	Cluster   bool       `json:"cluster,omitempty"`  // Return one representative per story cluster
+	Pinned    *bool      `json:"pinned,omitempty"`   // Only return pinned (true) or unpinned (false) articles
+	FeedURL   string     `json:"feed_url,omitempty"` // Only return articles polled from this feed URL
+	Archive   bool       `json:"archive,omitempty"`  // Search the cold archive instead of the database
+}
+
+// SkipToken is a keyset pagination cursor: the sort key of the last article
+// of the previous page (articles are ordered by published_at, then ID, descending)
+type SkipToken struct {
+	PublishedAt time.Time
+	ID          string
 }
 
 // FilterCriteria represents filter conditions
@@ -75,3 +228,64 @@ type FilterCriteria struct {
 	Operator string `json:"operator"`
 	Value    string `json:"value"`
 }
+
+// Digest is an email subscription to the articles newly assigned to a
+// topic, sent on a daily or weekly schedule
+type Digest struct {
+	ID           int64      `json:"id"`
+	Recipient    string     `json:"recipient"`
+	Topic        string     `json:"topic"`
+	Filter       string     `json:"filter,omitempty"`        // OData filter the articles must match
+	Schedule     string     `json:"schedule"`                // "daily [HH:MM]" or "weekly [day] [HH:MM]", in UTC
+	Subject      string     `json:"subject,omitempty"`       // Subject template, a default one when empty
+	HTMLTemplate string     `json:"html_template,omitempty"` // html/template body, a default one when empty
+	TextTemplate string     `json:"text_template,omitempty"` // text/template body, a default one when empty
+	MaxArticles  int        `json:"max_articles"`            // Newest articles listed per digest
+	Cursor       int64      `json:"-"`                       // Last topic membership included in a digest
+	NextSendAt   time.Time  `json:"next_send_at"`
+	LastSentAt   *time.Time `json:"last_sent_at,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Read and starred states of articles, as tagged by Google Reader clients
+const (
+	ReaderStateRead    = "read"
+	ReaderStateStarred = "starred"
+)
+
+// ReaderQuery selects the items of a Google Reader stream, newest first
+type ReaderQuery struct {
+	Topic   string    // Articles of a topic, all articles when empty
+	FeedURL string    // Articles polled from a feed, all articles when empty
+	Read    bool      // Read articles only
+	Unread  bool      // Unread articles only
+	Starred bool      // Starred articles only
+	Since   time.Time // Stored at or after, no bound when zero
+	Until   time.Time // Stored before, no bound when zero
+	Oldest  bool      // Oldest first
+	Limit   int
+	Offset  int
+}
+
+// ReaderItemRef identifies an item of a Google Reader stream
+type ReaderItemRef struct {
+	ID        int64     // Item ID, increasing in storage order
+	CrawledAt time.Time // When the article was first stored
+}
+
+// ReaderItem is an article with its Google Reader item ID and state
+type ReaderItem struct {
+	ReaderItemRef
+	Article Article
+	Topics  []string
+	Read    bool
+	Starred bool
+}
+
+// ReaderSubscription is a configured feed as listed to Google Reader clients
+type ReaderSubscription struct {
+	URL    string
+	Title  string   // Title of the feed, its URL until polled
+	Topics []string // Topics the feed is configured in, sorted
+}
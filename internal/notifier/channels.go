@@ -0,0 +1,274 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gorssag/internal/models"
+)
+
+const (
+	maxTitleLength    = 250 // Discord embed titles are limited to 256 characters
+	maxDiscordEmbeds  = 10
+	discordEmbedColor = 0x3b82f6
+	mattermostColor   = "#3b82f6"
+	articleDateFormat = "2006-01-02 15:04 UTC"
+	matrixMessageType = "m.notice" // Bots should send notices, which clients do not answer
+)
+
+// webhookChannel posts JSON to an incoming webhook URL
+type webhookChannel struct {
+	url    string
+	format func(Batch) interface{}
+}
+
+func (c *webhookChannel) Request(ctx context.Context, batch Batch) (*http.Request, error) {
+	body, err := json.Marshal(c.format(batch))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// webhookURL checks that an incoming webhook URL is an absolute http(s) URL
+func webhookURL(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("webhook URL must be an http or https URL")
+	}
+	return rawURL, nil
+}
+
+// NewSlack creates a channel posting Block Kit messages to a Slack incoming webhook
+func NewSlack(rawURL string) (Channel, error) {
+	u, err := webhookURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &webhookChannel{url: u, format: slackMessage}, nil
+}
+
+// slackMessage lists the articles as Block Kit sections under a header
+func slackMessage(batch Batch) interface{} {
+	type text struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	type block struct {
+		Type     string `json:"type"`
+		Text     *text  `json:"text,omitempty"`
+		Elements []text `json:"elements,omitempty"`
+	}
+
+	blocks := []block{{Type: "header", Text: &text{Type: "plain_text", Text: batch.Title()}}}
+	for _, article := range batch.Articles {
+		line := fmt.Sprintf("*<%s|%s>*\n%s", slackEscape(article.Link), slackEscape(truncate(article.Title, maxTitleLength)), slackEscape(byline(article)))
+		blocks = append(blocks, block{Type: "section", Text: &text{Type: "mrkdwn", Text: line}})
+	}
+	if remainder := batch.Remainder(); remainder > 0 {
+		blocks = append(blocks, block{Type: "context", Elements: []text{{Type: "mrkdwn", Text: fmt.Sprintf("and %d more", remainder)}}})
+	}
+
+	return map[string]interface{}{"text": batch.Title(), "blocks": blocks}
+}
+
+// slackEscape escapes the characters Slack mrkdwn treats as control characters
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// NewMattermost creates a channel posting message attachments to a Mattermost incoming webhook
+func NewMattermost(rawURL string) (Channel, error) {
+	u, err := webhookURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &webhookChannel{url: u, format: mattermostMessage}, nil
+}
+
+// mattermostMessage lists the articles as one attachment each
+func mattermostMessage(batch Batch) interface{} {
+	type attachment struct {
+		Fallback   string `json:"fallback"`
+		Color      string `json:"color"`
+		AuthorName string `json:"author_name,omitempty"`
+		Title      string `json:"title"`
+		TitleLink  string `json:"title_link"`
+		Footer     string `json:"footer"`
+	}
+
+	attachments := make([]attachment, 0, len(batch.Articles))
+	for _, article := range batch.Articles {
+		title := truncate(article.Title, maxTitleLength)
+		attachments = append(attachments, attachment{
+			Fallback:   title + " " + article.Link,
+			Color:      mattermostColor,
+			AuthorName: article.Source,
+			Title:      title,
+			TitleLink:  article.Link,
+			Footer:     article.PublishedAt.UTC().Format(articleDateFormat),
+		})
+	}
+
+	text := "#### " + batch.Title()
+	if remainder := batch.Remainder(); remainder > 0 {
+		text += fmt.Sprintf("\nThe latest %d are listed, and %d more.", len(batch.Articles), remainder)
+	}
+	return map[string]interface{}{"text": text, "attachments": attachments}
+}
+
+// NewDiscord creates a channel posting embeds to a Discord webhook
+func NewDiscord(rawURL string) (Channel, error) {
+	u, err := webhookURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &webhookChannel{url: u, format: discordMessage}, nil
+}
+
+// discordMessage lists the articles as embeds, at most the ten Discord accepts
+func discordMessage(batch Batch) interface{} {
+	type author struct {
+		Name string `json:"name"`
+	}
+	type embed struct {
+		Title     string  `json:"title"`
+		URL       string  `json:"url"`
+		Color     int     `json:"color"`
+		Author    *author `json:"author,omitempty"`
+		Timestamp string  `json:"timestamp,omitempty"`
+	}
+
+	articles := batch.Articles
+	if len(articles) > maxDiscordEmbeds {
+		articles = articles[:maxDiscordEmbeds]
+	}
+	embeds := make([]embed, 0, len(articles))
+	for _, article := range articles {
+		e := embed{Title: truncate(article.Title, maxTitleLength), URL: article.Link, Color: discordEmbedColor}
+		if article.Source != "" {
+			e.Author = &author{Name: truncate(article.Source, maxTitleLength)}
+		}
+		if !article.PublishedAt.IsZero() {
+			e.Timestamp = article.PublishedAt.UTC().Format(time.RFC3339)
+		}
+		embeds = append(embeds, e)
+	}
+
+	content := "**" + batch.Title() + "**"
+	if remainder := batch.Total - len(articles); remainder > 0 {
+		content += fmt.Sprintf("\nThe latest %d are listed, and %d more.", len(articles), remainder)
+	}
+	return map[string]interface{}{
+		"content":          content,
+		"embeds":           embeds,
+		"allowed_mentions": map[string]interface{}{"parse": []string{}}, // Never ping from article titles
+	}
+}
+
+// matrixChannel sends m.room.message events through the client-server API
+type matrixChannel struct {
+	homeserver string
+	roomID     string
+	token      string
+}
+
+// matrixTxn makes the transaction IDs of this process unique
+var matrixTxn int64
+
+// NewMatrix creates a channel sending HTML messages to a Matrix room. The URL
+// is the homeserver, the room ID as path and the access token as query:
+// https://matrix.example.org/!roomid:example.org?access_token=...
+func NewMatrix(rawURL string) (Channel, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("homeserver must be an http or https URL")
+	}
+	roomID := strings.TrimPrefix(parsed.Path, "/")
+	if !strings.HasPrefix(roomID, "!") || !strings.Contains(roomID, ":") {
+		return nil, fmt.Errorf("path must be a room ID such as /!roomid:example.org")
+	}
+	token := parsed.Query().Get("access_token")
+	if token == "" {
+		return nil, fmt.Errorf("access_token query parameter is required")
+	}
+	return &matrixChannel{homeserver: parsed.Scheme + "://" + parsed.Host, roomID: roomID, token: token}, nil
+}
+
+func (c *matrixChannel) Request(ctx context.Context, batch Batch) (*http.Request, error) {
+	body, err := json.Marshal(matrixMessage(batch))
+	if err != nil {
+		return nil, err
+	}
+
+	txnID := fmt.Sprintf("gorssag-%d-%d", time.Now().UnixNano(), atomic.AddInt64(&matrixTxn, 1))
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", c.homeserver, url.PathEscape(c.roomID), txnID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	return req, nil
+}
+
+// matrixMessage lists the articles as an HTML list with a plain text fallback
+func matrixMessage(batch Batch) map[string]string {
+	var plain, formatted strings.Builder
+	plain.WriteString(batch.Title())
+	formatted.WriteString("<p><strong>" + html.EscapeString(batch.Title()) + "</strong></p><ul>")
+	for _, article := range batch.Articles {
+		title := truncate(article.Title, maxTitleLength)
+		fmt.Fprintf(&plain, "\n- %s (%s) %s", title, byline(article), article.Link)
+		fmt.Fprintf(&formatted, `<li><a href="%s">%s</a> <em>%s</em></li>`, html.EscapeString(article.Link), html.EscapeString(title), html.EscapeString(byline(article)))
+	}
+	formatted.WriteString("</ul>")
+	if remainder := batch.Remainder(); remainder > 0 {
+		fmt.Fprintf(&plain, "\nand %d more", remainder)
+		fmt.Fprintf(&formatted, "<p>and %d more</p>", remainder)
+	}
+
+	return map[string]string{
+		"msgtype":        matrixMessageType,
+		"body":           plain.String(),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted.String(),
+	}
+}
+
+// byline returns the source and publication date of an article
+func byline(article models.Article) string {
+	date := article.PublishedAt.UTC().Format(articleDateFormat)
+	if article.Source == "" {
+		return date
+	}
+	return article.Source + ", " + date
+}
+
+// truncate shortens s to at most max runes, ending with an ellipsis when cut
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,359 @@
+// Package notifier posts the new articles of a topic to chat services,
+// batching the articles of each channel to respect its rate limit.
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorssag/internal/config"
+	"gorssag/internal/models"
+)
+
+const (
+	defaultInterval    = time.Minute
+	defaultMaxArticles = 10
+	maxListedArticles  = 25               // Keeps messages within the block and size limits of chat services
+	flushInterval      = 5 * time.Second  // How often queued articles are checked
+	requestTimeout     = 10 * time.Second // Timeout of a single message request
+	maxFailures        = 3                // Failed sends before a batch is dropped
+	maxErrorLength     = 512              // Bytes of the response body kept as the error
+)
+
+// Batch is what a message announces: the new articles of a topic
+type Batch struct {
+	Topic    string
+	Articles []models.Article // Newest first, at most NOTIFY_MAX_ARTICLES
+	Total    int              // New articles since the previous message, listed or not
+}
+
+// Title returns the headline of a batch, e.g. "12 new articles in security"
+func (b Batch) Title() string {
+	if b.Total == 1 {
+		return "1 new article in " + b.Topic
+	}
+	return fmt.Sprintf("%d new articles in %s", b.Total, b.Topic)
+}
+
+// Remainder returns how many articles of the batch are not listed
+func (b Batch) Remainder() int {
+	return b.Total - len(b.Articles)
+}
+
+// Channel is a chat destination batches are posted to
+type Channel interface {
+	// Request builds the HTTP request posting a batch
+	Request(ctx context.Context, batch Batch) (*http.Request, error)
+}
+
+// Factory creates a channel from its configured URL
+type Factory func(rawURL string) (Channel, error)
+
+var factories = map[string]Factory{
+	"slack":      NewSlack,
+	"mattermost": NewMattermost,
+	"matrix":     NewMatrix,
+	"discord":    NewDiscord,
+}
+
+// Register makes a kind of channel available to the NOTIFY_TOPIC_* settings.
+// It is meant to be called from init functions.
+func Register(kind string, factory Factory) {
+	factories[strings.ToLower(kind)] = factory
+}
+
+// queue holds the articles waiting for the next message to a channel
+type queue struct {
+	kind       string
+	topic      string
+	channel    Channel
+	articles   []models.Article // Newest first, at most maxArticles
+	queued     map[string]bool  // IDs counted in total
+	total      int
+	failures   int
+	nextSendAt time.Time // Earliest time of the next message
+}
+
+// Notifier batches the new articles of each topic and posts them to the
+// chat channels of the topic, at most one message per channel every interval
+type Notifier struct {
+	queues      map[string][]*queue // By topic
+	client      *http.Client
+	interval    time.Duration
+	maxArticles int
+	now         func() time.Time
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// New creates a notifier for the channels configured per topic. Channels of
+// an unknown kind or with an invalid URL are skipped with a warning.
+func New(cfg *config.Config) *Notifier {
+	interval, maxArticles := defaultInterval, defaultMaxArticles
+	if cfg.NotifyInterval > 0 {
+		interval = cfg.NotifyInterval
+	}
+	if cfg.NotifyMaxArticles > 0 {
+		maxArticles = cfg.NotifyMaxArticles
+	}
+	if maxArticles > maxListedArticles {
+		maxArticles = maxListedArticles
+	}
+
+	queues := make(map[string][]*queue)
+	for topic, targets := range cfg.Notifiers {
+		for _, target := range targets {
+			factory, ok := factories[target.Kind]
+			if !ok {
+				log.Printf("Warning: unknown notifier %q for topic %s", target.Kind, topic)
+				continue
+			}
+			channel, err := factory(target.URL)
+			if err != nil {
+				log.Printf("Warning: invalid %s notifier for topic %s: %v", target.Kind, topic, err)
+				continue
+			}
+			queues[topic] = append(queues[topic], &queue{kind: target.Kind, topic: topic, channel: channel, queued: make(map[string]bool)})
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Notifier{
+		queues:      queues,
+		client:      &http.Client{Timeout: requestTimeout},
+		interval:    interval,
+		maxArticles: maxArticles,
+		now:         time.Now,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Enabled reports whether any channel is configured
+func (n *Notifier) Enabled() bool {
+	return len(n.queues) > 0
+}
+
+// Notify queues the new articles of a topic for its channels. It does not
+// block on the network, messages are sent by the background loop.
+func (n *Notifier) Notify(topic string, articles []models.Article) {
+	if n == nil || len(articles) == 0 {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, q := range n.queues[topic] {
+		q.add(articles, n.maxArticles)
+	}
+}
+
+// add queues articles, keeping the newest maxArticles and counting the others
+func (q *queue) add(articles []models.Article, maxArticles int) {
+	for _, article := range articles {
+		if q.queued[article.ID] {
+			continue
+		}
+		q.queued[article.ID] = true
+		q.total++
+		q.articles = append(q.articles, article)
+	}
+	q.articles = newest(q.articles, maxArticles)
+}
+
+// newest sorts articles newest first and keeps the first max of them
+func newest(articles []models.Article, max int) []models.Article {
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].PublishedAt.After(articles[j].PublishedAt)
+	})
+	if len(articles) > max {
+		articles = articles[:max]
+	}
+	return articles
+}
+
+// Start sends the queued articles every flushInterval until Stop
+func (n *Notifier) Start() {
+	n.mu.Lock()
+	if n.running {
+		n.mu.Unlock()
+		return
+	}
+	n.running = true
+	n.mu.Unlock()
+
+	log.Printf("Starting chat notifier for %d topics, at most one message per channel every %v", len(n.queues), n.interval)
+
+	n.wg.Add(1)
+	go n.flushLoop()
+}
+
+// Stop waits for the messages in progress and stops the notifier. Articles
+// still queued are not sent.
+func (n *Notifier) Stop() {
+	n.mu.Lock()
+	if !n.running {
+		n.mu.Unlock()
+		return
+	}
+	n.running = false
+	n.mu.Unlock()
+
+	n.cancel()
+	n.wg.Wait()
+	log.Println("Chat notifier stopped")
+}
+
+func (n *Notifier) flushLoop() {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n.Flush(n.ctx)
+		case <-n.ctx.Done():
+			return
+		}
+	}
+}
+
+// Flush posts a message to every channel with queued articles whose rate
+// limit allows it, and returns how many messages were sent
+func (n *Notifier) Flush(ctx context.Context) int {
+	type pendingBatch struct {
+		queue *queue
+		batch Batch
+		ids   map[string]bool
+	}
+
+	// Take the due batches, so Notify can queue new articles meanwhile
+	n.mu.Lock()
+	now := n.now()
+	var due []pendingBatch
+	for _, queues := range n.queues {
+		for _, q := range queues {
+			if q.total == 0 || now.Before(q.nextSendAt) {
+				continue
+			}
+			due = append(due, pendingBatch{queue: q, batch: Batch{Topic: q.topic, Articles: q.articles, Total: q.total}, ids: q.queued})
+			q.articles, q.queued, q.total = nil, make(map[string]bool), 0
+		}
+	}
+	n.mu.Unlock()
+
+	sent := 0
+	for _, pending := range due {
+		err := n.send(ctx, pending.queue.channel, pending.batch)
+
+		n.mu.Lock()
+		q := pending.queue
+		now := n.now()
+		switch {
+		case err == nil:
+			q.failures = 0
+			q.nextSendAt = now.Add(n.interval)
+			sent++
+		case ctx.Err() != nil:
+			q.requeue(pending.batch, pending.ids, n.maxArticles)
+		default:
+			q.failures++
+			q.nextSendAt = now.Add(n.interval)
+			var limited *rateLimitError
+			if errors.As(err, &limited) && limited.retryAfter > n.interval {
+				q.nextSendAt = now.Add(limited.retryAfter)
+			}
+			if q.failures >= maxFailures {
+				log.Printf("Warning: dropping %d articles of topic %s after %d failed %s notifications: %v", pending.batch.Total, q.topic, q.failures, q.kind, err)
+				q.failures = 0
+			} else {
+				log.Printf("Warning: %s notification for topic %s failed, retrying in %v: %v", q.kind, q.topic, q.nextSendAt.Sub(now), err)
+				q.requeue(pending.batch, pending.ids, n.maxArticles)
+			}
+		}
+		n.mu.Unlock()
+	}
+	return sent
+}
+
+// requeue merges a batch that was not sent with the articles queued meanwhile
+func (q *queue) requeue(batch Batch, ids map[string]bool, maxArticles int) {
+	articles := batch.Articles
+	for _, article := range q.articles {
+		if !ids[article.ID] {
+			articles = append(articles, article)
+		}
+	}
+	for id := range q.queued {
+		if ids[id] {
+			q.total-- // Counted in the batch already
+		}
+		ids[id] = true
+	}
+
+	q.queued = ids
+	q.total += batch.Total
+	q.articles = newest(articles, maxArticles)
+}
+
+// rateLimitError is a 429 response, with the delay the service asked for
+type rateLimitError struct {
+	retryAfter time.Duration
+	message    string
+}
+
+func (e *rateLimitError) Error() string {
+	return e.message
+}
+
+// send posts a batch to a channel
+func (n *Notifier) send(ctx context.Context, channel Channel, batch Batch) error {
+	req, err := channel.Request(ctx, batch)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", "gorssag-notifier")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &rateLimitError{
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			message:    fmt.Sprintf("rate limited: %s", strings.TrimSpace(string(body))),
+		}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds, possibly
+// fractional as Discord sends them
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
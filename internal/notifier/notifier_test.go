@@ -0,0 +1,236 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorssag/internal/config"
+	"gorssag/internal/models"
+)
+
+// chatServer records the requests it receives and answers with the next
+// queued status, 200 once they run out
+type chatServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	statuses []int
+}
+
+func newChatServer(t *testing.T) *chatServer {
+	t.Helper()
+	s := &chatServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, string(body))
+		if len(s.statuses) > 0 {
+			if s.statuses[0] == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "120")
+			}
+			w.WriteHeader(s.statuses[0])
+			s.statuses = s.statuses[1:]
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func testArticles(count int) []models.Article {
+	published := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	articles := make([]models.Article, count)
+	for i := range articles {
+		articles[i] = models.Article{
+			ID:          fmt.Sprintf("a%d", i+1),
+			Title:       fmt.Sprintf("Story %d", i+1),
+			Link:        fmt.Sprintf("https://example.com/%d", i+1),
+			Source:      "Wire",
+			PublishedAt: published.Add(time.Duration(i) * time.Minute),
+		}
+	}
+	return articles
+}
+
+func TestChannels_Formats(t *testing.T) {
+	batch := Batch{Topic: "security", Total: 12, Articles: testArticles(11)}
+	batch.Articles[0].Title = "<Zero day> & @everyone"
+	batch.Articles = newest(batch.Articles, 11)
+
+	tests := []struct {
+		kind   string
+		url    string
+		method string
+		path   string
+		check  func(t *testing.T, body map[string]interface{})
+	}{
+		{"slack", "/services/T0/B0/x", http.MethodPost, "/services/T0/B0/x", func(t *testing.T, body map[string]interface{}) {
+			blocks := body["blocks"].([]interface{})
+			if body["text"] != "12 new articles in security" || len(blocks) != 13 {
+				t.Fatalf("Expected a header, 11 sections and a context block, got %v", body)
+			}
+			header := blocks[0].(map[string]interface{})["text"].(map[string]interface{})
+			if header["text"] != "12 new articles in security" {
+				t.Errorf("Unexpected header %v", header)
+			}
+			last := blocks[11].(map[string]interface{})["text"].(map[string]interface{})["text"].(string)
+			if !strings.Contains(last, "<https://example.com/1|&lt;Zero day&gt; &amp; @everyone>") {
+				t.Errorf("Expected an escaped link to the oldest article, got %q", last)
+			}
+			if !strings.Contains(fmt.Sprint(blocks[12]), "and 1 more") {
+				t.Errorf("Expected the remainder, got %v", blocks[12])
+			}
+		}},
+		{"mattermost", "/hooks/abc", http.MethodPost, "/hooks/abc", func(t *testing.T, body map[string]interface{}) {
+			attachments := body["attachments"].([]interface{})
+			if !strings.HasPrefix(body["text"].(string), "#### 12 new articles in security") || len(attachments) != 11 {
+				t.Fatalf("Expected a heading and 11 attachments, got %v", body)
+			}
+			first := attachments[0].(map[string]interface{})
+			if first["title"] != "Story 11" || first["title_link"] != "https://example.com/11" || first["author_name"] != "Wire" {
+				t.Errorf("Unexpected attachment %v", first)
+			}
+		}},
+		{"discord", "/api/webhooks/1/y", http.MethodPost, "/api/webhooks/1/y", func(t *testing.T, body map[string]interface{}) {
+			embeds := body["embeds"].([]interface{})
+			if len(embeds) != maxDiscordEmbeds || !strings.Contains(body["content"].(string), "and 2 more") {
+				t.Fatalf("Expected 10 embeds and the remainder, got %v", body)
+			}
+			first := embeds[0].(map[string]interface{})
+			if first["title"] != "Story 11" || first["url"] != "https://example.com/11" || first["timestamp"] != "2024-01-15T09:10:00Z" {
+				t.Errorf("Unexpected embed %v", first)
+			}
+			if mentions := body["allowed_mentions"].(map[string]interface{}); len(mentions["parse"].([]interface{})) != 0 {
+				t.Errorf("Expected mentions to be disabled, got %v", mentions)
+			}
+		}},
+		{"matrix", "/!room:example.org?access_token=token", http.MethodPut, "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/", func(t *testing.T, body map[string]interface{}) {
+			if body["msgtype"] != "m.notice" || body["format"] != "org.matrix.custom.html" {
+				t.Fatalf("Unexpected message %v", body)
+			}
+			if !strings.HasPrefix(body["body"].(string), "12 new articles in security\n- Story 11 (Wire, 2024-01-15 09:10 UTC) https://example.com/11") {
+				t.Errorf("Unexpected plain body %q", body["body"])
+			}
+			formatted := body["formatted_body"].(string)
+			if !strings.Contains(formatted, `<a href="https://example.com/1">&lt;Zero day&gt; &amp; @everyone</a>`) || !strings.Contains(formatted, "<p>and 1 more</p>") {
+				t.Errorf("Unexpected HTML body %q", formatted)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			server := newChatServer(t)
+			channel, err := factories[tt.kind](server.URL + tt.url)
+			if err != nil {
+				t.Fatalf("New %s channel error = %v", tt.kind, err)
+			}
+			n := &Notifier{client: server.Client()}
+			if err := n.send(context.Background(), channel, batch); err != nil {
+				t.Fatalf("send() error = %v", err)
+			}
+
+			req := server.requests[0]
+			if req.Method != tt.method || !strings.HasPrefix(req.URL.EscapedPath(), tt.path) {
+				t.Errorf("Expected %s %s, got %s %s", tt.method, tt.path, req.Method, req.URL.EscapedPath())
+			}
+			if tt.kind == "matrix" && req.Header.Get("Authorization") != "Bearer token" {
+				t.Errorf("Expected the access token as bearer token, got %q", req.Header.Get("Authorization"))
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal([]byte(server.bodies[0]), &body); err != nil {
+				t.Fatalf("Invalid JSON body %s: %v", server.bodies[0], err)
+			}
+			tt.check(t, body)
+		})
+	}
+}
+
+func TestNew_SkipsInvalidChannels(t *testing.T) {
+	n := New(&config.Config{Notifiers: map[string][]config.NotifierTarget{
+		"tech": {
+			{Kind: "irc", URL: "irc://example.org/#tech"},
+			{Kind: "slack", URL: "not a url"},
+			{Kind: "matrix", URL: "https://matrix.example.org/!room:example.org"},
+			{Kind: "matrix", URL: "https://matrix.example.org/#alias:example.org?access_token=t"},
+		},
+		"news": {{Kind: "discord", URL: "https://discord.com/api/webhooks/1/y"}},
+	}})
+	if len(n.queues["tech"]) != 0 || len(n.queues["news"]) != 1 || !n.Enabled() {
+		t.Errorf("Expected only the discord channel, got %+v", n.queues)
+	}
+	if New(&config.Config{}).Enabled() {
+		t.Error("Expected a notifier without channels to be disabled")
+	}
+}
+
+func TestNotifier_BatchesAndRateLimits(t *testing.T) {
+	server := newChatServer(t)
+	n := New(&config.Config{
+		Notifiers:         map[string][]config.NotifierTarget{"security": {{Kind: "slack", URL: server.URL}}},
+		NotifyInterval:    time.Minute,
+		NotifyMaxArticles: 3,
+	})
+	clock := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	// Polls of several feeds are batched, duplicates and other topics ignored
+	articles := testArticles(12)
+	n.Notify("security", articles[:5])
+	n.Notify("security", articles[3:])
+	n.Notify("tech", articles)
+	if sent := n.Flush(ctx); sent != 1 || len(server.bodies) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(server.bodies))
+	}
+	if body := server.bodies[0]; !strings.Contains(body, "12 new articles in security") || !strings.Contains(body, "Story 12") || strings.Contains(body, "Story 9>") || !strings.Contains(body, "and 9 more") {
+		t.Errorf("Expected the 3 newest of 12 articles, got %s", body)
+	}
+
+	// Articles arriving within the interval wait for the next message
+	n.Notify("security", testArticles(1))
+	if sent := n.Flush(ctx); sent != 0 {
+		t.Errorf("Expected no message within the interval, sent %d", sent)
+	}
+	clock = clock.Add(time.Minute)
+
+	// A 429 keeps the batch for after Retry-After, merged with newer articles
+	server.statuses = []int{http.StatusTooManyRequests}
+	if sent := n.Flush(ctx); sent != 0 {
+		t.Errorf("Expected the rate limited message to fail, sent %d", sent)
+	}
+	n.Notify("security", testArticles(2))
+	clock = clock.Add(time.Minute)
+	if sent := n.Flush(ctx); sent != 0 {
+		t.Errorf("Expected to wait for Retry-After, sent %d", sent)
+	}
+	clock = clock.Add(time.Minute)
+	if sent := n.Flush(ctx); sent != 1 {
+		t.Fatalf("Expected the retried message, sent %d", sent)
+	}
+	if body := server.bodies[len(server.bodies)-1]; !strings.Contains(body, "2 new articles in security") {
+		t.Errorf("Expected the merged batch of 2 articles, got %s", body)
+	}
+
+	// Batches failing repeatedly are dropped
+	server.statuses = []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}
+	n.Notify("security", testArticles(1))
+	for i := 0; i < maxFailures; i++ {
+		clock = clock.Add(time.Minute)
+		n.Flush(ctx)
+	}
+	clock = clock.Add(time.Minute)
+	if sent := n.Flush(ctx); sent != 0 {
+		t.Errorf("Expected the failing batch to be dropped, sent %d", sent)
+	}
+}
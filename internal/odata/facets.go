@@ -0,0 +1,41 @@
+package odata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FacetFields lists the fields $facets can aggregate on; "published" is a per-day histogram
+var FacetFields = []string{"source", "author", "category", "language", "topic", "published"}
+
+// FacetLimit caps the number of values returned per facet (the histogram is not capped)
+const FacetLimit = 20
+
+// ParseFacets parses a comma-separated $facets parameter (e.g. "source,category,language")
+func ParseFacets(facetsStr string) ([]string, error) {
+	var facets []string
+	seen := make(map[string]bool)
+
+	for _, facet := range strings.Split(facetsStr, ",") {
+		facet = strings.ToLower(strings.TrimSpace(facet))
+		if facet == "" || seen[facet] {
+			continue
+		}
+		if !isFacetField(facet) {
+			return nil, fmt.Errorf("unsupported facet %q (supported: %s)", facet, strings.Join(FacetFields, ", "))
+		}
+		seen[facet] = true
+		facets = append(facets, facet)
+	}
+
+	return facets, nil
+}
+
+func isFacetField(name string) bool {
+	for _, field := range FacetFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package odata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFacets(t *testing.T) {
+	tests := []struct {
+		facets   string
+		expected []string
+		wantErr  bool
+	}{
+		{"", nil, false},
+		{"source", []string{"source"}, false},
+		{"source, Category ,language", []string{"source", "category", "language"}, false},
+		{"topic,topic,published", []string{"topic", "published"}, false},
+		{"source,color", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.facets, func(t *testing.T) {
+			facets, err := ParseFacets(tt.facets)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFacets(%q) error = %v, wantErr %v", tt.facets, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(facets, tt.expected) {
+				t.Errorf("ParseFacets(%q) = %v, want %v", tt.facets, facets, tt.expected)
+			}
+		})
+	}
+}
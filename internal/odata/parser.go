@@ -2,6 +2,7 @@ package odata
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -243,6 +244,18 @@ func (p *FilterParser) Evaluate(expr *FilterExpression, article models.Article)
 }
 
 func (p *FilterParser) evaluateComparison(expr *FilterExpression, article models.Article) (bool, error) {
+	// An article can come from several feeds; eq and ne match any of them
+	if strings.EqualFold(expr.Field, "feed_url") && (expr.Operator == "eq" || expr.Operator == "ne") {
+		matched := false
+		for _, url := range article.FeedURLs {
+			if url == expr.Value {
+				matched = true
+				break
+			}
+		}
+		return matched == (expr.Operator == "eq"), nil
+	}
+
 	fieldValue := p.getFieldValue(expr.Field, article)
 
 	switch expr.Operator {
@@ -293,6 +306,10 @@ func (p *FilterParser) getFieldValue(field string, article models.Article) strin
 		return article.Source
 	case "published_at":
 		return article.PublishedAt.Format(time.RFC3339)
+	case "pinned":
+		return strconv.FormatBool(article.Pinned)
+	case "feed_url":
+		return strings.Join(article.FeedURLs, " ")
 	default:
 		return ""
 	}
@@ -158,6 +158,7 @@ func TestFilterParser_Evaluate(t *testing.T) {
 		Source:      "Tech News",
 		PublishedAt: time.Date(2023, 6, 15, 10, 0, 0, 0, time.UTC),
 		Categories:  []string{"technology", "ai"},
+		FeedURLs:    []string{"https://a.example.com/rss", "https://b.example.com/rss"},
 	}
 
 	tests := []struct {
@@ -210,6 +211,16 @@ func TestFilterParser_Evaluate(t *testing.T) {
 			filter:   "title eq 'Wrong Title' or author eq 'Wrong Author'",
 			expected: false,
 		},
+		{
+			name:     "feed_url matches any feed",
+			filter:   "feed_url eq 'https://b.example.com/rss'",
+			expected: true,
+		},
+		{
+			name:     "feed_url ne excludes any feed",
+			filter:   "feed_url ne 'https://a.example.com/rss'",
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
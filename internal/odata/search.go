@@ -0,0 +1,333 @@
+package odata
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gorssag/internal/models"
+)
+
+// SearchFields lists the article fields a $search term can be scoped to (e.g. title:kubernetes)
+var SearchFields = []string{"title", "description", "content", "author", "source", "categories"}
+
+// SearchWordSeparators are the characters a prefix term (kube*) may follow
+// inside a field, besides the start of the field. The SQL backends match one
+// LIKE pattern per separator, so both sides agree on what starts a word.
+const SearchWordSeparators = " \t\n"
+
+type SearchParser struct{}
+
+// SearchExpression is a node of a parsed $search expression.
+// Leaves carry a Term; inner nodes carry an Operator ("and", "or", "not").
+type SearchExpression struct {
+	Operator string
+	Left     *SearchExpression
+	Right    *SearchExpression // Unused for "not"
+	Field    string            // Optional field scope, empty means all fields
+	Term     string            // Lower-cased term or phrase
+	Phrase   bool              // Term was a quoted phrase
+	Prefix   bool              // Term ended with a * wildcard
+}
+
+type searchToken struct {
+	kind  string // "word", "phrase", "and", "or", "not", "(", ")"
+	value string
+}
+
+func NewSearchParser() *SearchParser {
+	return &SearchParser{}
+}
+
+// Parse parses a $search expression supporting quoted phrases, AND/OR/NOT,
+// parentheses, field-scoped terms (title:kubernetes) and prefix wildcards (kube*).
+// Juxtaposed terms are combined with AND, commas are accepted as OR for
+// compatibility with the original comma-separated syntax.
+func (p *SearchParser) Parse(search string) (*SearchExpression, error) {
+	tokens, err := p.tokenize(search)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	pos := 0
+	expr, err := p.parseOr(tokens, &pos)
+	if err != nil {
+		return nil, err
+	}
+	if pos < len(tokens) {
+		return nil, fmt.Errorf("unexpected %q in search expression", tokens[pos].value)
+	}
+
+	return expr, nil
+}
+
+// ParseTerms parses several $search expressions and combines them with OR
+func (p *SearchParser) ParseTerms(terms []string) (*SearchExpression, error) {
+	var result *SearchExpression
+	for _, term := range terms {
+		expr, err := p.Parse(term)
+		if err != nil {
+			return nil, err
+		}
+		if expr == nil {
+			continue
+		}
+		if result == nil {
+			result = expr
+		} else {
+			result = &SearchExpression{Operator: "or", Left: result, Right: expr}
+		}
+	}
+	return result, nil
+}
+
+func (p *SearchParser) tokenize(search string) ([]searchToken, error) {
+	var tokens []searchToken
+	runes := []rune(search)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, searchToken{kind: string(r), value: string(r)})
+			i++
+		case r == ',':
+			tokens = append(tokens, searchToken{kind: "or", value: ","})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated phrase in search expression")
+			}
+			tokens = append(tokens, searchToken{kind: "phrase", value: string(runes[i+1 : end])})
+			i = end + 1
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()\",", runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+
+			// A field scope may be followed directly by a quoted phrase (title:"zero day")
+			if strings.HasSuffix(word, ":") && i < len(runes) && runes[i] == '"' && isSearchField(strings.TrimSuffix(word, ":")) {
+				end := i + 1
+				for end < len(runes) && runes[end] != '"' {
+					end++
+				}
+				if end >= len(runes) {
+					return nil, fmt.Errorf("unterminated phrase in search expression")
+				}
+				tokens = append(tokens, searchToken{kind: "phrase", value: word + string(runes[i+1:end])})
+				i = end + 1
+				continue
+			}
+
+			switch word {
+			case "AND":
+				tokens = append(tokens, searchToken{kind: "and", value: word})
+			case "OR":
+				tokens = append(tokens, searchToken{kind: "or", value: word})
+			case "NOT":
+				tokens = append(tokens, searchToken{kind: "not", value: word})
+			default:
+				tokens = append(tokens, searchToken{kind: "word", value: word})
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+func (p *SearchParser) parseOr(tokens []searchToken, pos *int) (*SearchExpression, error) {
+	left, err := p.parseAnd(tokens, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	for *pos < len(tokens) && tokens[*pos].kind == "or" {
+		*pos++
+		right, err := p.parseAnd(tokens, pos)
+		if err != nil {
+			return nil, err
+		}
+		left = &SearchExpression{Operator: "or", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *SearchParser) parseAnd(tokens []searchToken, pos *int) (*SearchExpression, error) {
+	left, err := p.parseNot(tokens, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	for *pos < len(tokens) {
+		kind := tokens[*pos].kind
+		if kind == "and" {
+			*pos++
+		} else if kind == "or" || kind == ")" {
+			break
+		}
+		// Anything else is an implicit AND
+		right, err := p.parseNot(tokens, pos)
+		if err != nil {
+			return nil, err
+		}
+		left = &SearchExpression{Operator: "and", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *SearchParser) parseNot(tokens []searchToken, pos *int) (*SearchExpression, error) {
+	if *pos < len(tokens) && tokens[*pos].kind == "not" {
+		*pos++
+		operand, err := p.parseNot(tokens, pos)
+		if err != nil {
+			return nil, err
+		}
+		return &SearchExpression{Operator: "not", Left: operand}, nil
+	}
+	return p.parsePrimary(tokens, pos)
+}
+
+func (p *SearchParser) parsePrimary(tokens []searchToken, pos *int) (*SearchExpression, error) {
+	if *pos >= len(tokens) {
+		return nil, fmt.Errorf("unexpected end of search expression")
+	}
+
+	token := tokens[*pos]
+	*pos++
+
+	switch token.kind {
+	case "(":
+		expr, err := p.parseOr(tokens, pos)
+		if err != nil {
+			return nil, err
+		}
+		if *pos >= len(tokens) || tokens[*pos].kind != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis in search expression")
+		}
+		*pos++
+		return expr, nil
+	case "word", "phrase":
+		return p.parseTerm(token)
+	default:
+		return nil, fmt.Errorf("unexpected %q in search expression", token.value)
+	}
+}
+
+func (p *SearchParser) parseTerm(token searchToken) (*SearchExpression, error) {
+	expr := &SearchExpression{Phrase: token.kind == "phrase"}
+	value := token.value
+
+	// Field scope: only recognised field names are treated as scopes so that
+	// terms such as "https://..." keep working as plain words
+	if idx := strings.Index(value, ":"); idx > 0 && isSearchField(value[:idx]) {
+		expr.Field = strings.ToLower(value[:idx])
+		if expr.Field == "category" {
+			expr.Field = "categories"
+		}
+		value = value[idx+1:]
+	}
+
+	if !expr.Phrase && len(value) > 1 && strings.HasSuffix(value, "*") {
+		expr.Prefix = true
+		value = strings.TrimSuffix(value, "*")
+	}
+
+	expr.Term = strings.ToLower(strings.Join(strings.Fields(value), " "))
+	if expr.Term == "" {
+		return nil, fmt.Errorf("empty search term %q", token.value)
+	}
+	return expr, nil
+}
+
+func isSearchField(name string) bool {
+	name = strings.ToLower(name)
+	if name == "category" {
+		return true
+	}
+	for _, field := range SearchFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate reports whether an article matches a parsed $search expression
+func (p *SearchParser) Evaluate(expr *SearchExpression, article models.Article) bool {
+	if expr == nil {
+		return true
+	}
+
+	switch expr.Operator {
+	case "and":
+		return p.Evaluate(expr.Left, article) && p.Evaluate(expr.Right, article)
+	case "or":
+		return p.Evaluate(expr.Left, article) || p.Evaluate(expr.Right, article)
+	case "not":
+		return !p.Evaluate(expr.Left, article)
+	}
+
+	fields := SearchFields
+	if expr.Field != "" {
+		fields = []string{expr.Field}
+	}
+
+	for _, field := range fields {
+		if MatchSearchTerm(strings.ToLower(p.getFieldText(field, article)), expr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchSearchTerm reports whether a lower-cased field text matches a search
+// term: prefixes at the start of the text or after a SearchWordSeparators
+// character, words and phrases anywhere. It mirrors the LIKE patterns of the
+// SQL backends.
+func MatchSearchTerm(text string, expr *SearchExpression) bool {
+	if !expr.Prefix {
+		return strings.Contains(text, expr.Term)
+	}
+	if strings.HasPrefix(text, expr.Term) {
+		return true
+	}
+	for _, separator := range SearchWordSeparators {
+		if strings.Contains(text, string(separator)+expr.Term) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *SearchParser) getFieldText(field string, article models.Article) string {
+	switch field {
+	case "title":
+		return article.Title
+	case "description":
+		return article.Description
+	case "content":
+		return article.Content
+	case "author":
+		return article.Author
+	case "source":
+		return article.Source
+	case "categories":
+		return strings.Join(article.Categories, " ")
+	default:
+		return ""
+	}
+}
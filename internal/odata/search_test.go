@@ -0,0 +1,112 @@
+package odata
+
+import (
+	"testing"
+
+	"gorssag/internal/models"
+)
+
+func TestSearchParser_Parse(t *testing.T) {
+	parser := NewSearchParser()
+
+	expr, err := parser.Parse(`title:kubernetes AND ("zero day" OR exploit*) NOT game`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	// ((title:kubernetes AND (phrase OR prefix)) AND NOT game)
+	if expr.Operator != "and" {
+		t.Fatalf("Operator = %v, want 'and'", expr.Operator)
+	}
+	if expr.Right.Operator != "not" || expr.Right.Left.Term != "game" {
+		t.Errorf("Expected NOT game on the right, got %+v", expr.Right)
+	}
+
+	left := expr.Left
+	if left.Left.Field != "title" || left.Left.Term != "kubernetes" {
+		t.Errorf("Expected title:kubernetes, got %+v", left.Left)
+	}
+	group := left.Right
+	if group.Operator != "or" {
+		t.Fatalf("Expected grouped OR, got %+v", group)
+	}
+	if !group.Left.Phrase || group.Left.Term != "zero day" {
+		t.Errorf("Expected phrase 'zero day', got %+v", group.Left)
+	}
+	if !group.Right.Prefix || group.Right.Term != "exploit" {
+		t.Errorf("Expected prefix 'exploit', got %+v", group.Right)
+	}
+}
+
+func TestSearchParser_ParseErrors(t *testing.T) {
+	parser := NewSearchParser()
+
+	invalid := []string{
+		`"zero day`,
+		`(rust OR go`,
+		`rust NOT`,
+		`OR rust`,
+		`rust )`,
+		`title:`,
+		`title:""`,
+		`""`,
+	}
+
+	for _, search := range invalid {
+		t.Run(search, func(t *testing.T) {
+			if _, err := parser.Parse(search); err == nil {
+				t.Errorf("Parse(%q) expected error, got nil", search)
+			}
+		})
+	}
+}
+
+func TestSearchParser_Evaluate(t *testing.T) {
+	parser := NewSearchParser()
+
+	article := models.Article{
+		Title:       "Rust 1.80 released",
+		Description: "A zero day in the wild was patched",
+		Content:     "Kubernetes operators written in Rust\nTokio-based runtime",
+		Author:      "Jane Doe",
+		Source:      "Tech News",
+		Categories:  []string{"programming"},
+	}
+
+	tests := []struct {
+		search   string
+		expected bool
+	}{
+		{"rust", true},
+		{"rust NOT game", true},
+		{"rust NOT kubernetes", false},
+		{`"zero day"`, true},
+		{`"day zero"`, false},
+		{"zero day", true},
+		{"title:kubernetes", false},
+		{"content:kubernetes", true},
+		{`description:"zero day"`, true},
+		{"kube*", true},
+		{"ubernetes*", false},
+		{"tokio*", true},
+		{"based*", false},
+		{"category:programming", true},
+		{"golang OR rust", true},
+		{"golang AND rust", false},
+		{"(golang OR rust) AND released", true},
+		{"golang, rust", true},
+		{"https://example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.search, func(t *testing.T) {
+			expr, err := parser.Parse(tt.search)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := parser.Evaluate(expr, article); got != tt.expected {
+				t.Errorf("Evaluate(%q) = %v, want %v", tt.search, got, tt.expected)
+			}
+		})
+	}
+}
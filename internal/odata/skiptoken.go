@@ -0,0 +1,64 @@
+package odata
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorssag/internal/models"
+)
+
+type skipTokenPayload struct {
+	PublishedAt string `json:"p"`
+	ID          string `json:"id"`
+}
+
+// EncodeSkipToken returns the opaque $skiptoken pointing after the given article
+func EncodeSkipToken(article models.Article) string {
+	payload, _ := json.Marshal(skipTokenPayload{
+		// Keep the original offset so the token matches the stored value exactly
+		PublishedAt: article.PublishedAt.Format(time.RFC3339Nano),
+		ID:          article.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// DecodeSkipToken parses a $skiptoken produced by EncodeSkipToken
+func DecodeSkipToken(token string) (*models.SkipToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(token, "="))
+	if err != nil {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var payload skipTokenPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.ID == "" {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	publishedAt, err := time.Parse(time.RFC3339Nano, payload.PublishedAt)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token date: %v", err)
+	}
+
+	return &models.SkipToken{PublishedAt: publishedAt, ID: payload.ID}, nil
+}
+
+// IsKeysetOrder reports whether an $orderby value is compatible with $skiptoken
+// cursors (the default published_at descending order)
+func IsKeysetOrder(orderBy string) bool {
+	orderBy = strings.ToLower(strings.Join(strings.Fields(orderBy), " "))
+	return orderBy == "" || orderBy == "published_at desc"
+}
+
+// AfterSkipToken reports whether an article sorts after the cursor in keyset order
+func AfterSkipToken(article models.Article, token *models.SkipToken) bool {
+	if token == nil {
+		return true
+	}
+	if !article.PublishedAt.Equal(token.PublishedAt) {
+		return article.PublishedAt.Before(token.PublishedAt)
+	}
+	return article.ID < token.ID
+}
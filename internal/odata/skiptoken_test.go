@@ -0,0 +1,76 @@
+package odata
+
+import (
+	"testing"
+	"time"
+
+	"gorssag/internal/models"
+)
+
+func TestSkipToken_RoundTrip(t *testing.T) {
+	publishedAt := time.Date(2024, 3, 10, 12, 30, 0, 123456789, time.FixedZone("CET", 3600))
+	token := EncodeSkipToken(models.Article{ID: "abc", PublishedAt: publishedAt})
+
+	decoded, err := DecodeSkipToken(token)
+	if err != nil {
+		t.Fatalf("DecodeSkipToken() error = %v", err)
+	}
+	if decoded.ID != "abc" {
+		t.Errorf("ID = %v, want abc", decoded.ID)
+	}
+	// The offset is preserved so the cursor binds to the stored text
+	if decoded.PublishedAt.Format(time.RFC3339Nano) != publishedAt.Format(time.RFC3339Nano) {
+		t.Errorf("PublishedAt = %v, want %v", decoded.PublishedAt, publishedAt)
+	}
+}
+
+func TestDecodeSkipToken_Invalid(t *testing.T) {
+	for _, token := range []string{"not base64!", "bm90IGpzb24", "eyJwIjoieWVzdGVyZGF5IiwiaWQiOiJhIn0", "e30"} {
+		t.Run(token, func(t *testing.T) {
+			if _, err := DecodeSkipToken(token); err == nil {
+				t.Errorf("DecodeSkipToken(%q) expected error, got nil", token)
+			}
+		})
+	}
+}
+
+func TestAfterSkipToken(t *testing.T) {
+	now := time.Now()
+	token := &models.SkipToken{PublishedAt: now, ID: "m"}
+
+	tests := []struct {
+		name     string
+		article  models.Article
+		expected bool
+	}{
+		{"older", models.Article{ID: "z", PublishedAt: now.Add(-time.Minute)}, true},
+		{"newer", models.Article{ID: "a", PublishedAt: now.Add(time.Minute)}, false},
+		{"same time lower id", models.Article{ID: "a", PublishedAt: now}, true},
+		{"same time higher id", models.Article{ID: "z", PublishedAt: now}, false},
+		{"cursor itself", models.Article{ID: "m", PublishedAt: now}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AfterSkipToken(tt.article, token); got != tt.expected {
+				t.Errorf("AfterSkipToken() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsKeysetOrder(t *testing.T) {
+	tests := map[string]bool{
+		"":                   true,
+		"published_at desc":  true,
+		"Published_At  DESC": true,
+		"published_at asc":   false,
+		"title asc":          false,
+	}
+
+	for orderBy, expected := range tests {
+		if got := IsKeysetOrder(orderBy); got != expected {
+			t.Errorf("IsKeysetOrder(%q) = %v, want %v", orderBy, got, expected)
+		}
+	}
+}
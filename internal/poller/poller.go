@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
@@ -33,7 +34,8 @@ type Poller struct {
 	wg               sync.WaitGroup
 	mu               sync.RWMutex
 	lastPolled       map[string]time.Time
-	isPolling        bool
+	isPolling        bool // Started and not stopped
+	cycleRunning     bool // A poll cycle is in progress
 
 	// Storage optimization fields
 	config           *config.Config
@@ -70,6 +72,15 @@ func (p *Poller) Start() {
 
 	p.wg.Add(1)
 	go p.pollLoop()
+
+	if p.config != nil && p.config.BackupInterval > 0 {
+		if _, ok := p.storage.(storage.Backupper); ok {
+			p.wg.Add(1)
+			go p.backupLoop()
+		} else {
+			log.Printf("Warning: scheduled backups are not supported by the %s storage driver", p.config.StorageDriver)
+		}
+	}
 }
 
 func (p *Poller) Stop() {
@@ -107,23 +118,33 @@ func (p *Poller) pollLoop() {
 }
 
 func (p *Poller) pollAllFeeds() {
+	// Skip the cycle while the previous one still runs
 	p.mu.Lock()
-	if p.isPolling {
+	if p.cycleRunning {
 		p.mu.Unlock()
 		return
 	}
+	p.cycleRunning = true
 	p.mu.Unlock()
 
+	defer func() {
+		p.mu.Lock()
+		p.cycleRunning = false
+		p.mu.Unlock()
+	}()
+
 	log.Printf("Starting background feed polling...")
 
 	// Use the new centralized polling system
-	err := p.aggregator.PollAllFeeds()
+	err := p.aggregator.PollAllFeeds(p.ctx)
 	if err != nil {
 		log.Printf("Error polling feeds: %v", err)
 	}
 
 	// Clean up old articles based on retention policy
-	if err := p.storage.CleanupOldArticles(p.articleRetention); err != nil {
+	policy := storage.NewRetentionPolicy(p.config)
+	policy.MaxAge = p.articleRetention
+	if err := p.storage.CleanupOldArticles(p.ctx, policy); err != nil {
 		log.Printf("Warning: failed to cleanup old articles: %v", err)
 	}
 
@@ -133,6 +154,34 @@ func (p *Poller) pollAllFeeds() {
 	log.Printf("Background feed polling completed")
 }
 
+// backupLoop writes a scheduled backup under DataDir every BackupInterval
+func (p *Poller) backupLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.BackupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.runScheduledBackup()
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// runScheduledBackup writes a backup and rotates the older ones
+func (p *Poller) runScheduledBackup() {
+	dir := filepath.Join(p.config.DataDir, "backups")
+	path, err := storage.CreateBackup(p.ctx, p.storage, dir, p.config.BackupKeep)
+	if err != nil {
+		log.Printf("Warning: failed to write scheduled backup: %v", err)
+		return
+	}
+	log.Printf("Scheduled backup written to %s", path)
+}
+
 // runStorageOptimization runs storage optimization tasks periodically
 func (p *Poller) runStorageOptimization() {
 	// Check if it's time to run optimization
@@ -143,34 +192,39 @@ func (p *Poller) runStorageOptimization() {
 	log.Printf("Running storage optimization...")
 
 	// Compress old articles that are still uncompressed
-	if err := p.storage.CompressOldArticles(); err != nil {
+	if err := p.storage.CompressOldArticles(p.ctx); err != nil {
 		log.Printf("Warning: failed to compress old articles: %v", err)
 	}
 
 	// Remove duplicate articles if enabled
 	if p.config.EnableDuplicateRemoval {
-		if err := p.storage.RemoveDuplicateArticles(); err != nil {
+		if err := p.storage.RemoveDuplicateArticles(p.ctx); err != nil {
 			log.Printf("Warning: failed to remove duplicate articles: %v", err)
 		}
 	}
 
 	// Run database optimization
-	if err := p.storage.OptimizeDatabase(); err != nil {
+	if err := p.storage.OptimizeDatabase(p.ctx); err != nil {
 		log.Printf("Warning: failed to optimize database: %v", err)
 	}
 
 	// Get and log database statistics
-	if stats, err := p.storage.GetDatabaseStats(); err == nil {
+	if stats, err := p.storage.GetDatabaseStats(p.ctx); err == nil {
 		log.Printf("Database stats: %+v", stats)
 	} else {
 		log.Printf("Warning: failed to get database stats: %v", err)
 	}
 
+	// Keep the statistics history used for growth forecasts
+	if _, err := p.storage.RecordStatsSnapshot(p.ctx); err != nil {
+		log.Printf("Warning: failed to record stats snapshot: %v", err)
+	}
+
 	p.lastOptimization = time.Now()
 	log.Printf("Storage optimization completed")
 }
 
-func (p *Poller) pollTopicFeeds(topic string) {
+func (p *Poller) pollTopicFeeds(ctx context.Context, topic string) {
 	log.Printf("Polling feeds for topic: %s", topic)
 
 	topicConfig, exists := p.feeds[topic]
@@ -180,7 +234,7 @@ func (p *Poller) pollTopicFeeds(topic string) {
 	}
 
 	// Fetch articles from all feeds for this topic
-	articles, err := p.fetchFeedsParallel(topicConfig.URLs)
+	articles, err := p.fetchFeedsParallel(ctx, topicConfig.URLs)
 	if err != nil {
 		log.Printf("Error fetching feeds for topic '%s': %v", topic, err)
 		p.lastPolled[topic] = time.Now()
@@ -196,6 +250,13 @@ func (p *Poller) pollTopicFeeds(topic string) {
 		return
 	}
 
+	// Articles already stored under another ID keep it
+	if err := p.aggregator.ReuseStoredArticleIDs(ctx, filteredArticles); err != nil {
+		log.Printf("Error looking up stored articles for topic '%s': %v", topic, err)
+		p.lastPolled[topic] = time.Now()
+		return
+	}
+
 	// Create aggregated feed
 	feed := &models.AggregatedFeed{
 		Topic:    topic,
@@ -205,7 +266,7 @@ func (p *Poller) pollTopicFeeds(topic string) {
 	}
 
 	// Save to storage
-	if err := p.storage.SaveFeed(topic, feed); err != nil {
+	if err := p.storage.SaveFeed(ctx, topic, feed); err != nil {
 		log.Printf("Error saving feed for topic '%s': %v", topic, err)
 	} else {
 		log.Printf("Saved %d articles for topic: %s", len(filteredArticles), topic)
@@ -256,7 +317,7 @@ func (p *Poller) articleMatchesFilters(article models.Article, filters []string)
 	return false
 }
 
-func (p *Poller) fetchFeedsParallel(feedURLs []string) ([]models.Article, error) {
+func (p *Poller) fetchFeedsParallel(ctx context.Context, feedURLs []string) ([]models.Article, error) {
 	var wg sync.WaitGroup
 	results := make(chan aggregator.FeedResult, len(feedURLs))
 
@@ -265,7 +326,7 @@ func (p *Poller) fetchFeedsParallel(feedURLs []string) ([]models.Article, error)
 		wg.Add(1)
 		go func(feedURL string) {
 			defer wg.Done()
-			articles, err := p.fetchFeed(feedURL)
+			articles, err := p.fetchFeed(ctx, feedURL)
 			results <- aggregator.FeedResult{
 				URL:      feedURL,
 				Articles: articles,
@@ -295,6 +356,8 @@ func (p *Poller) fetchFeedsParallel(feedURLs []string) ([]models.Article, error)
 			} else {
 				allArticles = append(allArticles, result.Articles...)
 			}
+		case <-ctx.Done():
+			return allArticles, ctx.Err()
 		case <-timeout:
 			log.Printf("Timeout waiting for feed results")
 			return allArticles, nil
@@ -302,8 +365,8 @@ func (p *Poller) fetchFeedsParallel(feedURLs []string) ([]models.Article, error)
 	}
 }
 
-func (p *Poller) fetchFeed(url string) ([]models.Article, error) {
-	feed, err := p.parser.ParseURL(url)
+func (p *Poller) fetchFeed(ctx context.Context, url string) ([]models.Article, error) {
+	feed, err := p.parser.ParseURLWithContext(url, ctx)
 	if err != nil {
 		return nil, fmt.Errorf("http error: %v", err)
 	}
@@ -524,11 +587,11 @@ func (p *Poller) GetLastPolledTime() map[string]time.Time {
 	return result
 }
 
-func (p *Poller) ForcePoll(topic string) error {
+func (p *Poller) ForcePoll(ctx context.Context, topic string) error {
 	log.Printf("Force polling topic: %s", topic)
 	if _, exists := p.feeds[topic]; !exists {
 		return fmt.Errorf("topic '%s' not found", topic)
 	}
-	p.pollTopicFeeds(topic)
+	p.pollTopicFeeds(ctx, topic)
 	return nil
 }
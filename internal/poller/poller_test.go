@@ -1,6 +1,7 @@
 package poller
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -28,7 +29,7 @@ func TestPoller_New(t *testing.T) {
 		ArticleRetention:         24 * time.Hour,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -56,7 +57,7 @@ func TestPoller_IsPolling(t *testing.T) {
 		ArticleRetention:         24 * time.Hour,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -97,7 +98,7 @@ func TestPoller_GetLastPolledTime(t *testing.T) {
 		ArticleRetention:         24 * time.Hour,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -110,7 +111,7 @@ func TestPoller_GetLastPolledTime(t *testing.T) {
 	}
 
 	// Force poll a topic
-	err := p.ForcePoll("tech")
+	err := p.ForcePoll(context.Background(), "tech")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -144,14 +145,14 @@ func TestPoller_ForcePoll_InvalidTopic(t *testing.T) {
 		ArticleRetention:         24 * time.Hour,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
 	p := New(agg, cacheManager, storageManager, feeds, 1*time.Minute, 1*time.Minute, cfg)
 
 	// Try to force poll an invalid topic
-	err := p.ForcePoll("invalid-topic")
+	err := p.ForcePoll(context.Background(), "invalid-topic")
 	if err == nil {
 		t.Error("Expected error for invalid topic, got nil")
 	}
@@ -174,14 +175,14 @@ func TestPoller_ForcePoll_ValidTopic(t *testing.T) {
 		ArticleRetention:         24 * time.Hour,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
 	p := New(agg, cacheManager, storageManager, feeds, 1*time.Minute, 1*time.Minute, cfg)
 
 	// Test force poll with valid topic
-	err := p.ForcePoll("tech")
+	err := p.ForcePoll(context.Background(), "tech")
 	if err != nil {
 		t.Errorf("Expected no error for valid topic force poll, got %v", err)
 	}
@@ -204,7 +205,7 @@ func TestPoller_StartStopMultipleTimes(t *testing.T) {
 		ArticleRetention:         24 * time.Hour,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -232,6 +233,43 @@ func TestPoller_StartStopMultipleTimes(t *testing.T) {
 	}
 }
 
+func TestPoller_StartRecordsStatsSnapshots(t *testing.T) {
+	cacheManager := cache.NewManager(5 * time.Minute)
+
+	cfg := &config.Config{
+		MaxContentLength:         10000,
+		ArticleRetention:         24 * time.Hour,
+		DatabaseOptimizeInterval: time.Millisecond,
+	}
+
+	storageManager := storage.NewMemoryStorage(cfg)
+	defer storageManager.Close()
+
+	feeds := map[string]config.TopicConfig{}
+	agg := aggregator.New(cacheManager, storageManager, feeds)
+	p := New(agg, cacheManager, storageManager, feeds, 10*time.Millisecond, 24*time.Hour, cfg)
+
+	start := time.Now().Add(-time.Second)
+	p.Start()
+	defer p.Stop()
+
+	// Every poll cycle runs the storage optimization, recording a snapshot
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		snapshots, err := storageManager.GetStatsSnapshots(context.Background(), start)
+		if err != nil {
+			t.Fatalf("GetStatsSnapshots() error = %v", err)
+		}
+		if len(snapshots) >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the running poller to record stats snapshots, got %d", len(snapshots))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func TestPoller_ArticleMatchesFilters(t *testing.T) {
 	feeds := map[string]config.TopicConfig{
 		"tech": {
@@ -249,7 +287,7 @@ func TestPoller_ArticleMatchesFilters(t *testing.T) {
 		ArticleRetention:         24 * time.Hour,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -301,7 +339,7 @@ func TestPoller_FilterArticlesForTopic(t *testing.T) {
 		ArticleRetention:         24 * time.Hour,
 	}
 
-	storageManager, _ := storage.NewStorage("./testdata", cfg)
+	storageManager := storage.NewMemoryStorage(cfg)
 	defer storageManager.Close()
 
 	agg := aggregator.New(cacheManager, storageManager, feeds)
@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+)
+
+// archiveFilePrefix and archiveFileSuffix frame the month of archive files
+const (
+	archiveFilePrefix = "articles-"
+	archiveFileSuffix = ".db"
+)
+
+// archiveSchema is the table of a monthly archive file. Columns share the
+// names of the articles table so the article conditions apply unchanged;
+// archived content is never compressed, so compressed_content stays empty.
+const archiveSchema = `
+	CREATE TABLE IF NOT EXISTS articles (
+		article_id TEXT PRIMARY KEY,
+		title TEXT NOT NULL,
+		link TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		content TEXT NOT NULL DEFAULT '',
+		author TEXT NOT NULL DEFAULT '',
+		source TEXT NOT NULL DEFAULT '',
+		categories TEXT NOT NULL DEFAULT '[]', -- JSON array
+		published_at DATETIME NOT NULL,
+		language TEXT NOT NULL DEFAULT 'en',
+		pinned BOOLEAN NOT NULL DEFAULT 0,
+		topics TEXT NOT NULL DEFAULT '[]', -- JSON array of the topics at archive time
+		archived_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_articles_published_at ON articles(published_at DESC, article_id DESC);
+	CREATE TABLE IF NOT EXISTS compressed_content (
+		article_id TEXT PRIMARY KEY,
+		compressed_content BLOB NOT NULL,
+		codec TEXT NOT NULL DEFAULT 'gzip'
+	);
+`
+
+// Archive is the cold tier receiving the articles expired by retention: one
+// SQLite file per publication month, searchable with the usual query options
+type Archive struct {
+	dir   string
+	mutex sync.Mutex // Serializes writes
+}
+
+// NewArchive returns the archive stored in dir, created on first write
+func NewArchive(dir string) *Archive {
+	return &Archive{dir: dir}
+}
+
+// ArchiveDir returns the archive directory inside a data directory
+func ArchiveDir(dataDir string) string {
+	return filepath.Join(dataDir, "archive")
+}
+
+// Store writes articles into the archive file of their publication month,
+// replacing earlier copies of the same articles
+func (a *Archive) Store(ctx context.Context, articles []models.ExportedArticle) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if err := os.MkdirAll(a.dir, 0750); err != nil {
+		return fmt.Errorf("failed to create archive directory: %v", err)
+	}
+
+	byMonth := make(map[string][]models.ExportedArticle)
+	for _, article := range articles {
+		month := article.PublishedAt.UTC().Format("2006-01")
+		byMonth[month] = append(byMonth[month], article)
+	}
+	for month, batch := range byMonth {
+		if err := a.storeMonth(ctx, month, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeMonth upserts articles into the archive file of a month
+func (a *Archive) storeMonth(ctx context.Context, month string, articles []models.ExportedArticle) error {
+	db, err := sql.Open(sqliteDriver, filepath.Join(a.dir, archiveFilePrefix+month+archiveFileSuffix)+"?_busy_timeout=30000")
+	if err != nil {
+		return fmt.Errorf("failed to open archive %s: %v", month, err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, archiveSchema); err != nil {
+		return fmt.Errorf("failed to initialize archive %s: %v", month, err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT OR REPLACE INTO articles (article_id, title, link, description, content, author, source, categories, published_at, language, pinned, topics)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare archive statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, article := range articles {
+		categoriesJSON, _ := json.Marshal(nonNilStrings(article.Categories))
+		topicsJSON, _ := json.Marshal(nonNilStrings(article.Topics))
+		if _, err := stmt.ExecContext(ctx, article.ID, article.Title, article.Link, article.Description, article.Content,
+			article.Author, article.Source, string(categoriesJSON), article.PublishedAt, article.Language, article.Pinned, string(topicsJSON)); err != nil {
+			return fmt.Errorf("failed to archive article %s: %v", article.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit archive %s: %v", month, err)
+	}
+	return nil
+}
+
+// nonNilStrings returns values, or an empty slice so it encodes as a JSON array
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// files returns the archive files, newest month first
+func (a *Archive) files() ([]string, error) {
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read archive directory: %v", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, archiveFilePrefix) || !strings.HasSuffix(name, archiveFileSuffix) {
+			continue
+		}
+		files = append(files, filepath.Join(a.dir, name))
+	}
+
+	// Months are fixed width, so names sort chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files, nil
+}
+
+// ErrInvalidArchiveQuery is returned when a query uses an option the archive
+// cannot answer
+var ErrInvalidArchiveQuery = errors.New("invalid archive query")
+
+// Query returns the archived articles matching a query, restricted to a
+// topic when topic is set, with the total number of matches. Months never
+// overlap, so files are scanned newest first until the page is full. Only the
+// default published_at desc ordering is supported.
+func (a *Archive) Query(ctx context.Context, topic string, query *models.ODataQuery) ([]models.Article, int, error) {
+	if !odata.IsKeysetOrder(query.OrderBy) {
+		return nil, 0, fmt.Errorf("%w: only the default published_at desc ordering is supported", ErrInvalidArchiveQuery)
+	}
+	if query.FeedURL != "" {
+		return nil, 0, fmt.Errorf("%w: feed_url is not supported", ErrInvalidArchiveQuery)
+	}
+
+	conditions, args, err := buildArticleConditions(query, "a")
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidArchiveQuery, err)
+	}
+	if topic != "" {
+		conditions += " AND EXISTS (SELECT 1 FROM json_each(a.topics) WHERE json_each.value = ?)"
+		args = append(args, topic)
+	}
+
+	files, err := a.files()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	articles := []models.Article{}
+	totalCount := 0
+	offset := 0
+	if query.SkipToken == nil {
+		offset = query.Skip
+	}
+
+	for _, file := range files {
+		count, page, err := queryArchiveFile(ctx, file, conditions, args, query, offset, len(articles))
+		if err != nil {
+			return nil, 0, err
+		}
+		totalCount += count
+		articles = append(articles, page...)
+
+		// The offset is spent on the first file holding matches beyond it
+		if offset > 0 {
+			offset -= count
+			if offset < 0 {
+				offset = 0
+			}
+		}
+	}
+
+	return articles, totalCount, nil
+}
+
+// queryArchiveFile counts the matches of one archive file and, while the page
+// is not full yet, returns those after offset
+func queryArchiveFile(ctx context.Context, file, conditions string, args []interface{}, query *models.ODataQuery, offset, collected int) (int, []models.Article, error) {
+	db, err := sql.Open(sqliteDriver, file+"?mode=ro&_busy_timeout=30000")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to open archive %s: %v", filepath.Base(file), err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM articles a WHERE 1=1"+conditions, args...).Scan(&count); err != nil {
+		return 0, nil, fmt.Errorf("failed to count archived articles: %v", err)
+	}
+
+	full := query.Top > 0 && collected >= query.Top
+	if count == 0 || full || (query.SkipToken == nil && offset >= count) {
+		return count, nil, nil
+	}
+
+	pageQuery := `
+		SELECT a.article_id, a.title, a.description, a.content, a.link, a.author, a.source, a.published_at, a.categories, a.language, a.pinned, a.topics
+		FROM articles a
+		WHERE 1=1` + conditions
+	pageArgs := append([]interface{}{}, args...)
+	if query.SkipToken != nil {
+		keyset, keysetArgs := buildKeysetCondition(query.SkipToken, "a")
+		pageQuery += keyset
+		pageArgs = append(pageArgs, keysetArgs...)
+	}
+	limit := -1
+	if query.Top > 0 {
+		limit = query.Top - collected
+	}
+	pageQuery += " ORDER BY " + keysetOrder("a") + " LIMIT ? OFFSET ?"
+	pageArgs = append(pageArgs, limit, offset)
+
+	rows, err := db.QueryContext(ctx, pageQuery, pageArgs...)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to query archived articles: %v", err)
+	}
+	defer rows.Close()
+
+	var articles []models.Article
+	for rows.Next() {
+		var article models.Article
+		var categoriesJSON, topicsJSON string
+		if err := rows.Scan(&article.ID, &article.Title, &article.Description, &article.Content, &article.Link, &article.Author,
+			&article.Source, &article.PublishedAt, &categoriesJSON, &article.Language, &article.Pinned, &topicsJSON); err != nil {
+			return 0, nil, fmt.Errorf("failed to scan archived article: %v", err)
+		}
+
+		json.Unmarshal([]byte(categoriesJSON), &article.Categories)
+		var topics []string
+		json.Unmarshal([]byte(topicsJSON), &topics)
+		if len(topics) > 0 {
+			article.Topic = topics[0]
+		}
+		article.ClusterID = article.ID
+
+		articles = append(articles, article)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	return count, articles, nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorssag/internal/models"
+)
+
+// articleLinkBatch bounds the number of links per lookup statement
+const articleLinkBatch = 500
+
+// getSQLArticles loads articles by ID from a SQL backend, in the requested
+// order, skipping unknown IDs
+func getSQLArticles(ctx context.Context, q queryer, ids []string) ([]models.Article, error) {
+	found, err := getArticlesByIDs(ctx, q, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	articles := make([]models.Article, 0, len(found))
+	for _, id := range ids {
+		if article, ok := found[id]; ok {
+			articles = append(articles, article)
+			delete(found, id) // Repeated IDs are returned once
+		}
+	}
+
+	if err := attachSQLFeedURLs(ctx, q, articles); err != nil {
+		return nil, err
+	}
+	return articles, nil
+}
+
+// GetArticles returns the articles with the given IDs, with decompressed content
+func (s *SQLiteStorage) GetArticles(ctx context.Context, ids []string) ([]models.Article, error) {
+	return getSQLArticles(ctx, s.db, ids)
+}
+
+// GetArticles returns the articles with the given IDs, with decompressed content
+func (s *PostgresStorage) GetArticles(ctx context.Context, ids []string) ([]models.Article, error) {
+	return getSQLArticles(ctx, s.db, ids)
+}
+
+// getSQLArticleIDsByLink returns the stored article ID of each known link,
+// the lowest when several articles share a link
+func getSQLArticleIDsByLink(ctx context.Context, q queryer, links []string) (map[string]string, error) {
+	ids := make(map[string]string, len(links))
+	for start := 0; start < len(links); start += articleLinkBatch {
+		end := start + articleLinkBatch
+		if end > len(links) {
+			end = len(links)
+		}
+
+		placeholders := make([]string, end-start)
+		args := make([]interface{}, end-start)
+		for i, link := range links[start:end] {
+			placeholders[i] = "?"
+			args[i] = link
+		}
+		rows, err := q.QueryContext(ctx, "SELECT link, MIN(article_id) FROM articles WHERE link IN ("+strings.Join(placeholders, ",")+") GROUP BY link", args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up articles by link: %v", err)
+		}
+		for rows.Next() {
+			var link, id string
+			if err := rows.Scan(&link, &id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan article link: %v", err)
+			}
+			ids[link] = id
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error during rows iteration: %v", err)
+		}
+	}
+	return ids, nil
+}
+
+// GetArticleIDsByLink returns the stored article ID of each known link
+func (s *SQLiteStorage) GetArticleIDsByLink(ctx context.Context, links []string) (map[string]string, error) {
+	return getSQLArticleIDsByLink(ctx, s.db, links)
+}
+
+// GetArticleIDsByLink returns the stored article ID of each known link
+func (s *PostgresStorage) GetArticleIDsByLink(ctx context.Context, links []string) (map[string]string, error) {
+	return getSQLArticleIDsByLink(ctx, s.db, links)
+}
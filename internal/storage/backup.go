@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sqliteDatabaseFile is the name of the SQLite database inside the data directory
+const sqliteDatabaseFile = "rss_aggregator.db"
+
+// backupFilePrefix and backupFileSuffix frame the timestamp of scheduled backups
+const (
+	backupFilePrefix = "rss_aggregator-"
+	backupFileSuffix = ".db.gz"
+)
+
+// ErrBackupUnsupported is returned when the storage backend cannot take backups
+var ErrBackupUnsupported = errors.New("storage backend does not support backups")
+
+// Backupper is implemented by storages able to write a consistent snapshot of
+// their database while it is in use
+type Backupper interface {
+	// Backup writes a snapshot of the database to path, which must not exist
+	Backup(ctx context.Context, path string) error
+}
+
+// Backup writes a consistent snapshot of the live database with VACUUM INTO
+func (s *SQLiteStorage) Backup(ctx context.Context, path string) error {
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to write backup: %v", err)
+	}
+	return nil
+}
+
+// CreateBackup writes a timestamped compressed snapshot of s into dir and
+// removes all but the keep most recent ones. keep <= 0 keeps every backup.
+func CreateBackup(ctx context.Context, s Storage, dir string, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %v", err)
+	}
+
+	snapshot, cleanup, err := Snapshot(ctx, s)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	name := backupFilePrefix + time.Now().UTC().Format("20060102T150405Z") + backupFileSuffix
+	target := filepath.Join(dir, name)
+
+	// Write next to the target and rename so a partial backup is never picked up
+	file, err := os.CreateTemp(dir, ".backup-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup file: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	if err := CompressFile(snapshot, file); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write backup file: %v", err)
+	}
+	if err := os.Rename(file.Name(), target); err != nil {
+		return "", fmt.Errorf("failed to move backup into place: %v", err)
+	}
+
+	if err := rotateBackups(dir, keep); err != nil {
+		return target, err
+	}
+	return target, nil
+}
+
+// ListBackups returns the scheduled backups in dir, oldest first
+func ListBackups(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %v", err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupFilePrefix) || !strings.HasSuffix(name, backupFileSuffix) {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+
+	// Timestamps are fixed width, so names sort chronologically
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// rotateBackups removes the oldest backups in dir beyond keep
+func rotateBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return err
+	}
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil {
+			return fmt.Errorf("failed to remove old backup: %v", err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// RestoreBackup replaces the SQLite database in dataDir with a backup, plain
+// or gzip compressed. The backup must pass an integrity check and carry a
+// schema version this binary can migrate. The replaced database is kept with a
+// .pre-restore suffix. The server must not be running.
+func RestoreBackup(dataDir, backupPath string) error {
+	if err := os.MkdirAll(dataDir, 0750); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	source, err := os.Open(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %v", err)
+	}
+	defer source.Close()
+
+	reader, err := maybeGunzip(source)
+	if err != nil {
+		return err
+	}
+
+	// Stage the restored database next to the live one so the swap is a rename
+	staged, err := os.CreateTemp(dataDir, ".restore-*.db")
+	if err != nil {
+		return fmt.Errorf("failed to create staging file: %v", err)
+	}
+	stagedPath := staged.Name()
+	defer os.Remove(stagedPath)
+
+	if _, err := io.Copy(staged, reader); err != nil {
+		staged.Close()
+		return fmt.Errorf("failed to read backup: %v", err)
+	}
+	if err := staged.Close(); err != nil {
+		return fmt.Errorf("failed to write staging file: %v", err)
+	}
+
+	if err := validateBackup(stagedPath); err != nil {
+		return err
+	}
+
+	dbPath := filepath.Join(dataDir, sqliteDatabaseFile)
+	if _, err := os.Stat(dbPath); err == nil {
+		if err := os.Rename(dbPath, dbPath+".pre-restore"); err != nil {
+			return fmt.Errorf("failed to keep current database: %v", err)
+		}
+	}
+	// Stale WAL files belong to the replaced database
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s file: %v", suffix, err)
+		}
+	}
+	if err := os.Rename(stagedPath, dbPath); err != nil {
+		return fmt.Errorf("failed to move restored database into place: %v", err)
+	}
+
+	return nil
+}
+
+// validateBackup checks that path is an intact SQLite database with a schema
+// version between the first migration and the newest one embedded
+func validateBackup(path string) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("backup is not a readable database: %v", err)
+	}
+	defer conn.Close()
+
+	var integrity string
+	if err := conn.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&integrity); err != nil {
+		return fmt.Errorf("backup is not a readable database: %v", err)
+	}
+	if integrity != "ok" {
+		return fmt.Errorf("backup failed integrity check: %s", integrity)
+	}
+
+	version, err := schemaVersion(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("backup has no schema version: %v", err)
+	}
+	latest, err := latestSQLiteSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version <= 0 {
+		return fmt.Errorf("backup has no applied migrations")
+	}
+	if version > latest {
+		return fmt.Errorf("backup schema version %d is newer than this binary supports (%d)", version, latest)
+	}
+	return nil
+}
+
+// Snapshot takes a backup of s into a temporary file, returning its path and
+// a function removing it
+func Snapshot(ctx context.Context, s Storage) (string, func(), error) {
+	backupper, ok := s.(Backupper)
+	if !ok {
+		return "", nil, ErrBackupUnsupported
+	}
+
+	dir, err := os.MkdirTemp("", "gorssag-backup-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	path := filepath.Join(dir, sqliteDatabaseFile)
+	if err := backupper.Backup(ctx, path); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return path, cleanup, nil
+}
+
+// CompressFile writes the gzip compressed content of path to w
+func CompressFile(path string, w io.Writer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %v", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(w)
+	if _, err := io.Copy(gz, file); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %v", err)
+	}
+	return nil
+}
+
+// maybeGunzip returns a reader decompressing r when it starts with the gzip
+// magic bytes, and reading it as is otherwise
+func maybeGunzip(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read backup: %v", err)
+	}
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return buffered, nil
+	}
+
+	gz, err := gzip.NewReader(buffered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress backup: %v", err)
+	}
+	return gz, nil
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorssag/internal/models"
+)
+
+func TestCreateBackup_RestoreBackup(t *testing.T) {
+	ctx := context.Background()
+	source, err := NewSQLiteStorage(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer source.Close()
+
+	feed := &models.AggregatedFeed{
+		Topic:    "tech",
+		Articles: []models.Article{{ID: "a1", Title: "Backed up", Link: "https://example.com/a1", Source: "Example", PublishedAt: time.Now()}},
+		Updated:  time.Now(),
+	}
+	if err := source.SaveFeed(ctx, "tech", feed); err != nil {
+		t.Fatalf("SaveFeed() error = %v", err)
+	}
+
+	// Older backups beyond keep are rotated away, unrelated files are left alone
+	backupDir := t.TempDir()
+	for _, name := range []string{"rss_aggregator-20200101T000000Z.db.gz", "rss_aggregator-20200102T000000Z.db.gz", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(backupDir, name), []byte("old"), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	path, err := CreateBackup(ctx, source, backupDir, 2)
+	if err != nil {
+		t.Fatalf("CreateBackup() error = %v", err)
+	}
+	backups, err := ListBackups(backupDir)
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 2 || backups[0] != filepath.Join(backupDir, "rss_aggregator-20200102T000000Z.db.gz") || backups[1] != path {
+		t.Errorf("Unexpected backups after rotation: %v", backups)
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, "notes.txt")); err != nil {
+		t.Errorf("Expected unrelated files to be kept: %v", err)
+	}
+
+	// Restore over an existing database, keeping it aside
+	dataDir := t.TempDir()
+	existing, err := NewSQLiteStorage(dataDir, nil)
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	existing.Close()
+
+	if err := RestoreBackup(dataDir, path); err != nil {
+		t.Fatalf("RestoreBackup() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, sqliteDatabaseFile+".pre-restore")); err != nil {
+		t.Errorf("Expected the replaced database to be kept: %v", err)
+	}
+
+	restored, err := NewSQLiteStorage(dataDir, nil)
+	if err != nil {
+		t.Fatalf("Failed to open restored database: %v", err)
+	}
+	defer restored.Close()
+
+	loaded, err := restored.LoadFeed(ctx, "tech")
+	if err != nil {
+		t.Fatalf("LoadFeed() error = %v", err)
+	}
+	if len(loaded.Articles) != 1 || loaded.Articles[0].ID != "a1" {
+		t.Errorf("Expected the backed up article, got %v", loaded.Articles)
+	}
+}
+
+func TestRestoreBackup_Invalid(t *testing.T) {
+	tempDir := t.TempDir()
+
+	garbage := filepath.Join(tempDir, "garbage.db")
+	if err := os.WriteFile(garbage, []byte("not a database"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// A database migrated by a newer binary
+	newerDir := t.TempDir()
+	newer, err := NewSQLiteStorage(newerDir, nil)
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	if _, err := newer.db.Exec("INSERT INTO schema_migrations (version, name) VALUES (9999, 'future')"); err != nil {
+		t.Fatalf("Failed to record future migration: %v", err)
+	}
+	newerBackup := filepath.Join(tempDir, "newer.db")
+	if err := newer.Backup(context.Background(), newerBackup); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	newer.Close()
+
+	for _, path := range []string{garbage, newerBackup, filepath.Join(tempDir, "missing.db")} {
+		dataDir := t.TempDir()
+		if err := RestoreBackup(dataDir, path); err == nil {
+			t.Errorf("RestoreBackup(%s) succeeded, want error", filepath.Base(path))
+		}
+		if _, err := os.Stat(filepath.Join(dataDir, sqliteDatabaseFile)); !os.IsNotExist(err) {
+			t.Errorf("RestoreBackup(%s) left a database behind", filepath.Base(path))
+		}
+	}
+}
+
+func TestSnapshot_Unsupported(t *testing.T) {
+	if _, _, err := Snapshot(context.Background(), NewMemoryStorage(nil)); err != ErrBackupUnsupported {
+		t.Errorf("Snapshot() error = %v, want ErrBackupUnsupported", err)
+	}
+}
@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorssag/internal/models"
+)
+
+const (
+	// clusterMaxDistance is the largest SimHash Hamming distance between two
+	// articles of the same story
+	clusterMaxDistance = 7
+
+	// clusterBands is the number of SimHash bands indexed for candidate lookup.
+	// Articles within clusterMaxDistance always share at least one band
+	// (pigeonhole principle), so it must stay above clusterMaxDistance.
+	clusterBands = 8
+
+	// clusterWindow bounds how far apart in time two articles of a story can be
+	clusterWindow = 72 * time.Hour
+)
+
+// simHash computes a 64 bit SimHash of the text using its words as features
+func simHash(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	for _, word := range words {
+		h := fnv.New64a()
+		h.Write([]byte(word))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	var hash uint64
+	for i := 0; i < 64; i++ {
+		if weights[i] > 0 {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// simHashBands splits a SimHash into clusterBands bands
+func simHashBands(hash uint64) []int64 {
+	width := uint(64 / clusterBands)
+	bands := make([]int64, clusterBands)
+	for i := range bands {
+		bands[i] = int64((hash >> (width * uint(i))) & (1<<width - 1))
+	}
+	return bands
+}
+
+// assignClusterWithTx computes the article SimHash and joins the cluster of
+// the closest article published within clusterWindow, or starts a new one
+func (s *SQLiteStorage) assignClusterWithTx(ctx context.Context, tx *sql.Tx, article models.Article, content string) error {
+	text := content
+	if text == "" {
+		text = article.Description
+	}
+	hash := simHash(article.Title + " " + text)
+	if hash == 0 {
+		return nil
+	}
+	bands := simHashBands(hash)
+
+	bandConditions := make([]string, len(bands))
+	args := make([]interface{}, 0, 2*len(bands)+3)
+	for i, value := range bands {
+		bandConditions[i] = "(b.band = ? AND b.value = ?)"
+		args = append(args, i, value)
+	}
+	args = append(args, article.ID, article.PublishedAt, clusterWindow.Hours()/24)
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT ac.cluster_id, ac.simhash
+		FROM article_cluster_bands b
+		JOIN article_clusters ac ON ac.article_id = b.article_id
+		JOIN articles a ON a.article_id = b.article_id
+		WHERE (`+strings.Join(bandConditions, " OR ")+`)
+			AND b.article_id != ?
+			AND ABS(julianday(a.published_at) - julianday(?)) <= ?
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query cluster candidates: %v", err)
+	}
+
+	clusterID := article.ID
+	bestDistance := clusterMaxDistance + 1
+	for rows.Next() {
+		var candidateCluster string
+		var candidateHash int64
+		if err := rows.Scan(&candidateCluster, &candidateHash); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan cluster candidate: %v", err)
+		}
+		if distance := bits.OnesCount64(hash ^ uint64(candidateHash)); distance < bestDistance {
+			bestDistance = distance
+			clusterID = candidateCluster
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO article_clusters (article_id, cluster_id, simhash)
+		VALUES (?, ?, ?)
+	`, article.ID, clusterID, int64(hash)); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM article_cluster_bands WHERE article_id = ?", article.ID); err != nil {
+		return err
+	}
+	for i, value := range bands {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO article_cluster_bands (article_id, band, value) VALUES (?, ?, ?)", article.ID, i, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildClusterCondition builds the " AND ..." condition keeping only the
+// newest matching article of each cluster (in published_at, article_id order).
+// The article table must be aliased "a" and joined with article_clusters as "ac";
+// when topic is set, other cluster members only count if they belong to it.
+func buildClusterCondition(query *models.ODataQuery, topic string) (string, []interface{}, error) {
+	conditions, args, err := buildArticleConditions(query, "o")
+	if err != nil {
+		return "", nil, err
+	}
+
+	if topic != "" {
+		conditions += " AND o.article_id IN (SELECT ot.article_id FROM article_topics ot JOIN topics otn ON ot.topic_id = otn.id WHERE otn.name = ?)"
+		args = append(args, topic)
+	}
+
+	condition := ` AND NOT EXISTS (
+		SELECT 1 FROM article_clusters oc
+		JOIN articles o ON o.article_id = oc.article_id
+		WHERE oc.cluster_id = ac.cluster_id
+			AND (o.published_at > a.published_at OR (o.published_at = a.published_at AND o.article_id > a.article_id))` +
+		conditions + `)`
+
+	return condition, args, nil
+}
+
+// attachClusterSources fills ClusterSources with the other articles of each article's cluster
+func attachClusterSources(ctx context.Context, db queryer, articles []models.Article) error {
+	var clusterIDs []interface{}
+	placeholders := []string{}
+	for _, article := range articles {
+		if article.ClusterID != "" {
+			clusterIDs = append(clusterIDs, article.ClusterID)
+			placeholders = append(placeholders, "?")
+		}
+	}
+	if len(clusterIDs) == 0 {
+		return nil
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT ac.cluster_id, a.article_id, a.title, a.link, a.source, a.published_at
+		FROM article_clusters ac
+		JOIN articles a ON a.article_id = ac.article_id
+		WHERE ac.cluster_id IN (`+strings.Join(placeholders, ",")+`)
+		ORDER BY a.published_at DESC, a.article_id DESC
+	`, clusterIDs...)
+	if err != nil {
+		return fmt.Errorf("failed to query cluster sources: %v", err)
+	}
+	defer rows.Close()
+
+	members := make(map[string][]models.ClusterSource)
+	for rows.Next() {
+		var clusterID string
+		var member models.ClusterSource
+		if err := rows.Scan(&clusterID, &member.ID, &member.Title, &member.Link, &member.Source, &member.PublishedAt); err != nil {
+			return fmt.Errorf("failed to scan cluster source: %v", err)
+		}
+		members[clusterID] = append(members[clusterID], member)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	for i := range articles {
+		for _, member := range members[articles[i].ClusterID] {
+			if member.ID != articles[i].ID {
+				articles[i].ClusterSources = append(articles[i].ClusterSources, member)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"gorssag/internal/config"
+	"gorssag/internal/models"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Content compression codecs, recorded in compressed_content.codec
+const (
+	codecGzip = "gzip"
+	codecZstd = "zstd"
+)
+
+// sqliteDriver is the go-sqlite3 driver extended with decompress_content(codec, data),
+// so SQL conditions can match the content of compressed articles
+const sqliteDriver = "sqlite3_gorssag"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("decompress_content", sqliteDecompressContent, true)
+		},
+	})
+}
+
+// sqliteDecompressContent implements decompress_content; unreadable content
+// matches nothing rather than failing the whole query
+func sqliteDecompressContent(codec string, compressed []byte) string {
+	content, err := decompressContent(codec, compressed)
+	if err != nil {
+		log.Printf("Warning: failed to decompress content in query: %v", err)
+		return ""
+	}
+	return content
+}
+
+// sqliteContentExpression is the content of the article referenced by alias,
+// decompressed lazily: only rows whose content was moved to compressed_content
+// run decompress_content
+func sqliteContentExpression(alias string) string {
+	return "COALESCE(NULLIF(" + alias + ".content, ''), (SELECT decompress_content(zc.codec, zc.compressed_content) FROM compressed_content zc WHERE zc.article_id = " + alias + ".article_id), '')"
+}
+
+// compressionCodec returns the codec new content is compressed with
+func compressionCodec(cfg *config.Config) string {
+	if cfg == nil || cfg.ContentCompressionCodec == "" {
+		return codecGzip
+	}
+	return cfg.ContentCompressionCodec
+}
+
+// validateCompressionCodec checks that a configured codec is supported
+func validateCompressionCodec(cfg *config.Config) error {
+	switch codec := compressionCodec(cfg); codec {
+	case codecGzip, codecZstd:
+		return nil
+	default:
+		return fmt.Errorf("unsupported content compression codec %q (want gzip or zstd)", codec)
+	}
+}
+
+// contentSelected reports whether a listing returns article content: always
+// unless $select names other fields only
+func contentSelected(query *models.ODataQuery) bool {
+	if query == nil || len(query.Select) == 0 {
+		return true
+	}
+	for _, field := range query.Select {
+		if strings.EqualFold(field, "content") {
+			return true
+		}
+	}
+	return false
+}
+
+// articleContentColumns selects the content, codec and compressed content of
+// a listed article, joined as "a" and "cc". When the query leaves content out
+// of $select they read as empty, so neither bodies nor compressed blobs load.
+func articleContentColumns(query *models.ODataQuery) string {
+	if !contentSelected(query) {
+		return "'' AS content, NULL AS codec, NULL AS compressed_content"
+	}
+	return "a.content, cc.codec, cc.compressed_content"
+}
+
+// zstd encoders and decoders are safe for concurrent EncodeAll/DecodeAll calls
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+func zstdCodec() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		if zstdEncoder, zstdErr = zstd.NewWriter(nil); zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+// compressContent compresses text content with a codec
+func compressContent(codec, content string) ([]byte, error) {
+	if content == "" {
+		return nil, nil
+	}
+
+	switch codec {
+	case codecZstd:
+		encoder, _, err := zstdCodec()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %v", err)
+		}
+		return encoder.EncodeAll([]byte(content), nil), nil
+	case codecGzip, "":
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+
+		if _, err := gw.Write([]byte(content)); err != nil {
+			return nil, fmt.Errorf("failed to compress content: %v", err)
+		}
+
+		if err := gw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close gzip writer: %v", err)
+		}
+
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression codec %q", codec)
+	}
+}
+
+// decompressContent decompresses content written with a codec; rows without
+// codec marker predate it and are gzip
+func decompressContent(codec string, compressed []byte) (string, error) {
+	if len(compressed) == 0 {
+		return "", nil
+	}
+
+	switch codec {
+	case codecZstd:
+		_, decoder, err := zstdCodec()
+		if err != nil {
+			return "", fmt.Errorf("failed to create zstd decoder: %v", err)
+		}
+		decompressed, err := decoder.DecodeAll(compressed, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress content: %v", err)
+		}
+		return string(decompressed), nil
+	case codecGzip, "":
+		gr, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return "", fmt.Errorf("failed to create gzip reader: %v", err)
+		}
+		defer gr.Close()
+
+		decompressed, err := io.ReadAll(gr)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress content: %v", err)
+		}
+
+		return string(decompressed), nil
+	default:
+		return "", fmt.Errorf("unsupported compression codec %q", codec)
+	}
+}
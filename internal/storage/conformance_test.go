@@ -0,0 +1,70 @@
+package storage_test
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	"gorssag/internal/config"
+	"gorssag/internal/storage"
+	"gorssag/internal/storage/storagetest"
+)
+
+func TestSQLiteStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, cfg *config.Config) storage.Storage {
+		s, err := storage.NewSQLiteStorage(t.TempDir(), cfg)
+		if err != nil {
+			t.Fatalf("Failed to create SQLite storage: %v", err)
+		}
+		return s
+	})
+}
+
+func TestMemoryStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, cfg *config.Config) storage.Storage {
+		return storage.NewMemoryStorage(cfg)
+	})
+}
+
+func TestPostgresStorage_Conformance(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+
+	storagetest.Run(t, func(t *testing.T, cfg *config.Config) storage.Storage {
+		s, err := storage.NewPostgresStorage(dsn, cfg)
+		if err != nil {
+			t.Fatalf("Failed to create PostgreSQL storage: %v", err)
+		}
+
+		// Every subtest starts from an empty database, only the applied
+		// migrations are kept
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+		rows, err := db.Query("SELECT quote_ident(tablename) FROM pg_tables WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'")
+		if err != nil {
+			t.Fatalf("Failed to list tables: %v", err)
+		}
+		var tables []string
+		for rows.Next() {
+			var table string
+			if err := rows.Scan(&table); err != nil {
+				t.Fatalf("Failed to scan table name: %v", err)
+			}
+			tables = append(tables, table)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			t.Fatalf("Failed to list tables: %v", err)
+		}
+		if _, err := db.Exec("TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE"); err != nil {
+			t.Fatalf("Failed to truncate tables: %v", err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gorssag/internal/models"
+)
+
+// digestColumns are the digests columns in the order scanDigests reads them
+const digestColumns = `id, recipient, topic, filter, schedule, subject, html_template, text_template,
+	max_articles, last_membership_id, next_send_at, last_sent_at, last_error, created_at`
+
+// getSQLTopicArrivals returns the IDs of the articles assigned to a topic
+// after a membership cursor, oldest first, with the cursor of the last one.
+// A negative cursor only returns the current cursor.
+func getSQLTopicArrivals(ctx context.Context, q queryer, topic string, after int64) ([]string, int64, error) {
+	if after < 0 {
+		var cursor int64
+		if err := q.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM article_topics").Scan(&cursor); err != nil {
+			return nil, 0, fmt.Errorf("failed to get topic membership cursor: %v", err)
+		}
+		return nil, cursor, nil
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT at.id, at.article_id
+		FROM article_topics at
+		JOIN topics t ON t.id = at.topic_id
+		WHERE t.name = ? AND at.id > ?
+		ORDER BY at.id
+	`, topic, after)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query topic arrivals: %v", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	cursor := after
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&cursor, &id); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan topic arrival: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, cursor, rows.Err()
+}
+
+// createSQLDigest stores a digest, setting its ID and creation time
+func createSQLDigest(ctx context.Context, q queryer, digest *models.Digest) error {
+	digest.CreatedAt = time.Now().UTC()
+	if err := q.QueryRowContext(ctx, `
+		INSERT INTO digests (recipient, topic, filter, schedule, subject, html_template, text_template, max_articles, last_membership_id, next_send_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, digest.Recipient, digest.Topic, digest.Filter, digest.Schedule, digest.Subject, digest.HTMLTemplate, digest.TextTemplate,
+		digest.MaxArticles, digest.Cursor, digest.NextSendAt.UTC(), digest.CreatedAt).Scan(&digest.ID); err != nil {
+		return fmt.Errorf("failed to create digest: %v", err)
+	}
+	return nil
+}
+
+// listSQLDigests returns the digests matching a WHERE clause, by ID
+func listSQLDigests(ctx context.Context, q queryer, where string, args ...interface{}) ([]models.Digest, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+digestColumns+" FROM digests "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digests: %v", err)
+	}
+	defer rows.Close()
+
+	digests := []models.Digest{}
+	for rows.Next() {
+		var digest models.Digest
+		var lastSentAt sql.NullTime
+		if err := rows.Scan(&digest.ID, &digest.Recipient, &digest.Topic, &digest.Filter, &digest.Schedule, &digest.Subject, &digest.HTMLTemplate, &digest.TextTemplate,
+			&digest.MaxArticles, &digest.Cursor, &digest.NextSendAt, &lastSentAt, &digest.LastError, &digest.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan digest: %v", err)
+		}
+		if lastSentAt.Valid {
+			digest.LastSentAt = &lastSentAt.Time
+		}
+		digests = append(digests, digest)
+	}
+	return digests, rows.Err()
+}
+
+// getSQLDigest returns a digest by ID
+func getSQLDigest(ctx context.Context, q queryer, id int64) (*models.Digest, error) {
+	digests, err := listSQLDigests(ctx, q, "WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(digests) == 0 {
+		return nil, ErrNotFound
+	}
+	return &digests[0], nil
+}
+
+// deleteSQLDigest deletes a digest
+func deleteSQLDigest(ctx context.Context, q queryer, id int64) error {
+	result, err := q.ExecContext(ctx, "DELETE FROM digests WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete digest: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// updateSQLDigestState stores the sending state of a digest
+func updateSQLDigestState(ctx context.Context, q queryer, digest *models.Digest) error {
+	var lastSentAt interface{}
+	if digest.LastSentAt != nil {
+		lastSentAt = digest.LastSentAt.UTC()
+	}
+	result, err := q.ExecContext(ctx, `
+		UPDATE digests SET last_membership_id = ?, next_send_at = ?, last_sent_at = ?, last_error = ?
+		WHERE id = ?
+	`, digest.Cursor, digest.NextSendAt.UTC(), lastSentAt, digest.LastError, digest.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update digest: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetTopicArrivals returns the articles assigned to a topic after a membership cursor
+func (s *SQLiteStorage) GetTopicArrivals(ctx context.Context, topic string, after int64) ([]string, int64, error) {
+	return getSQLTopicArrivals(ctx, s.db, topic, after)
+}
+
+// CreateDigest stores a digest, setting its ID and creation time
+func (s *SQLiteStorage) CreateDigest(ctx context.Context, digest *models.Digest) error {
+	return createSQLDigest(ctx, s.db, digest)
+}
+
+// ListDigests returns all digests
+func (s *SQLiteStorage) ListDigests(ctx context.Context) ([]models.Digest, error) {
+	return listSQLDigests(ctx, s.db, "")
+}
+
+// GetDigest returns a digest by ID
+func (s *SQLiteStorage) GetDigest(ctx context.Context, id int64) (*models.Digest, error) {
+	return getSQLDigest(ctx, s.db, id)
+}
+
+// DeleteDigest deletes a digest
+func (s *SQLiteStorage) DeleteDigest(ctx context.Context, id int64) error {
+	return deleteSQLDigest(ctx, s.db, id)
+}
+
+// GetDueDigests returns the digests due by now
+func (s *SQLiteStorage) GetDueDigests(ctx context.Context, now time.Time) ([]models.Digest, error) {
+	return listSQLDigests(ctx, s.db, "WHERE next_send_at <= ?", now.UTC())
+}
+
+// UpdateDigestState stores the sending state of a digest
+func (s *SQLiteStorage) UpdateDigestState(ctx context.Context, digest *models.Digest) error {
+	return updateSQLDigestState(ctx, s.db, digest)
+}
+
+// GetTopicArrivals returns the articles assigned to a topic after a membership cursor
+func (s *PostgresStorage) GetTopicArrivals(ctx context.Context, topic string, after int64) ([]string, int64, error) {
+	return getSQLTopicArrivals(ctx, s.db, topic, after)
+}
+
+// CreateDigest stores a digest, setting its ID and creation time
+func (s *PostgresStorage) CreateDigest(ctx context.Context, digest *models.Digest) error {
+	return createSQLDigest(ctx, s.db, digest)
+}
+
+// ListDigests returns all digests
+func (s *PostgresStorage) ListDigests(ctx context.Context) ([]models.Digest, error) {
+	return listSQLDigests(ctx, s.db, "")
+}
+
+// GetDigest returns a digest by ID
+func (s *PostgresStorage) GetDigest(ctx context.Context, id int64) (*models.Digest, error) {
+	return getSQLDigest(ctx, s.db, id)
+}
+
+// DeleteDigest deletes a digest
+func (s *PostgresStorage) DeleteDigest(ctx context.Context, id int64) error {
+	return deleteSQLDigest(ctx, s.db, id)
+}
+
+// GetDueDigests returns the digests due by now
+func (s *PostgresStorage) GetDueDigests(ctx context.Context, now time.Time) ([]models.Digest, error) {
+	return listSQLDigests(ctx, s.db, "WHERE next_send_at <= ?", now.UTC())
+}
+
+// UpdateDigestState stores the sending state of a digest
+func (s *PostgresStorage) UpdateDigestState(ctx context.Context, digest *models.Digest) error {
+	return updateSQLDigestState(ctx, s.db, digest)
+}
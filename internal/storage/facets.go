@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+)
+
+// facetSources maps a facet to the value expression and extra joins used to count it
+var facetSources = map[string]struct {
+	value string
+	joins string
+}{
+	"source":   {value: "a.source"},
+	"author":   {value: "a.author"},
+	"language": {value: "COALESCE(a.language, 'en')"},
+	"category": {
+		value: "c.value",
+		joins: ", json_each(CASE WHEN json_valid(a.categories) THEN a.categories ELSE '[]' END) c",
+	},
+	"topic": {
+		value: "t.name",
+		joins: " JOIN article_topics at ON a.article_id = at.article_id JOIN topics t ON at.topic_id = t.id",
+	},
+	"published": {value: "substr(a.published_at, 1, 10)"},
+}
+
+// GetArticleFacets counts the values of each requested facet over every
+// article matching the query, ignoring $top/$skip
+func (s *SQLiteStorage) GetArticleFacets(ctx context.Context, query *models.ODataQuery) (models.Facets, error) {
+	facets := make(models.Facets)
+	if len(query.Facets) == 0 {
+		return facets, nil
+	}
+
+	conditions, args, err := buildArticleConditions(query, "a")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, facet := range query.Facets {
+		source, ok := facetSources[facet]
+		if !ok {
+			return nil, fmt.Errorf("unsupported facet: %s", facet)
+		}
+
+		facetQuery := `
+			SELECT ` + source.value + ` AS value, COUNT(DISTINCT a.article_id) AS count
+			FROM articles a` + source.joins + `
+			WHERE ` + source.value + ` IS NOT NULL AND ` + source.value + ` != ''` + conditions + `
+			GROUP BY value`
+		facetArgs := args
+
+		// The date histogram is returned in full and in chronological order
+		if facet == "published" {
+			facetQuery += " ORDER BY value ASC"
+		} else {
+			facetQuery += " ORDER BY count DESC, value ASC LIMIT ?"
+			facetArgs = append(append([]interface{}{}, args...), odata.FacetLimit)
+		}
+
+		counts, err := queryFacetCounts(ctx, s.db, facetQuery, facetArgs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute %s facet: %v", facet, err)
+		}
+		facets[facet] = counts
+	}
+
+	return facets, nil
+}
+
+// queryFacetCounts runs a facet query returning (value, count) rows
+func queryFacetCounts(ctx context.Context, db queryer, query string, args []interface{}) ([]models.FacetCount, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.FacetCount{}
+	for rows.Next() {
+		var count models.FacetCount
+		if err := rows.Scan(&count.Value, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
@@ -1,8 +1,30 @@
 package storage
 
-import "gorssag/internal/config"
+import (
+	"fmt"
 
-// NewStorage creates a new SQLite storage instance
+	"gorssag/internal/config"
+)
+
+// NewStorage creates the storage backend selected by cfg.StorageDriver
 func NewStorage(dataDir string, cfg *config.Config) (Storage, error) {
-	return NewSQLiteStorage(dataDir, cfg)
+	driver := "sqlite"
+	if cfg != nil && cfg.StorageDriver != "" {
+		driver = cfg.StorageDriver
+	}
+
+	if err := validateCompressionCodec(cfg); err != nil {
+		return nil, err
+	}
+
+	switch driver {
+	case "sqlite":
+		return NewSQLiteStorage(dataDir, cfg)
+	case "postgres", "postgresql":
+		return NewPostgresStorage(cfg.DatabaseURL, cfg)
+	case "memory":
+		return NewMemoryStorage(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", driver)
+	}
 }
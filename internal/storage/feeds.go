@@ -0,0 +1,352 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorssag/internal/models"
+)
+
+// feedAssignBatch bounds the number of article IDs per article_feeds statement
+const feedAssignBatch = 500
+
+// assignSQLArticlesToFeed records that stored articles were polled from a
+// feed, registering the feed by URL. Unknown articles are skipped. A feed
+// registered for the first time also adopts the stored articles of its title
+// linked to no feed, such as those stored before feeds were tracked.
+func assignSQLArticlesToFeed(ctx context.Context, q queryer, articleIDs []string, feedURL, title string) (int64, error) {
+	var known int
+	err := q.QueryRowContext(ctx, "SELECT 1 FROM feeds WHERE url = ?", feedURL).Scan(&known)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to look up feed %s: %v", feedURL, err)
+	}
+	firstPoll := err == sql.ErrNoRows
+
+	if _, err := q.ExecContext(ctx, `
+		INSERT INTO feeds (url, title) VALUES (?, ?)
+		ON CONFLICT (url) DO UPDATE SET title = excluded.title, last_seen_at = CURRENT_TIMESTAMP
+	`, feedURL, title); err != nil {
+		return 0, fmt.Errorf("failed to register feed %s: %v", feedURL, err)
+	}
+
+	var feedID int64
+	if err := q.QueryRowContext(ctx, "SELECT id FROM feeds WHERE url = ?", feedURL).Scan(&feedID); err != nil {
+		return 0, fmt.Errorf("failed to look up feed %s: %v", feedURL, err)
+	}
+
+	var assigned int64
+	if firstPoll {
+		result, err := q.ExecContext(ctx, `
+			INSERT INTO article_feeds (article_id, feed_id)
+			SELECT a.article_id, CAST(? AS BIGINT) FROM articles a
+			WHERE a.source = ? AND NOT EXISTS (SELECT 1 FROM article_feeds af WHERE af.article_id = a.article_id)
+			ON CONFLICT DO NOTHING
+		`, feedID, title)
+		if err != nil {
+			return 0, fmt.Errorf("failed to link the stored articles of feed %s: %v", feedURL, err)
+		}
+		if count, _ := result.RowsAffected(); count > 0 {
+			log.Printf("AssignArticlesToFeed: Linked %d stored articles of '%s' to feed '%s'", count, title, feedURL)
+		}
+	}
+
+	for start := 0; start < len(articleIDs); start += feedAssignBatch {
+		end := start + feedAssignBatch
+		if end > len(articleIDs) {
+			end = len(articleIDs)
+		}
+
+		placeholders := make([]string, end-start)
+		args := []interface{}{feedID}
+		for i, id := range articleIDs[start:end] {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		result, err := q.ExecContext(ctx, `
+			INSERT INTO article_feeds (article_id, feed_id)
+			SELECT a.article_id, CAST(? AS BIGINT) FROM articles a WHERE a.article_id IN (`+strings.Join(placeholders, ",")+`)
+			ON CONFLICT DO NOTHING
+		`, args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to assign articles to feed %s: %v", feedURL, err)
+		}
+		count, _ := result.RowsAffected()
+		assigned += count
+	}
+	return assigned, nil
+}
+
+// attachSQLFeedURLs sets the FeedURLs of listed articles, oldest feed first.
+// The listing rows must be consumed first, SQLite runs on a single connection.
+func attachSQLFeedURLs(ctx context.Context, q queryer, articles []models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	positions := make(map[string][]int, len(articles))
+	for i, article := range articles {
+		positions[article.ID] = append(positions[article.ID], i)
+	}
+
+	ids := make([]string, 0, len(positions))
+	for id := range positions {
+		ids = append(ids, id)
+	}
+	for start := 0; start < len(ids); start += feedAssignBatch {
+		end := start + feedAssignBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		placeholders := make([]string, end-start)
+		args := make([]interface{}, end-start)
+		for i, id := range ids[start:end] {
+			placeholders[i] = "?"
+			args[i] = id
+		}
+		rows, err := q.QueryContext(ctx, `
+			SELECT af.article_id, f.url
+			FROM article_feeds af
+			JOIN feeds f ON f.id = af.feed_id
+			WHERE af.article_id IN (`+strings.Join(placeholders, ",")+`)
+			ORDER BY af.first_seen_at, f.id
+		`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query article feeds: %v", err)
+		}
+		for rows.Next() {
+			var articleID, url string
+			if err := rows.Scan(&articleID, &url); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan article feed: %v", err)
+			}
+			for _, i := range positions[articleID] {
+				articles[i].FeedURLs = append(articles[i].FeedURLs, url)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("error during rows iteration: %v", err)
+		}
+	}
+	return nil
+}
+
+// getSQLFeedURLStats counts the stored articles polled from each feed URL
+func getSQLFeedURLStats(ctx context.Context, q queryer) ([]map[string]interface{}, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT f.id, f.url, f.title, COUNT(af.article_id) AS article_count
+		FROM feeds f
+		LEFT JOIN article_feeds af ON af.feed_id = f.id
+		GROUP BY f.id, f.url, f.title
+		ORDER BY article_count DESC, f.url
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feed URL stats: %v", err)
+	}
+	defer rows.Close()
+
+	feeds := []map[string]interface{}{}
+	for rows.Next() {
+		var id int64
+		var url, title string
+		var articleCount int
+		if err := rows.Scan(&id, &url, &title, &articleCount); err != nil {
+			return nil, fmt.Errorf("failed to scan feed URL stat: %v", err)
+		}
+		feeds = append(feeds, map[string]interface{}{
+			"id":            id,
+			"url":           url,
+			"title":         title,
+			"article_count": articleCount,
+		})
+	}
+	return feeds, rows.Err()
+}
+
+// deleteSQLFeed removes a feed and its article links. When purging, the
+// feed's articles go too unless pinned, starred or also polled from another feed; their
+// side tables follow through ON DELETE CASCADE. searchIndex is set on backends
+// with a search_index table, so its deleted rows can be reported.
+func deleteSQLFeed(ctx context.Context, q queryer, feedID int64, purge, searchIndex bool) (*models.FeedDeletion, error) {
+	deletion := &models.FeedDeletion{ID: feedID, Purged: purge, DeletedArticleIDs: []string{}, UnsubscribedTopics: []string{}}
+	err := q.QueryRowContext(ctx, "SELECT url, title FROM feeds WHERE id = ?", feedID).Scan(&deletion.URL, &deletion.Title)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up feed %d: %v", feedID, err)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT af.article_id, a.pinned OR `+fmt.Sprintf(sqlStarred, "a")+`,
+			EXISTS (SELECT 1 FROM article_feeds other WHERE other.article_id = af.article_id AND other.feed_id <> af.feed_id)
+		FROM article_feeds af
+		JOIN articles a ON a.article_id = af.article_id
+		WHERE af.feed_id = ?
+		ORDER BY af.article_id
+	`, feedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query articles of feed %d: %v", feedID, err)
+	}
+	var purged []string
+	for rows.Next() {
+		var articleID string
+		var pinned, shared bool
+		if err := rows.Scan(&articleID, &pinned, &shared); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan article of feed %d: %v", feedID, err)
+		}
+		deletion.LinkedArticles++
+		if purge && !pinned && !shared {
+			purged = append(purged, articleID)
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	for start := 0; start < len(purged); start += feedAssignBatch {
+		end := start + feedAssignBatch
+		if end > len(purged) {
+			end = len(purged)
+		}
+
+		placeholders := make([]string, end-start)
+		args := make([]interface{}, end-start)
+		for i, id := range purged[start:end] {
+			placeholders[i] = "?"
+			args[i] = id
+		}
+		in := "(" + strings.Join(placeholders, ",") + ")"
+
+		var count int
+		if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM compressed_content WHERE article_id IN "+in, args...).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count compressed content: %v", err)
+		}
+		deletion.DeletedCompressed += count
+		if searchIndex {
+			if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM search_index WHERE article_id IN "+in, args...).Scan(&count); err != nil {
+				return nil, fmt.Errorf("failed to count search terms: %v", err)
+			}
+			deletion.DeletedSearchTerms += count
+		}
+
+		if _, err := q.ExecContext(ctx, "DELETE FROM articles WHERE article_id IN "+in, args...); err != nil {
+			return nil, fmt.Errorf("failed to delete articles of feed %d: %v", feedID, err)
+		}
+	}
+
+	if _, err := q.ExecContext(ctx, "DELETE FROM feeds WHERE id = ?", feedID); err != nil {
+		return nil, fmt.Errorf("failed to delete feed %d: %v", feedID, err)
+	}
+
+	deletion.DeletedArticles = len(purged)
+	deletion.KeptArticles = deletion.LinkedArticles - deletion.DeletedArticles
+	deletion.DeletedArticleIDs = append(deletion.DeletedArticleIDs, purged...)
+	return deletion, nil
+}
+
+// feedURLCondition restricts the articles referenced by alias to those
+// polled from a feed URL; it runs unchanged on SQLite and PostgreSQL
+func feedURLCondition(alias string) string {
+	return " AND EXISTS (SELECT 1 FROM article_feeds af JOIN feeds f ON f.id = af.feed_id WHERE af.article_id = " + alias + ".article_id AND f.url = ?)"
+}
+
+// AssignArticlesToFeed records that stored articles were polled from a feed URL
+func (s *SQLiteStorage) AssignArticlesToFeed(ctx context.Context, articleIDs []string, feedURL, title string) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	assigned, err := assignSQLArticlesToFeed(ctx, tx, articleIDs, feedURL, title)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("AssignArticlesToFeed: Assigned %d/%d new articles to feed '%s'", assigned, len(articleIDs), feedURL)
+	return nil
+}
+
+// AssignArticlesToFeed records that stored articles were polled from a feed URL
+func (s *PostgresStorage) AssignArticlesToFeed(ctx context.Context, articleIDs []string, feedURL, title string) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	assigned, err := assignSQLArticlesToFeed(ctx, tx, articleIDs, feedURL, title)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("AssignArticlesToFeed: Assigned %d/%d new articles to feed '%s'", assigned, len(articleIDs), feedURL)
+	return nil
+}
+
+// DeleteSourceFeed forgets a polled feed, purging its articles no other feed or pin references
+func (s *SQLiteStorage) DeleteSourceFeed(ctx context.Context, feedID int64, purge bool) (*models.FeedDeletion, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	deletion, err := deleteSQLFeed(ctx, tx, feedID, purge, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit feed deletion: %v", err)
+	}
+
+	log.Printf("DeleteSourceFeed: Deleted feed '%s', %d articles deleted, %d kept", deletion.URL, deletion.DeletedArticles, deletion.KeptArticles)
+	return deletion, nil
+}
+
+// DeleteSourceFeed forgets a polled feed, purging its articles no other feed or pin references
+func (s *PostgresStorage) DeleteSourceFeed(ctx context.Context, feedID int64, purge bool) (*models.FeedDeletion, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	deletion, err := deleteSQLFeed(ctx, tx, feedID, purge, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit feed deletion: %v", err)
+	}
+
+	log.Printf("DeleteSourceFeed: Deleted feed '%s', %d articles deleted, %d kept", deletion.URL, deletion.DeletedArticles, deletion.KeptArticles)
+	return deletion, nil
+}
@@ -1,37 +1,77 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"gorssag/internal/models"
 	"time"
 )
 
+// ErrNotFound is returned when a requested article does not exist
+var ErrNotFound = errors.New("not found")
+
 // Storage defines the interface for different storage backends
 type Storage interface {
-	SaveFeed(topic string, feed *models.AggregatedFeed) error
-	LoadFeed(topic string) (*models.AggregatedFeed, error)
-	QueryArticles(topic string, query *models.ODataQuery) ([]models.Article, error)
-	GetAllArticles(query *models.ODataQuery) ([]models.Article, int, error) // New method for all articles across all topics
-	ListTopics() ([]string, error)
-	GetFeedInfo(topic string) (*models.FeedInfo, error)
-	DeleteFeed(topic string) error
+	SaveFeed(ctx context.Context, topic string, feed *models.AggregatedFeed) error
+	LoadFeed(ctx context.Context, topic string) (*models.AggregatedFeed, error)
+	QueryArticles(ctx context.Context, topic string, query *models.ODataQuery) ([]models.Article, error)
+	GetAllArticles(ctx context.Context, query *models.ODataQuery) ([]models.Article, int, error) // New method for all articles across all topics
+	GetArticleFacets(ctx context.Context, query *models.ODataQuery) (models.Facets, error)       // Facet counts (query.Facets) over the full result set
+	ListTopics(ctx context.Context) ([]string, error)
+	GetFeedInfo(ctx context.Context, topic string) (*models.FeedInfo, error)
+	DeleteFeed(ctx context.Context, topic string) error
 	Close() error
 
 	// New feed-centric storage methods
-	SaveArticles(articles []models.Article) error                  // Save articles without topic assignment
-	AssignArticlesToTopic(articleIDs []string, topic string) error // Assign articles to topics after storage
-	GetCombinedFilters(topics []string) ([]string, bool)           // Get combined filters for multiple topics
+	SaveArticles(ctx context.Context, articles []models.Article) error                            // Save articles without topic assignment
+	AssignArticlesToTopic(ctx context.Context, articleIDs []string, topic string) error           // Assign articles to topics after storage
+	RestoreArticlesToTopic(ctx context.Context, articleIDs []string, topic string) error          // Assign imported articles to a topic without queuing webhook deliveries
+	AssignArticlesToFeed(ctx context.Context, articleIDs []string, feedURL, title string) error   // Record the feed URL articles were polled from
+	GetCombinedFilters(ctx context.Context, topics []string) ([]string, bool)                     // Get combined filters for multiple topics
+	DeleteSourceFeed(ctx context.Context, feedID int64, purge bool) (*models.FeedDeletion, error) // Forget a polled feed, purging its unshared articles (ErrNotFound if unknown)
 
 	// Enhanced topic membership methods
-	AddArticleToTopic(articleID string, topic string) error                                 // Add a single article to a topic
-	RemoveArticleFromTopic(articleID string, topic string) error                            // Remove article from topic
-	GetArticleTopics(articleID string) ([]string, error)                                    // Get all topics for an article
-	GetTopicArticles(topic string, query *models.ODataQuery) ([]models.Article, int, error) // Get articles for a topic using membership table
+	AddArticleToTopic(ctx context.Context, articleID string, topic string) error                                 // Add a single article to a topic
+	RemoveArticleFromTopic(ctx context.Context, articleID string, topic string) error                            // Remove article from topic
+	GetArticleTopics(ctx context.Context, articleID string) ([]string, error)                                    // Get all topics for an article
+	GetTopicArticles(ctx context.Context, topic string, query *models.ODataQuery) ([]models.Article, int, error) // Get articles for a topic using membership table
+	GetArticles(ctx context.Context, ids []string) ([]models.Article, error)                                     // Articles by ID in the requested order, unknown IDs skipped
+	GetArticleIDsByLink(ctx context.Context, links []string) (map[string]string, error)                          // Stored article ID of each known link, the lowest when several share it
+	GetRelatedArticles(ctx context.Context, articleID string, limit int) ([]models.Article, error)               // Similar articles across topics (ErrNotFound if unknown)
+	SetArticlePinned(ctx context.Context, articleID string, pinned bool) error                                   // Pin or unpin an article (ErrNotFound if unknown)
+	GetArticleRevisions(ctx context.Context, articleID string) ([]models.ArticleRevision, error)                 // Earlier versions of an article, oldest first (ErrNotFound if unknown)
 
 	// Storage optimization methods
-	CleanupOldArticles(retention time.Duration) error
-	OptimizeDatabase() error
-	GetDatabaseStats() (map[string]interface{}, error)
-	RemoveDuplicateArticles() error
-	CompressOldArticles() error
-	GetFeedStats() (map[string]interface{}, error) // New method for feed statistics
+	CleanupOldArticles(ctx context.Context, policy RetentionPolicy) error // Delete articles no topic retains any longer
+	OptimizeDatabase(ctx context.Context) error
+	GetDatabaseStats(ctx context.Context) (map[string]interface{}, error)
+	RemoveDuplicateArticles(ctx context.Context) error
+	CompressOldArticles(ctx context.Context) error
+	GetFeedStats(ctx context.Context) (map[string]interface{}, error)                       // New method for feed statistics
+	RecordStatsSnapshot(ctx context.Context) (*models.StatsSnapshot, error)                 // Store the current storage statistics in the history
+	GetStatsSnapshots(ctx context.Context, since time.Time) ([]models.StatsSnapshot, error) // Stats history since a time, oldest first
+
+	// Webhook methods; AssignArticlesToTopic queues the deliveries of new memberships
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error                                                      // Store a webhook, setting its ID and creation time
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)                                                            // All webhooks, oldest first
+	GetWebhook(ctx context.Context, id int64) (*models.Webhook, error)                                                     // Webhook by ID (ErrNotFound if unknown)
+	DeleteWebhook(ctx context.Context, id int64) error                                                                     // Delete a webhook with its deliveries (ErrNotFound if unknown)
+	GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)               // Pending deliveries due by now, oldest first
+	GetWebhookDeliveries(ctx context.Context, webhookID int64, status string, limit int) ([]models.WebhookDelivery, error) // Delivery log of a webhook, newest first (ErrNotFound if unknown)
+	GetWebhookDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error)                                     // Delivery by ID (ErrNotFound if unknown)
+	UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error                                     // Store the outcome of a delivery attempt
+
+	// Email digest methods
+	GetTopicArrivals(ctx context.Context, topic string, after int64) ([]string, int64, error) // Articles assigned to a topic after a membership cursor, oldest first, and the next cursor
+	CreateDigest(ctx context.Context, digest *models.Digest) error                            // Store a digest, setting its ID and creation time
+	ListDigests(ctx context.Context) ([]models.Digest, error)                                 // All digests, by ID
+	GetDigest(ctx context.Context, id int64) (*models.Digest, error)                          // Digest by ID (ErrNotFound if unknown)
+	DeleteDigest(ctx context.Context, id int64) error                                         // Delete a digest (ErrNotFound if unknown)
+	GetDueDigests(ctx context.Context, now time.Time) ([]models.Digest, error)                // Digests whose next send is due by now
+	UpdateDigestState(ctx context.Context, digest *models.Digest) error                       // Store the cursor, send times and last error of a digest
+
+	// Google Reader methods; item IDs are int64 and grow in storage order
+	GetReaderItemRefs(ctx context.Context, query *models.ReaderQuery) ([]models.ReaderItemRef, error) // Items of a stream, newest first unless query.Oldest
+	GetReaderItems(ctx context.Context, ids []int64) ([]models.ReaderItem, error)                     // Items by ID in the requested order, unknown IDs skipped
+	SetReaderItemState(ctx context.Context, ids []int64, state string, on bool) error                 // Set or clear the read or starred state of items, unknown IDs ignored
 }
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"gorssag/internal/models"
+
+	"github.com/pemistahl/lingua-go"
+)
+
+// newLanguageDetector builds a detector for the languages common in RSS feeds
+func newLanguageDetector() lingua.LanguageDetector {
+	return lingua.NewLanguageDetectorBuilder().
+		FromLanguages(
+			lingua.English, lingua.German, lingua.French, lingua.Spanish,
+			lingua.Chinese, lingua.Russian, lingua.Italian, lingua.Portuguese,
+			lingua.Dutch, lingua.Swedish, lingua.Danish, lingua.Finnish,
+			lingua.Polish, lingua.Czech, lingua.Hungarian, lingua.Romanian,
+		).
+		Build()
+}
+
+// articleLanguage returns the language already recorded on an article, as for
+// imported ones, and detects it otherwise
+func articleLanguage(detector lingua.LanguageDetector, article models.Article) string {
+	if article.Language != "" {
+		return article.Language
+	}
+	return detectLanguageCode(detector, article.Title+" "+article.Description+" "+article.Content)
+}
+
+// detectLanguageCode detects the language of the given text and returns its ISO 639-1 code
+func detectLanguageCode(detector lingua.LanguageDetector, text string) string {
+	if text == "" {
+		return "en" // Default to English
+	}
+
+	// Use the proper language detection library
+	language, exists := detector.DetectLanguageOf(text)
+	if !exists {
+		return "en" // Default to English if detection fails
+	}
+
+	// Convert lingua language to our language codes
+	switch language {
+	case lingua.English:
+		return "en"
+	case lingua.German:
+		return "de"
+	case lingua.French:
+		return "fr"
+	case lingua.Spanish:
+		return "es"
+	case lingua.Chinese:
+		return "zh"
+	case lingua.Russian:
+		return "ru"
+	case lingua.Italian:
+		return "it"
+	case lingua.Portuguese:
+		return "pt"
+	case lingua.Dutch:
+		return "nl"
+	case lingua.Swedish:
+		return "sv"
+	case lingua.Danish:
+		return "da"
+	case lingua.Finnish:
+		return "fi"
+	case lingua.Polish:
+		return "pl"
+	case lingua.Czech:
+		return "cs"
+	case lingua.Hungarian:
+		return "hu"
+	case lingua.Romanian:
+		return "ro"
+	default:
+		return "en" // Default to English for unsupported languages
+	}
+}
@@ -0,0 +1,1685 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorssag/internal/config"
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+
+	"github.com/pemistahl/lingua-go"
+)
+
+// MemoryStorage keeps articles and topics in memory. Nothing is persisted,
+// which makes it suited to tests and throwaway instances.
+type MemoryStorage struct {
+	mutex      sync.RWMutex
+	config     *config.Config
+	detector   lingua.LanguageDetector
+	articles   map[string]*memoryArticle
+	topics     map[string]*memoryTopic
+	feeds      map[string]*memoryFeed // Polled feeds by URL
+	sequence   int64                  // Insertion order of articles and topic memberships
+	lastFeedID int64
+	snapshots  []models.StatsSnapshot // Stats history, oldest first
+
+	webhooks       []models.Webhook         // Oldest first
+	deliveries     []models.WebhookDelivery // Webhook delivery queue and log, oldest first
+	lastWebhookID  int64
+	lastDeliveryID int64
+
+	digests      []models.Digest // By ID
+	lastDigestID int64
+}
+
+// memoryArticle is a stored article with the data the SQL backends keep in
+// side tables (compressed content, search terms, story cluster)
+type memoryArticle struct {
+	article    models.Article // Content is empty when compressed is set
+	seq        int64
+	createdAt  time.Time // First time the article was stored
+	compressed []byte
+	codec      string          // Codec of compressed
+	terms      map[string]bool // Title, description and content terms, for related articles
+	simhash    uint64          // Zero when the article has no story cluster
+	clusterID  string
+	hash       string                   // Hash of the title, description and content
+	revisions  []models.ArticleRevision // Replaced versions, oldest first
+	read       bool                     // Google Reader item states
+	starred    bool
+}
+
+// memoryFeed is a polled feed, identified like the SQL feeds table rows
+type memoryFeed struct {
+	id    int64
+	title string
+}
+
+// memoryTopic is a topic with its article memberships
+type memoryTopic struct {
+	updatedAt time.Time
+	members   map[string]int64 // Article ID to membership sequence
+}
+
+// NewMemoryStorage creates an empty in-memory storage
+func NewMemoryStorage(cfg *config.Config) *MemoryStorage {
+	return &MemoryStorage{
+		config:   cfg,
+		detector: newLanguageDetector(),
+		articles: make(map[string]*memoryArticle),
+		topics:   make(map[string]*memoryTopic),
+		feeds:    make(map[string]*memoryFeed),
+	}
+}
+
+// kept reports whether the article is pinned or starred, which retention,
+// deduplication, compression and feed purges leave alone
+func (a *memoryArticle) kept() bool {
+	return a.article.Pinned || a.starred
+}
+
+// content returns the full article content, decompressing it when needed
+func (a *memoryArticle) content() string {
+	if a.article.Content != "" || len(a.compressed) == 0 {
+		return a.article.Content
+	}
+	decompressed, err := decompressContent(a.codec, a.compressed)
+	if err != nil {
+		log.Printf("Warning: failed to decompress content for article %s: %v", a.article.ID, err)
+		return "Content unavailable (decompression failed)"
+	}
+	return decompressed
+}
+
+// toArticle returns a copy of the article as served to callers
+func (s *MemoryStorage) toArticle(a *memoryArticle) models.Article {
+	return s.listedArticle(a, true)
+}
+
+// listedArticle returns a copy of the article, with its content only when
+// withContent is set so compressed content is decompressed lazily
+func (s *MemoryStorage) listedArticle(a *memoryArticle, withContent bool) models.Article {
+	article := a.article
+	article.Content = ""
+	if withContent {
+		article.Content = a.content()
+	}
+	article.Categories = append([]string(nil), a.article.Categories...)
+	article.FeedURLs = append([]string(nil), a.article.FeedURLs...)
+	article.Topic = s.firstTopic(a.article.ID)
+	article.ClusterID = a.clusterID
+	if article.ClusterID == "" {
+		article.ClusterID = article.ID
+	}
+	return article
+}
+
+// firstTopic returns the topic the article was first assigned to
+func (s *MemoryStorage) firstTopic(articleID string) string {
+	first := ""
+	var firstSeq int64
+	for name, topic := range s.topics {
+		if seq, ok := topic.members[articleID]; ok && (first == "" || seq < firstSeq) {
+			first, firstSeq = name, seq
+		}
+	}
+	return first
+}
+
+func (s *MemoryStorage) getOrCreateTopic(name string) *memoryTopic {
+	topic, ok := s.topics[name]
+	if !ok {
+		topic = &memoryTopic{updatedAt: time.Now(), members: make(map[string]int64)}
+		s.topics[name] = topic
+	}
+	return topic
+}
+
+func (s *MemoryStorage) addMember(topic *memoryTopic, articleID string) bool {
+	if _, ok := topic.members[articleID]; ok {
+		return false
+	}
+	s.sequence++
+	topic.members[articleID] = s.sequence
+	return true
+}
+
+// deleteArticle removes an article and its topic memberships
+func (s *MemoryStorage) deleteArticle(articleID string) {
+	delete(s.articles, articleID)
+	for _, topic := range s.topics {
+		delete(topic.members, articleID)
+	}
+}
+
+// saveArticle upserts an article with its compressed content, search terms
+// and story cluster
+func (s *MemoryStorage) saveArticle(article models.Article) {
+	content := cleanAndOptimizeContent(article.Content)
+
+	stored := &memoryArticle{article: article}
+	stored.article.Content = content
+	stored.article.Categories = append([]string(nil), article.Categories...)
+	stored.article.Topic = ""
+	stored.article.ClusterID = ""
+	stored.article.ClusterSources = nil
+	stored.article.UpdatedAt = nil
+	stored.article.FeedURLs = nil
+	stored.article.Language = articleLanguage(s.detector, article)
+	stored.hash = revisionHash(article.Title, article.Description, content)
+
+	// Old articles keep their content compressed only
+	if s.config != nil && s.config.EnableContentCompression &&
+		article.PublishedAt.Before(time.Now().AddDate(0, 0, -3)) && len(content) > 0 {
+		codec := compressionCodec(s.config)
+		compressed, err := compressContent(codec, content)
+		if err != nil {
+			log.Printf("Warning: failed to compress content for article %s: %v", article.ID, err)
+		} else {
+			stored.compressed = compressed
+			stored.codec = codec
+			stored.article.Content = ""
+		}
+	}
+
+	stored.terms = make(map[string]bool)
+	for _, text := range []string{article.Title, article.Description, content} {
+		for _, terms := range extractSearchTerms(s.detector, text) {
+			for _, term := range terms {
+				if len(term) > 2 {
+					stored.terms[strings.ToLower(term)] = true
+				}
+			}
+		}
+	}
+
+	if existing, ok := s.articles[article.ID]; ok {
+		stored.seq = existing.seq
+		stored.createdAt = existing.createdAt
+		stored.article.Pinned = article.Pinned || existing.article.Pinned // Saving never unpins
+		stored.article.UpdatedAt = existing.article.UpdatedAt
+		stored.article.FeedURLs = existing.article.FeedURLs
+		stored.revisions = existing.revisions
+		stored.read, stored.starred = existing.read, existing.starred
+
+		// Keep the replaced version when the publisher changed the article
+		if existing.hash != stored.hash {
+			previous := existing.article
+			previous.Content = existing.content()
+			current := article
+			current.Content = content
+			changedAt := time.Now().UTC()
+			stored.revisions = append(append([]models.ArticleRevision(nil), existing.revisions...), models.ArticleRevision{
+				Revision:    len(existing.revisions) + 1,
+				ContentHash: existing.hash,
+				Title:       previous.Title,
+				Diff:        revisionDiff(previous, current),
+				ChangedAt:   changedAt,
+			})
+			stored.article.UpdatedAt = &changedAt
+		}
+	} else {
+		s.sequence++
+		stored.seq = s.sequence
+		stored.createdAt = time.Now()
+	}
+
+	s.assignCluster(stored, content)
+	s.articles[article.ID] = stored
+}
+
+// assignCluster is the in-memory counterpart of SQLiteStorage.assignClusterWithTx
+func (s *MemoryStorage) assignCluster(stored *memoryArticle, content string) {
+	text := content
+	if text == "" {
+		text = stored.article.Description
+	}
+	stored.simhash = simHash(stored.article.Title + " " + text)
+	stored.clusterID = ""
+	if stored.simhash == 0 {
+		return
+	}
+
+	stored.clusterID = stored.article.ID
+	bestDistance := clusterMaxDistance + 1
+	for _, candidate := range s.sortedArticles() {
+		if candidate.article.ID == stored.article.ID || candidate.simhash == 0 {
+			continue
+		}
+		gap := candidate.article.PublishedAt.Sub(stored.article.PublishedAt)
+		if gap > clusterWindow || gap < -clusterWindow {
+			continue
+		}
+		if distance := bits.OnesCount64(stored.simhash ^ candidate.simhash); distance < bestDistance {
+			bestDistance = distance
+			stored.clusterID = candidate.clusterID
+		}
+	}
+}
+
+// sortedArticles returns the stored articles in insertion order
+func (s *MemoryStorage) sortedArticles() []*memoryArticle {
+	articles := make([]*memoryArticle, 0, len(s.articles))
+	for _, article := range s.articles {
+		articles = append(articles, article)
+	}
+	sort.Slice(articles, func(i, j int) bool { return articles[i].seq < articles[j].seq })
+	return articles
+}
+
+// matchArticle reports whether an article matches the $search, $filter and
+// drill-down conditions of a query, like buildArticleConditions
+func matchArticle(article models.Article, searchExpr *odata.SearchExpression, query *models.ODataQuery) bool {
+	if searchExpr != nil && !odata.NewSearchParser().Evaluate(searchExpr, article) {
+		return false
+	}
+
+	if query.Filter != "" {
+		filter := strings.ToLower(query.Filter)
+		if !containsFold(article.Title, filter) && !containsFold(article.Description, filter) &&
+			!containsFold(article.Content, filter) && !containsFold(article.Author, filter) {
+			return false
+		}
+	}
+
+	if query.DateFrom != nil && article.PublishedAt.Before(*query.DateFrom) {
+		return false
+	}
+	if query.DateTo != nil && article.PublishedAt.After(*query.DateTo) {
+		return false
+	}
+
+	if query.Source != "" && !containsFold(article.Source, query.Source) {
+		return false
+	}
+	if query.Author != "" && !containsFold(article.Author, query.Author) {
+		return false
+	}
+	if query.Category != "" && !containsFold(strings.Join(article.Categories, "\n"), query.Category) {
+		return false
+	}
+	if query.Pinned != nil && article.Pinned != *query.Pinned {
+		return false
+	}
+	if query.FeedURL != "" && !containsString(article.FeedURLs, query.FeedURL) {
+		return false
+	}
+
+	return true
+}
+
+// containsString reports whether values holds value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether substr is within s, ignoring case
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// keysetLess reports whether a sorts before b in keyset order (newest first)
+func keysetLess(a, b models.Article) bool {
+	if !a.PublishedAt.Equal(b.PublishedAt) {
+		return a.PublishedAt.After(b.PublishedAt)
+	}
+	return a.ID > b.ID
+}
+
+// sortArticles orders articles by a $orderby value, like postgresOrderBy
+func sortArticles(articles []models.Article, orderBy string) {
+	var less func(a, b models.Article) bool
+	switch strings.ToLower(strings.Join(strings.Fields(orderBy), " ")) {
+	case "title asc":
+		less = func(a, b models.Article) bool { return a.Title < b.Title || (a.Title == b.Title && a.ID < b.ID) }
+	case "title desc":
+		less = func(a, b models.Article) bool { return a.Title > b.Title || (a.Title == b.Title && a.ID > b.ID) }
+	case "author asc":
+		less = func(a, b models.Article) bool { return a.Author < b.Author || (a.Author == b.Author && a.ID < b.ID) }
+	case "author desc":
+		less = func(a, b models.Article) bool { return a.Author > b.Author || (a.Author == b.Author && a.ID > b.ID) }
+	case "source asc":
+		less = func(a, b models.Article) bool { return a.Source < b.Source || (a.Source == b.Source && a.ID < b.ID) }
+	case "source desc":
+		less = func(a, b models.Article) bool { return a.Source > b.Source || (a.Source == b.Source && a.ID > b.ID) }
+	case "published_at asc":
+		less = func(a, b models.Article) bool { return keysetLess(b, a) }
+	default:
+		less = keysetLess
+	}
+	sort.SliceStable(articles, func(i, j int) bool { return less(articles[i], articles[j]) })
+}
+
+// matchingArticles returns the articles matching a query, restricted to a
+// topic when topic is set, unordered
+func (s *MemoryStorage) matchingArticles(topic string, query *models.ODataQuery) ([]models.Article, error) {
+	searchExpr, err := parseSearch(query.Search)
+	if err != nil {
+		return nil, err
+	}
+
+	var members map[string]int64
+	if topic != "" {
+		t, ok := s.topics[topic]
+		if !ok {
+			return []models.Article{}, nil
+		}
+		members = t.members
+	}
+
+	// Content is only decompressed when it is matched or returned
+	selected := contentSelected(query)
+	withContent := selected || searchExpr != nil || query.Filter != ""
+
+	articles := []models.Article{}
+	for id, stored := range s.articles {
+		if members != nil {
+			if _, ok := members[id]; !ok {
+				continue
+			}
+		}
+		article := s.listedArticle(stored, withContent)
+		if matchArticle(article, searchExpr, query) {
+			if !selected {
+				article.Content = ""
+			}
+			articles = append(articles, article)
+		}
+	}
+
+	return articles, nil
+}
+
+// listArticles runs an OData article listing, restricted to a topic when
+// topic is set, and returns the page with the total number of matches
+func (s *MemoryStorage) listArticles(ctx context.Context, topic string, query *models.ODataQuery) ([]models.Article, int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// Nothing blocks in memory, but a cancelled request still gets no results
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	articles, err := s.matchingArticles(topic, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Keep the newest matching article of each story cluster
+	if query.Cluster {
+		newest := make(map[string]models.Article)
+		for _, article := range articles {
+			if s.articles[article.ID].clusterID == "" {
+				continue
+			}
+			if current, ok := newest[article.ClusterID]; !ok || keysetLess(article, current) {
+				newest[article.ClusterID] = article
+			}
+		}
+		representatives := articles[:0]
+		for _, article := range articles {
+			if s.articles[article.ID].clusterID == "" || newest[article.ClusterID].ID == article.ID {
+				representatives = append(representatives, article)
+			}
+		}
+		articles = representatives
+	}
+
+	totalCount := len(articles)
+
+	// Apply keyset pagination and ordering
+	if query.SkipToken != nil {
+		after := articles[:0]
+		for _, article := range articles {
+			if odata.AfterSkipToken(article, query.SkipToken) {
+				after = append(after, article)
+			}
+		}
+		articles = after
+		sortArticles(articles, "")
+	} else {
+		sortArticles(articles, query.OrderBy)
+		if query.Skip > 0 {
+			if query.Skip >= len(articles) {
+				articles = articles[:0]
+			} else {
+				articles = articles[query.Skip:]
+			}
+		}
+	}
+	if query.Top > 0 && query.Top < len(articles) {
+		articles = articles[:query.Top]
+	}
+
+	if query.Cluster {
+		s.attachClusterSources(articles)
+	}
+
+	return articles, totalCount, nil
+}
+
+// attachClusterSources fills ClusterSources with the other articles of each article's cluster
+func (s *MemoryStorage) attachClusterSources(articles []models.Article) {
+	members := make(map[string][]models.Article)
+	for _, stored := range s.articles {
+		if stored.clusterID != "" {
+			members[stored.clusterID] = append(members[stored.clusterID], stored.article)
+		}
+	}
+
+	for i := range articles {
+		cluster := members[articles[i].ClusterID]
+		sortArticles(cluster, "")
+		for _, member := range cluster {
+			if member.ID != articles[i].ID {
+				articles[i].ClusterSources = append(articles[i].ClusterSources, models.ClusterSource{
+					ID:          member.ID,
+					Title:       member.Title,
+					Link:        member.Link,
+					Source:      member.Source,
+					PublishedAt: member.PublishedAt,
+				})
+			}
+		}
+	}
+}
+
+func (s *MemoryStorage) SaveFeed(ctx context.Context, topic string, feed *models.AggregatedFeed) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t := s.getOrCreateTopic(topic)
+
+	// Articles shared with other topics are kept, only this topic's membership goes
+	for articleID := range t.members {
+		shared := false
+		for name, other := range s.topics {
+			if _, ok := other.members[articleID]; ok && name != topic {
+				shared = true
+				break
+			}
+		}
+		if !shared {
+			s.deleteArticle(articleID)
+		}
+	}
+	t.members = make(map[string]int64)
+
+	for _, article := range feed.Articles {
+		s.saveArticle(article)
+		s.addMember(t, article.ID)
+	}
+	t.updatedAt = time.Now()
+
+	log.Printf("SaveFeed: Saved %d articles for topic '%s'", len(feed.Articles), topic)
+	return nil
+}
+
+func (s *MemoryStorage) LoadFeed(ctx context.Context, topic string) (*models.AggregatedFeed, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	t, ok := s.topics[topic]
+	if !ok {
+		return nil, fmt.Errorf("topic not found: %s", topic)
+	}
+
+	var articles []models.Article
+	for articleID := range t.members {
+		article := s.toArticle(s.articles[articleID])
+		if article.Content == "" {
+			article.Content = "Content unavailable"
+		}
+		articles = append(articles, article)
+	}
+	sortArticles(articles, "")
+
+	if len(articles) == 0 {
+		return nil, fmt.Errorf("no articles found for topic '%s'", topic)
+	}
+
+	return &models.AggregatedFeed{
+		Topic:    topic,
+		Articles: articles,
+		Count:    len(articles),
+		Updated:  time.Now(),
+	}, nil
+}
+
+func (s *MemoryStorage) QueryArticles(ctx context.Context, topic string, query *models.ODataQuery) ([]models.Article, error) {
+	s.mutex.RLock()
+	_, ok := s.topics[topic]
+	s.mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("topic not found: %s", topic)
+	}
+
+	articles, _, err := s.listArticles(ctx, topic, query)
+	return articles, err
+}
+
+// GetAllArticles returns articles across all topics
+func (s *MemoryStorage) GetAllArticles(ctx context.Context, query *models.ODataQuery) ([]models.Article, int, error) {
+	return s.listArticles(ctx, "", query)
+}
+
+// GetTopicArticles returns articles for a topic using its memberships
+func (s *MemoryStorage) GetTopicArticles(ctx context.Context, topic string, query *models.ODataQuery) ([]models.Article, int, error) {
+	return s.listArticles(ctx, topic, query)
+}
+
+// GetArticleFacets counts the values of each requested facet over every
+// article matching the query, ignoring $top/$skip
+func (s *MemoryStorage) GetArticleFacets(ctx context.Context, query *models.ODataQuery) (models.Facets, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	facets := make(models.Facets)
+	if len(query.Facets) == 0 {
+		return facets, nil
+	}
+
+	articles, err := s.matchingArticles("", query)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, facet := range query.Facets {
+		counts := make(map[string]int)
+		for _, article := range articles {
+			var values []string
+			switch facet {
+			case "source":
+				values = []string{article.Source}
+			case "author":
+				values = []string{article.Author}
+			case "language":
+				values = []string{article.Language}
+			case "category":
+				values = article.Categories
+			case "topic":
+				for name, topic := range s.topics {
+					if _, ok := topic.members[article.ID]; ok {
+						values = append(values, name)
+					}
+				}
+			case "published":
+				values = []string{article.PublishedAt.UTC().Format("2006-01-02")}
+			default:
+				return nil, fmt.Errorf("unsupported facet: %s", facet)
+			}
+
+			// Each article counts once per value
+			seen := make(map[string]bool)
+			for _, value := range values {
+				if value != "" && !seen[value] {
+					seen[value] = true
+					counts[value]++
+				}
+			}
+		}
+
+		result := []models.FacetCount{}
+		for value, count := range counts {
+			result = append(result, models.FacetCount{Value: value, Count: count})
+		}
+
+		// The date histogram is returned in full and in chronological order
+		if facet == "published" {
+			sort.Slice(result, func(i, j int) bool { return result[i].Value < result[j].Value })
+		} else {
+			sort.Slice(result, func(i, j int) bool {
+				if result[i].Count != result[j].Count {
+					return result[i].Count > result[j].Count
+				}
+				return result[i].Value < result[j].Value
+			})
+			if len(result) > odata.FacetLimit {
+				result = result[:odata.FacetLimit]
+			}
+		}
+		facets[facet] = result
+	}
+
+	return facets, nil
+}
+
+// GetRelatedArticles returns up to limit articles similar to the given one,
+// across all topics, most similar first. Shared terms and categories weigh
+// 1/df like relatedArticlesQuery.
+func (s *MemoryStorage) GetRelatedArticles(ctx context.Context, articleID string, limit int) ([]models.Article, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	source, ok := s.articles[articleID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	categorySet := func(article *memoryArticle) map[string]bool {
+		categories := make(map[string]bool)
+		for _, category := range article.article.Categories {
+			if category != "" {
+				categories[strings.ToLower(category)] = true
+			}
+		}
+		return categories
+	}
+	sourceCategories := categorySet(source)
+
+	termCounts := make(map[string]int)
+	categoryCounts := make(map[string]int)
+	articleCategories := make(map[string]map[string]bool, len(s.articles))
+	for id, article := range s.articles {
+		for term := range article.terms {
+			if source.terms[term] {
+				termCounts[term]++
+			}
+		}
+		articleCategories[id] = categorySet(article)
+		for category := range articleCategories[id] {
+			if sourceCategories[category] {
+				categoryCounts[category]++
+			}
+		}
+	}
+
+	type scoredArticle struct {
+		article models.Article
+		score   float64
+	}
+	var scored []scoredArticle
+	for id, article := range s.articles {
+		if id == articleID {
+			continue
+		}
+		score := 0.0
+		for term := range article.terms {
+			if count, ok := termCounts[term]; ok {
+				score += 1.0 / float64(count)
+			}
+		}
+		for category := range articleCategories[id] {
+			if count, ok := categoryCounts[category]; ok {
+				score += 1.0 / float64(count)
+			}
+		}
+		if score > 0 {
+			scored = append(scored, scoredArticle{article: s.toArticle(article), score: score})
+		}
+	}
+
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].score != scored[j].score {
+			return scored[i].score > scored[j].score
+		}
+		return keysetLess(scored[i].article, scored[j].article)
+	})
+	if limit >= 0 && len(scored) > limit {
+		scored = scored[:limit]
+	}
+
+	related := make([]models.Article, 0, len(scored))
+	for _, candidate := range scored {
+		related = append(related, candidate.article)
+	}
+
+	return related, nil
+}
+
+func (s *MemoryStorage) ListTopics(ctx context.Context) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var topics []string
+	for name := range s.topics {
+		topics = append(topics, name)
+	}
+	sort.Strings(topics)
+
+	return topics, nil
+}
+
+func (s *MemoryStorage) GetFeedInfo(ctx context.Context, topic string) (*models.FeedInfo, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	t, ok := s.topics[topic]
+	if !ok {
+		return nil, fmt.Errorf("topic not found: %s", topic)
+	}
+
+	return &models.FeedInfo{
+		Topic:        topic,
+		FileSize:     0, // Not applicable in memory
+		LastModified: t.updatedAt,
+		ArticleCount: len(t.members),
+	}, nil
+}
+
+func (s *MemoryStorage) DeleteFeed(ctx context.Context, topic string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.topics, topic)
+	return nil
+}
+
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+// SaveArticles saves articles without changing their topic memberships
+func (s *MemoryStorage) SaveArticles(ctx context.Context, articles []models.Article) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, article := range articles {
+		s.saveArticle(article)
+	}
+
+	return nil
+}
+
+// AssignArticlesToTopic assigns stored articles to a topic, creating it if needed
+func (s *MemoryStorage) AssignArticlesToTopic(ctx context.Context, articleIDs []string, topic string) error {
+	return s.assignArticlesToTopic(articleIDs, topic, true)
+}
+
+// RestoreArticlesToTopic assigns imported articles to a topic without queuing webhook deliveries
+func (s *MemoryStorage) RestoreArticlesToTopic(ctx context.Context, articleIDs []string, topic string) error {
+	return s.assignArticlesToTopic(articleIDs, topic, false)
+}
+
+// assignArticlesToTopic records topic memberships, queuing the webhook
+// deliveries of the new ones when queueWebhooks is set
+func (s *MemoryStorage) assignArticlesToTopic(articleIDs []string, topic string, queueWebhooks bool) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t := s.getOrCreateTopic(topic)
+	var newArticles []models.Article
+	for _, articleID := range articleIDs {
+		stored, ok := s.articles[articleID]
+		if !ok {
+			log.Printf("Warning: failed to assign article %s to topic %s: article not found", articleID, topic)
+			continue
+		}
+		if s.addMember(t, articleID) && queueWebhooks {
+			newArticles = append(newArticles, s.listedArticle(stored, true))
+		}
+	}
+
+	// Webhooks only fire for new memberships
+	var webhooks []models.Webhook
+	for _, webhook := range s.webhooks {
+		if webhook.Topic == topic {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	for _, delivery := range webhookDeliveries(webhooks, newArticles, topic, time.Now().UTC()) {
+		s.lastDeliveryID++
+		delivery.ID = s.lastDeliveryID
+		s.deliveries = append(s.deliveries, delivery)
+	}
+
+	return nil
+}
+
+// GetCombinedFilters combines filters from multiple topics
+func (s *MemoryStorage) GetCombinedFilters(ctx context.Context, topics []string) ([]string, bool) {
+	// Filters are handled in the aggregator layer
+	return nil, false
+}
+
+// AddArticleToTopic adds a single article to a topic
+func (s *MemoryStorage) AddArticleToTopic(ctx context.Context, articleID string, topic string) error {
+	return s.AssignArticlesToTopic(ctx, []string{articleID}, topic)
+}
+
+// AssignArticlesToFeed records that stored articles were polled from a feed URL
+func (s *MemoryStorage) AssignArticlesToFeed(ctx context.Context, articleIDs []string, feedURL, title string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if feed, ok := s.feeds[feedURL]; ok {
+		feed.title = title
+	} else {
+		s.lastFeedID++
+		s.feeds[feedURL] = &memoryFeed{id: s.lastFeedID, title: title}
+
+		// A new feed adopts the stored articles of its title linked to no feed
+		for _, stored := range s.articles {
+			if len(stored.article.FeedURLs) == 0 && stored.article.Source == title {
+				stored.article.FeedURLs = []string{feedURL}
+			}
+		}
+	}
+	for _, articleID := range articleIDs {
+		stored, ok := s.articles[articleID]
+		if !ok || containsString(stored.article.FeedURLs, feedURL) {
+			continue
+		}
+		stored.article.FeedURLs = append(append([]string(nil), stored.article.FeedURLs...), feedURL)
+	}
+	return nil
+}
+
+// DeleteSourceFeed forgets a polled feed, purging its articles no other feed or pin references
+func (s *MemoryStorage) DeleteSourceFeed(ctx context.Context, feedID int64, purge bool) (*models.FeedDeletion, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	deletion := &models.FeedDeletion{ID: feedID, Purged: purge, DeletedArticleIDs: []string{}, UnsubscribedTopics: []string{}}
+	for url, feed := range s.feeds {
+		if feed.id == feedID {
+			deletion.URL = url
+			deletion.Title = feed.title
+		}
+	}
+	if deletion.URL == "" {
+		return nil, ErrNotFound
+	}
+	delete(s.feeds, deletion.URL)
+
+	ids := make([]string, 0, len(s.articles))
+	for id := range s.articles {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		stored := s.articles[id]
+		if !containsString(stored.article.FeedURLs, deletion.URL) {
+			continue
+		}
+		deletion.LinkedArticles++
+
+		if purge && !stored.kept() && len(stored.article.FeedURLs) == 1 {
+			if len(stored.compressed) > 0 {
+				deletion.DeletedCompressed++
+			}
+			deletion.DeletedSearchTerms += len(stored.terms)
+			deletion.DeletedArticleIDs = append(deletion.DeletedArticleIDs, id)
+			s.deleteArticle(id)
+			continue
+		}
+
+		var feedURLs []string
+		for _, url := range stored.article.FeedURLs {
+			if url != deletion.URL {
+				feedURLs = append(feedURLs, url)
+			}
+		}
+		stored.article.FeedURLs = feedURLs
+	}
+
+	deletion.DeletedArticles = len(deletion.DeletedArticleIDs)
+	deletion.KeptArticles = deletion.LinkedArticles - deletion.DeletedArticles
+	return deletion, nil
+}
+
+// RemoveArticleFromTopic removes an article from a topic
+func (s *MemoryStorage) RemoveArticleFromTopic(ctx context.Context, articleID string, topic string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t, ok := s.topics[topic]
+	if !ok {
+		return fmt.Errorf("topic not found: %s", topic)
+	}
+	delete(t.members, articleID)
+
+	return nil
+}
+
+// GetArticleTopics returns all topics for an article
+func (s *MemoryStorage) GetArticleTopics(ctx context.Context, articleID string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var topics []string
+	for name, topic := range s.topics {
+		if _, ok := topic.members[articleID]; ok {
+			topics = append(topics, name)
+		}
+	}
+	sort.Strings(topics)
+
+	return topics, nil
+}
+
+// GetArticles returns the articles with the given IDs, in the requested order
+func (s *MemoryStorage) GetArticles(ctx context.Context, ids []string) ([]models.Article, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	articles := make([]models.Article, 0, len(ids))
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		stored, ok := s.articles[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		articles = append(articles, s.toArticle(stored))
+	}
+	return articles, nil
+}
+
+// GetArticleIDsByLink returns the stored article ID of each known link, the
+// lowest when several articles share a link
+func (s *MemoryStorage) GetArticleIDsByLink(ctx context.Context, links []string) (map[string]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	wanted := make(map[string]bool, len(links))
+	for _, link := range links {
+		wanted[link] = true
+	}
+	ids := make(map[string]string)
+	for id, stored := range s.articles {
+		link := stored.article.Link
+		if wanted[link] && (ids[link] == "" || id < ids[link]) {
+			ids[link] = id
+		}
+	}
+	return ids, nil
+}
+
+// GetArticleRevisions returns the earlier versions of an article
+func (s *MemoryStorage) GetArticleRevisions(ctx context.Context, articleID string) ([]models.ArticleRevision, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	stored, ok := s.articles[articleID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]models.ArticleRevision{}, stored.revisions...), nil
+}
+
+// SetArticlePinned pins or unpins an article
+func (s *MemoryStorage) SetArticlePinned(ctx context.Context, articleID string, pinned bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.articles[articleID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.article.Pinned = pinned
+
+	return nil
+}
+
+// CleanupOldArticles deletes the articles no longer retained by the policy
+func (s *MemoryStorage) CleanupOldArticles(ctx context.Context, policy RetentionPolicy) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	topicsByArticle := make(map[string][]string)
+	for name, topic := range s.topics {
+		for id := range topic.members {
+			topicsByArticle[id] = append(topicsByArticle[id], name)
+		}
+	}
+
+	candidates := make([]retentionCandidate, 0, len(s.articles))
+	for id, stored := range s.articles {
+		at := stored.article.PublishedAt
+		if policy.ByIngestion {
+			at = stored.createdAt
+		}
+		feeds := stored.article.FeedURLs
+		if len(feeds) == 0 {
+			feeds = []string{stored.article.Source}
+		}
+		candidates = append(candidates, retentionCandidate{id: id, feeds: feeds, at: at, topics: topicsByArticle[id], pinned: stored.kept()})
+	}
+
+	expired := policy.expiredArticles(candidates, time.Now())
+	if policy.Archive != nil {
+		archived := make([]models.ExportedArticle, 0, len(expired))
+		for _, id := range expired {
+			archived = append(archived, models.ExportedArticle{Article: s.toArticle(s.articles[id]), Topics: topicsByArticle[id]})
+		}
+		if err := policy.Archive.Store(ctx, archived); err != nil {
+			return err
+		}
+	}
+	for _, id := range expired {
+		s.deleteArticle(id)
+	}
+
+	if len(expired) > 0 {
+		log.Printf("Cleaned up %d old articles", len(expired))
+	}
+
+	return nil
+}
+
+// OptimizeDatabase has nothing to do in memory
+func (s *MemoryStorage) OptimizeDatabase(ctx context.Context) error {
+	return nil
+}
+
+// GetDatabaseStats returns storage statistics
+func (s *MemoryStorage) GetDatabaseStats(ctx context.Context) (map[string]interface{}, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	compressed, uncompressed, contentLength := 0, 0, 0
+	for _, article := range s.articles {
+		if len(article.compressed) > 0 {
+			compressed++
+		}
+		if article.article.Content != "" {
+			uncompressed++
+			contentLength += utf8.RuneCountInString(article.article.Content)
+		}
+	}
+
+	avgContentLength := 0.0
+	if uncompressed > 0 {
+		avgContentLength = float64(contentLength) / float64(uncompressed)
+	}
+
+	articlesByTopic := make(map[string]int)
+	for name, topic := range s.topics {
+		articlesByTopic[name] = len(topic.members)
+	}
+
+	return map[string]interface{}{
+		"total_articles":        len(s.articles),
+		"total_topics":          len(s.topics),
+		"avg_content_length":    avgContentLength,
+		"compressed_articles":   compressed,
+		"uncompressed_articles": uncompressed,
+		"database_size_bytes":   int64(0),
+		"articles_by_topic":     articlesByTopic,
+	}, nil
+}
+
+// RemoveDuplicateArticles keeps the first stored article of each link and
+// any pinned or starred one
+func (s *MemoryStorage) RemoveDuplicateArticles(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	seen := make(map[string]bool)
+	removed := 0
+	for _, article := range s.sortedArticles() {
+		if seen[article.article.Link] && !article.kept() {
+			s.deleteArticle(article.article.ID)
+			removed++
+			continue
+		}
+		seen[article.article.Link] = true
+	}
+
+	if removed > 0 {
+		log.Printf("Removed %d duplicate articles", removed)
+	}
+
+	return nil
+}
+
+// CompressOldArticles compresses unpinned, unstarred articles older than 3
+// days that are still uncompressed
+func (s *MemoryStorage) CompressOldArticles(ctx context.Context) error {
+	if s.config == nil || !s.config.EnableContentCompression {
+		return nil // Compression not enabled
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	threeDaysAgo := time.Now().AddDate(0, 0, -3)
+	codec := compressionCodec(s.config)
+
+	compressedCount := 0
+	for _, article := range s.articles {
+		if !article.article.PublishedAt.Before(threeDaysAgo) || article.article.Content == "" || article.kept() || len(article.compressed) > 0 {
+			continue
+		}
+		compressed, err := compressContent(codec, article.article.Content)
+		if err != nil {
+			log.Printf("Warning: failed to compress content for article %s: %v", article.article.ID, err)
+			continue
+		}
+		article.compressed = compressed
+		article.codec = codec
+		article.article.Content = ""
+		compressedCount++
+	}
+
+	if compressedCount > 0 {
+		log.Printf("Compressed %d old articles", compressedCount)
+	}
+
+	return nil
+}
+
+// GetFeedStats returns detailed statistics for each feed source
+func (s *MemoryStorage) GetFeedStats(ctx context.Context) (map[string]interface{}, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	type sourceStats struct {
+		articleCount, totalContentSize, nonEnglishCount int
+		oldest, newest                                  time.Time
+	}
+	bySource := make(map[string]*sourceStats)
+	for _, stored := range s.articles {
+		article := stored.article
+		stats, ok := bySource[article.Source]
+		if !ok {
+			stats = &sourceStats{oldest: article.PublishedAt, newest: article.PublishedAt}
+			bySource[article.Source] = stats
+		}
+		stats.articleCount++
+		stats.totalContentSize += utf8.RuneCountInString(article.Content) + utf8.RuneCountInString(article.Title) + utf8.RuneCountInString(article.Description)
+		if article.Language != "en" {
+			stats.nonEnglishCount++
+		}
+		if article.PublishedAt.Before(stats.oldest) {
+			stats.oldest = article.PublishedAt
+		}
+		if article.PublishedAt.After(stats.newest) {
+			stats.newest = article.PublishedAt
+		}
+	}
+
+	var feeds []map[string]interface{}
+	for source, stats := range bySource {
+		feeds = append(feeds, map[string]interface{}{
+			"source":             source,
+			"article_count":      stats.articleCount,
+			"avg_content_size":   stats.totalContentSize / stats.articleCount,
+			"total_content_size": stats.totalContentSize,
+			"non_english_count":  stats.nonEnglishCount,
+			"oldest_article":     stats.oldest.Format(time.RFC3339),
+			"newest_article":     stats.newest.Format(time.RFC3339),
+		})
+	}
+	sort.Slice(feeds, func(i, j int) bool {
+		if feeds[i]["article_count"].(int) != feeds[j]["article_count"].(int) {
+			return feeds[i]["article_count"].(int) > feeds[j]["article_count"].(int)
+		}
+		return feeds[i]["source"].(string) < feeds[j]["source"].(string)
+	})
+
+	byURL := make(map[string]int)
+	for _, stored := range s.articles {
+		for _, url := range stored.article.FeedURLs {
+			byURL[url]++
+		}
+	}
+	feedURLs := []map[string]interface{}{}
+	for url, feed := range s.feeds {
+		feedURLs = append(feedURLs, map[string]interface{}{
+			"id":            feed.id,
+			"url":           url,
+			"title":         feed.title,
+			"article_count": byURL[url],
+		})
+	}
+	sort.Slice(feedURLs, func(i, j int) bool {
+		if feedURLs[i]["article_count"].(int) != feedURLs[j]["article_count"].(int) {
+			return feedURLs[i]["article_count"].(int) > feedURLs[j]["article_count"].(int)
+		}
+		return feedURLs[i]["url"].(string) < feedURLs[j]["url"].(string)
+	})
+
+	return map[string]interface{}{
+		"feeds":     feeds,
+		"feed_urls": feedURLs,
+	}, nil
+}
+
+// RecordStatsSnapshot stores the current row counts in the history; nothing
+// is on disk, so sizes stay zero
+func (s *MemoryStorage) RecordStatsSnapshot(ctx context.Context) (*models.StatsSnapshot, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snapshot := models.StatsSnapshot{
+		TakenAt:        time.Now().UTC(),
+		TableRows:      map[string]int64{"articles": int64(len(s.articles)), "topics": int64(len(s.topics)), "feeds": int64(len(s.feeds))},
+		IndexSizeBytes: map[string]int64{},
+	}
+	var original, compressed int
+	for _, stored := range s.articles {
+		snapshot.TableRows["article_revisions"] += int64(len(stored.revisions))
+		if len(stored.compressed) > 0 {
+			snapshot.TableRows["compressed_content"]++
+			original += len(stored.content())
+			compressed += len(stored.compressed)
+		}
+	}
+	for _, topic := range s.topics {
+		snapshot.TableRows["article_topics"] += int64(len(topic.members))
+	}
+	if compressed > 0 {
+		snapshot.CompressionRatio = float64(original) / float64(compressed)
+	}
+
+	// Drop the expired snapshots
+	kept := s.snapshots[:0]
+	for _, existing := range s.snapshots {
+		if !existing.TakenAt.Before(snapshot.TakenAt.Add(-statsSnapshotRetention)) {
+			kept = append(kept, existing)
+		}
+	}
+	s.snapshots = append(kept, snapshot)
+	return &snapshot, nil
+}
+
+// GetStatsSnapshots returns the stats snapshots taken since a time, oldest first
+func (s *MemoryStorage) GetStatsSnapshots(ctx context.Context, since time.Time) ([]models.StatsSnapshot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	snapshots := []models.StatsSnapshot{}
+	for _, snapshot := range s.snapshots {
+		if !snapshot.TakenAt.Before(since) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots, nil
+}
+
+// CreateWebhook stores a webhook, setting its ID and creation time
+func (s *MemoryStorage) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastWebhookID++
+	webhook.ID = s.lastWebhookID
+	webhook.CreatedAt = time.Now().UTC()
+	s.webhooks = append(s.webhooks, *webhook)
+	return nil
+}
+
+// ListWebhooks returns all webhooks, oldest first
+func (s *MemoryStorage) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]models.Webhook{}, s.webhooks...), nil
+}
+
+// GetWebhook returns a webhook by ID
+func (s *MemoryStorage) GetWebhook(ctx context.Context, id int64) (*models.Webhook, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, webhook := range s.webhooks {
+		if webhook.ID == id {
+			return &webhook, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// DeleteWebhook deletes a webhook with its deliveries
+func (s *MemoryStorage) DeleteWebhook(ctx context.Context, id int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	found := false
+	webhooks := s.webhooks[:0]
+	for _, webhook := range s.webhooks {
+		if webhook.ID == id {
+			found = true
+			continue
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	s.webhooks = webhooks
+	if !found {
+		return ErrNotFound
+	}
+
+	deliveries := s.deliveries[:0]
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID != id {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	s.deliveries = deliveries
+	return nil
+}
+
+// GetDueWebhookDeliveries returns the pending deliveries due by now, oldest first
+func (s *MemoryStorage) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	due := []models.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.Status == models.WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook, newest first
+func (s *MemoryStorage) GetWebhookDeliveries(ctx context.Context, webhookID int64, status string, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	deliveries := []models.WebhookDelivery{}
+	for i := len(s.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		delivery := s.deliveries[i]
+		if delivery.WebhookID == webhookID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+// GetWebhookDelivery returns a delivery by ID
+func (s *MemoryStorage) GetWebhookDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, delivery := range s.deliveries {
+		if delivery.ID == id {
+			return &delivery, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// UpdateWebhookDelivery stores the outcome of a delivery attempt
+func (s *MemoryStorage) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.deliveries {
+		if s.deliveries[i].ID == delivery.ID {
+			stored := &s.deliveries[i]
+			stored.Status = delivery.Status
+			stored.Attempts = delivery.Attempts
+			stored.NextAttemptAt = delivery.NextAttemptAt
+			stored.LastError = delivery.LastError
+			stored.ResponseStatus = delivery.ResponseStatus
+			stored.DeliveredAt = delivery.DeliveredAt
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// GetTopicArrivals returns the IDs of the articles assigned to a topic after
+// a membership cursor, oldest first, with the cursor of the last one. A
+// negative cursor only returns the current cursor.
+func (s *MemoryStorage) GetTopicArrivals(ctx context.Context, topic string, after int64) ([]string, int64, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if after < 0 {
+		return nil, s.sequence, nil
+	}
+
+	ids := []string{}
+	cursor := after
+	t, ok := s.topics[topic]
+	if !ok {
+		return ids, cursor, nil
+	}
+	var seqs []int64
+	bySeq := make(map[int64]string)
+	for articleID, seq := range t.members {
+		if seq > after {
+			seqs = append(seqs, seq)
+			bySeq[seq] = articleID
+		}
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	for _, seq := range seqs {
+		ids = append(ids, bySeq[seq])
+		cursor = seq
+	}
+	return ids, cursor, nil
+}
+
+// CreateDigest stores a digest, setting its ID and creation time
+func (s *MemoryStorage) CreateDigest(ctx context.Context, digest *models.Digest) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastDigestID++
+	digest.ID = s.lastDigestID
+	digest.CreatedAt = time.Now().UTC()
+	s.digests = append(s.digests, *digest)
+	return nil
+}
+
+// ListDigests returns all digests
+func (s *MemoryStorage) ListDigests(ctx context.Context) ([]models.Digest, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]models.Digest{}, s.digests...), nil
+}
+
+// GetDigest returns a digest by ID
+func (s *MemoryStorage) GetDigest(ctx context.Context, id int64) (*models.Digest, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, digest := range s.digests {
+		if digest.ID == id {
+			return &digest, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// DeleteDigest deletes a digest
+func (s *MemoryStorage) DeleteDigest(ctx context.Context, id int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, digest := range s.digests {
+		if digest.ID == id {
+			s.digests = append(s.digests[:i], s.digests[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// GetDueDigests returns the digests due by now
+func (s *MemoryStorage) GetDueDigests(ctx context.Context, now time.Time) ([]models.Digest, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	due := []models.Digest{}
+	for _, digest := range s.digests {
+		if !digest.NextSendAt.After(now) {
+			due = append(due, digest)
+		}
+	}
+	return due, nil
+}
+
+// UpdateDigestState stores the sending state of a digest
+func (s *MemoryStorage) UpdateDigestState(ctx context.Context, digest *models.Digest) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.digests {
+		if s.digests[i].ID == digest.ID {
+			stored := &s.digests[i]
+			stored.Cursor = digest.Cursor
+			stored.NextSendAt = digest.NextSendAt
+			stored.LastSentAt = digest.LastSentAt
+			stored.LastError = digest.LastError
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// GetReaderItemRefs returns the items of a Google Reader stream. Item IDs are
+// the article sequences, which grow in storage order.
+func (s *MemoryStorage) GetReaderItemRefs(ctx context.Context, query *models.ReaderQuery) ([]models.ReaderItemRef, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	articles := s.sortedArticles()
+	if !query.Oldest {
+		for i, j := 0, len(articles)-1; i < j; i, j = i+1, j-1 {
+			articles[i], articles[j] = articles[j], articles[i]
+		}
+	}
+
+	refs := []models.ReaderItemRef{}
+	skipped := 0
+	for _, stored := range articles {
+		if !s.matchReaderQuery(stored, query) {
+			continue
+		}
+		if skipped < query.Offset {
+			skipped++
+			continue
+		}
+		if len(refs) == query.Limit {
+			break
+		}
+		refs = append(refs, models.ReaderItemRef{ID: stored.seq, CrawledAt: stored.createdAt})
+	}
+	return refs, nil
+}
+
+// matchReaderQuery reports whether an article is an item of a Google Reader stream
+func (s *MemoryStorage) matchReaderQuery(stored *memoryArticle, query *models.ReaderQuery) bool {
+	if query.Topic != "" {
+		topic, ok := s.topics[query.Topic]
+		if !ok {
+			return false
+		}
+		if _, ok := topic.members[stored.article.ID]; !ok {
+			return false
+		}
+	}
+	if query.FeedURL != "" && !containsString(stored.article.FeedURLs, query.FeedURL) {
+		return false
+	}
+	if (query.Read && !stored.read) || (query.Unread && stored.read) || (query.Starred && !stored.starred) {
+		return false
+	}
+	if !query.Since.IsZero() && stored.createdAt.Before(query.Since) {
+		return false
+	}
+	return query.Until.IsZero() || stored.createdAt.Before(query.Until)
+}
+
+// GetReaderItems returns items by ID with their article, topics and state
+func (s *MemoryStorage) GetReaderItems(ctx context.Context, ids []int64) ([]models.ReaderItem, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	bySeq := s.articlesBySeq(ids)
+	items := make([]models.ReaderItem, 0, len(bySeq))
+	for _, id := range ids {
+		stored, ok := bySeq[id]
+		if !ok {
+			continue
+		}
+		delete(bySeq, id) // Repeated IDs are returned once
+		items = append(items, models.ReaderItem{
+			ReaderItemRef: models.ReaderItemRef{ID: stored.seq, CrawledAt: stored.createdAt},
+			Article:       s.toArticle(stored),
+			Topics:        s.memberTopics(stored.article.ID),
+			Read:          stored.read,
+			Starred:       stored.starred,
+		})
+	}
+	return items, nil
+}
+
+// memberTopics returns the topics of an article in membership order
+func (s *MemoryStorage) memberTopics(articleID string) []string {
+	var topics []string
+	seqs := make(map[string]int64)
+	for name, topic := range s.topics {
+		if seq, ok := topic.members[articleID]; ok {
+			topics = append(topics, name)
+			seqs[name] = seq
+		}
+	}
+	sort.Slice(topics, func(i, j int) bool { return seqs[topics[i]] < seqs[topics[j]] })
+	return topics
+}
+
+// articlesBySeq returns the stored articles with the given sequences
+func (s *MemoryStorage) articlesBySeq(seqs []int64) map[int64]*memoryArticle {
+	wanted := make(map[int64]bool, len(seqs))
+	for _, seq := range seqs {
+		wanted[seq] = true
+	}
+	found := make(map[int64]*memoryArticle, len(seqs))
+	for _, stored := range s.articles {
+		if wanted[stored.seq] {
+			found[stored.seq] = stored
+		}
+	}
+	return found
+}
+
+// SetReaderItemState sets or clears the read or starred state of items
+func (s *MemoryStorage) SetReaderItemState(ctx context.Context, ids []int64, state string, on bool) error {
+	if _, ok := readerStateColumns[state]; !ok {
+		return fmt.Errorf("unknown item state %q", state)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, stored := range s.articlesBySeq(ids) {
+		if state == models.ReaderStateRead {
+			stored.read = on
+		} else {
+			stored.starred = on
+		}
+	}
+	return nil
+}
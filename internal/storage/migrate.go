@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+// foreignKeysOffMarker flags a migration that must run with foreign key
+// enforcement disabled, such as a table rebuild
+const foreignKeysOffMarker = "-- migrate: foreign_keys=off"
+
+// migration is one ordered, up-only schema change
+type migration struct {
+	version        int
+	name           string
+	sql            string
+	foreignKeysOff bool
+}
+
+// loadMigrations reads NNNN_name.sql files from dir, ordered by version
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %v", err)
+	}
+
+	var migrations []migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		if other, exists := seen[version]; exists {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, other, entry.Name())
+		}
+		seen[version] = entry.Name()
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %v", entry.Name(), err)
+		}
+
+		migrations = append(migrations, migration{
+			version:        version,
+			name:           name,
+			sql:            string(content),
+			foreignKeysOff: strings.HasPrefix(string(content), foreignKeysOffMarker),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// latestSQLiteSchemaVersion returns the version of the newest embedded SQLite migration
+func latestSQLiteSchemaVersion() (int, error) {
+	migrations, err := loadMigrations(migrationFiles, "migrations/sqlite")
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].version, nil
+}
+
+// migrateSQLite applies the pending embedded migrations in order. Each
+// migration runs in its own BEGIN IMMEDIATE transaction, which holds the
+// database write lock, so concurrent instances apply every migration once.
+func migrateSQLite(db *sql.DB) error {
+	migrations, err := loadMigrations(migrationFiles, "migrations/sqlite")
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	// PRAGMAs and manual transactions must stay on a single connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+
+	current, err := schemaVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	pending, err := pendingMigrations(migrations, current)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		if err := applySQLiteMigration(ctx, conn, m); err != nil {
+			return fmt.Errorf("migration %04d_%s failed: %v", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+// pendingMigrations returns the migrations newer than the current schema
+// version, refusing databases migrated by a newer binary
+func pendingMigrations(migrations []migration, current int) ([]migration, error) {
+	if len(migrations) > 0 && current > migrations[len(migrations)-1].version {
+		return nil, fmt.Errorf("database schema version %d is newer than this binary supports (%d)", current, migrations[len(migrations)-1].version)
+	}
+
+	var pending []migration
+	for _, m := range migrations {
+		if m.version > current {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+func applySQLiteMigration(ctx context.Context, conn *sql.Conn, m migration) (err error) {
+	// foreign_keys cannot be changed inside a transaction
+	if m.foreignKeysOff {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return err
+		}
+		defer func() {
+			if _, fkErr := conn.ExecContext(ctx, "PRAGMA foreign_keys = ON"); fkErr != nil && err == nil {
+				err = fkErr
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("failed to lock database: %v", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if _, rbErr := conn.ExecContext(ctx, "ROLLBACK"); rbErr != nil {
+				log.Printf("Warning: failed to rollback migration %04d_%s: %v", m.version, m.name, rbErr)
+			}
+		}
+	}()
+
+	// Another instance may have applied it while we waited for the lock
+	var applied int
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations WHERE version = ?", m.version).Scan(&applied); err != nil {
+		return err
+	}
+	if applied > 0 {
+		_, err := conn.ExecContext(ctx, "COMMIT")
+		committed = err == nil
+		return err
+	}
+
+	if _, err := conn.ExecContext(ctx, m.sql); err != nil {
+		return err
+	}
+
+	if m.foreignKeysOff {
+		if err := checkForeignKeys(ctx, conn); err != nil {
+			return err
+		}
+	}
+
+	if _, err := conn.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.version, m.name); err != nil {
+		return err
+	}
+
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		return err
+	}
+	committed = true
+
+	log.Printf("Applied database migration %04d_%s", m.version, m.name)
+	return nil
+}
+
+// checkForeignKeys fails if the schema change left dangling references
+func checkForeignKeys(ctx context.Context, conn *sql.Conn) error {
+	rows, err := conn.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var fkID int
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return err
+		}
+		return fmt.Errorf("foreign key violation in %s (row %d) referencing %s", table, rowID.Int64, parent)
+	}
+	return rows.Err()
+}
+
+// postgresMigrationLock is the advisory lock key held while migrating PostgreSQL
+const postgresMigrationLock = 7464328717
+
+// migratePostgres applies the pending embedded PostgreSQL migrations in order,
+// each in its own transaction. A session advisory lock keeps concurrent
+// replicas from migrating at the same time.
+func migratePostgres(db *sql.DB) error {
+	migrations, err := loadMigrations(migrationFiles, "migrations/postgres")
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	// Advisory locks belong to the session, so everything runs on one connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", postgresMigrationLock); err != nil {
+		return fmt.Errorf("failed to lock database: %v", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", postgresMigrationLock); err != nil {
+			log.Printf("Warning: failed to release migration lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+
+	current, err := schemaVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	pending, err := pendingMigrations(migrations, current)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		if err := applyPostgresMigration(ctx, conn, m); err != nil {
+			return fmt.Errorf("migration %04d_%s failed: %v", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func applyPostgresMigration(ctx context.Context, conn *sql.Conn, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Without arguments the whole file is sent as one multi-statement query
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Applied database migration %04d_%s", m.version, m.name)
+	return nil
+}
+
+// schemaVersion returns the highest applied migration version
+func schemaVersion(ctx context.Context, conn *sql.Conn) (int, error) {
+	var version sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %v", err)
+	}
+	return int(version.Int64), nil
+}
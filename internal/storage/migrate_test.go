@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gorssag/internal/models"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_second.sql": {Data: []byte("-- migrate: foreign_keys=off\nSELECT 2;")},
+		"m/0001_first.sql":  {Data: []byte("SELECT 1;")},
+		"m/README.md":       {Data: []byte("ignored")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("loadMigrations() error = %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("Expected 2 migrations, got %d", len(migrations))
+	}
+	if migrations[0].version != 1 || migrations[0].name != "first" || migrations[0].foreignKeysOff {
+		t.Errorf("Unexpected first migration: %+v", migrations[0])
+	}
+	if migrations[1].version != 2 || migrations[1].name != "second" || !migrations[1].foreignKeysOff {
+		t.Errorf("Unexpected second migration: %+v", migrations[1])
+	}
+
+	invalid := map[string]fstest.MapFS{
+		"bad name":  {"m/first.sql": {Data: []byte("SELECT 1;")}},
+		"duplicate": {"m/0001_a.sql": {Data: []byte("SELECT 1;")}, "m/0001_b.sql": {Data: []byte("SELECT 1;")}},
+	}
+	for name, fsys := range invalid {
+		if _, err := loadMigrations(fsys, "m"); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestMigrateSQLite_FreshDatabase(t *testing.T) {
+	tempDir := t.TempDir()
+
+	storage, err := NewSQLiteStorage(tempDir, nil)
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	storage.Close()
+
+	// Reopening applies nothing and keeps the data directory usable
+	storage, err = NewSQLiteStorage(tempDir, nil)
+	if err != nil {
+		t.Fatalf("Failed to reopen SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	latest, err := latestSQLiteSchemaVersion()
+	if err != nil {
+		t.Fatalf("latestSQLiteSchemaVersion() error = %v", err)
+	}
+
+	var version, applied int
+	if err := storage.db.QueryRow("SELECT MAX(version), COUNT(*) FROM schema_migrations").Scan(&version, &applied); err != nil {
+		t.Fatalf("Failed to read schema_migrations: %v", err)
+	}
+	if version != latest || applied != latest {
+		t.Errorf("Expected %d applied migrations up to version %d, got %d up to %d", latest, latest, applied, version)
+	}
+
+	var topicColumn int
+	if err := storage.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('articles') WHERE name = 'topic_id'").Scan(&topicColumn); err != nil {
+		t.Fatalf("Failed to inspect articles table: %v", err)
+	}
+	if topicColumn != 0 {
+		t.Errorf("Expected articles.topic_id to be dropped")
+	}
+}
+
+func TestMigrateSQLite_LegacyDatabase(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Build a database as it looked before versioned migrations
+	baseline, err := migrationFiles.ReadFile("migrations/sqlite/0001_baseline.sql")
+	if err != nil {
+		t.Fatalf("Failed to read baseline: %v", err)
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(tempDir, "rss_aggregator.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	published := time.Now().Add(-time.Hour)
+	statements := []string{
+		string(baseline),
+		"INSERT INTO topics (id, name) VALUES (1, 'tech'), (2, 'news'), (3, 'security')",
+		// Only recorded in the legacy column, on the first topic
+		"INSERT INTO articles (article_id, topic_id, title, link, description, content, author, source, categories, published_at) VALUES ('legacy', 1, 'Legacy', 'https://example.com/legacy', '', '', '', 'Example', '[\"Cloud\",\"\"]', ?)",
+		// Also in the membership table, on another topic
+		"INSERT INTO articles (article_id, topic_id, title, link, description, content, author, source, published_at) VALUES ('member', 2, 'Member', 'https://example.com/member', '', '', '', 'Example', ?)",
+		"INSERT INTO article_topics (article_id, topic_id) VALUES ('member', 3)",
+		// Already in the membership table, on the same topic
+		"INSERT INTO articles (article_id, topic_id, title, link, description, content, author, source, published_at) VALUES ('assigned', 2, 'Assigned', 'https://example.com/assigned', '', '', '', 'Example', ?)",
+		"INSERT INTO article_topics (article_id, topic_id) VALUES ('assigned', 2)",
+		"INSERT INTO search_index (article_id, search_term, field_type, language) VALUES ('legacy', 'legacy', 'title', 'en')",
+	}
+	for _, statement := range statements {
+		var args []interface{}
+		if strings.Count(statement, "?") == 1 {
+			args = append(args, published)
+		}
+		if _, err := db.Exec(statement, args...); err != nil {
+			t.Fatalf("Failed to prepare legacy database: %v", err)
+		}
+	}
+	db.Close()
+
+	storage, err := NewSQLiteStorage(tempDir, nil)
+	if err != nil {
+		t.Fatalf("Failed to migrate legacy database: %v", err)
+	}
+	defer storage.Close()
+
+	tests := map[string][]string{
+		"legacy":   {"tech"},
+		"member":   {"news", "security"},
+		"assigned": {"news"},
+	}
+	for articleID, expected := range tests {
+		topics, err := storage.GetArticleTopics(context.Background(), articleID)
+		if err != nil {
+			t.Fatalf("GetArticleTopics(%s) error = %v", articleID, err)
+		}
+		if strings.Join(topics, ",") != strings.Join(expected, ",") {
+			t.Errorf("GetArticleTopics(%s) = %v, want %v", articleID, topics, expected)
+		}
+	}
+
+	// Rows referencing articles survive the table rebuild
+	var terms int
+	if err := storage.db.QueryRow("SELECT COUNT(*) FROM search_index WHERE article_id = 'legacy'").Scan(&terms); err != nil {
+		t.Fatalf("Failed to count search terms: %v", err)
+	}
+	if terms != 1 {
+		t.Errorf("Expected search index entries to be kept, got %d", terms)
+	}
+
+	// Categories of stored articles are indexed
+	var category string
+	if err := storage.db.QueryRow("SELECT group_concat(category) FROM article_categories WHERE article_id = 'legacy'").Scan(&category); err != nil || category != "cloud" {
+		t.Errorf("Expected the legacy category to be indexed, got %q (err %v)", category, err)
+	}
+
+	feed, err := storage.LoadFeed(context.Background(), "tech")
+	if err != nil {
+		t.Fatalf("LoadFeed() error = %v", err)
+	}
+	if len(feed.Articles) != 1 || feed.Articles[0].ID != "legacy" {
+		t.Errorf("Expected the legacy article in tech, got %v", feed.Articles)
+	}
+
+	// New articles are saved without the dropped column
+	if err := storage.SaveArticles(context.Background(), []models.Article{{ID: "fresh", Title: "Fresh", Link: "https://example.com/fresh", Source: "Example", PublishedAt: time.Now()}}); err != nil {
+		t.Fatalf("SaveArticles() error = %v", err)
+	}
+}
+
+func TestMigrateSQLite_NewerDatabase(t *testing.T) {
+	tempDir := t.TempDir()
+
+	storage, err := NewSQLiteStorage(tempDir, nil)
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	if _, err := storage.db.Exec("INSERT INTO schema_migrations (version, name) VALUES (9999, 'future')"); err != nil {
+		t.Fatalf("Failed to record future migration: %v", err)
+	}
+	storage.Close()
+
+	if _, err := NewSQLiteStorage(tempDir, nil); err == nil {
+		t.Error("Expected error opening a database newer than the binary")
+	}
+}
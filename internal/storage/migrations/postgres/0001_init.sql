@@ -0,0 +1,67 @@
+-- Initial PostgreSQL schema, equivalent to the SQLite schema at version 2.
+-- Full-text search uses a weighted tsvector instead of the search_index table:
+-- A = title, B = description, C = content, D = author, source and categories.
+
+CREATE TABLE topics (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE articles (
+	id BIGSERIAL PRIMARY KEY,
+	article_id TEXT UNIQUE NOT NULL,
+	title TEXT NOT NULL,
+	link TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	content TEXT NOT NULL DEFAULT '',
+	author TEXT NOT NULL DEFAULT '',
+	source TEXT NOT NULL,
+	categories JSONB NOT NULL DEFAULT '[]',
+	published_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	language TEXT NOT NULL DEFAULT 'en',
+	search_vector TSVECTOR NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_articles_published_at ON articles(published_at DESC, article_id DESC);
+CREATE INDEX idx_articles_author ON articles(author);
+CREATE INDEX idx_articles_source ON articles(source);
+CREATE INDEX idx_articles_link ON articles(link);
+CREATE INDEX idx_articles_search_vector ON articles USING GIN (search_vector);
+
+CREATE TABLE compressed_content (
+	article_id TEXT PRIMARY KEY REFERENCES articles(article_id) ON DELETE CASCADE,
+	compressed_content BYTEA NOT NULL,
+	compressed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE article_topics (
+	id BIGSERIAL PRIMARY KEY,
+	article_id TEXT NOT NULL REFERENCES articles(article_id) ON DELETE CASCADE,
+	topic_id BIGINT NOT NULL REFERENCES topics(id) ON DELETE CASCADE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (article_id, topic_id)
+);
+
+CREATE INDEX idx_article_topics_topic ON article_topics(topic_id);
+
+CREATE TABLE article_clusters (
+	article_id TEXT PRIMARY KEY REFERENCES articles(article_id) ON DELETE CASCADE,
+	cluster_id TEXT NOT NULL,
+	simhash BIGINT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_article_clusters_cluster ON article_clusters(cluster_id);
+
+CREATE TABLE article_cluster_bands (
+	article_id TEXT NOT NULL REFERENCES articles(article_id) ON DELETE CASCADE,
+	band INTEGER NOT NULL,
+	value BIGINT NOT NULL,
+	PRIMARY KEY (band, value, article_id)
+);
+
+CREATE INDEX idx_article_cluster_bands_article ON article_cluster_bands(article_id);
@@ -0,0 +1,16 @@
+-- Track publisher changes to articles. content_hash identifies the stored
+-- title, description and content; content_updated_at is only set once the text changed.
+ALTER TABLE articles ADD COLUMN content_hash TEXT;
+ALTER TABLE articles ADD COLUMN content_updated_at TIMESTAMPTZ;
+
+-- Each revision holds the replaced version: its hash, title and a line diff to its successor.
+CREATE TABLE article_revisions (
+	id BIGSERIAL PRIMARY KEY,
+	article_id TEXT NOT NULL REFERENCES articles(article_id) ON DELETE CASCADE,
+	revision INTEGER NOT NULL,
+	content_hash TEXT NOT NULL,
+	title TEXT NOT NULL,
+	diff TEXT NOT NULL,
+	changed_at TIMESTAMPTZ NOT NULL,
+	UNIQUE (article_id, revision)
+);
@@ -0,0 +1,2 @@
+-- Compressed content records the codec it was written with; older rows are gzip.
+ALTER TABLE compressed_content ADD COLUMN codec TEXT NOT NULL DEFAULT 'gzip';
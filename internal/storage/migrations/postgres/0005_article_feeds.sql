@@ -0,0 +1,18 @@
+-- Feeds are identified by URL; their title is the article source.
+CREATE TABLE feeds (
+	id BIGSERIAL PRIMARY KEY,
+	url TEXT NOT NULL UNIQUE,
+	title TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- An article can be polled from several feeds.
+CREATE TABLE article_feeds (
+	article_id TEXT NOT NULL REFERENCES articles(article_id) ON DELETE CASCADE,
+	feed_id BIGINT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+	first_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (article_id, feed_id)
+);
+
+CREATE INDEX idx_article_feeds_feed_id ON article_feeds(feed_id);
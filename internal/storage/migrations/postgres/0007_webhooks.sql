@@ -0,0 +1,29 @@
+-- Webhook subscriptions and the queue and log of their deliveries.
+CREATE TABLE webhooks (
+	id BIGSERIAL PRIMARY KEY,
+	url TEXT NOT NULL,
+	topic TEXT NOT NULL,
+	filter TEXT NOT NULL DEFAULT '',
+	secret TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_webhooks_topic ON webhooks(topic);
+
+CREATE TABLE webhook_deliveries (
+	id BIGSERIAL PRIMARY KEY,
+	webhook_id BIGINT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	article_id TEXT NOT NULL,
+	topic TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMPTZ NOT NULL,
+	last_error TEXT NOT NULL DEFAULT '',
+	response_status INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL,
+	delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id);
@@ -0,0 +1,8 @@
+-- Read and starred state of articles, set through the Google Reader API.
+CREATE TABLE article_states (
+	article_id TEXT PRIMARY KEY REFERENCES articles(article_id) ON DELETE CASCADE,
+	read_at TIMESTAMPTZ,
+	starred_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_article_states_starred_at ON article_states(starred_at);
@@ -0,0 +1,105 @@
+-- Baseline schema, as created before versioned migrations existed.
+-- Every statement is idempotent so existing databases are adopted as-is.
+
+CREATE TABLE IF NOT EXISTS topics (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT UNIQUE NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Articles table with comprehensive indexing
+CREATE TABLE IF NOT EXISTS articles (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	article_id TEXT UNIQUE NOT NULL, -- UUID for consistent article identification
+	topic_id INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	link TEXT NOT NULL,
+	description TEXT,
+	content TEXT,
+	author TEXT,
+	source TEXT NOT NULL,
+	categories TEXT, -- JSON array
+	published_at DATETIME NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	language TEXT DEFAULT 'en', -- New column for article language
+	FOREIGN KEY (topic_id) REFERENCES topics(id) ON DELETE CASCADE
+);
+
+-- Table for storing compressed content separately
+CREATE TABLE IF NOT EXISTS compressed_content (
+	article_id TEXT PRIMARY KEY,
+	compressed_content BLOB NOT NULL,
+	compressed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (article_id) REFERENCES articles(article_id) ON DELETE CASCADE
+);
+
+-- Search index table for efficient full-text search with multi-language support
+CREATE TABLE IF NOT EXISTS search_index (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	article_id TEXT NOT NULL,
+	search_term TEXT NOT NULL,
+	field_type TEXT NOT NULL, -- 'title', 'description', 'content', 'author', 'source'
+	language TEXT NOT NULL, -- 'en', 'zh', 'de', 'fr', 'es', etc.
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (article_id) REFERENCES articles(article_id) ON DELETE CASCADE
+);
+
+-- Index for efficient search with language support
+CREATE INDEX IF NOT EXISTS idx_search_index_article_term ON search_index(article_id, search_term);
+CREATE INDEX IF NOT EXISTS idx_search_index_term_lang ON search_index(search_term, language);
+CREATE INDEX IF NOT EXISTS idx_search_index_field ON search_index(field_type);
+CREATE INDEX IF NOT EXISTS idx_search_index_language ON search_index(language);
+
+-- Article-Topic membership table for many-to-many relationships
+CREATE TABLE IF NOT EXISTS article_topics (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	article_id TEXT NOT NULL,
+	topic_id INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (article_id) REFERENCES articles(article_id) ON DELETE CASCADE,
+	FOREIGN KEY (topic_id) REFERENCES topics(id) ON DELETE CASCADE,
+	UNIQUE(article_id, topic_id) -- Prevent duplicate assignments
+);
+
+-- Indexes for efficient topic membership queries
+CREATE INDEX IF NOT EXISTS idx_article_topics_article ON article_topics(article_id);
+CREATE INDEX IF NOT EXISTS idx_article_topics_topic ON article_topics(topic_id);
+
+-- Story clusters: near-duplicate articles share a cluster_id (the ID of the cluster's first article)
+CREATE TABLE IF NOT EXISTS article_clusters (
+	article_id TEXT PRIMARY KEY,
+	cluster_id TEXT NOT NULL,
+	simhash INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (article_id) REFERENCES articles(article_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_article_clusters_cluster ON article_clusters(cluster_id);
+
+-- SimHash bands used to look up cluster candidates
+CREATE TABLE IF NOT EXISTS article_cluster_bands (
+	article_id TEXT NOT NULL,
+	band INTEGER NOT NULL,
+	value INTEGER NOT NULL,
+	PRIMARY KEY (band, value, article_id),
+	FOREIGN KEY (article_id) REFERENCES articles(article_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_article_cluster_bands_article ON article_cluster_bands(article_id);
+
+-- Indexes for fast OData queries
+CREATE INDEX IF NOT EXISTS idx_articles_topic_id ON articles(topic_id);
+CREATE INDEX IF NOT EXISTS idx_articles_published_at ON articles(published_at DESC);
+CREATE INDEX IF NOT EXISTS idx_articles_author ON articles(author);
+CREATE INDEX IF NOT EXISTS idx_articles_source ON articles(source);
+
+-- Text search indexes using LIKE for basic text search
+CREATE INDEX IF NOT EXISTS idx_articles_title_like ON articles(title);
+CREATE INDEX IF NOT EXISTS idx_articles_description_like ON articles(description);
+CREATE INDEX IF NOT EXISTS idx_articles_content_like ON articles(content);
+
+-- Composite indexes for complex queries
+CREATE INDEX IF NOT EXISTS idx_articles_topic_published ON articles(topic_id, published_at DESC);
+CREATE INDEX IF NOT EXISTS idx_articles_author_source ON articles(author, source);
@@ -0,0 +1,43 @@
+-- migrate: foreign_keys=off
+-- Drop the legacy articles.topic_id column, topic membership lives in article_topics.
+-- SQLite cannot drop a column used by a foreign key, so the table is rebuilt.
+
+-- Keep the memberships recorded in the legacy column, which the membership table
+-- may already hold.
+INSERT INTO article_topics (article_id, topic_id)
+SELECT a.article_id, a.topic_id
+FROM articles a
+WHERE a.topic_id IS NOT NULL
+	AND a.topic_id IN (SELECT id FROM topics)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE articles_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	article_id TEXT UNIQUE NOT NULL, -- UUID for consistent article identification
+	title TEXT NOT NULL,
+	link TEXT NOT NULL,
+	description TEXT,
+	content TEXT,
+	author TEXT,
+	source TEXT NOT NULL,
+	categories TEXT, -- JSON array
+	published_at DATETIME NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	language TEXT DEFAULT 'en'
+);
+
+INSERT INTO articles_new (id, article_id, title, link, description, content, author, source, categories, published_at, created_at, updated_at, language)
+SELECT id, article_id, title, link, description, content, author, source, categories, published_at, created_at, updated_at, language
+FROM articles;
+
+DROP TABLE articles;
+ALTER TABLE articles_new RENAME TO articles;
+
+CREATE INDEX idx_articles_published_at ON articles(published_at DESC);
+CREATE INDEX idx_articles_author ON articles(author);
+CREATE INDEX idx_articles_source ON articles(source);
+CREATE INDEX idx_articles_title_like ON articles(title);
+CREATE INDEX idx_articles_description_like ON articles(description);
+CREATE INDEX idx_articles_content_like ON articles(content);
+CREATE INDEX idx_articles_author_source ON articles(author, source);
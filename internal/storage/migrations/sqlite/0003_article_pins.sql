@@ -0,0 +1,4 @@
+-- Pinned articles are exempt from retention, deduplication and compression.
+ALTER TABLE articles ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_articles_pinned ON articles(pinned) WHERE pinned = 1;
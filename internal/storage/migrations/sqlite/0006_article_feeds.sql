@@ -0,0 +1,20 @@
+-- Feeds are identified by URL; their title is the article source.
+CREATE TABLE feeds (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	url TEXT NOT NULL UNIQUE,
+	title TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_seen_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- An article can be polled from several feeds.
+CREATE TABLE article_feeds (
+	article_id TEXT NOT NULL,
+	feed_id INTEGER NOT NULL,
+	first_seen_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (article_id, feed_id),
+	FOREIGN KEY (article_id) REFERENCES articles(article_id) ON DELETE CASCADE,
+	FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_article_feeds_feed_id ON article_feeds(feed_id);
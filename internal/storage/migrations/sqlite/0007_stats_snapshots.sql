@@ -0,0 +1,15 @@
+-- Compressed content records its original size, for the compression ratio.
+ALTER TABLE compressed_content ADD COLUMN original_size INTEGER NOT NULL DEFAULT 0;
+
+-- Storage statistics recorded after each optimization cycle; maps are JSON.
+CREATE TABLE stats_snapshots (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	taken_at DATETIME NOT NULL,
+	table_rows TEXT NOT NULL DEFAULT '{}',
+	database_size_bytes INTEGER NOT NULL DEFAULT 0,
+	wal_size_bytes INTEGER NOT NULL DEFAULT 0,
+	compression_ratio REAL NOT NULL DEFAULT 0,
+	index_size_bytes TEXT NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX idx_stats_snapshots_taken_at ON stats_snapshots(taken_at);
@@ -0,0 +1,19 @@
+-- Email digest subscriptions; last_membership_id is the last article_topics id sent.
+CREATE TABLE digests (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	recipient TEXT NOT NULL,
+	topic TEXT NOT NULL,
+	filter TEXT NOT NULL DEFAULT '',
+	schedule TEXT NOT NULL,
+	subject TEXT NOT NULL DEFAULT '',
+	html_template TEXT NOT NULL DEFAULT '',
+	text_template TEXT NOT NULL DEFAULT '',
+	max_articles INTEGER NOT NULL,
+	last_membership_id INTEGER NOT NULL DEFAULT 0,
+	next_send_at DATETIME NOT NULL,
+	last_sent_at DATETIME,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_digests_next_send_at ON digests(next_send_at);
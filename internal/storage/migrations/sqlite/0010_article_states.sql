@@ -0,0 +1,9 @@
+-- Read and starred state of articles, set through the Google Reader API.
+CREATE TABLE article_states (
+	article_id TEXT PRIMARY KEY,
+	read_at DATETIME,
+	starred_at DATETIME,
+	FOREIGN KEY (article_id) REFERENCES articles(article_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_article_states_starred_at ON article_states(starred_at);
@@ -0,0 +1,15 @@
+-- Lower-cased categories of each article, so the articles sharing a category
+-- are found through an index instead of parsing the categories of every row.
+CREATE TABLE article_categories (
+	article_id TEXT NOT NULL,
+	category TEXT NOT NULL,
+	PRIMARY KEY (article_id, category),
+	FOREIGN KEY (article_id) REFERENCES articles(article_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_article_categories_category ON article_categories(category);
+
+INSERT OR IGNORE INTO article_categories (article_id, category)
+SELECT a.article_id, lower(c.value)
+FROM articles a, json_each(CASE WHEN json_valid(a.categories) THEN a.categories ELSE '[]' END) c
+WHERE c.type = 'text' AND c.value != '';
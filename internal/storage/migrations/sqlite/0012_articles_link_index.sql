@@ -0,0 +1,2 @@
+-- Polls look stored articles up by link to keep their ID.
+CREATE INDEX idx_articles_link ON articles(link);
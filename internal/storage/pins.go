@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// sqlStarred is true for the articles starred from a Google Reader client,
+// formatted with the articles table name or alias. Starred articles are kept
+// by retention, deduplication, compression and feed purges like pinned ones.
+const sqlStarred = "EXISTS (SELECT 1 FROM article_states st WHERE st.article_id = %s.article_id AND st.starred_at IS NOT NULL)"
+
+// setSQLArticlePinned sets the pin flag of an article in a SQL backend
+func setSQLArticlePinned(ctx context.Context, q queryer, articleID string, pinned bool) error {
+	result, err := q.ExecContext(ctx, "UPDATE articles SET pinned = ? WHERE article_id = ?", pinned, articleID)
+	if err != nil {
+		return fmt.Errorf("failed to update article pin: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetArticlePinned pins or unpins an article
+func (s *SQLiteStorage) SetArticlePinned(ctx context.Context, articleID string, pinned bool) error {
+	return setSQLArticlePinned(ctx, s.db, articleID, pinned)
+}
+
+// SetArticlePinned pins or unpins an article
+func (s *PostgresStorage) SetArticlePinned(ctx context.Context, articleID string, pinned bool) error {
+	return setSQLArticlePinned(ctx, s.db, articleID, pinned)
+}
@@ -0,0 +1,1112 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/bits"
+	"strings"
+	"time"
+
+	"gorssag/internal/config"
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+
+	"github.com/lib/pq"
+	"github.com/pemistahl/lingua-go"
+)
+
+// PostgresStorage stores feeds in PostgreSQL. Unlike SQLiteStorage it does
+// not serialize writes in process, so several replicas can share a database.
+type PostgresStorage struct {
+	db       postgresDB
+	config   *config.Config
+	detector lingua.LanguageDetector
+}
+
+// NewPostgresStorage connects to the database at dsn and applies pending migrations
+func NewPostgresStorage(dsn string, cfg *config.Config) (*PostgresStorage, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("a database URL is required for the postgres storage driver")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(time.Hour)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	// Bring the schema up to date with the embedded migrations
+	if err := migratePostgres(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %v", err)
+	}
+
+	log.Printf("Connected to PostgreSQL database")
+
+	return &PostgresStorage{
+		db:       postgresDB{db},
+		config:   cfg,
+		detector: newLanguageDetector(),
+	}, nil
+}
+
+// postgresArticleColumns returns the columns scanned by scanPostgresArticle;
+// the query must join compressed_content as "cc" and article_clusters as "ac"
+func postgresArticleColumns(query *models.ODataQuery) string {
+	return `
+	a.article_id,
+	a.title,
+	a.description,
+	a.link,
+	a.author,
+	a.source,
+	a.published_at,
+	a.categories,
+	a.language,
+	a.pinned,
+	a.content_updated_at,
+	(SELECT t.name FROM article_topics at JOIN topics t ON at.topic_id = t.id WHERE at.article_id = a.article_id ORDER BY at.id LIMIT 1),
+	COALESCE(ac.cluster_id, a.article_id),
+	` + articleContentColumns(query)
+}
+
+// scanPostgresArticle scans a row selected with postgresArticleColumns
+func scanPostgresArticle(rows *sql.Rows) (models.Article, error) {
+	var article models.Article
+	var categoriesJSON, compressedContent []byte
+	var topic, codec sql.NullString
+
+	if err := rows.Scan(
+		&article.ID,
+		&article.Title,
+		&article.Description,
+		&article.Link,
+		&article.Author,
+		&article.Source,
+		&article.PublishedAt,
+		&categoriesJSON,
+		&article.Language,
+		&article.Pinned,
+		&article.UpdatedAt,
+		&topic,
+		&article.ClusterID,
+		&article.Content,
+		&codec,
+		&compressedContent,
+	); err != nil {
+		return article, fmt.Errorf("failed to scan article: %v", err)
+	}
+
+	if article.Content == "" && len(compressedContent) > 0 {
+		decompressed, err := decompressContent(codec.String, compressedContent)
+		if err != nil {
+			log.Printf("Warning: failed to decompress content for article %s: %v", article.ID, err)
+			article.Content = "Content unavailable (decompression failed)"
+		} else {
+			article.Content = decompressed
+		}
+	}
+
+	if len(categoriesJSON) > 0 {
+		if err := json.Unmarshal(categoriesJSON, &article.Categories); err != nil {
+			log.Printf("Warning: failed to parse categories for article %s: %v", article.ID, err)
+			article.Categories = []string{}
+		}
+	}
+
+	article.Topic = topic.String
+	return article, nil
+}
+
+func (s *PostgresStorage) getOrCreateTopic(ctx context.Context, tx postgresTx, topic string) (int64, error) {
+	// The no-op update makes RETURNING work for existing topics too
+	var topicID int64
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO topics (name) VALUES (?)
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id
+	`, topic).Scan(&topicID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create topic: %v", err)
+	}
+	return topicID, nil
+}
+
+// saveArticleWithTx upserts an article with its compressed content, search
+// vector and story cluster
+func (s *PostgresStorage) saveArticleWithTx(ctx context.Context, tx postgresTx, article models.Article) error {
+	categories := article.Categories
+	if categories == nil {
+		categories = []string{}
+	}
+	categoriesJSON, _ := json.Marshal(categories)
+
+	content := cleanAndOptimizeContent(article.Content)
+	contentToStore := content
+
+	// Old articles keep their content in compressed_content only
+	var compressed []byte
+	codec := compressionCodec(s.config)
+	if s.config != nil && s.config.EnableContentCompression &&
+		article.PublishedAt.Before(time.Now().AddDate(0, 0, -3)) && len(content) > 0 {
+		var err error
+		compressed, err = compressContent(codec, content)
+		if err != nil {
+			log.Printf("Warning: failed to compress content for article %s: %v", article.ID, err)
+			compressed = nil
+		} else {
+			contentToStore = ""
+		}
+	}
+
+	language := articleLanguage(s.detector, article)
+
+	// Keep the replaced version when the publisher changed the article
+	contentHash, changedAt, err := recordSQLRevision(ctx, tx, article, content)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{article.ID, article.Title, article.Link, article.Description, contentToStore, article.Author, article.Source, string(categoriesJSON), article.PublishedAt, language, article.Pinned, contentHash, changedAt}
+	args = append(args, postgresSearchVectorArgs(article, content)...)
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO articles (article_id, title, link, description, content, author, source, categories, published_at, language, pinned, content_hash, content_updated_at, search_vector)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?::jsonb, ?, ?, ?, ?, ?, `+postgresSearchVector+`)
+		ON CONFLICT (article_id) DO UPDATE SET
+			title = EXCLUDED.title,
+			link = EXCLUDED.link,
+			description = EXCLUDED.description,
+			content = EXCLUDED.content,
+			author = EXCLUDED.author,
+			source = EXCLUDED.source,
+			categories = EXCLUDED.categories,
+			published_at = EXCLUDED.published_at,
+			language = EXCLUDED.language,
+			pinned = articles.pinned OR EXCLUDED.pinned,
+			content_hash = EXCLUDED.content_hash,
+			content_updated_at = COALESCE(EXCLUDED.content_updated_at, articles.content_updated_at),
+			search_vector = EXCLUDED.search_vector,
+			updated_at = now()
+	`, args...); err != nil {
+		return fmt.Errorf("failed to insert article %s: %v", article.ID, err)
+	}
+
+	if compressed != nil {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO compressed_content (article_id, compressed_content, codec, original_size, compressed_at)
+			VALUES (?, ?, ?, ?, now())
+			ON CONFLICT (article_id) DO UPDATE SET compressed_content = EXCLUDED.compressed_content, codec = EXCLUDED.codec, original_size = EXCLUDED.original_size, compressed_at = now()
+		`, article.ID, compressed, codec, len(content)); err != nil {
+			return fmt.Errorf("failed to store compressed content for article %s: %v", article.ID, err)
+		}
+	} else if _, err := tx.ExecContext(ctx, "DELETE FROM compressed_content WHERE article_id = ?", article.ID); err != nil {
+		return fmt.Errorf("failed to clear compressed content for article %s: %v", article.ID, err)
+	}
+
+	if err := s.assignClusterWithTx(ctx, tx, article, content); err != nil {
+		return fmt.Errorf("failed to cluster article %s: %v", article.ID, err)
+	}
+
+	return nil
+}
+
+// assignClusterWithTx is the PostgreSQL counterpart of SQLiteStorage.assignClusterWithTx
+func (s *PostgresStorage) assignClusterWithTx(ctx context.Context, tx postgresTx, article models.Article, content string) error {
+	text := content
+	if text == "" {
+		text = article.Description
+	}
+	hash := simHash(article.Title + " " + text)
+	if hash == 0 {
+		return nil
+	}
+	bands := simHashBands(hash)
+
+	bandConditions := make([]string, len(bands))
+	args := make([]interface{}, 0, 2*len(bands)+3)
+	for i, value := range bands {
+		bandConditions[i] = "(b.band = ? AND b.value = ?)"
+		args = append(args, i, value)
+	}
+	args = append(args, article.ID, article.PublishedAt.Add(-clusterWindow), article.PublishedAt.Add(clusterWindow))
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT ac.cluster_id, ac.simhash
+		FROM article_cluster_bands b
+		JOIN article_clusters ac ON ac.article_id = b.article_id
+		JOIN articles a ON a.article_id = b.article_id
+		WHERE (`+strings.Join(bandConditions, " OR ")+`)
+			AND b.article_id != ?
+			AND a.published_at BETWEEN ? AND ?
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query cluster candidates: %v", err)
+	}
+
+	clusterID := article.ID
+	bestDistance := clusterMaxDistance + 1
+	for rows.Next() {
+		var candidateCluster string
+		var candidateHash int64
+		if err := rows.Scan(&candidateCluster, &candidateHash); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan cluster candidate: %v", err)
+		}
+		if distance := bits.OnesCount64(hash ^ uint64(candidateHash)); distance < bestDistance {
+			bestDistance = distance
+			clusterID = candidateCluster
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO article_clusters (article_id, cluster_id, simhash)
+		VALUES (?, ?, ?)
+		ON CONFLICT (article_id) DO UPDATE SET cluster_id = EXCLUDED.cluster_id, simhash = EXCLUDED.simhash
+	`, article.ID, clusterID, int64(hash)); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM article_cluster_bands WHERE article_id = ?", article.ID); err != nil {
+		return err
+	}
+	for i, value := range bands {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO article_cluster_bands (article_id, band, value) VALUES (?, ?, ?)", article.ID, i, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) SaveFeed(ctx context.Context, topic string, feed *models.AggregatedFeed) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	topicID, err := s.getOrCreateTopic(ctx, tx, topic)
+	if err != nil {
+		return err
+	}
+
+	// Articles shared with other topics are kept, only this topic's membership goes
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM articles
+		WHERE article_id IN (SELECT article_id FROM article_topics WHERE topic_id = ?)
+			AND article_id NOT IN (SELECT article_id FROM article_topics WHERE topic_id != ?)
+	`, topicID, topicID); err != nil {
+		return fmt.Errorf("failed to delete existing articles: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM article_topics WHERE topic_id = ?", topicID); err != nil {
+		return fmt.Errorf("failed to delete topic assignments: %v", err)
+	}
+
+	for _, article := range feed.Articles {
+		if err := s.saveArticleWithTx(ctx, tx, article); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO article_topics (article_id, topic_id) VALUES (?, ?) ON CONFLICT DO NOTHING", article.ID, topicID); err != nil {
+			return fmt.Errorf("failed to assign article %s to topic: %v", article.ID, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE topics SET updated_at = now() WHERE id = ?", topicID); err != nil {
+		return fmt.Errorf("failed to update topic timestamp: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("SaveFeed: Saved %d articles for topic '%s'", len(feed.Articles), topic)
+	return nil
+}
+
+func (s *PostgresStorage) LoadFeed(ctx context.Context, topic string) (*models.AggregatedFeed, error) {
+	var topicID int64
+	if err := s.db.QueryRowContext(ctx, "SELECT id FROM topics WHERE name = ?", topic).Scan(&topicID); err != nil {
+		return nil, fmt.Errorf("topic not found: %v", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+postgresArticleColumns(nil)+`
+		FROM articles a
+		JOIN article_topics tm ON a.article_id = tm.article_id
+		LEFT JOIN compressed_content cc ON a.article_id = cc.article_id
+		LEFT JOIN article_clusters ac ON a.article_id = ac.article_id
+		WHERE tm.topic_id = ?
+		ORDER BY `+keysetOrder("a"),
+		topicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query articles: %v", err)
+	}
+	defer rows.Close()
+
+	var articles []models.Article
+	for rows.Next() {
+		article, err := scanPostgresArticle(rows)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, article)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	if err := attachSQLFeedURLs(ctx, s.db, articles); err != nil {
+		return nil, err
+	}
+
+	if len(articles) == 0 {
+		return nil, fmt.Errorf("no articles found for topic '%s'", topic)
+	}
+
+	return &models.AggregatedFeed{
+		Topic:    topic,
+		Articles: articles,
+		Count:    len(articles),
+		Updated:  time.Now(),
+	}, nil
+}
+
+// listArticles runs an OData article listing, restricted to a topic when
+// topic is set, and returns the page with the total number of matches
+func (s *PostgresStorage) listArticles(ctx context.Context, topic string, query *models.ODataQuery) ([]models.Article, int, error) {
+	where := " WHERE TRUE"
+	var args []interface{}
+	if topic != "" {
+		where += " AND a.article_id IN (SELECT tm.article_id FROM article_topics tm JOIN topics tn ON tm.topic_id = tn.id WHERE tn.name = ?)"
+		args = append(args, topic)
+	}
+
+	conditions, conditionArgs, err := buildPostgresArticleConditions(query, "a")
+	if err != nil {
+		return nil, 0, err
+	}
+	where += conditions
+	args = append(args, conditionArgs...)
+
+	// Keep one representative per story cluster
+	if query.Cluster {
+		clusterCondition, clusterArgs, err := buildPostgresClusterCondition(query, topic)
+		if err != nil {
+			return nil, 0, err
+		}
+		where += clusterCondition
+		args = append(args, clusterArgs...)
+	}
+
+	var totalCount int
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM articles a
+		LEFT JOIN article_clusters ac ON a.article_id = ac.article_id`+where,
+		args...).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to count articles: %v", err)
+	}
+
+	listQuery := `
+		SELECT ` + postgresArticleColumns(query) + `
+		FROM articles a
+		LEFT JOIN compressed_content cc ON a.article_id = cc.article_id
+		LEFT JOIN article_clusters ac ON a.article_id = ac.article_id` + where
+
+	// Add keyset pagination and ordering
+	if query.SkipToken != nil {
+		keyset, keysetArgs := buildKeysetCondition(query.SkipToken, "a")
+		listQuery += keyset + " ORDER BY " + keysetOrder("a")
+		args = append(args, keysetArgs...)
+	} else {
+		listQuery += " ORDER BY " + postgresOrderBy(query.OrderBy)
+	}
+
+	if query.Top > 0 {
+		listQuery += " LIMIT ?"
+		args = append(args, query.Top)
+	}
+	if query.Skip > 0 && query.SkipToken == nil {
+		listQuery += " OFFSET ?"
+		args = append(args, query.Skip)
+	}
+
+	rows, err := s.db.QueryContext(ctx, listQuery, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query articles: %v", err)
+	}
+	defer rows.Close()
+
+	var articles []models.Article
+	for rows.Next() {
+		article, err := scanPostgresArticle(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		articles = append(articles, article)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	if err := attachSQLFeedURLs(ctx, s.db, articles); err != nil {
+		return nil, 0, err
+	}
+
+	if query.Cluster {
+		if err := attachClusterSources(ctx, s.db, articles); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return articles, totalCount, nil
+}
+
+func (s *PostgresStorage) QueryArticles(ctx context.Context, topic string, query *models.ODataQuery) ([]models.Article, error) {
+	var topicID int64
+	if err := s.db.QueryRowContext(ctx, "SELECT id FROM topics WHERE name = ?", topic).Scan(&topicID); err != nil {
+		return nil, fmt.Errorf("topic not found: %v", err)
+	}
+
+	articles, _, err := s.listArticles(ctx, topic, query)
+	return articles, err
+}
+
+// GetAllArticles returns articles across all topics
+func (s *PostgresStorage) GetAllArticles(ctx context.Context, query *models.ODataQuery) ([]models.Article, int, error) {
+	return s.listArticles(ctx, "", query)
+}
+
+// GetTopicArticles returns articles for a topic using the membership table
+func (s *PostgresStorage) GetTopicArticles(ctx context.Context, topic string, query *models.ODataQuery) ([]models.Article, int, error) {
+	return s.listArticles(ctx, topic, query)
+}
+
+// postgresFacetSources is the PostgreSQL counterpart of facetSources
+var postgresFacetSources = map[string]struct {
+	value string
+	joins string
+}{
+	"source":   {value: "a.source"},
+	"author":   {value: "a.author"},
+	"language": {value: "a.language"},
+	"category": {
+		value: "c.value",
+		joins: ", jsonb_array_elements_text(CASE WHEN jsonb_typeof(a.categories) = 'array' THEN a.categories ELSE '[]'::jsonb END) AS c(value)",
+	},
+	"topic": {
+		value: "t.name",
+		joins: " JOIN article_topics at ON a.article_id = at.article_id JOIN topics t ON at.topic_id = t.id",
+	},
+	"published": {value: "to_char(a.published_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')"},
+}
+
+// GetArticleFacets counts the values of each requested facet over every
+// article matching the query, ignoring $top/$skip
+func (s *PostgresStorage) GetArticleFacets(ctx context.Context, query *models.ODataQuery) (models.Facets, error) {
+	facets := make(models.Facets)
+	if len(query.Facets) == 0 {
+		return facets, nil
+	}
+
+	conditions, args, err := buildPostgresArticleConditions(query, "a")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, facet := range query.Facets {
+		source, ok := postgresFacetSources[facet]
+		if !ok {
+			return nil, fmt.Errorf("unsupported facet: %s", facet)
+		}
+
+		facetQuery := `
+			SELECT ` + source.value + ` AS facet_value, COUNT(DISTINCT a.article_id) AS facet_count
+			FROM articles a` + source.joins + `
+			WHERE ` + source.value + ` IS NOT NULL AND ` + source.value + ` != ''` + conditions + `
+			GROUP BY facet_value`
+		facetArgs := args
+
+		// The date histogram is returned in full and in chronological order
+		if facet == "published" {
+			facetQuery += " ORDER BY facet_value ASC"
+		} else {
+			facetQuery += " ORDER BY facet_count DESC, facet_value ASC LIMIT ?"
+			facetArgs = append(append([]interface{}{}, args...), odata.FacetLimit)
+		}
+
+		counts, err := queryFacetCounts(ctx, s.db, facetQuery, facetArgs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute %s facet: %v", facet, err)
+		}
+		facets[facet] = counts
+	}
+
+	return facets, nil
+}
+
+// postgresRelatedQuery is the PostgreSQL counterpart of relatedArticlesQuery,
+// reading terms from the title, description and content weights of search_vector
+const postgresRelatedQuery = `
+	WITH terms AS (
+		SELECT DISTINCT a.article_id, t.lexeme AS term
+		FROM articles a, unnest(ts_filter(a.search_vector, '{a,b,c}')) t
+		WHERE length(t.lexeme) > 2 AND t.lexeme != ALL(?)
+	),
+	categories AS (
+		SELECT DISTINCT a.article_id, lower(c.value) AS category
+		FROM articles a, jsonb_array_elements_text(CASE WHEN jsonb_typeof(a.categories) = 'array' THEN a.categories ELSE '[]'::jsonb END) AS c(value)
+		WHERE c.value != ''
+	),
+	term_weights AS (
+		SELECT t.term, 1.0 / COUNT(*) AS weight
+		FROM terms t
+		WHERE t.term IN (SELECT term FROM terms WHERE article_id = ?)
+		GROUP BY t.term
+	),
+	category_weights AS (
+		SELECT c.category, 1.0 / COUNT(*) AS weight
+		FROM categories c
+		WHERE c.category IN (SELECT category FROM categories WHERE article_id = ?)
+		GROUP BY c.category
+	),
+	scores AS (
+		SELECT t.article_id, tw.weight AS score
+		FROM terms t JOIN term_weights tw ON t.term = tw.term
+		UNION ALL
+		SELECT c.article_id, cw.weight AS score
+		FROM categories c JOIN category_weights cw ON c.category = cw.category
+	)
+	SELECT s.article_id
+	FROM scores s
+	JOIN articles a ON a.article_id = s.article_id
+	WHERE s.article_id != ?
+	GROUP BY s.article_id, a.published_at
+	ORDER BY SUM(s.score) DESC, a.published_at DESC
+	LIMIT ?
+`
+
+// relatedStopWords lists the stop words of every supported language, which
+// the search vector keeps but related scoring ignores
+func relatedStopWords() []string {
+	var words []string
+	for _, language := range []string{"en", "de", "fr", "es", "it", "pt", "ru", "zh"} {
+		for word := range getStopWords(language) {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// GetRelatedArticles returns up to limit articles similar to the given one,
+// across all topics, most similar first
+func (s *PostgresStorage) GetRelatedArticles(ctx context.Context, articleID string, limit int) ([]models.Article, error) {
+	var exists int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM articles WHERE article_id = ?", articleID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up article: %v", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, postgresRelatedQuery, pq.Array(relatedStopWords()), articleID, articleID, articleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query related articles: %v", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan related article: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	found, err := getArticlesByIDs(ctx, s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the similarity order
+	related := make([]models.Article, 0, len(ids))
+	for _, id := range ids {
+		if article, ok := found[id]; ok {
+			related = append(related, article)
+		}
+	}
+
+	return related, nil
+}
+
+func (s *PostgresStorage) ListTopics(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT name FROM topics ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query topics: %v", err)
+	}
+	defer rows.Close()
+
+	var topics []string
+	for rows.Next() {
+		var topic string
+		if err := rows.Scan(&topic); err != nil {
+			return nil, fmt.Errorf("failed to scan topic: %v", err)
+		}
+		topics = append(topics, topic)
+	}
+
+	return topics, rows.Err()
+}
+
+func (s *PostgresStorage) GetFeedInfo(ctx context.Context, topic string) (*models.FeedInfo, error) {
+	var topicID int64
+	var updatedAt time.Time
+	if err := s.db.QueryRowContext(ctx, "SELECT id, updated_at FROM topics WHERE name = ?", topic).Scan(&topicID, &updatedAt); err != nil {
+		return nil, fmt.Errorf("topic not found: %v", err)
+	}
+
+	var articleCount int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM article_topics WHERE topic_id = ?", topicID).Scan(&articleCount); err != nil {
+		return nil, fmt.Errorf("failed to count articles: %v", err)
+	}
+
+	return &models.FeedInfo{
+		Topic:        topic,
+		FileSize:     0, // Not applicable for PostgreSQL
+		LastModified: updatedAt,
+		ArticleCount: articleCount,
+	}, nil
+}
+
+func (s *PostgresStorage) DeleteFeed(ctx context.Context, topic string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM topics WHERE name = ?", topic); err != nil {
+		return fmt.Errorf("failed to delete topic: %v", err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) Close() error {
+	return s.db.Close()
+}
+
+// SaveArticles saves articles independently of topics
+func (s *PostgresStorage) SaveArticles(ctx context.Context, articles []models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, article := range articles {
+		if err := s.saveArticleWithTx(ctx, tx, article); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	log.Printf("SaveArticles: Successfully saved %d articles", len(articles))
+	return nil
+}
+
+// AssignArticlesToTopic assigns articles to a topic after they're stored
+func (s *PostgresStorage) AssignArticlesToTopic(ctx context.Context, articleIDs []string, topic string) error {
+	return s.assignArticlesToTopic(ctx, articleIDs, topic, true)
+}
+
+// RestoreArticlesToTopic assigns imported articles to a topic without queuing webhook deliveries
+func (s *PostgresStorage) RestoreArticlesToTopic(ctx context.Context, articleIDs []string, topic string) error {
+	return s.assignArticlesToTopic(ctx, articleIDs, topic, false)
+}
+
+// assignArticlesToTopic records topic memberships, queuing the webhook
+// deliveries of the new ones when queueWebhooks is set
+func (s *PostgresStorage) assignArticlesToTopic(ctx context.Context, articleIDs []string, topic string, queueWebhooks bool) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	topicID, err := s.getOrCreateTopic(ctx, tx, topic)
+	if err != nil {
+		return err
+	}
+
+	// Unknown articles are skipped, like the foreign key failures SQLite logs
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO article_topics (article_id, topic_id)
+		SELECT a.article_id, ?::bigint FROM articles a WHERE a.article_id = ANY(?)
+		ON CONFLICT DO NOTHING
+		RETURNING article_id
+	`, topicID, pq.Array(articleIDs))
+	if err != nil {
+		return fmt.Errorf("failed to assign articles to topic: %v", err)
+	}
+	var newIDs []string
+	for rows.Next() {
+		var articleID string
+		if err := rows.Scan(&articleID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan assigned article: %v", err)
+		}
+		newIDs = append(newIDs, articleID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to assign articles to topic: %v", err)
+	}
+
+	// Webhooks only fire for new memberships, with the membership itself
+	if queueWebhooks {
+		if _, err := queueSQLWebhookDeliveries(ctx, tx, newIDs, topic); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	assigned := len(newIDs)
+	log.Printf("AssignArticlesToTopic: Assigned %d/%d articles to topic '%s'", assigned, len(articleIDs), topic)
+	return nil
+}
+
+// GetCombinedFilters combines filters from multiple topics
+func (s *PostgresStorage) GetCombinedFilters(ctx context.Context, topics []string) ([]string, bool) {
+	// Filters live in the topic configuration, combined by the aggregator
+	return nil, false
+}
+
+// AddArticleToTopic adds a single article to a topic
+func (s *PostgresStorage) AddArticleToTopic(ctx context.Context, articleID string, topic string) error {
+	return s.AssignArticlesToTopic(ctx, []string{articleID}, topic)
+}
+
+// RemoveArticleFromTopic removes an article from a topic
+func (s *PostgresStorage) RemoveArticleFromTopic(ctx context.Context, articleID string, topic string) error {
+	var topicID int64
+	if err := s.db.QueryRowContext(ctx, "SELECT id FROM topics WHERE name = ?", topic).Scan(&topicID); err != nil {
+		return fmt.Errorf("topic not found: %v", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM article_topics WHERE article_id = ? AND topic_id = ?", articleID, topicID); err != nil {
+		return fmt.Errorf("failed to remove article from topic: %v", err)
+	}
+	return nil
+}
+
+// GetArticleTopics returns all topics for an article
+func (s *PostgresStorage) GetArticleTopics(ctx context.Context, articleID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.name
+		FROM topics t
+		JOIN article_topics at ON t.id = at.topic_id
+		WHERE at.article_id = ?
+		ORDER BY t.name
+	`, articleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query article topics: %v", err)
+	}
+	defer rows.Close()
+
+	var topics []string
+	for rows.Next() {
+		var topic string
+		if err := rows.Scan(&topic); err != nil {
+			return nil, fmt.Errorf("failed to scan topic: %v", err)
+		}
+		topics = append(topics, topic)
+	}
+
+	return topics, rows.Err()
+}
+
+// CleanupOldArticles deletes the articles no longer retained by the policy
+func (s *PostgresStorage) CleanupOldArticles(ctx context.Context, policy RetentionPolicy) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	removed, archived, err := cleanupSQLArticles(ctx, tx, policy, func(expression string) string { return expression })
+	if err != nil {
+		return err
+	}
+	if err := commitCleanup(ctx, tx, policy, archived); err != nil {
+		return err
+	}
+
+	if removed > 0 {
+		log.Printf("Cleaned up %d old articles", removed)
+	}
+
+	return nil
+}
+
+// OptimizeDatabase reclaims space and refreshes planner statistics
+func (s *PostgresStorage) OptimizeDatabase(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "VACUUM ANALYZE"); err != nil {
+		return fmt.Errorf("failed to vacuum database: %v", err)
+	}
+
+	log.Printf("Database optimization completed")
+	return nil
+}
+
+// GetDatabaseStats returns database statistics
+func (s *PostgresStorage) GetDatabaseStats(ctx context.Context) (map[string]interface{}, error) {
+	stats := make(map[string]interface{})
+
+	var totalArticles, totalTopics, compressedCount, uncompressedCount int
+	var avgContentLength sql.NullFloat64
+	var dbSize int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM articles),
+			(SELECT COUNT(*) FROM topics),
+			(SELECT AVG(LENGTH(content)) FROM articles WHERE content != ''),
+			(SELECT COUNT(*) FROM compressed_content),
+			(SELECT COUNT(*) FROM articles WHERE content != ''),
+			pg_database_size(current_database())
+	`).Scan(&totalArticles, &totalTopics, &avgContentLength, &compressedCount, &uncompressedCount, &dbSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database stats: %v", err)
+	}
+
+	stats["total_articles"] = totalArticles
+	stats["total_topics"] = totalTopics
+	stats["avg_content_length"] = 0.0
+	if avgContentLength.Valid {
+		stats["avg_content_length"] = avgContentLength.Float64
+	}
+	stats["compressed_articles"] = compressedCount
+	stats["uncompressed_articles"] = uncompressedCount
+	stats["database_size_bytes"] = dbSize
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.name, COUNT(at.id) AS count
+		FROM topics t
+		LEFT JOIN article_topics at ON t.id = at.topic_id
+		GROUP BY t.id, t.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get articles by topic: %v", err)
+	}
+	defer rows.Close()
+
+	articlesByTopic := make(map[string]int)
+	for rows.Next() {
+		var topicName string
+		var count int
+		if err := rows.Scan(&topicName, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan topic count: %v", err)
+		}
+		articlesByTopic[topicName] = count
+	}
+	stats["articles_by_topic"] = articlesByTopic
+
+	return stats, rows.Err()
+}
+
+// RemoveDuplicateArticles removes articles sharing a link, keeping the first
+// stored and any pinned or starred one
+func (s *PostgresStorage) RemoveDuplicateArticles(ctx context.Context) error {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM articles
+		WHERE NOT pinned AND NOT `+fmt.Sprintf(sqlStarred, "articles")+` AND id NOT IN (
+			SELECT MIN(id)
+			FROM articles
+			GROUP BY link
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to remove duplicate articles: %v", err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected > 0 {
+		log.Printf("Removed %d duplicate articles", rowsAffected)
+	}
+
+	return nil
+}
+
+// CompressOldArticles compresses unpinned, unstarred articles older than 3
+// days that are still uncompressed
+func (s *PostgresStorage) CompressOldArticles(ctx context.Context) error {
+	if s.config == nil || !s.config.EnableContentCompression {
+		return nil // Compression not enabled
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT a.article_id, a.content
+		FROM articles a
+		LEFT JOIN compressed_content c ON a.article_id = c.article_id
+		WHERE a.published_at < ?
+			AND a.content != ''
+			AND NOT a.pinned
+			AND NOT `+fmt.Sprintf(sqlStarred, "a")+`
+			AND c.article_id IS NULL
+		LIMIT 1000
+	`, time.Now().AddDate(0, 0, -3))
+	if err != nil {
+		return fmt.Errorf("failed to query old articles: %v", err)
+	}
+
+	contents := make(map[string]string)
+	for rows.Next() {
+		var articleID, content string
+		if err := rows.Scan(&articleID, &content); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan article for compression: %v", err)
+		}
+		contents[articleID] = content
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	if len(contents) == 0 {
+		log.Printf("No old articles to compress")
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	codec := compressionCodec(s.config)
+	compressedCount := 0
+	for articleID, content := range contents {
+		compressed, err := compressContent(codec, content)
+		if err != nil {
+			log.Printf("Warning: failed to compress content for article %s: %v", articleID, err)
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO compressed_content (article_id, compressed_content, codec, original_size, compressed_at)
+			VALUES (?, ?, ?, ?, now())
+			ON CONFLICT (article_id) DO UPDATE SET compressed_content = EXCLUDED.compressed_content, codec = EXCLUDED.codec, original_size = EXCLUDED.original_size, compressed_at = now()
+		`, articleID, compressed, codec, len(content)); err != nil {
+			return fmt.Errorf("failed to store compressed content for article %s: %v", articleID, err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE articles SET content = '' WHERE article_id = ?", articleID); err != nil {
+			return fmt.Errorf("failed to clear uncompressed content for article %s: %v", articleID, err)
+		}
+
+		compressedCount++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit compression transaction: %v", err)
+	}
+
+	log.Printf("Compressed %d old articles", compressedCount)
+	return nil
+}
+
+// GetFeedStats returns detailed statistics for each feed
+func (s *PostgresStorage) GetFeedStats(ctx context.Context) (map[string]interface{}, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			source,
+			COUNT(*) AS article_count,
+			AVG(LENGTH(content) + LENGTH(title) + LENGTH(description)) AS avg_content_size,
+			SUM(LENGTH(content) + LENGTH(title) + LENGTH(description)) AS total_content_size,
+			COUNT(CASE WHEN language != 'en' THEN 1 END) AS non_english_count,
+			MIN(published_at) AS oldest_article,
+			MAX(published_at) AS newest_article
+		FROM articles
+		GROUP BY source
+		ORDER BY article_count DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feed stats: %v", err)
+	}
+	defer rows.Close()
+
+	var feeds []map[string]interface{}
+	for rows.Next() {
+		var source string
+		var articleCount, nonEnglishCount int
+		var avgContentSize, totalContentSize sql.NullFloat64
+		var oldestArticle, newestArticle sql.NullTime
+
+		if err := rows.Scan(&source, &articleCount, &avgContentSize, &totalContentSize, &nonEnglishCount, &oldestArticle, &newestArticle); err != nil {
+			log.Printf("Warning: failed to scan feed stat: %v", err)
+			continue
+		}
+
+		feed := map[string]interface{}{
+			"source":            source,
+			"article_count":     articleCount,
+			"non_english_count": nonEnglishCount,
+		}
+		if avgContentSize.Valid {
+			feed["avg_content_size"] = int(avgContentSize.Float64)
+		}
+		if totalContentSize.Valid {
+			feed["total_content_size"] = int(totalContentSize.Float64)
+		}
+		if oldestArticle.Valid {
+			feed["oldest_article"] = oldestArticle.Time.Format(time.RFC3339)
+		}
+		if newestArticle.Valid {
+			feed["newest_article"] = newestArticle.Time.Format(time.RFC3339)
+		}
+
+		feeds = append(feeds, feed)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	// Article counts per feed URL; sources only tell feeds apart by title
+	feedURLs, err := getSQLFeedURLStats(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"feeds":     feeds,
+		"feed_urls": feedURLs,
+	}, nil
+}
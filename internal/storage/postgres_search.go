@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"strings"
+
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+)
+
+// postgresSearchWeights maps $search field scopes to the search_vector weight
+// holding them. Author, source and categories share weight D, so scoped
+// searches on them match the columns with ILIKE instead.
+var postgresSearchWeights = map[string]string{
+	"title":       "a",
+	"description": "b",
+	"content":     "c",
+}
+
+// postgresSearchVector is the expression computing an article search_vector
+// from its title, description, content and author/source/categories text
+const postgresSearchVector = `setweight(to_tsvector('simple', ?), 'A') ||
+	setweight(to_tsvector('simple', ?), 'B') ||
+	setweight(to_tsvector('simple', ?), 'C') ||
+	setweight(to_tsvector('simple', ?), 'D')`
+
+// postgresSearchVectorArgs returns the postgresSearchVector arguments for an article
+func postgresSearchVectorArgs(article models.Article, content string) []interface{} {
+	other := strings.Join(append([]string{article.Author, article.Source}, article.Categories...), " ")
+	return []interface{}{article.Title, article.Description, content, other}
+}
+
+// postgresTSQuery returns the tsquery expression and argument matching a $search term
+func postgresTSQuery(expr *odata.SearchExpression) (string, interface{}) {
+	switch {
+	case expr.Prefix:
+		// Quote the lexeme so to_tsquery operators in the term are taken literally
+		term := strings.ReplaceAll(expr.Term, `\`, `\\`)
+		term = strings.ReplaceAll(term, "'", "''")
+		return "to_tsquery('simple', ?)", "'" + term + "':*"
+	case expr.Phrase || strings.ContainsAny(expr.Term, " \t"):
+		return "phraseto_tsquery('simple', ?)", expr.Term
+	default:
+		return "plainto_tsquery('simple', ?)", expr.Term
+	}
+}
+
+// buildPostgresSearchClause translates a $search expression into a condition
+// on the search_vector of the articles table referenced by alias
+func buildPostgresSearchClause(expr *odata.SearchExpression, alias string) (string, []interface{}) {
+	if expr == nil {
+		return "TRUE", nil
+	}
+
+	switch expr.Operator {
+	case "and", "or":
+		left, leftArgs := buildPostgresSearchClause(expr.Left, alias)
+		right, rightArgs := buildPostgresSearchClause(expr.Right, alias)
+		return "(" + left + " " + strings.ToUpper(expr.Operator) + " " + right + ")", append(leftArgs, rightArgs...)
+	case "not":
+		operand, args := buildPostgresSearchClause(expr.Left, alias)
+		return "NOT " + operand, args
+	}
+
+	if expr.Field == "" {
+		tsQuery, arg := postgresTSQuery(expr)
+		return "(" + alias + ".search_vector @@ " + tsQuery + ")", []interface{}{arg}
+	}
+
+	if weight, ok := postgresSearchWeights[expr.Field]; ok {
+		tsQuery, arg := postgresTSQuery(expr)
+		return "(ts_filter(" + alias + ".search_vector, '{" + weight + "}') @@ " + tsQuery + ")", []interface{}{arg}
+	}
+
+	column := alias + "." + searchColumns[expr.Field]
+	if expr.Field == "categories" {
+		column += "::text"
+	}
+	if expr.Prefix {
+		return prefixLikePatterns(column, "ILIKE", expr.Term)
+	}
+	return "(" + column + " ILIKE ? ESCAPE '\\')", []interface{}{"%" + escapeLike(expr.Term) + "%"}
+}
+
+// buildPostgresArticleConditions is the PostgreSQL counterpart of
+// buildArticleConditions
+func buildPostgresArticleConditions(query *models.ODataQuery, alias string) (string, []interface{}, error) {
+	var conditions string
+	var args []interface{}
+
+	searchExpr, err := parseSearch(query.Search)
+	if err != nil {
+		return "", nil, err
+	}
+	if searchExpr != nil {
+		searchClause, searchArgs := buildPostgresSearchClause(searchExpr, alias)
+		conditions += " AND " + searchClause
+		args = append(args, searchArgs...)
+	}
+
+	if query.Filter != "" {
+		// Compressed content cannot be read by PostgreSQL, so it is matched
+		// through the content words kept in search_vector instead
+		conditions += " AND (" + alias + ".title ILIKE ? OR " + alias + ".description ILIKE ? OR " + alias + ".content ILIKE ? OR " + alias + ".author ILIKE ?" +
+			" OR (" + alias + ".content = '' AND ts_filter(" + alias + ".search_vector, '{c}') @@ phraseto_tsquery('simple', ?)))"
+		args = append(args, "%"+query.Filter+"%", "%"+query.Filter+"%", "%"+query.Filter+"%", "%"+query.Filter+"%", query.Filter)
+	}
+
+	if query.DateFrom != nil {
+		conditions += " AND " + alias + ".published_at >= ?"
+		args = append(args, *query.DateFrom)
+	}
+	if query.DateTo != nil {
+		conditions += " AND " + alias + ".published_at <= ?"
+		args = append(args, *query.DateTo)
+	}
+
+	if query.Source != "" {
+		conditions += " AND " + alias + ".source ILIKE ? ESCAPE '\\'"
+		args = append(args, "%"+escapeLike(query.Source)+"%")
+	}
+	if query.Author != "" {
+		conditions += " AND " + alias + ".author ILIKE ? ESCAPE '\\'"
+		args = append(args, "%"+escapeLike(query.Author)+"%")
+	}
+	if query.Category != "" {
+		conditions += " AND " + alias + ".categories::text ILIKE ? ESCAPE '\\'"
+		args = append(args, "%"+escapeLike(query.Category)+"%")
+	}
+	if query.Pinned != nil {
+		conditions += " AND " + alias + ".pinned = ?"
+		args = append(args, *query.Pinned)
+	}
+	if query.FeedURL != "" {
+		conditions += feedURLCondition(alias)
+		args = append(args, query.FeedURL)
+	}
+
+	return conditions, args, nil
+}
+
+// buildPostgresClusterCondition is the PostgreSQL counterpart of buildClusterCondition
+func buildPostgresClusterCondition(query *models.ODataQuery, topic string) (string, []interface{}, error) {
+	conditions, args, err := buildPostgresArticleConditions(query, "o")
+	if err != nil {
+		return "", nil, err
+	}
+
+	if topic != "" {
+		conditions += " AND o.article_id IN (SELECT ot.article_id FROM article_topics ot JOIN topics otn ON ot.topic_id = otn.id WHERE otn.name = ?)"
+		args = append(args, topic)
+	}
+
+	condition := ` AND NOT EXISTS (
+		SELECT 1 FROM article_clusters oc
+		JOIN articles o ON o.article_id = oc.article_id
+		WHERE oc.cluster_id = ac.cluster_id
+			AND (o.published_at > a.published_at OR (o.published_at = a.published_at AND o.article_id > a.article_id))` +
+		conditions + `)`
+
+	return condition, args, nil
+}
+
+// postgresOrderBy maps a $orderby value to an ORDER BY clause on alias "a",
+// with article_id as tie breaker so pages are stable
+func postgresOrderBy(orderBy string) string {
+	switch strings.ToLower(strings.Join(strings.Fields(orderBy), " ")) {
+	case "title asc":
+		return "a.title ASC, a.article_id ASC"
+	case "title desc":
+		return "a.title DESC, a.article_id DESC"
+	case "author asc":
+		return "a.author ASC, a.article_id ASC"
+	case "author desc":
+		return "a.author DESC, a.article_id DESC"
+	case "source asc":
+		return "a.source ASC, a.article_id ASC"
+	case "source desc":
+		return "a.source DESC, a.article_id DESC"
+	case "published_at asc":
+		return "a.published_at ASC, a.article_id ASC"
+	default:
+		return keysetOrder("a")
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"testing"
+
+	"gorssag/internal/config"
+	"gorssag/internal/models"
+)
+
+func TestNewPostgresStorage_RequiresDSN(t *testing.T) {
+	if _, err := NewStorage(t.TempDir(), &config.Config{StorageDriver: "postgres"}); err == nil {
+		t.Error("Expected error without a database URL")
+	}
+	if _, err := NewStorage(t.TempDir(), &config.Config{StorageDriver: "oracle"}); err == nil {
+		t.Error("Expected error for an unknown storage driver")
+	}
+}
+
+func TestRebindPostgres(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT * FROM a WHERE x = ? AND y = ?", "SELECT * FROM a WHERE x = $1 AND y = $2"},
+		{"SELECT '?' WHERE x = ? AND y LIKE 'it''s?'", "SELECT '?' WHERE x = $1 AND y LIKE 'it''s?'"},
+	}
+
+	for _, tt := range tests {
+		if got := rebindPostgres(tt.query); got != tt.expected {
+			t.Errorf("rebindPostgres(%q) = %q, want %q", tt.query, got, tt.expected)
+		}
+	}
+}
+
+func TestBuildPostgresSearchClause(t *testing.T) {
+	tests := []struct {
+		search   string
+		expected string
+		args     []interface{}
+	}{
+		{"rust", "(a.search_vector @@ plainto_tsquery('simple', ?))", []interface{}{"rust"}},
+		{`"zero day"`, "(a.search_vector @@ phraseto_tsquery('simple', ?))", []interface{}{"zero day"}},
+		{"kube*", "(a.search_vector @@ to_tsquery('simple', ?))", []interface{}{"'kube':*"}},
+		{"title:rust", "(ts_filter(a.search_vector, '{a}') @@ plainto_tsquery('simple', ?))", []interface{}{"rust"}},
+		{"author:carol", "(a.author ILIKE ? ESCAPE '\\')", []interface{}{"%carol%"}},
+	}
+
+	for _, tt := range tests {
+		expr, err := parseSearch([]string{tt.search})
+		if err != nil {
+			t.Fatalf("parseSearch(%q) error = %v", tt.search, err)
+		}
+		clause, args := buildPostgresSearchClause(expr, "a")
+		if clause != tt.expected {
+			t.Errorf("buildPostgresSearchClause(%q) = %q, want %q", tt.search, clause, tt.expected)
+		}
+		if len(args) != len(tt.args) || args[0] != tt.args[0] {
+			t.Errorf("buildPostgresSearchClause(%q) args = %v, want %v", tt.search, args, tt.args)
+		}
+	}
+
+	if _, _, err := buildPostgresArticleConditions(&models.ODataQuery{Search: []string{"("}}, "a"); err == nil {
+		t.Error("Expected error for invalid search expression")
+	}
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// queryer is the subset of *sql.DB and *sql.Tx used by the helpers shared
+// between the SQL backends. Queries are written with ? placeholders.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// rebindPostgres rewrites ? placeholders to PostgreSQL's $1, $2, ...
+// Question marks inside single quoted literals are left untouched.
+func rebindPostgres(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+
+	n := 0
+	inLiteral := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inLiteral = !inLiteral
+		case r == '?' && !inLiteral:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// postgresDB runs ? placeholder queries against PostgreSQL
+type postgresDB struct {
+	*sql.DB
+}
+
+func (d postgresDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.DB.ExecContext(ctx, rebindPostgres(query), args...)
+}
+
+func (d postgresDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.DB.QueryContext(ctx, rebindPostgres(query), args...)
+}
+
+func (d postgresDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.DB.QueryRowContext(ctx, rebindPostgres(query), args...)
+}
+
+// BeginTx starts a transaction that also accepts ? placeholders
+func (d postgresDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (postgresTx, error) {
+	tx, err := d.DB.BeginTx(ctx, opts)
+	return postgresTx{tx}, err
+}
+
+// postgresTx runs ? placeholder queries inside a PostgreSQL transaction
+type postgresTx struct {
+	*sql.Tx
+}
+
+func (t postgresTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, rebindPostgres(query), args...)
+}
+
+func (t postgresTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.Tx.QueryContext(ctx, rebindPostgres(query), args...)
+}
+
+func (t postgresTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.Tx.QueryRowContext(ctx, rebindPostgres(query), args...)
+}
+
+func (t postgresTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.Tx.PrepareContext(ctx, rebindPostgres(query))
+}
@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorssag/internal/models"
+)
+
+// readerStateColumns maps the item states to their article_states column
+var readerStateColumns = map[string]string{
+	models.ReaderStateRead:    "read_at",
+	models.ReaderStateStarred: "starred_at",
+}
+
+// Time comparisons of the backends, formatted with the column and operator
+const (
+	sqliteTimeComparison   = "julianday(%s) %s julianday(?)" // julianday() normalises the stored offsets, keeping fractional seconds
+	postgresTimeComparison = "%s %s ?"
+)
+
+// getSQLReaderItemRefs returns the items of a Google Reader stream. Item IDs
+// are the articles row IDs, which grow in storage order.
+func getSQLReaderItemRefs(ctx context.Context, q queryer, timeComparison string, query *models.ReaderQuery) ([]models.ReaderItemRef, error) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if query.Topic != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM article_topics at JOIN topics t ON t.id = at.topic_id
+			WHERE at.article_id = a.article_id AND t.name = ?)`)
+		args = append(args, query.Topic)
+	}
+	if query.FeedURL != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM article_feeds af JOIN feeds f ON f.id = af.feed_id
+			WHERE af.article_id = a.article_id AND f.url = ?)`)
+		args = append(args, query.FeedURL)
+	}
+	if query.Read {
+		conditions = append(conditions, "s.read_at IS NOT NULL")
+	}
+	if query.Unread {
+		conditions = append(conditions, "s.read_at IS NULL")
+	}
+	if query.Starred {
+		conditions = append(conditions, "s.starred_at IS NOT NULL")
+	}
+	if !query.Since.IsZero() {
+		conditions = append(conditions, fmt.Sprintf(timeComparison, "a.created_at", ">="))
+		args = append(args, query.Since.UTC().Format(time.RFC3339Nano))
+	}
+	if !query.Until.IsZero() {
+		conditions = append(conditions, fmt.Sprintf(timeComparison, "a.created_at", "<"))
+		args = append(args, query.Until.UTC().Format(time.RFC3339Nano))
+	}
+
+	order := "DESC"
+	if query.Oldest {
+		order = "ASC"
+	}
+	args = append(args, query.Limit, query.Offset)
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT a.id, a.created_at
+		FROM articles a
+		LEFT JOIN article_states s ON s.article_id = a.article_id
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY a.id `+order+`
+		LIMIT ? OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reader items: %v", err)
+	}
+	defer rows.Close()
+
+	refs := []models.ReaderItemRef{}
+	for rows.Next() {
+		var ref models.ReaderItemRef
+		if err := rows.Scan(&ref.ID, &ref.CrawledAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reader item: %v", err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// getSQLReaderItems loads items by ID with their article, topics and state,
+// in the requested order, skipping unknown IDs
+func getSQLReaderItems(ctx context.Context, q queryer, ids []int64) ([]models.ReaderItem, error) {
+	if len(ids) == 0 {
+		return []models.ReaderItem{}, nil
+	}
+
+	placeholders, args := int64Placeholders(ids)
+	rows, err := q.QueryContext(ctx, `
+		SELECT a.id, a.article_id, a.created_at, s.read_at IS NOT NULL, s.starred_at IS NOT NULL
+		FROM articles a
+		LEFT JOIN article_states s ON s.article_id = a.article_id
+		WHERE a.id IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reader items: %v", err)
+	}
+
+	byID := make(map[int64]*models.ReaderItem, len(ids))
+	articleIDs := make([]string, 0, len(ids))
+	for rows.Next() {
+		var item models.ReaderItem
+		if err := rows.Scan(&item.ID, &item.Article.ID, &item.CrawledAt, &item.Read, &item.Starred); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan reader item: %v", err)
+		}
+		byID[item.ID] = &item
+		articleIDs = append(articleIDs, item.Article.ID)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	articles, err := getSQLArticles(ctx, q, articleIDs)
+	if err != nil {
+		return nil, err
+	}
+	topics, err := getSQLArticlesTopics(ctx, q, articleIDs)
+	if err != nil {
+		return nil, err
+	}
+	byArticleID := make(map[string]models.Article, len(articles))
+	for _, article := range articles {
+		byArticleID[article.ID] = article
+	}
+
+	items := make([]models.ReaderItem, 0, len(byID))
+	for _, id := range ids {
+		item, ok := byID[id]
+		if !ok {
+			continue
+		}
+		article, ok := byArticleID[item.Article.ID]
+		if !ok {
+			continue // Deleted meanwhile
+		}
+		item.Article = article
+		item.Topics = topics[article.ID]
+		items = append(items, *item)
+		delete(byID, id) // Repeated IDs are returned once
+	}
+	return items, nil
+}
+
+// getSQLArticlesTopics returns the topics of articles by article ID
+func getSQLArticlesTopics(ctx context.Context, q queryer, articleIDs []string) (map[string][]string, error) {
+	topics := make(map[string][]string, len(articleIDs))
+	if len(articleIDs) == 0 {
+		return topics, nil
+	}
+
+	placeholders := make([]string, len(articleIDs))
+	args := make([]interface{}, len(articleIDs))
+	for i, id := range articleIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT at.article_id, t.name
+		FROM article_topics at
+		JOIN topics t ON t.id = at.topic_id
+		WHERE at.article_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY at.id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query article topics: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var articleID, topic string
+		if err := rows.Scan(&articleID, &topic); err != nil {
+			return nil, fmt.Errorf("failed to scan article topic: %v", err)
+		}
+		topics[articleID] = append(topics[articleID], topic)
+	}
+	return topics, rows.Err()
+}
+
+// setSQLReaderItemState sets or clears a state of items, keeping the time
+// an item first got it. Unknown IDs are ignored.
+func setSQLReaderItemState(ctx context.Context, q queryer, ids []int64, state string, on bool) error {
+	column, ok := readerStateColumns[state]
+	if !ok {
+		return fmt.Errorf("unknown item state %q", state)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	placeholders, args := int64Placeholders(ids)
+	var err error
+	if on {
+		_, err = q.ExecContext(ctx, `
+			INSERT INTO article_states (article_id, `+column+`)
+			SELECT article_id, CURRENT_TIMESTAMP FROM articles WHERE id IN (`+placeholders+`)
+			ON CONFLICT (article_id) DO UPDATE SET `+column+` = COALESCE(article_states.`+column+`, excluded.`+column+`)
+		`, args...)
+	} else {
+		_, err = q.ExecContext(ctx, `
+			UPDATE article_states SET `+column+` = NULL
+			WHERE article_id IN (SELECT article_id FROM articles WHERE id IN (`+placeholders+`))
+		`, args...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update %s state: %v", state, err)
+	}
+	return nil
+}
+
+// int64Placeholders returns the placeholders and arguments of an IN list
+func int64Placeholders(ids []int64) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+// GetReaderItemRefs returns the items of a Google Reader stream
+func (s *SQLiteStorage) GetReaderItemRefs(ctx context.Context, query *models.ReaderQuery) ([]models.ReaderItemRef, error) {
+	return getSQLReaderItemRefs(ctx, s.db, sqliteTimeComparison, query)
+}
+
+// GetReaderItems returns items by ID with their article, topics and state
+func (s *SQLiteStorage) GetReaderItems(ctx context.Context, ids []int64) ([]models.ReaderItem, error) {
+	return getSQLReaderItems(ctx, s.db, ids)
+}
+
+// SetReaderItemState sets or clears the read or starred state of items
+func (s *SQLiteStorage) SetReaderItemState(ctx context.Context, ids []int64, state string, on bool) error {
+	return setSQLReaderItemState(ctx, s.db, ids, state, on)
+}
+
+// GetReaderItemRefs returns the items of a Google Reader stream
+func (s *PostgresStorage) GetReaderItemRefs(ctx context.Context, query *models.ReaderQuery) ([]models.ReaderItemRef, error) {
+	return getSQLReaderItemRefs(ctx, s.db, postgresTimeComparison, query)
+}
+
+// GetReaderItems returns items by ID with their article, topics and state
+func (s *PostgresStorage) GetReaderItems(ctx context.Context, ids []int64) ([]models.ReaderItem, error) {
+	return getSQLReaderItems(ctx, s.db, ids)
+}
+
+// SetReaderItemState sets or clears the read or starred state of items
+func (s *PostgresStorage) SetReaderItemState(ctx context.Context, ids []int64, state string, on bool) error {
+	return setSQLReaderItemState(ctx, s.db, ids, state, on)
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorssag/internal/models"
+)
+
+// relatedFields are the indexed fields compared for "more like this"; author
+// and source are left out so related coverage is not just the same outlet
+const relatedFields = "'title', 'description', 'content'"
+
+// relatedArticlesQuery scores the articles sharing terms or categories with
+// the source article, found through the term and category indexes from the
+// source article's own ones. Each shared term or category weighs 1/df (df =
+// number of articles containing it), so rare terms count most.
+const relatedArticlesQuery = `
+	WITH source_terms AS (
+		SELECT DISTINCT search_term
+		FROM search_index
+		WHERE article_id = ? AND field_type IN (` + relatedFields + `)
+	),
+	terms AS (
+		SELECT DISTINCT si.article_id, si.search_term
+		FROM source_terms st
+		JOIN search_index si ON si.search_term = st.search_term
+		WHERE si.field_type IN (` + relatedFields + `)
+	),
+	categories AS (
+		SELECT ac.article_id, ac.category
+		FROM article_categories ac
+		WHERE ac.category IN (SELECT category FROM article_categories WHERE article_id = ?)
+	),
+	term_weights AS (
+		SELECT search_term, 1.0 / COUNT(*) AS weight
+		FROM terms
+		GROUP BY search_term
+	),
+	category_weights AS (
+		SELECT category, 1.0 / COUNT(*) AS weight
+		FROM categories
+		GROUP BY category
+	),
+	scores AS (
+		SELECT t.article_id, tw.weight AS score
+		FROM terms t JOIN term_weights tw ON t.search_term = tw.search_term
+		UNION ALL
+		SELECT c.article_id, cw.weight AS score
+		FROM categories c JOIN category_weights cw ON c.category = cw.category
+	)
+	SELECT s.article_id
+	FROM scores s
+	JOIN articles a ON a.article_id = s.article_id
+	WHERE s.article_id != ?
+	GROUP BY s.article_id
+	ORDER BY SUM(s.score) DESC, a.published_at DESC
+	LIMIT ?
+`
+
+// GetRelatedArticles returns up to limit articles similar to the given one,
+// across all topics, most similar first
+func (s *SQLiteStorage) GetRelatedArticles(ctx context.Context, articleID string, limit int) ([]models.Article, error) {
+	var exists int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM articles WHERE article_id = ?", articleID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up article: %v", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, relatedArticlesQuery, articleID, articleID, articleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query related articles: %v", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan related article: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	found, err := getArticlesByIDs(ctx, s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the similarity order
+	related := make([]models.Article, 0, len(ids))
+	for _, id := range ids {
+		if article, ok := found[id]; ok {
+			related = append(related, article)
+		}
+	}
+
+	return related, nil
+}
+
+// getArticlesByIDs loads full articles (with decompressed content) keyed by ID
+func getArticlesByIDs(ctx context.Context, db queryer, ids []string) (map[string]models.Article, error) {
+	articles := make(map[string]models.Article, len(ids))
+	if len(ids) == 0 {
+		return articles, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			a.article_id,
+			a.title,
+			a.description,
+			a.content,
+			a.link,
+			a.author,
+			a.source,
+			a.published_at,
+			a.categories,
+			a.language,
+			a.pinned,
+			a.content_updated_at,
+			(SELECT t.name FROM article_topics at JOIN topics t ON at.topic_id = t.id WHERE at.article_id = a.article_id ORDER BY at.id LIMIT 1) as topic,
+			cc.codec,
+			cc.compressed_content,
+			COALESCE(ac.cluster_id, a.article_id) as cluster_id
+		FROM articles a
+		LEFT JOIN compressed_content cc ON a.article_id = cc.article_id
+		LEFT JOIN article_clusters ac ON a.article_id = ac.article_id
+		WHERE a.article_id IN (`+strings.Join(placeholders, ",")+`)
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query articles: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var article models.Article
+		var categoriesJSON, language, topic, codec, compressedContent sql.NullString
+
+		if err := rows.Scan(
+			&article.ID,
+			&article.Title,
+			&article.Description,
+			&article.Content,
+			&article.Link,
+			&article.Author,
+			&article.Source,
+			&article.PublishedAt,
+			&categoriesJSON,
+			&language,
+			&article.Pinned,
+			&article.UpdatedAt,
+			&topic,
+			&codec,
+			&compressedContent,
+			&article.ClusterID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan article: %v", err)
+		}
+
+		if article.Content == "" && compressedContent.Valid {
+			decompressed, err := decompressContent(codec.String, []byte(compressedContent.String))
+			if err != nil {
+				log.Printf("Warning: failed to decompress content for article %s: %v", article.ID, err)
+				article.Content = "Content unavailable (decompression failed)"
+			} else {
+				article.Content = decompressed
+			}
+		}
+
+		if categoriesJSON.Valid {
+			if err := json.Unmarshal([]byte(categoriesJSON.String), &article.Categories); err != nil {
+				log.Printf("Warning: failed to parse categories for article %s: %v", article.ID, err)
+				article.Categories = []string{}
+			}
+		}
+
+		article.Language = "en"
+		if language.Valid {
+			article.Language = language.String
+		}
+		article.Topic = topic.String
+
+		articles[article.ID] = article
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	return articles, nil
+}
@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorssag/internal/config"
+	"gorssag/internal/models"
+)
+
+// RetentionPolicy decides which articles CleanupOldArticles deletes. An
+// article is kept while at least one of its topics retains it: it is within
+// the topic age limit and among the topic's newest MaxCount articles.
+// Articles without topic only follow MaxAge. MaxPerFeed then drops the
+// oldest articles of each polled feed beyond the limit, whatever their topics;
+// an article polled from several feeds is kept while one of them retains it,
+// and articles linked to no feed are counted by source. Pinned articles are
+// always kept and do not count towards any limit.
+// Expired articles are moved to Archive when set instead of being dropped.
+type RetentionPolicy struct {
+	MaxAge      time.Duration                    // Default age limit, 0 keeps articles of any age
+	Topics      map[string]config.TopicRetention // Per-topic overrides of MaxAge and count limits
+	MaxPerFeed  int                              // Newest articles kept per feed, 0 for no limit
+	ByIngestion bool                             // Age articles by when they were stored rather than published
+	Archive     *Archive                         // Cold tier receiving expired articles, nil to delete them
+}
+
+// NewRetentionPolicy returns the retention policy configured in cfg
+func NewRetentionPolicy(cfg *config.Config) RetentionPolicy {
+	if cfg == nil {
+		return RetentionPolicy{}
+	}
+	policy := RetentionPolicy{
+		MaxAge:      cfg.ArticleRetention,
+		Topics:      cfg.TopicRetention,
+		MaxPerFeed:  cfg.MaxArticlesPerFeed,
+		ByIngestion: cfg.RetentionByIngestion,
+	}
+	if cfg.ArchiveExpiredArticles {
+		policy.Archive = NewArchive(ArchiveDir(cfg.DataDir))
+	}
+	return policy
+}
+
+// retentionCandidate is an article as seen by the retention policy
+type retentionCandidate struct {
+	id     string
+	feeds  []string  // Feed URLs the article was polled from, or its source when none
+	at     time.Time // Publication or ingestion time, depending on the policy
+	topics []string
+	pinned bool // Pinned or starred, never deleted
+}
+
+// expiredArticles returns the IDs of the candidates the policy deletes
+func (p RetentionPolicy) expiredArticles(candidates []retentionCandidate, now time.Time) []string {
+	// Newest first, so counts rank articles by recency
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].at.Equal(candidates[j].at) {
+			return candidates[i].at.After(candidates[j].at)
+		}
+		return candidates[i].id > candidates[j].id
+	})
+
+	topicCounts := make(map[string]int)
+	feedCounts := make(map[string]int)
+	var expired []string
+
+	for _, candidate := range candidates {
+		if candidate.pinned {
+			continue
+		}
+
+		retained := len(candidate.topics) == 0 && p.withinAge(candidate.at, p.MaxAge, now)
+		for _, topic := range candidate.topics {
+			limits := p.Topics[topic]
+			maxAge := p.MaxAge
+			if limits.MaxAge > 0 {
+				maxAge = limits.MaxAge
+			}
+			if !p.withinAge(candidate.at, maxAge, now) {
+				continue
+			}
+
+			// Every topic counts the article, even when another one already retains it
+			topicCounts[topic]++
+			if limits.MaxCount <= 0 || topicCounts[topic] <= limits.MaxCount {
+				retained = true
+			}
+		}
+
+		if retained && p.MaxPerFeed > 0 {
+			retained = false
+			for _, feed := range candidate.feeds {
+				feedCounts[feed]++
+				if feedCounts[feed] <= p.MaxPerFeed {
+					retained = true
+				}
+			}
+		}
+
+		if !retained {
+			expired = append(expired, candidate.id)
+		}
+	}
+
+	return expired
+}
+
+// withinAge reports whether a timestamp is within maxAge of now, 0 meaning no limit
+func (p RetentionPolicy) withinAge(at time.Time, maxAge time.Duration, now time.Time) bool {
+	return maxAge <= 0 || !at.Before(now.Add(-maxAge))
+}
+
+// retentionDeleteBatch bounds the number of IDs per DELETE statement
+const retentionDeleteBatch = 500
+
+// cleanupSQLArticles applies a retention policy to a SQL backend and returns
+// the number of deleted articles, with the deleted articles to archive when
+// the policy has an archive. timeExpression wraps a timestamp so that the
+// backend compares and orders it as a time. Memberships and side tables
+// follow through their ON DELETE CASCADE foreign keys.
+func cleanupSQLArticles(ctx context.Context, q queryer, policy RetentionPolicy, timeExpression func(string) string) (int, []models.ExportedArticle, error) {
+	query, args := policy.expiredArticlesQuery(time.Now(), timeExpression)
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to query articles for retention: %v", err)
+	}
+	var expired []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("failed to scan article for retention: %v", err)
+		}
+		expired = append(expired, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("failed to read articles for retention: %v", err)
+	}
+
+	var archived []models.ExportedArticle
+
+	for start := 0; start < len(expired); start += retentionDeleteBatch {
+		end := start + retentionDeleteBatch
+		if end > len(expired) {
+			end = len(expired)
+		}
+
+		// Read the articles before deleting them, commitCleanup archives them
+		if policy.Archive != nil {
+			found, err := getArticlesByIDs(ctx, q, expired[start:end])
+			if err != nil {
+				return 0, nil, err
+			}
+			topics, err := getSQLArticlesTopics(ctx, q, expired[start:end])
+			if err != nil {
+				return 0, nil, err
+			}
+			for _, id := range expired[start:end] {
+				if article, ok := found[id]; ok {
+					archived = append(archived, models.ExportedArticle{Article: article, Topics: topics[id]})
+				}
+			}
+		}
+
+		placeholders := make([]string, end-start)
+		args := make([]interface{}, end-start)
+		for i, id := range expired[start:end] {
+			placeholders[i] = "?"
+			args[i] = id
+		}
+		if _, err := q.ExecContext(ctx, "DELETE FROM articles WHERE article_id IN ("+strings.Join(placeholders, ",")+")", args...); err != nil {
+			return 0, nil, fmt.Errorf("failed to delete old articles: %v", err)
+		}
+	}
+
+	return len(expired), archived, nil
+}
+
+// committer is the Commit of a SQL transaction
+type committer interface {
+	Commit() error
+}
+
+// commitCleanup commits the deletions of cleanupSQLArticles, then stores the
+// deleted articles in the policy archive. A failed commit leaves them in the
+// database only, never in both places.
+func commitCleanup(ctx context.Context, tx committer, policy RetentionPolicy, archived []models.ExportedArticle) error {
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit cleanup: %v", err)
+	}
+	if policy.Archive != nil {
+		if err := policy.Archive.Store(ctx, archived); err != nil {
+			return fmt.Errorf("failed to archive %d deleted articles: %v", len(archived), err)
+		}
+	}
+	return nil
+}
+
+// expiredArticlesQuery returns the query listing the IDs of the articles the
+// policy deletes, the SQL counterpart of expiredArticles. Topic memberships
+// within their age limit are ranked per topic and feeds per feed, so only the
+// expired IDs leave the database.
+func (p RetentionPolicy) expiredArticlesQuery(now time.Time, timeExpression func(string) string) (string, []interface{}) {
+	at := timeExpression("a.published_at")
+	if p.ByIngestion {
+		at = timeExpression("a.created_at")
+	}
+	kept := "(a.pinned OR " + fmt.Sprintf(sqlStarred, "a") + ")"
+
+	withinAge := func(maxAge time.Duration) (string, []interface{}) {
+		if maxAge <= 0 {
+			return "1=1", nil
+		}
+		return at + " >= " + timeExpression("?"), []interface{}{now.Add(-maxAge).UTC()}
+	}
+
+	topics := make([]string, 0, len(p.Topics))
+	for topic := range p.Topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	// Age limit of the membership topic, the default one unless overridden
+	defaultAge, defaultAgeArgs := withinAge(p.MaxAge)
+	topicAge, topicCount := defaultAge, "1=1"
+	var topicAgeArgs, topicCountArgs []interface{}
+	var ageCases, countCases []string
+	for _, topic := range topics {
+		limits := p.Topics[topic]
+		if limits.MaxAge > 0 {
+			condition, args := withinAge(limits.MaxAge)
+			ageCases = append(ageCases, "WHEN ? THEN "+condition)
+			topicAgeArgs = append(append(topicAgeArgs, topic), args...)
+		}
+		if limits.MaxCount > 0 {
+			countCases = append(countCases, fmt.Sprintf("WHEN ? THEN topic_rank <= %d", limits.MaxCount))
+			topicCountArgs = append(topicCountArgs, topic)
+		}
+	}
+	if len(ageCases) > 0 {
+		topicAge = "CASE t.name " + strings.Join(ageCases, " ") + " ELSE " + defaultAge + " END"
+		topicAgeArgs = append(topicAgeArgs, defaultAgeArgs...)
+	} else {
+		topicAgeArgs = defaultAgeArgs
+	}
+	if len(countCases) > 0 {
+		topicCount = "CASE name " + strings.Join(countCases, " ") + " ELSE 1=1 END"
+	}
+
+	query := `
+		WITH memberships AS (
+			SELECT at.article_id, t.name,
+				ROW_NUMBER() OVER (PARTITION BY at.topic_id ORDER BY ` + at + ` DESC, a.article_id DESC) AS topic_rank
+			FROM article_topics at
+			JOIN topics t ON t.id = at.topic_id
+			JOIN articles a ON a.article_id = at.article_id
+			WHERE NOT ` + kept + ` AND ` + topicAge + `
+		),
+		retained AS (
+			SELECT article_id FROM memberships WHERE ` + topicCount + `
+			UNION
+			SELECT a.article_id FROM articles a
+			WHERE NOT ` + kept + `
+				AND NOT EXISTS (SELECT 1 FROM article_topics at WHERE at.article_id = a.article_id)
+				AND ` + defaultAge + `
+		)`
+	args := append(append(topicAgeArgs, topicCountArgs...), defaultAgeArgs...)
+	expired := "a.article_id NOT IN (SELECT article_id FROM retained)"
+
+	if p.MaxPerFeed > 0 {
+		// Articles linked to no feed are counted by source
+		query += `,
+		feed_ranks AS (
+			SELECT r.article_id,
+				ROW_NUMBER() OVER (PARTITION BY af.feed_id, CASE WHEN af.feed_id IS NULL THEN a.source END ORDER BY ` + at + ` DESC, a.article_id DESC) AS feed_rank
+			FROM retained r
+			JOIN articles a ON a.article_id = r.article_id
+			LEFT JOIN article_feeds af ON af.article_id = r.article_id
+		)`
+		expired = fmt.Sprintf("(%s OR a.article_id IN (SELECT article_id FROM feed_ranks GROUP BY article_id HAVING MIN(feed_rank) > %d))", expired, p.MaxPerFeed)
+	}
+
+	query += `
+		SELECT a.article_id FROM articles a
+		WHERE NOT ` + kept + ` AND ` + expired
+	return query, args
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorssag/internal/models"
+)
+
+// failingCommitter is a transaction whose commit fails
+type failingCommitter struct{}
+
+func (failingCommitter) Commit() error {
+	return errors.New("database is locked")
+}
+
+// committed is a transaction whose commit succeeds
+type committed struct{}
+
+func (committed) Commit() error {
+	return nil
+}
+
+func TestCommitCleanup_ArchivesOnlyCommittedDeletions(t *testing.T) {
+	ctx := context.Background()
+	archive := NewArchive(t.TempDir())
+	policy := RetentionPolicy{Archive: archive}
+	expired := []models.ExportedArticle{{Article: models.Article{ID: "expired", Title: "Expired", Link: "https://example.com/expired", PublishedAt: time.Now().AddDate(0, -2, 0)}, Topics: []string{"tech"}}}
+
+	// A failed commit keeps the articles in the database, out of the archive
+	if err := commitCleanup(ctx, failingCommitter{}, policy, expired); err == nil {
+		t.Fatal("commitCleanup() error = nil, want the commit error")
+	}
+	if files, err := archive.files(); err != nil || len(files) != 0 {
+		t.Fatalf("Expected no archive file after a failed commit, got %v (err %v)", files, err)
+	}
+
+	if err := commitCleanup(ctx, committed{}, policy, expired); err != nil {
+		t.Fatalf("commitCleanup() error = %v", err)
+	}
+	archived, total, err := archive.Query(ctx, "", &models.ODataQuery{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if total != 1 || archived[0].ID != "expired" {
+		t.Errorf("Expected the committed deletion to be archived, got %v", archived)
+	}
+}
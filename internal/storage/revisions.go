@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorssag/internal/models"
+)
+
+// maxDiffCells bounds the line diff table; larger texts are diffed as a whole
+const maxDiffCells = 1 << 20
+
+// revisionHash identifies the title, description and content of an article version
+func revisionHash(title, description, content string) string {
+	sum := sha256.Sum256([]byte(title + "\x00" + description + "\x00" + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// revisionDiff returns a line diff of the fields changed between two
+// versions, each introduced by an "@@ field" header
+func revisionDiff(before, after models.Article) string {
+	var diff strings.Builder
+	for _, field := range []struct{ name, before, after string }{
+		{"title", before.Title, after.Title},
+		{"description", before.Description, after.Description},
+		{"content", before.Content, after.Content},
+	} {
+		if field.before == field.after {
+			continue
+		}
+		diff.WriteString("@@ " + field.name + "\n")
+		diff.WriteString(lineDiff(field.before, field.after))
+	}
+	return diff.String()
+}
+
+// lineDiff returns a line diff from before to after: kept lines are prefixed
+// with " ", removed ones with "-" and added ones with "+"
+func lineDiff(before, after string) string {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	var diff strings.Builder
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			diff.WriteString("-" + line + "\n")
+		}
+		for _, line := range b {
+			diff.WriteString("+" + line + "\n")
+		}
+		return diff.String()
+	}
+
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			diff.WriteString(" " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			diff.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+	return diff.String()
+}
+
+// recordSQLRevision compares an article about to be saved, with its cleaned
+// content, to the stored version. When the text changed, the previous version
+// is recorded in article_revisions. It returns the hash to store and the
+// change time, nil for new or unchanged articles.
+func recordSQLRevision(ctx context.Context, q queryer, article models.Article, content string) (string, *time.Time, error) {
+	hash := revisionHash(article.Title, article.Description, content)
+
+	var previous models.Article
+	var storedHash, codec sql.NullString
+	var compressed []byte
+	err := q.QueryRowContext(ctx, `
+		SELECT a.title, COALESCE(a.description, ''), COALESCE(a.content, ''), a.content_hash, cc.codec, cc.compressed_content
+		FROM articles a
+		LEFT JOIN compressed_content cc ON a.article_id = cc.article_id
+		WHERE a.article_id = ?
+	`, article.ID).Scan(&previous.Title, &previous.Description, &previous.Content, &storedHash, &codec, &compressed)
+	if err == sql.ErrNoRows {
+		return hash, nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load previous version of article %s: %v", article.ID, err)
+	}
+
+	if previous.Content == "" && len(compressed) > 0 {
+		if previous.Content, err = decompressContent(codec.String, compressed); err != nil {
+			return "", nil, fmt.Errorf("failed to decompress previous version of article %s: %v", article.ID, err)
+		}
+	}
+
+	// Articles stored before revision tracking have no hash yet
+	previousHash := storedHash.String
+	if !storedHash.Valid {
+		previousHash = revisionHash(previous.Title, previous.Description, previous.Content)
+	}
+	if previousHash == hash {
+		return hash, nil, nil
+	}
+
+	current := article
+	current.Content = content
+	changedAt := time.Now().UTC()
+	if _, err := q.ExecContext(ctx, `
+		INSERT INTO article_revisions (article_id, revision, content_hash, title, diff, changed_at)
+		VALUES (?, (SELECT COUNT(*) + 1 FROM article_revisions WHERE article_id = ?), ?, ?, ?, ?)
+	`, article.ID, article.ID, previousHash, previous.Title, revisionDiff(previous, current), changedAt); err != nil {
+		return "", nil, fmt.Errorf("failed to record revision of article %s: %v", article.ID, err)
+	}
+	return hash, &changedAt, nil
+}
+
+// getSQLArticleRevisions returns the revisions of an article, oldest first
+func getSQLArticleRevisions(ctx context.Context, q queryer, articleID string) ([]models.ArticleRevision, error) {
+	var exists int
+	if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM articles WHERE article_id = ?", articleID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up article: %v", err)
+	}
+	if exists == 0 {
+		return nil, ErrNotFound
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT revision, content_hash, title, diff, changed_at
+		FROM article_revisions
+		WHERE article_id = ?
+		ORDER BY revision
+	`, articleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query article revisions: %v", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.ArticleRevision{}
+	for rows.Next() {
+		var revision models.ArticleRevision
+		if err := rows.Scan(&revision.Revision, &revision.ContentHash, &revision.Title, &revision.Diff, &revision.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan article revision: %v", err)
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// GetArticleRevisions returns the earlier versions of an article
+func (s *SQLiteStorage) GetArticleRevisions(ctx context.Context, articleID string) ([]models.ArticleRevision, error) {
+	return getSQLArticleRevisions(ctx, s.db, articleID)
+}
+
+// GetArticleRevisions returns the earlier versions of an article
+func (s *PostgresStorage) GetArticleRevisions(ctx context.Context, articleID string) ([]models.ArticleRevision, error) {
+	return getSQLArticleRevisions(ctx, s.db, articleID)
+}
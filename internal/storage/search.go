@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"gorssag/internal/odata"
+)
+
+// searchColumns maps $search field scopes to article columns
+var searchColumns = map[string]string{
+	"title":       "title",
+	"description": "description",
+	"content":     "content",
+	"author":      "author",
+	"source":      "source",
+	"categories":  "categories",
+}
+
+// parseSearch parses the $search expressions of a query (OR'ed together)
+func parseSearch(terms []string) (*odata.SearchExpression, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	expr, err := odata.NewSearchParser().ParseTerms(terms)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search expression: %v", err)
+	}
+	return expr, nil
+}
+
+// escapeLike escapes LIKE wildcards so search terms match literally (used with ESCAPE '\')
+func escapeLike(term string) string {
+	term = strings.ReplaceAll(term, `\`, `\\`)
+	term = strings.ReplaceAll(term, "%", `\%`)
+	term = strings.ReplaceAll(term, "_", `\_`)
+	return term
+}
+
+// prefixLikePatterns returns the condition matching a prefix term at the start
+// of column or after any odata.SearchWordSeparators character, with its arguments
+func prefixLikePatterns(column, operator, term string) (string, []interface{}) {
+	patterns := []string{column + " " + operator + " ? ESCAPE '\\'"}
+	args := []interface{}{escapeLike(term) + "%"}
+	for _, separator := range odata.SearchWordSeparators {
+		patterns = append(patterns, column+" "+operator+" ? ESCAPE '\\'")
+		args = append(args, "%"+string(separator)+escapeLike(term)+"%")
+	}
+	return "(" + strings.Join(patterns, " OR ") + ")", args
+}
+
+// buildSearchClause translates a $search expression into a LIKE based SQL
+// condition on the articles table referenced by alias
+func buildSearchClause(expr *odata.SearchExpression, alias string) (string, []interface{}) {
+	if expr == nil {
+		return "1=1", nil
+	}
+
+	switch expr.Operator {
+	case "and", "or":
+		left, leftArgs := buildSearchClause(expr.Left, alias)
+		right, rightArgs := buildSearchClause(expr.Right, alias)
+		return "(" + left + " " + strings.ToUpper(expr.Operator) + " " + right + ")", append(leftArgs, rightArgs...)
+	case "not":
+		operand, args := buildSearchClause(expr.Left, alias)
+		return "NOT " + operand, args
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, field := range odata.SearchFields {
+		if expr.Field != "" && expr.Field != field {
+			continue
+		}
+		column := "COALESCE(" + alias + "." + searchColumns[field] + ", '')"
+		if field == "content" {
+			column = sqliteContentExpression(alias)
+		}
+		if expr.Prefix {
+			// Match the prefix at the start of the field or of any word, like odata.MatchSearchTerm
+			patterns, prefixArgs := prefixLikePatterns(column, "LIKE", expr.Term)
+			conditions = append(conditions, patterns)
+			args = append(args, prefixArgs...)
+		} else {
+			conditions = append(conditions, column+" LIKE ? ESCAPE '\\'")
+			args = append(args, "%"+escapeLike(expr.Term)+"%")
+		}
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// buildIndexedSearchClause translates a $search expression into a condition
+// using the search_index table; idColumn is the article_id column to correlate on.
+// Phrases and terms too short to be indexed fall back to LIKE matching.
+func buildIndexedSearchClause(expr *odata.SearchExpression, alias, idColumn string) (string, []interface{}) {
+	if expr == nil {
+		return "1=1", nil
+	}
+
+	switch expr.Operator {
+	case "and", "or":
+		left, leftArgs := buildIndexedSearchClause(expr.Left, alias, idColumn)
+		right, rightArgs := buildIndexedSearchClause(expr.Right, alias, idColumn)
+		return "(" + left + " " + strings.ToUpper(expr.Operator) + " " + right + ")", append(leftArgs, rightArgs...)
+	case "not":
+		operand, args := buildIndexedSearchClause(expr.Left, alias, idColumn)
+		return "NOT " + operand, args
+	}
+
+	// Categories are not part of the search index, and the index only holds single words longer than 2 characters
+	if expr.Phrase || expr.Field == "categories" || len(expr.Term) <= 2 || strings.ContainsAny(expr.Term, " \t") {
+		return buildSearchClause(expr, alias)
+	}
+
+	condition := "EXISTS (SELECT 1 FROM search_index si WHERE si.article_id = " + idColumn
+	var args []interface{}
+	if expr.Prefix {
+		condition += " AND si.search_term LIKE ? ESCAPE '\\'"
+		args = append(args, escapeLike(expr.Term)+"%")
+	} else {
+		condition += " AND si.search_term = ?"
+		args = append(args, expr.Term)
+	}
+	if expr.Field != "" {
+		condition += " AND si.field_type = ?"
+		args = append(args, expr.Field)
+	}
+	condition += ")"
+
+	return condition, args
+}
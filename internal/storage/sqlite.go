@@ -1,13 +1,10 @@
 package storage
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -19,8 +16,8 @@ import (
 
 	"gorssag/internal/config"
 	"gorssag/internal/models"
+	"gorssag/internal/odata"
 
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/pemistahl/lingua-go"
 )
 
@@ -52,40 +49,10 @@ func NewSQLiteStorage(dataDir string, cfg *config.Config) (*SQLiteStorage, error
 		return nil, fmt.Errorf("failed to create data directory: %v", err)
 	}
 
-	dbPath := filepath.Join(dataDir, "rss_aggregator.db")
+	dbPath := filepath.Join(dataDir, sqliteDatabaseFile)
 	log.Printf("Initializing database at: %s", dbPath)
 
-	// Check if database exists and validate schema
-	needsRecreation := false
-
-	// Check for force recreation environment variable
-	if os.Getenv("FORCE_DB_RECREATE") == "true" {
-		log.Printf("Force database recreation requested via environment variable")
-		needsRecreation = true
-	} else if _, err := os.Stat(dbPath); err == nil {
-		// Database exists, validate schema
-		log.Printf("Database exists, validating schema...")
-		if !validateSchema(dbPath) {
-			log.Printf("Database schema validation failed, will recreate database")
-			needsRecreation = true
-		}
-	} else {
-		// Database doesn't exist, will create it
-		log.Printf("Database doesn't exist, will create new database")
-		needsRecreation = true
-	}
-
-	// If recreation is needed, remove existing database
-	if needsRecreation {
-		if err := os.Remove(dbPath); err != nil && !os.IsNotExist(err) {
-			return nil, fmt.Errorf("failed to remove existing database: %v", err)
-		}
-		log.Printf("Creating new database with proper schema")
-	} else {
-		log.Printf("Using existing database with valid schema")
-	}
-
-	db, err := sql.Open("sqlite3", dbPath+"?_journal=WAL&_synchronous=NORMAL&_cache_size=10000&_temp_store=MEMORY&_timeout=30000&_busy_timeout=30000&_mmap_size=268435456")
+	db, err := sql.Open(sqliteDriver, dbPath+"?_journal=WAL&_synchronous=NORMAL&_cache_size=10000&_temp_store=MEMORY&_timeout=30000&_busy_timeout=30000&_mmap_size=268435456")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
@@ -116,244 +83,57 @@ func NewSQLiteStorage(dataDir string, cfg *config.Config) (*SQLiteStorage, error
 		}
 	}
 
-	// Create tables with proper indexing
-	if err := createTables(db); err != nil {
-		return nil, fmt.Errorf("failed to create tables: %v", err)
+	// Bring the schema up to date with the embedded migrations
+	if err := migrateSQLite(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %v", err)
 	}
 
-	// Initialize language detector with common RSS languages
-	detector := lingua.NewLanguageDetectorBuilder().
-		FromLanguages(
-			lingua.English, lingua.German, lingua.French, lingua.Spanish,
-			lingua.Chinese, lingua.Russian, lingua.Italian, lingua.Portuguese,
-			lingua.Dutch, lingua.Swedish, lingua.Danish, lingua.Finnish,
-			lingua.Polish, lingua.Czech, lingua.Hungarian, lingua.Romanian,
-		).
-		Build()
-
 	return &SQLiteStorage{
 		db:           db,
 		config:       cfg,
-		detector:     detector,
+		detector:     newLanguageDetector(),
 		topicMutexes: make(map[string]*sync.Mutex),
 	}, nil
 }
 
-func createTables(db *sql.DB) error {
-	// Create topics table
-	topicsTable := `
-	CREATE TABLE IF NOT EXISTS topics (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT UNIQUE NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	// Create articles table with comprehensive indexing
-	articlesTable := `
-	CREATE TABLE IF NOT EXISTS articles (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		article_id TEXT UNIQUE NOT NULL, -- UUID for consistent article identification
-		topic_id INTEGER NOT NULL,
-		title TEXT NOT NULL,
-		link TEXT NOT NULL,
-		description TEXT,
-		content TEXT,
-		author TEXT,
-		source TEXT NOT NULL,
-		categories TEXT, -- JSON array
-		published_at DATETIME NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		language TEXT DEFAULT 'en', -- New column for article language
-		FOREIGN KEY (topic_id) REFERENCES topics(id) ON DELETE CASCADE
-	);
-
-	-- Table for storing compressed content separately
-	CREATE TABLE IF NOT EXISTS compressed_content (
-		article_id TEXT PRIMARY KEY,
-		compressed_content BLOB NOT NULL,
-		compressed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (article_id) REFERENCES articles(article_id) ON DELETE CASCADE
-	);
-
-	-- Search index table for efficient full-text search with multi-language support
-	CREATE TABLE IF NOT EXISTS search_index (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		article_id TEXT NOT NULL,
-		search_term TEXT NOT NULL,
-		field_type TEXT NOT NULL, -- 'title', 'description', 'content', 'author', 'source'
-		language TEXT NOT NULL, -- 'en', 'zh', 'de', 'fr', 'es', etc.
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (article_id) REFERENCES articles(article_id) ON DELETE CASCADE
-	);
-
-	-- Index for efficient search with language support
-	CREATE INDEX IF NOT EXISTS idx_search_index_article_term ON search_index(article_id, search_term);
-	CREATE INDEX IF NOT EXISTS idx_search_index_term_lang ON search_index(search_term, language);
-	CREATE INDEX IF NOT EXISTS idx_search_index_field ON search_index(field_type);
-	CREATE INDEX IF NOT EXISTS idx_search_index_language ON search_index(language);
-
-	-- Article-Topic membership table for many-to-many relationships
-	CREATE TABLE IF NOT EXISTS article_topics (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		article_id TEXT NOT NULL,
-		topic_id INTEGER NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (article_id) REFERENCES articles(article_id) ON DELETE CASCADE,
-		FOREIGN KEY (topic_id) REFERENCES topics(id) ON DELETE CASCADE,
-		UNIQUE(article_id, topic_id) -- Prevent duplicate assignments
-	);
-
-	-- Indexes for efficient topic membership queries
-	CREATE INDEX IF NOT EXISTS idx_article_topics_article ON article_topics(article_id);
-	CREATE INDEX IF NOT EXISTS idx_article_topics_topic ON article_topics(topic_id);`
-
-	// Create indexes for fast OData queries
-	indexes := []string{
-		// Primary indexes for common queries
-		"CREATE INDEX IF NOT EXISTS idx_articles_topic_id ON articles(topic_id);",
-		"CREATE INDEX IF NOT EXISTS idx_articles_published_at ON articles(published_at DESC);",
-		"CREATE INDEX IF NOT EXISTS idx_articles_author ON articles(author);",
-		"CREATE INDEX IF NOT EXISTS idx_articles_source ON articles(source);",
-
-		// Text search indexes using LIKE for basic text search
-		"CREATE INDEX IF NOT EXISTS idx_articles_title_like ON articles(title);",
-		"CREATE INDEX IF NOT EXISTS idx_articles_description_like ON articles(description);",
-		"CREATE INDEX IF NOT EXISTS idx_articles_content_like ON articles(content);",
-
-		// Composite indexes for complex queries
-		"CREATE INDEX IF NOT EXISTS idx_articles_topic_published ON articles(topic_id, published_at DESC);",
-		"CREATE INDEX IF NOT EXISTS idx_articles_author_source ON articles(author, source);",
-	}
-
-	// Execute table creation
-	if _, err := db.Exec(topicsTable); err != nil {
-		return fmt.Errorf("failed to create topics table: %v", err)
-	}
-
-	if _, err := db.Exec(articlesTable); err != nil {
-		return fmt.Errorf("failed to create articles table: %v", err)
-	}
-
-	// Execute index creation
-	for _, index := range indexes {
-		if _, err := db.Exec(index); err != nil {
-			return fmt.Errorf("failed to create index: %v", err)
-		}
-	}
-
-	return nil
+// sqliteTimeExpression compares timestamps as times: stored ones keep the
+// offset of their feed, so their text does not sort chronologically
+func sqliteTimeExpression(expression string) string {
+	return "julianday(" + expression + ")"
 }
 
-// validateSchema checks if the database has the correct schema
-func validateSchema(dbPath string) bool {
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		log.Printf("Failed to open database for schema validation: %v", err)
-		return false
-	}
-	defer db.Close()
-
-	// Check if required tables exist
-	requiredTables := []string{"topics", "articles", "compressed_content", "search_index"}
-	for _, table := range requiredTables {
-		var count int
-		query := "SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?"
-		err := db.QueryRow(query, table).Scan(&count)
-		if err != nil || count == 0 {
-			log.Printf("Missing required table: %s", table)
-			return false
-		}
-	}
-
-	// Check if articles table has all required columns
-	requiredArticleColumns := []string{
-		"id", "article_id", "topic_id", "title", "link", "description",
-		"content", "author", "source", "categories", "published_at",
-		"created_at", "updated_at", "language",
-	}
-	for _, column := range requiredArticleColumns {
-		var count int
-		query := "SELECT COUNT(*) FROM pragma_table_info('articles') WHERE name=?"
-		err := db.QueryRow(query, column).Scan(&count)
-		if err != nil || count == 0 {
-			log.Printf("Missing required column in articles table: %s", column)
-			return false
-		}
-	}
-
-	// Check if topics table has required columns
-	topicColumns := []string{"id", "name", "created_at", "updated_at"}
-	for _, column := range topicColumns {
-		var count int
-		query := "SELECT COUNT(*) FROM pragma_table_info('topics') WHERE name=?"
-		err := db.QueryRow(query, column).Scan(&count)
-		if err != nil || count == 0 {
-			log.Printf("Missing required column in topics table: %s", column)
-			return false
-		}
-	}
-
-	// Check if compressed_content table has required columns
-	compressedColumns := []string{"article_id", "compressed_content", "compressed_at"}
-	for _, column := range compressedColumns {
-		var count int
-		query := "SELECT COUNT(*) FROM pragma_table_info('compressed_content') WHERE name=?"
-		err := db.QueryRow(query, column).Scan(&count)
-		if err != nil || count == 0 {
-			log.Printf("Missing required column in compressed_content table: %s", column)
-			return false
-		}
-	}
-
-	// Check if search_index table has required columns
-	searchColumns := []string{"article_id", "search_term", "field_type", "language"}
-	for _, column := range searchColumns {
-		var count int
-		query := "SELECT COUNT(*) FROM pragma_table_info('search_index') WHERE name=?"
-		err := db.QueryRow(query, column).Scan(&count)
-		if err != nil || count == 0 {
-			log.Printf("Missing required column in search_index table: %s", column)
-			return false
-		}
-	}
-
-	log.Printf("Database schema validation passed")
-	return true
-}
-
-// CleanupOldArticles removes articles older than the specified retention period
-func (s *SQLiteStorage) CleanupOldArticles(retentionPeriod time.Duration) error {
+// CleanupOldArticles deletes the articles no longer retained by the policy
+func (s *SQLiteStorage) CleanupOldArticles(ctx context.Context, policy RetentionPolicy) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	cutoffTime := time.Now().Add(-retentionPeriod)
-
-	// Delete old articles
-	result, err := s.db.Exec("DELETE FROM articles WHERE published_at < ?", cutoffTime)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to delete old articles: %v", err)
+		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	removed, archived, err := cleanupSQLArticles(ctx, tx, policy, sqliteTimeExpression)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %v", err)
+		return err
+	}
+	if err := commitCleanup(ctx, tx, policy, archived); err != nil {
+		return err
 	}
 
-	if rowsAffected > 0 {
-		log.Printf("Cleaned up %d old articles (older than %v)", rowsAffected, retentionPeriod)
+	if removed > 0 {
+		log.Printf("Cleaned up %d old articles", removed)
 	}
 
 	return nil
 }
 
-func (s *SQLiteStorage) SaveFeed(topic string, feed *models.AggregatedFeed) error {
+func (s *SQLiteStorage) SaveFeed(ctx context.Context, topic string, feed *models.AggregatedFeed) error {
 
 	log.Printf("SaveFeed: [THREAD-%d] Starting to save %d articles for topic '%s'", getGoroutineID(), len(feed.Articles), topic)
 
 	log.Printf("SaveFeed: [THREAD-%d] Beginning database transaction for topic '%s'", getGoroutineID(), topic)
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Printf("SaveFeed: [THREAD-%d] Failed to begin transaction for topic '%s': %v", getGoroutineID(), topic, err)
 		return fmt.Errorf("failed to begin transaction: %v", err)
@@ -372,7 +152,7 @@ func (s *SQLiteStorage) SaveFeed(topic string, feed *models.AggregatedFeed) erro
 
 	// Get or create topic
 	log.Printf("SaveFeed: [THREAD-%d] Getting or creating topic '%s'", getGoroutineID(), topic)
-	topicID, err := s.getOrCreateTopic(tx, topic)
+	topicID, err := s.getOrCreateTopic(ctx, tx, topic)
 	if err != nil {
 		log.Printf("SaveFeed: [THREAD-%d] Failed to get or create topic '%s': %v", getGoroutineID(), topic, err)
 		return err
@@ -381,17 +161,39 @@ func (s *SQLiteStorage) SaveFeed(topic string, feed *models.AggregatedFeed) erro
 
 	// Delete existing articles for this topic
 	log.Printf("SaveFeed: [THREAD-%d] Deleting existing articles for topic ID %d", getGoroutineID(), topicID)
-	if _, err := tx.Exec("DELETE FROM articles WHERE topic_id = ?", topicID); err != nil {
+	// Articles shared with other topics are kept, only this topic's membership goes
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM articles
+		WHERE article_id IN (SELECT article_id FROM article_topics WHERE topic_id = ?)
+			AND article_id NOT IN (SELECT article_id FROM article_topics WHERE topic_id != ?)
+	`, topicID, topicID); err != nil {
 		log.Printf("SaveFeed: [THREAD-%d] Failed to delete existing articles for topic ID %d: %v", getGoroutineID(), topicID, err)
 		return fmt.Errorf("failed to delete existing articles: %v", err)
 	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM article_topics WHERE topic_id = ?", topicID); err != nil {
+		log.Printf("SaveFeed: [THREAD-%d] Failed to delete topic assignments for topic ID %d: %v", getGoroutineID(), topicID, err)
+		return fmt.Errorf("failed to delete topic assignments: %v", err)
+	}
 	log.Printf("SaveFeed: [THREAD-%d] Successfully deleted existing articles for topic ID %d", getGoroutineID(), topicID)
 
 	// Insert new articles
 	log.Printf("SaveFeed: [THREAD-%d] Preparing insert statement for topic '%s'", getGoroutineID(), topic)
-	stmt, err := tx.Prepare(`
-		INSERT INTO articles (article_id, topic_id, title, link, description, content, author, source, categories, published_at, language)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO articles (article_id, title, link, description, content, author, source, categories, published_at, language, content_hash, content_updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(article_id) DO UPDATE SET
+			title = excluded.title,
+			link = excluded.link,
+			description = excluded.description,
+			content = excluded.content,
+			author = excluded.author,
+			source = excluded.source,
+			categories = excluded.categories,
+			published_at = excluded.published_at,
+			language = excluded.language,
+			content_hash = excluded.content_hash,
+			content_updated_at = COALESCE(excluded.content_updated_at, articles.content_updated_at),
+			updated_at = CURRENT_TIMESTAMP
 	`)
 	if err != nil {
 		log.Printf("SaveFeed: [THREAD-%d] Failed to prepare insert statement for topic '%s': %v", getGoroutineID(), topic, err)
@@ -404,11 +206,21 @@ func (s *SQLiteStorage) SaveFeed(topic string, feed *models.AggregatedFeed) erro
 		}
 	}()
 
+	topicStmt, err := tx.PrepareContext(ctx, "INSERT OR IGNORE INTO article_topics (article_id, topic_id) VALUES (?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare topic assignment statement: %v", err)
+	}
+	defer func() {
+		if err := topicStmt.Close(); err != nil {
+			log.Printf("Warning: failed to close topic assignment statement: %v", err)
+		}
+	}()
+
 	// Prepare compressed content statement
 	log.Printf("SaveFeed: [THREAD-%d] Preparing compressed content statement for topic '%s'", getGoroutineID(), topic)
-	compressedStmt, err := tx.Prepare(`
-		INSERT OR REPLACE INTO compressed_content (article_id, compressed_content, compressed_at)
-		VALUES (?, ?, CURRENT_TIMESTAMP)
+	compressedStmt, err := tx.PrepareContext(ctx, `
+		INSERT OR REPLACE INTO compressed_content (article_id, compressed_content, codec, original_size, compressed_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
 	`)
 	if err != nil {
 		log.Printf("SaveFeed: [THREAD-%d] Failed to prepare compressed content statement for topic '%s': %v", getGoroutineID(), topic, err)
@@ -422,6 +234,7 @@ func (s *SQLiteStorage) SaveFeed(topic string, feed *models.AggregatedFeed) erro
 	}()
 
 	threeDaysAgo := time.Now().AddDate(0, 0, -3)
+	codec := compressionCodec(s.config)
 
 	log.Printf("SaveFeed: [THREAD-%d] Starting to process %d articles for topic '%s'", getGoroutineID(), len(feed.Articles), topic)
 
@@ -444,7 +257,7 @@ func (s *SQLiteStorage) SaveFeed(topic string, feed *models.AggregatedFeed) erro
 			shouldCompress = true
 
 			// Compress content (but don't store yet - wait until after article is inserted)
-			compressed, err := compressContent(content)
+			compressed, err := compressContent(codec, content)
 			if err != nil {
 				log.Printf("Warning: failed to compress content for article %s: %v", article.ID, err)
 				// Fall back to uncompressed content
@@ -460,27 +273,36 @@ func (s *SQLiteStorage) SaveFeed(topic string, feed *models.AggregatedFeed) erro
 		}
 
 		// Detect language for the article
-		articleLanguage := s.detectLanguage(article.Title + " " + article.Description + " " + article.Content)
+		language := articleLanguage(s.detector, article)
+
+		// Keep the replaced version when the publisher changed the article
+		contentHash, changedAt, err := recordSQLRevision(ctx, tx, article, content)
+		if err != nil {
+			return err
+		}
 
 		// Insert article using prepared statement
 		log.Printf("SaveFeed: [THREAD-%d] Inserting article %s into database", getGoroutineID(), article.ID)
-		_, err = stmt.Exec(article.ID, topicID, article.Title, article.Link, article.Description, contentToStore, article.Author, article.Source, categoriesJSON, article.PublishedAt, articleLanguage)
+		_, err = stmt.ExecContext(ctx, article.ID, article.Title, article.Link, article.Description, contentToStore, article.Author, article.Source, categoriesJSON, article.PublishedAt, language, contentHash, changedAt)
 		if err != nil {
 			log.Printf("SaveFeed: [THREAD-%d] Failed to insert article %s: %v", getGoroutineID(), article.ID, err)
 			return fmt.Errorf("failed to insert article %s: %v", article.ID, err)
 		}
+		if _, err := topicStmt.ExecContext(ctx, article.ID, topicID); err != nil {
+			return fmt.Errorf("failed to assign article %s to topic: %v", article.ID, err)
+		}
 		log.Printf("SaveFeed: [THREAD-%d] Successfully inserted article %s", getGoroutineID(), article.ID)
 
 		// Now store compressed content after article is inserted (to avoid FK constraint)
 		if shouldCompress && len(compressedContent) > 0 {
-			if _, err := compressedStmt.Exec(article.ID, compressedContent); err != nil {
+			if _, err := compressedStmt.ExecContext(ctx, article.ID, compressedContent, codec, len(content)); err != nil {
 				log.Printf("Warning: failed to store compressed content for article %s: %v", article.ID, err)
 			}
 		}
 
 		// Update FTS5 index with the original content (not compressed)
 		log.Printf("SaveFeed: [THREAD-%d] Updating search index for article %s", getGoroutineID(), article.ID)
-		if err := s.updateSearchIndexWithTx(tx, article.ID, article.Title, article.Description, content, article.Author, article.Source); err != nil {
+		if err := s.updateSearchIndexWithTx(ctx, tx, article.ID, article.Title, article.Description, content, article.Author, article.Source, article.Categories); err != nil {
 			log.Printf("SaveFeed: [THREAD-%d] Warning: failed to update FTS index for article %s: %v", getGoroutineID(), article.ID, err)
 		} else {
 			log.Printf("SaveFeed: [THREAD-%d] Successfully updated search index for article %s", getGoroutineID(), article.ID)
@@ -489,7 +311,7 @@ func (s *SQLiteStorage) SaveFeed(topic string, feed *models.AggregatedFeed) erro
 
 	// Update topic timestamp
 	log.Printf("SaveFeed: [THREAD-%d] Updating topic timestamp for topic ID %d", getGoroutineID(), topicID)
-	if _, err := tx.Exec("UPDATE topics SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", topicID); err != nil {
+	if _, err := tx.ExecContext(ctx, "UPDATE topics SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", topicID); err != nil {
 		log.Printf("SaveFeed: [THREAD-%d] Failed to update topic timestamp for topic ID %d: %v", getGoroutineID(), topicID, err)
 		return fmt.Errorf("failed to update topic timestamp: %v", err)
 	}
@@ -506,7 +328,7 @@ func (s *SQLiteStorage) SaveFeed(topic string, feed *models.AggregatedFeed) erro
 	// Verify the articles were actually saved by checking the database
 	log.Printf("SaveFeed: [THREAD-%d] Verifying article count for topic ID %d", getGoroutineID(), topicID)
 	var count int
-	err = s.db.QueryRow("SELECT COUNT(*) FROM articles WHERE topic_id = ?", topicID).Scan(&count)
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM article_topics WHERE topic_id = ?", topicID).Scan(&count)
 	if err != nil {
 		log.Printf("SaveFeed: [THREAD-%d] Failed to verify article count for topic ID %d: %v", getGoroutineID(), topicID, err)
 	} else {
@@ -520,12 +342,12 @@ func (s *SQLiteStorage) SaveFeed(topic string, feed *models.AggregatedFeed) erro
 	return nil
 }
 
-func (s *SQLiteStorage) LoadFeed(topic string) (*models.AggregatedFeed, error) {
+func (s *SQLiteStorage) LoadFeed(ctx context.Context, topic string) (*models.AggregatedFeed, error) {
 	log.Printf("LoadFeed: [THREAD-%d] Starting to load feed for topic '%s'", getGoroutineID(), topic)
 
 	// Get topic ID with timeout
 	var topicID int
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	log.Printf("LoadFeed: Starting database queries for topic '%s'", topic)
@@ -555,7 +377,7 @@ func (s *SQLiteStorage) LoadFeed(topic string) (*models.AggregatedFeed, error) {
 	log.Printf("LoadFeed: Found topic ID %d for topic '%s'", topicID, topic)
 
 	// Query articles with LEFT JOIN to get compressed content in a single query
-	ctx2, cancel2 := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx2, cancel2 := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel2()
 
 	rows, err := s.db.QueryContext(ctx2, `
@@ -570,10 +392,16 @@ func (s *SQLiteStorage) LoadFeed(topic string) (*models.AggregatedFeed, error) {
 			a.published_at, 
 			a.categories, 
 			a.language,
-			cc.compressed_content
+			a.pinned,
+			a.content_updated_at,
+			cc.codec,
+			cc.compressed_content,
+			COALESCE(ac.cluster_id, a.article_id) as cluster_id
 		FROM articles a
+		JOIN article_topics at ON a.article_id = at.article_id
 		LEFT JOIN compressed_content cc ON a.article_id = cc.article_id
-		WHERE a.topic_id = ? 
+		LEFT JOIN article_clusters ac ON a.article_id = ac.article_id
+		WHERE at.topic_id = ? 
 		ORDER BY a.published_at DESC
 	`, topicID)
 	if err != nil {
@@ -589,7 +417,7 @@ func (s *SQLiteStorage) LoadFeed(topic string) (*models.AggregatedFeed, error) {
 		var content string
 		var categoriesJSON sql.NullString
 		var language sql.NullString
-		var compressedContent sql.NullString
+		var codec, compressedContent sql.NullString
 		err = rows.Scan(
 			&article.ID,
 			&article.Title,
@@ -601,7 +429,11 @@ func (s *SQLiteStorage) LoadFeed(topic string) (*models.AggregatedFeed, error) {
 			&article.PublishedAt,
 			&categoriesJSON,
 			&language,
+			&article.Pinned,
+			&article.UpdatedAt,
+			&codec,
 			&compressedContent,
+			&article.ClusterID,
 		)
 		if err != nil {
 			log.Printf("LoadFeed: Failed to scan article %d: %v", articleCount, err)
@@ -617,7 +449,7 @@ func (s *SQLiteStorage) LoadFeed(topic string) (*models.AggregatedFeed, error) {
 		article.Content = content
 		if article.Content == "" && compressedContent.Valid {
 			// Try to decompress content
-			decompressed, err := decompressContent([]byte(compressedContent.String))
+			decompressed, err := decompressContent(codec.String, []byte(compressedContent.String))
 			if err != nil {
 				log.Printf("Warning: failed to decompress content for article %s: %v", article.ID, err)
 				article.Content = "Content unavailable (decompression failed)"
@@ -652,6 +484,10 @@ func (s *SQLiteStorage) LoadFeed(topic string) (*models.AggregatedFeed, error) {
 		return nil, fmt.Errorf("error during rows iteration: %v", err)
 	}
 
+	if err := attachSQLFeedURLs(ctx, s.db, articles); err != nil {
+		return nil, err
+	}
+
 	log.Printf("LoadFeed: Successfully loaded %d articles for topic '%s'", len(articles), topic)
 
 	if len(articles) == 0 {
@@ -667,18 +503,21 @@ func (s *SQLiteStorage) LoadFeed(topic string) (*models.AggregatedFeed, error) {
 	}, nil
 }
 
-func (s *SQLiteStorage) QueryArticles(topic string, query *models.ODataQuery) ([]models.Article, error) {
+func (s *SQLiteStorage) QueryArticles(ctx context.Context, topic string, query *models.ODataQuery) ([]models.Article, error) {
 	// Get topic ID
 	var topicID int
-	err := s.db.QueryRow("SELECT id FROM topics WHERE name = ?", topic).Scan(&topicID)
+	err := s.db.QueryRowContext(ctx, "SELECT id FROM topics WHERE name = ?", topic).Scan(&topicID)
 	if err != nil {
 		return nil, fmt.Errorf("topic not found: %v", err)
 	}
 
 	// Build SQL query with OData support
-	sqlQuery, args := s.buildODataQuery(topicID, query)
+	sqlQuery, args, err := s.buildODataQuery(ctx, topicID, query)
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := s.db.Query(sqlQuery, args...)
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query articles: %v", err)
 	}
@@ -704,21 +543,9 @@ func (s *SQLiteStorage) QueryArticles(topic string, query *models.ODataQuery) ([
 			return nil, fmt.Errorf("failed to scan article: %v", err)
 		}
 
-		// If content is empty, try to decompress from compressed_content table
-		if article.Content == "" {
-			var compressedData []byte
-			err := s.db.QueryRow("SELECT compressed_content FROM compressed_content WHERE article_id = ?", article.ID).Scan(&compressedData)
-			if err == nil && len(compressedData) > 0 {
-				decompressed, err := decompressContent(compressedData)
-				if err != nil {
-					log.Printf("Warning: failed to decompress content for article %s: %v", article.ID, err)
-					article.Content = "Content unavailable (decompression failed)"
-				} else {
-					article.Content = decompressed
-				}
-			} else {
-				article.Content = "Content unavailable"
-			}
+		// Compressed content is decompressed by the query itself
+		if article.Content == "" && contentSelected(query) {
+			article.Content = "Content unavailable"
 		}
 
 		// Parse categories JSON
@@ -731,105 +558,170 @@ func (s *SQLiteStorage) QueryArticles(topic string, query *models.ODataQuery) ([
 
 		articles = append(articles, article)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	if err := attachSQLFeedURLs(ctx, s.db, articles); err != nil {
+		return nil, err
+	}
 
 	return articles, nil
 }
 
+// buildArticleConditions builds the " AND ..." conditions shared by article
+// listings, counts and facets: $search, $filter and the $datefrom/$dateto,
+// $source, $author and $category drill-down filters
+func buildArticleConditions(query *models.ODataQuery, alias string) (string, []interface{}, error) {
+	var conditions string
+	var args []interface{}
+
+	searchExpr, err := parseSearch(query.Search)
+	if err != nil {
+		return "", nil, err
+	}
+	if searchExpr != nil {
+		searchClause, searchArgs := buildSearchClause(searchExpr, alias)
+		conditions += " AND " + searchClause
+		args = append(args, searchArgs...)
+	}
+
+	if query.Filter != "" {
+		conditions += " AND (" + alias + ".title LIKE ? OR " + alias + ".description LIKE ? OR " + sqliteContentExpression(alias) + " LIKE ? OR " + alias + ".author LIKE ?)"
+		args = append(args, "%"+query.Filter+"%", "%"+query.Filter+"%", "%"+query.Filter+"%", "%"+query.Filter+"%")
+	}
+
+	if query.DateFrom != nil {
+		// datetime() normalises the stored offsets so the comparison is chronological
+		conditions += " AND datetime(" + alias + ".published_at) >= datetime(?)"
+		args = append(args, query.DateFrom.Format(time.RFC3339))
+	}
+	if query.DateTo != nil {
+		conditions += " AND datetime(" + alias + ".published_at) <= datetime(?)"
+		args = append(args, query.DateTo.Format(time.RFC3339))
+	}
+
+	if query.Source != "" {
+		conditions += " AND " + alias + ".source LIKE ? ESCAPE '\\'"
+		args = append(args, "%"+escapeLike(query.Source)+"%")
+	}
+	if query.Author != "" {
+		conditions += " AND " + alias + ".author LIKE ? ESCAPE '\\'"
+		args = append(args, "%"+escapeLike(query.Author)+"%")
+	}
+	if query.Category != "" {
+		conditions += " AND " + alias + ".categories LIKE ? ESCAPE '\\'"
+		args = append(args, "%"+escapeLike(query.Category)+"%")
+	}
+	if query.Pinned != nil {
+		conditions += " AND " + alias + ".pinned = ?"
+		args = append(args, *query.Pinned)
+	}
+	if query.FeedURL != "" {
+		conditions += feedURLCondition(alias)
+		args = append(args, query.FeedURL)
+	}
+
+	return conditions, args, nil
+}
+
+// buildKeysetCondition builds the " AND ..." condition selecting the articles
+// after a $skiptoken cursor. The cursor time binds to the same text as the
+// stored published_at, so ties are broken on article_id.
+func buildKeysetCondition(token *models.SkipToken, alias string) (string, []interface{}) {
+	condition := " AND (" + alias + ".published_at < ? OR (" + alias + ".published_at = ? AND " + alias + ".article_id < ?))"
+	return condition, []interface{}{token.PublishedAt, token.PublishedAt, token.ID}
+}
+
+// keysetOrder is the ordering $skiptoken cursors rely on
+func keysetOrder(alias string) string {
+	return alias + ".published_at DESC, " + alias + ".article_id DESC"
+}
+
 // GetAllArticles returns all articles across all topics without topic filtering
-func (s *SQLiteStorage) GetAllArticles(query *models.ODataQuery) ([]models.Article, int, error) {
+func (s *SQLiteStorage) GetAllArticles(ctx context.Context, query *models.ODataQuery) ([]models.Article, int, error) {
 	// Build SQL query for all articles without topic filtering
 	baseQuery := `
 		SELECT 
 			a.article_id, 
 			a.title, 
 			a.description, 
-			a.content, 
 			a.link, 
 			a.author, 
 			a.source, 
 			a.published_at, 
 			a.categories, 
 			a.language,
-			t.name as topic,
-			cc.compressed_content
+			a.pinned,
+			a.content_updated_at,
+			(SELECT t.name FROM article_topics at JOIN topics t ON at.topic_id = t.id WHERE at.article_id = a.article_id ORDER BY at.id LIMIT 1) as topic,
+			COALESCE(ac.cluster_id, a.article_id) as cluster_id,
+			` + articleContentColumns(query) + `
 		FROM articles a
-		LEFT JOIN topics t ON a.topic_id = t.id
 		LEFT JOIN compressed_content cc ON a.article_id = cc.article_id
+		LEFT JOIN article_clusters ac ON a.article_id = ac.article_id
 		WHERE 1=1
 	`
-	args := []interface{}{}
 
-	// Add search conditions
-	if len(query.Search) > 0 {
-		searchConditions := make([]string, len(query.Search))
-		for i, term := range query.Search {
-			searchConditions[i] = "(a.title LIKE ? OR a.description LIKE ? OR a.content LIKE ? OR a.author LIKE ? OR a.source LIKE ?)"
-			args = append(args, "%"+term+"%", "%"+term+"%", "%"+term+"%", "%"+term+"%", "%"+term+"%")
-		}
-		baseQuery += " AND (" + strings.Join(searchConditions, " OR ") + ")"
+	// Add search, filter and drill-down conditions
+	conditions, args, err := buildArticleConditions(query, "a")
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Add filter conditions
-	if query.Filter != "" {
-		baseQuery += " AND (a.title LIKE ? OR a.description LIKE ? OR a.content LIKE ? OR a.author LIKE ?)"
-		args = append(args, "%"+query.Filter+"%", "%"+query.Filter+"%", "%"+query.Filter+"%", "%"+query.Filter+"%")
+	// Keep one representative per story cluster
+	if query.Cluster {
+		clusterCondition, clusterArgs, err := buildClusterCondition(query, "")
+		if err != nil {
+			return nil, 0, err
+		}
+		conditions += clusterCondition
+		args = append(args, clusterArgs...)
 	}
+	baseQuery += conditions
 
 	// Count total articles for pagination (before LIMIT/OFFSET)
 	countQuery := `
 		SELECT COUNT(DISTINCT a.article_id) 
 		FROM articles a
-		LEFT JOIN topics t ON a.topic_id = t.id
-		LEFT JOIN compressed_content cc ON a.article_id = cc.article_id
+		LEFT JOIN article_clusters ac ON a.article_id = ac.article_id
 		WHERE 1=1
-	`
-	countArgs := []interface{}{}
-
-	// Apply the same search conditions to count query
-	if len(query.Search) > 0 {
-		searchConditions := make([]string, len(query.Search))
-		for i, term := range query.Search {
-			searchConditions[i] = "(a.title LIKE ? OR a.description LIKE ? OR a.content LIKE ? OR a.author LIKE ? OR a.source LIKE ?)"
-			countArgs = append(countArgs, "%"+term+"%", "%"+term+"%", "%"+term+"%", "%"+term+"%", "%"+term+"%")
-		}
-		countQuery += " AND (" + strings.Join(searchConditions, " OR ") + ")"
-	}
-
-	// Apply the same filter conditions to count query
-	if query.Filter != "" {
-		countQuery += " AND (a.title LIKE ? OR a.description LIKE ? OR a.content LIKE ? OR a.author LIKE ?)"
-		countArgs = append(countArgs, "%"+query.Filter+"%", "%"+query.Filter+"%", "%"+query.Filter+"%", "%"+query.Filter+"%")
-	}
+	` + conditions
+	countArgs := append([]interface{}{}, args...)
 
 	var totalCount int
-	err := s.db.QueryRow(countQuery, countArgs...).Scan(&totalCount)
+	err = s.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&totalCount)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count articles: %v", err)
 	}
 
-	// Add ordering
-	if query.OrderBy != "" {
+	// Add keyset pagination and ordering
+	if query.SkipToken != nil {
+		keyset, keysetArgs := buildKeysetCondition(query.SkipToken, "a")
+		baseQuery += keyset + " ORDER BY " + keysetOrder("a")
+		args = append(args, keysetArgs...)
+	} else if !odata.IsKeysetOrder(query.OrderBy) {
 		baseQuery += " ORDER BY " + s.parseOrderBy(query.OrderBy)
 	} else {
-		baseQuery += " ORDER BY a.published_at DESC"
+		baseQuery += " ORDER BY " + keysetOrder("a")
 	}
 
 	// Add pagination - LIMIT must come before OFFSET in SQLite
 	if query.Top > 0 {
 		baseQuery += " LIMIT ?"
 		args = append(args, query.Top)
-		if query.Skip > 0 {
+		if query.Skip > 0 && query.SkipToken == nil {
 			baseQuery += " OFFSET ?"
 			args = append(args, query.Skip)
 		}
-	} else if query.Skip > 0 {
+	} else if query.Skip > 0 && query.SkipToken == nil {
 		// If only skip is specified, we need a default limit
 		baseQuery += " LIMIT -1 OFFSET ?"
 		args = append(args, query.Skip)
 	}
 
 	// Execute query
-	rows, err := s.db.Query(baseQuery, args...)
+	rows, err := s.db.QueryContext(ctx, baseQuery, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query all articles: %v", err)
 	}
@@ -842,20 +734,24 @@ func (s *SQLiteStorage) GetAllArticles(query *models.ODataQuery) ([]models.Artic
 		var categoriesJSON sql.NullString
 		var language sql.NullString
 		var topic sql.NullString
-		var compressedContent sql.NullString
+		var codec, compressedContent sql.NullString
 
 		err = rows.Scan(
 			&article.ID,
 			&article.Title,
 			&article.Description,
-			&content,
 			&article.Link,
 			&article.Author,
 			&article.Source,
 			&article.PublishedAt,
 			&categoriesJSON,
 			&language,
+			&article.Pinned,
+			&article.UpdatedAt,
 			&topic,
+			&article.ClusterID,
+			&content,
+			&codec,
 			&compressedContent,
 		)
 		if err != nil {
@@ -865,14 +761,14 @@ func (s *SQLiteStorage) GetAllArticles(query *models.ODataQuery) ([]models.Artic
 		// Handle content (regular or compressed)
 		article.Content = content
 		if article.Content == "" && compressedContent.Valid {
-			decompressed, err := decompressContent([]byte(compressedContent.String))
+			decompressed, err := decompressContent(codec.String, []byte(compressedContent.String))
 			if err != nil {
 				log.Printf("Warning: failed to decompress content for article %s: %v", article.ID, err)
 				article.Content = "Content unavailable (decompression failed)"
 			} else {
 				article.Content = decompressed
 			}
-		} else if article.Content == "" {
+		} else if article.Content == "" && contentSelected(query) {
 			article.Content = "Content unavailable"
 		}
 
@@ -902,30 +798,44 @@ func (s *SQLiteStorage) GetAllArticles(query *models.ODataQuery) ([]models.Artic
 		return nil, 0, fmt.Errorf("error during rows iteration: %v", err)
 	}
 
+	if err := attachSQLFeedURLs(ctx, s.db, articles); err != nil {
+		return nil, 0, err
+	}
+
+	if query.Cluster {
+		if err := attachClusterSources(ctx, s.db, articles); err != nil {
+			return nil, 0, err
+		}
+	}
+
 	return articles, totalCount, nil
 }
 
-func (s *SQLiteStorage) buildODataQuery(topicID int, query *models.ODataQuery) (string, []interface{}) {
+func (s *SQLiteStorage) buildODataQuery(ctx context.Context, topicID int, query *models.ODataQuery) (string, []interface{}, error) {
+	content := "''"
+	if contentSelected(query) {
+		content = sqliteContentExpression("articles")
+	}
 	baseQuery := `
-		SELECT article_id, title, link, description, content, author, source, categories, published_at
+		SELECT article_id, title, link, description, ` + content + ` AS content, author, source, categories, published_at
 		FROM articles 
-		WHERE topic_id = ?
+		WHERE article_id IN (SELECT article_id FROM article_topics WHERE topic_id = ?)
 	`
 	args := []interface{}{topicID}
 
-	// Add search conditions using FTS5
-	if len(query.Search) > 0 {
-		// Use FTS5 search instead of LIKE queries
-		searchArticleIDs, err := s.searchArticlesIndex(query.Search, topicID)
+	// Add search conditions using the search index
+	searchExpr, err := parseSearch(query.Search)
+	if err != nil {
+		return "", nil, err
+	}
+	if searchExpr != nil {
+		searchArticleIDs, err := s.searchArticlesIndex(ctx, searchExpr, topicID)
 		if err != nil {
-			log.Printf("Warning: FTS search failed, falling back to LIKE queries: %v", err)
+			log.Printf("Warning: index search failed, falling back to LIKE queries: %v", err)
 			// Fallback to LIKE queries
-			searchConditions := make([]string, len(query.Search))
-			for i, term := range query.Search {
-				searchConditions[i] = "(title LIKE ? OR description LIKE ? OR content LIKE ? OR author LIKE ? OR source LIKE ?)"
-				args = append(args, "%"+term+"%", "%"+term+"%", "%"+term+"%", "%"+term+"%", "%"+term+"%")
-			}
-			baseQuery += " AND (" + join(searchConditions, " OR ") + ")"
+			searchClause, searchArgs := buildSearchClause(searchExpr, "articles")
+			baseQuery += " AND " + searchClause
+			args = append(args, searchArgs...)
 		} else if len(searchArticleIDs) > 0 {
 			// Use FTS5 results
 			placeholders := make([]string, len(searchArticleIDs))
@@ -943,7 +853,7 @@ func (s *SQLiteStorage) buildODataQuery(topicID int, query *models.ODataQuery) (
 	// Add filter conditions (basic support - full OData parsing would be more complex)
 	if query.Filter != "" {
 		// For now, support basic text search in filter
-		baseQuery += " AND (title LIKE ? OR description LIKE ? OR content LIKE ? OR author LIKE ?)"
+		baseQuery += " AND (title LIKE ? OR description LIKE ? OR " + sqliteContentExpression("articles") + " LIKE ? OR author LIKE ?)"
 		args = append(args, "%"+query.Filter+"%", "%"+query.Filter+"%", "%"+query.Filter+"%", "%"+query.Filter+"%")
 	}
 
@@ -965,7 +875,7 @@ func (s *SQLiteStorage) buildODataQuery(topicID int, query *models.ODataQuery) (
 		args = append(args, query.Skip)
 	}
 
-	return baseQuery, args
+	return baseQuery, args, nil
 }
 
 func (s *SQLiteStorage) parseOrderBy(orderBy string) string {
@@ -992,17 +902,17 @@ func (s *SQLiteStorage) parseOrderBy(orderBy string) string {
 	}
 }
 
-func (s *SQLiteStorage) getOrCreateTopic(tx *sql.Tx, topic string) (int, error) {
+func (s *SQLiteStorage) getOrCreateTopic(ctx context.Context, tx *sql.Tx, topic string) (int, error) {
 	var topicID int
 
 	// Try to get existing topic
-	err := tx.QueryRow("SELECT id FROM topics WHERE name = ?", topic).Scan(&topicID)
+	err := tx.QueryRowContext(ctx, "SELECT id FROM topics WHERE name = ?", topic).Scan(&topicID)
 	if err == nil {
 		return topicID, nil
 	}
 
 	// Create new topic
-	result, err := tx.Exec("INSERT INTO topics (name) VALUES (?)", topic)
+	result, err := tx.ExecContext(ctx, "INSERT INTO topics (name) VALUES (?)", topic)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create topic: %v", err)
 	}
@@ -1015,8 +925,8 @@ func (s *SQLiteStorage) getOrCreateTopic(tx *sql.Tx, topic string) (int, error)
 	return int(newID), nil
 }
 
-func (s *SQLiteStorage) ListTopics() ([]string, error) {
-	rows, err := s.db.Query("SELECT name FROM topics ORDER BY name")
+func (s *SQLiteStorage) ListTopics(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT name FROM topics ORDER BY name")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query topics: %v", err)
 	}
@@ -1034,19 +944,19 @@ func (s *SQLiteStorage) ListTopics() ([]string, error) {
 	return topics, nil
 }
 
-func (s *SQLiteStorage) GetFeedInfo(topic string) (*models.FeedInfo, error) {
+func (s *SQLiteStorage) GetFeedInfo(ctx context.Context, topic string) (*models.FeedInfo, error) {
 	var topicID int
 	var updatedAt time.Time
 	var articleCount int
 
 	// Get topic info
-	err := s.db.QueryRow("SELECT id, updated_at FROM topics WHERE name = ?", topic).Scan(&topicID, &updatedAt)
+	err := s.db.QueryRowContext(ctx, "SELECT id, updated_at FROM topics WHERE name = ?", topic).Scan(&topicID, &updatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("topic not found: %v", err)
 	}
 
 	// Get article count
-	err = s.db.QueryRow("SELECT COUNT(*) FROM articles WHERE topic_id = ?", topicID).Scan(&articleCount)
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM article_topics WHERE topic_id = ?", topicID).Scan(&articleCount)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count articles: %v", err)
 	}
@@ -1059,11 +969,11 @@ func (s *SQLiteStorage) GetFeedInfo(topic string) (*models.FeedInfo, error) {
 	}, nil
 }
 
-func (s *SQLiteStorage) DeleteFeed(topic string) error {
+func (s *SQLiteStorage) DeleteFeed(ctx context.Context, topic string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	_, err := s.db.Exec("DELETE FROM topics WHERE name = ?", topic)
+	_, err := s.db.ExecContext(ctx, "DELETE FROM topics WHERE name = ?", topic)
 	if err != nil {
 		return fmt.Errorf("failed to delete topic: %v", err)
 	}
@@ -1089,26 +999,26 @@ func join(strs []string, sep string) string {
 }
 
 // OptimizeDatabase performs database maintenance operations
-func (s *SQLiteStorage) OptimizeDatabase() error {
+func (s *SQLiteStorage) OptimizeDatabase(ctx context.Context) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	// VACUUM to reclaim space and optimize storage
-	if _, err := s.db.Exec("VACUUM"); err != nil {
+	if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
 		return fmt.Errorf("failed to vacuum database: %v", err)
 	}
 
 	// ANALYZE to update statistics for query optimization
-	if _, err := s.db.Exec("ANALYZE"); err != nil {
+	if _, err := s.db.ExecContext(ctx, "ANALYZE"); err != nil {
 		return fmt.Errorf("failed to analyze database: %v", err)
 	}
 
 	// Update table statistics
-	if _, err := s.db.Exec("ANALYZE articles"); err != nil {
+	if _, err := s.db.ExecContext(ctx, "ANALYZE articles"); err != nil {
 		return fmt.Errorf("failed to analyze articles table: %v", err)
 	}
 
-	if _, err := s.db.Exec("ANALYZE topics"); err != nil {
+	if _, err := s.db.ExecContext(ctx, "ANALYZE topics"); err != nil {
 		return fmt.Errorf("failed to analyze topics table: %v", err)
 	}
 
@@ -1117,7 +1027,7 @@ func (s *SQLiteStorage) OptimizeDatabase() error {
 }
 
 // GetDatabaseStats returns database statistics
-func (s *SQLiteStorage) GetDatabaseStats() (map[string]interface{}, error) {
+func (s *SQLiteStorage) GetDatabaseStats(ctx context.Context) (map[string]interface{}, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
@@ -1125,7 +1035,7 @@ func (s *SQLiteStorage) GetDatabaseStats() (map[string]interface{}, error) {
 
 	// Get total articles count
 	var totalArticles int
-	err := s.db.QueryRow("SELECT COUNT(*) FROM articles").Scan(&totalArticles)
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM articles").Scan(&totalArticles)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total articles count: %v", err)
 	}
@@ -1133,7 +1043,7 @@ func (s *SQLiteStorage) GetDatabaseStats() (map[string]interface{}, error) {
 
 	// Get total topics count
 	var totalTopics int
-	err = s.db.QueryRow("SELECT COUNT(*) FROM topics").Scan(&totalTopics)
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM topics").Scan(&totalTopics)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total topics count: %v", err)
 	}
@@ -1141,7 +1051,7 @@ func (s *SQLiteStorage) GetDatabaseStats() (map[string]interface{}, error) {
 
 	// Get average content length (handle NULL values)
 	var avgContentLength sql.NullFloat64
-	err = s.db.QueryRow("SELECT AVG(LENGTH(content)) FROM articles WHERE content IS NOT NULL AND content != ''").Scan(&avgContentLength)
+	err = s.db.QueryRowContext(ctx, "SELECT AVG(LENGTH(content)) FROM articles WHERE content IS NOT NULL AND content != ''").Scan(&avgContentLength)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get average content length: %v", err)
 	}
@@ -1153,7 +1063,7 @@ func (s *SQLiteStorage) GetDatabaseStats() (map[string]interface{}, error) {
 
 	// Get compressed content count
 	var compressedCount int
-	err = s.db.QueryRow("SELECT COUNT(*) FROM compressed_content").Scan(&compressedCount)
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM compressed_content").Scan(&compressedCount)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get compressed content count: %v", err)
 	}
@@ -1161,7 +1071,7 @@ func (s *SQLiteStorage) GetDatabaseStats() (map[string]interface{}, error) {
 
 	// Get uncompressed content count
 	var uncompressedCount int
-	err = s.db.QueryRow("SELECT COUNT(*) FROM articles WHERE content IS NOT NULL AND content != ''").Scan(&uncompressedCount)
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM articles WHERE content IS NOT NULL AND content != ''").Scan(&uncompressedCount)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get uncompressed content count: %v", err)
 	}
@@ -1169,17 +1079,17 @@ func (s *SQLiteStorage) GetDatabaseStats() (map[string]interface{}, error) {
 
 	// Get database file size
 	var dbSize int64
-	err = s.db.QueryRow("SELECT page_count * page_size as size FROM pragma_page_count(), pragma_page_size()").Scan(&dbSize)
+	err = s.db.QueryRowContext(ctx, "SELECT page_count * page_size as size FROM pragma_page_count(), pragma_page_size()").Scan(&dbSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get database size: %v", err)
 	}
 	stats["database_size_bytes"] = dbSize
 
 	// Get articles by topic
-	rows, err := s.db.Query(`
-		SELECT t.name, COUNT(a.id) as count 
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.name, COUNT(at.id) as count 
 		FROM topics t 
-		LEFT JOIN articles a ON t.id = a.topic_id 
+		LEFT JOIN article_topics at ON t.id = at.topic_id 
 		GROUP BY t.id, t.name
 	`)
 	if err != nil {
@@ -1202,14 +1112,14 @@ func (s *SQLiteStorage) GetDatabaseStats() (map[string]interface{}, error) {
 }
 
 // RemoveDuplicateArticles removes duplicate articles based on content similarity
-func (s *SQLiteStorage) RemoveDuplicateArticles() error {
+func (s *SQLiteStorage) RemoveDuplicateArticles(ctx context.Context) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	// Remove exact duplicates based on link
-	result, err := s.db.Exec(`
+	// Remove exact duplicates based on link, pinned and starred articles are always kept
+	result, err := s.db.ExecContext(ctx, `
 		DELETE FROM articles 
-		WHERE id NOT IN (
+		WHERE pinned = 0 AND NOT `+fmt.Sprintf(sqlStarred, "articles")+` AND id NOT IN (
 			SELECT MIN(id) 
 			FROM articles 
 			GROUP BY link
@@ -1231,10 +1141,11 @@ func (s *SQLiteStorage) RemoveDuplicateArticles() error {
 	return nil
 }
 
-// CompressOldArticles compresses articles older than 3 days that are still uncompressed
-func (s *SQLiteStorage) CompressOldArticles() error {
+// CompressOldArticles compresses unpinned, unstarred articles older than 3
+// days that are still uncompressed
+func (s *SQLiteStorage) CompressOldArticles(ctx context.Context) error {
 	// Use a longer timeout to avoid deadlocks
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
 	if s.config == nil || !s.config.EnableContentCompression {
@@ -1251,6 +1162,8 @@ func (s *SQLiteStorage) CompressOldArticles() error {
 		LEFT JOIN compressed_content c ON a.article_id = c.article_id 
 		WHERE a.published_at < ? 
 		AND a.content != '' 
+		AND a.pinned = 0
+		AND NOT `+fmt.Sprintf(sqlStarred, "a")+`
 		AND c.article_id IS NULL
 	`, threeDaysAgo).Scan(&count)
 	if err != nil {
@@ -1271,13 +1184,30 @@ func (s *SQLiteStorage) CompressOldArticles() error {
 		LEFT JOIN compressed_content c ON a.article_id = c.article_id 
 		WHERE a.published_at < ? 
 		AND a.content != '' 
+		AND a.pinned = 0
+		AND NOT `+fmt.Sprintf(sqlStarred, "a")+`
 		AND c.article_id IS NULL
 		LIMIT 1000
 	`, threeDaysAgo)
 	if err != nil {
 		return fmt.Errorf("failed to query old articles: %v", err)
 	}
-	defer rows.Close()
+
+	// Read the articles first, the transaction needs the single connection
+	contents := make(map[string]string)
+	for rows.Next() {
+		var articleID, content string
+		if err := rows.Scan(&articleID, &content); err != nil {
+			log.Printf("Warning: failed to scan article for compression: %v", err)
+			continue
+		}
+		contents[articleID] = content
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read old articles: %v", err)
+	}
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -1294,16 +1224,16 @@ func (s *SQLiteStorage) CompressOldArticles() error {
 		}
 	}()
 
-	compressedStmt, err := tx.Prepare(`
-		INSERT OR REPLACE INTO compressed_content (article_id, compressed_content, compressed_at)
-		VALUES (?, ?, CURRENT_TIMESTAMP)
+	compressedStmt, err := tx.PrepareContext(ctx, `
+		INSERT OR REPLACE INTO compressed_content (article_id, compressed_content, codec, original_size, compressed_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare compressed content statement: %v", err)
 	}
 	defer compressedStmt.Close()
 
-	updateStmt, err := tx.Prepare(`
+	updateStmt, err := tx.PrepareContext(ctx, `
 		UPDATE articles SET content = '' WHERE article_id = ?
 	`)
 	if err != nil {
@@ -1311,33 +1241,28 @@ func (s *SQLiteStorage) CompressOldArticles() error {
 	}
 	defer updateStmt.Close()
 
+	codec := compressionCodec(s.config)
 	compressedCount := 0
-	for rows.Next() {
-		var articleID, content string
-		if err := rows.Scan(&articleID, &content); err != nil {
-			log.Printf("Warning: failed to scan article for compression: %v", err)
-			continue
-		}
-
+	for articleID, content := range contents {
 		if len(content) == 0 {
 			continue
 		}
 
 		// Compress content
-		compressed, err := compressContent(content)
+		compressed, err := compressContent(codec, content)
 		if err != nil {
 			log.Printf("Warning: failed to compress content for article %s: %v", articleID, err)
 			continue
 		}
 
 		// Store compressed content
-		if _, err := compressedStmt.Exec(articleID, compressed); err != nil {
+		if _, err := compressedStmt.ExecContext(ctx, articleID, compressed, codec, len(content)); err != nil {
 			log.Printf("Warning: failed to store compressed content for article %s: %v", articleID, err)
 			continue
 		}
 
 		// Clear uncompressed content
-		if _, err := updateStmt.Exec(articleID); err != nil {
+		if _, err := updateStmt.ExecContext(ctx, articleID); err != nil {
 			log.Printf("Warning: failed to clear uncompressed content for article %s: %v", articleID, err)
 			continue
 		}
@@ -1357,46 +1282,6 @@ func (s *SQLiteStorage) CompressOldArticles() error {
 	return nil
 }
 
-// compressContent compresses text content using gzip
-func compressContent(content string) ([]byte, error) {
-	if content == "" {
-		return nil, nil
-	}
-
-	var buf bytes.Buffer
-	gw := gzip.NewWriter(&buf)
-
-	if _, err := gw.Write([]byte(content)); err != nil {
-		return nil, fmt.Errorf("failed to compress content: %v", err)
-	}
-
-	if err := gw.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close gzip writer: %v", err)
-	}
-
-	return buf.Bytes(), nil
-}
-
-// decompressContent decompresses gzipped content
-func decompressContent(compressed []byte) (string, error) {
-	if len(compressed) == 0 {
-		return "", nil
-	}
-
-	gr, err := gzip.NewReader(bytes.NewReader(compressed))
-	if err != nil {
-		return "", fmt.Errorf("failed to create gzip reader: %v", err)
-	}
-	defer gr.Close()
-
-	decompressed, err := io.ReadAll(gr)
-	if err != nil {
-		return "", fmt.Errorf("failed to decompress content: %v", err)
-	}
-
-	return string(decompressed), nil
-}
-
 // cleanAndOptimizeContent cleans and optimizes article content for storage
 func cleanAndOptimizeContent(content string) string {
 	if content == "" {
@@ -1415,13 +1300,25 @@ func cleanAndOptimizeContent(content string) string {
 	return content
 }
 
-// updateSearchIndexWithTx updates the search index for an article using a transaction
-func (s *SQLiteStorage) updateSearchIndexWithTx(tx *sql.Tx, articleID string, title, description, content, author, source string) error {
+// updateSearchIndexWithTx updates the search index and the category index for
+// an article using a transaction
+func (s *SQLiteStorage) updateSearchIndexWithTx(ctx context.Context, tx *sql.Tx, articleID string, title, description, content, author, source string, categories []string) error {
 	// Delete existing search index entries for this article
-	if _, err := tx.Exec("DELETE FROM search_index WHERE article_id = ?", articleID); err != nil {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM search_index WHERE article_id = ?", articleID); err != nil {
 		log.Printf("Warning: failed to delete existing search index: %v", err)
 		return err
 	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM article_categories WHERE article_id = ?", articleID); err != nil {
+		return err
+	}
+	for _, category := range categories {
+		if category == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO article_categories (article_id, category) VALUES (?, ?)", articleID, strings.ToLower(category)); err != nil {
+			log.Printf("Warning: failed to insert category %s for article %s: %v", category, articleID, err)
+		}
+	}
 
 	// Extract and insert search terms for each field
 	fields := map[string]string{
@@ -1438,11 +1335,11 @@ func (s *SQLiteStorage) updateSearchIndexWithTx(tx *sql.Tx, articleID string, ti
 		}
 
 		// Extract search terms from text
-		terms := s.extractSearchTerms(text)
+		terms := extractSearchTerms(s.detector, text)
 		for lang, termList := range terms {
 			for _, term := range termList {
 				if len(term) > 2 { // Only index meaningful terms
-					_, err := tx.Exec(`
+					_, err := tx.ExecContext(ctx, `
 						INSERT INTO search_index (article_id, search_term, field_type, language)
 						VALUES (?, ?, ?, ?)
 					`, articleID, strings.ToLower(term), fieldType, lang)
@@ -1458,13 +1355,13 @@ func (s *SQLiteStorage) updateSearchIndexWithTx(tx *sql.Tx, articleID string, ti
 }
 
 // updateSearchIndex updates the search index for an article
-func (s *SQLiteStorage) updateSearchIndex(articleID string, title, description, content, author, source string) error {
+func (s *SQLiteStorage) updateSearchIndex(ctx context.Context, articleID string, title, description, content, author, source string) error {
 	// Remove mutex lock since this is called from within SaveFeed which already has the lock
 	// s.mutex.Lock()
 	// defer s.mutex.Unlock()
 
 	// Delete existing search index entries for this article
-	if _, err := s.db.Exec("DELETE FROM search_index WHERE article_id = ?", articleID); err != nil {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM search_index WHERE article_id = ?", articleID); err != nil {
 		log.Printf("Warning: failed to delete existing search index: %v", err)
 		return err
 	}
@@ -1484,11 +1381,11 @@ func (s *SQLiteStorage) updateSearchIndex(articleID string, title, description,
 		}
 
 		// Extract search terms from text
-		terms := s.extractSearchTerms(text)
+		terms := extractSearchTerms(s.detector, text)
 		for lang, termList := range terms {
 			for _, term := range termList {
 				if len(term) > 2 { // Only index meaningful terms
-					_, err := s.db.Exec(`
+					_, err := s.db.ExecContext(ctx, `
 						INSERT INTO search_index (article_id, search_term, field_type, language)
 						VALUES (?, ?, ?, ?)
 					`, articleID, strings.ToLower(term), fieldType, lang)
@@ -1503,57 +1400,6 @@ func (s *SQLiteStorage) updateSearchIndex(articleID string, title, description,
 	return nil
 }
 
-// detectLanguage detects the language of the given text using the lingua-go library
-func (s *SQLiteStorage) detectLanguage(text string) string {
-	if text == "" {
-		return "en" // Default to English
-	}
-
-	// Use the proper language detection library
-	language, exists := s.detector.DetectLanguageOf(text)
-	if !exists {
-		return "en" // Default to English if detection fails
-	}
-
-	// Convert lingua language to our language codes
-	switch language {
-	case lingua.English:
-		return "en"
-	case lingua.German:
-		return "de"
-	case lingua.French:
-		return "fr"
-	case lingua.Spanish:
-		return "es"
-	case lingua.Chinese:
-		return "zh"
-	case lingua.Russian:
-		return "ru"
-	case lingua.Italian:
-		return "it"
-	case lingua.Portuguese:
-		return "pt"
-	case lingua.Dutch:
-		return "nl"
-	case lingua.Swedish:
-		return "sv"
-	case lingua.Danish:
-		return "da"
-	case lingua.Finnish:
-		return "fi"
-	case lingua.Polish:
-		return "pl"
-	case lingua.Czech:
-		return "cs"
-	case lingua.Hungarian:
-		return "hu"
-	case lingua.Romanian:
-		return "ro"
-	default:
-		return "en" // Default to English for unsupported languages
-	}
-}
-
 // getStopWords returns stop words for the given language
 func getStopWords(language string) map[string]bool {
 	switch language {
@@ -1642,13 +1488,13 @@ func getStopWords(language string) map[string]bool {
 }
 
 // extractSearchTerms extracts meaningful search terms from text with language support
-func (s *SQLiteStorage) extractSearchTerms(text string) map[string][]string {
+func extractSearchTerms(detector lingua.LanguageDetector, text string) map[string][]string {
 	if text == "" {
 		return make(map[string][]string)
 	}
 
 	// Detect language using the proper library
-	language := s.detectLanguage(text)
+	language := detectLanguageCode(detector, text)
 
 	// Get language-specific stop words
 	stopWords := getStopWords(language)
@@ -1713,97 +1559,41 @@ func (s *SQLiteStorage) extractSearchTerms(text string) map[string][]string {
 	}
 }
 
-// searchArticlesIndex performs full-text search using the search index table with multi-language support
-func (s *SQLiteStorage) searchArticlesIndex(searchTerms []string, topicID int) ([]string, error) {
-	if len(searchTerms) == 0 {
+// searchArticlesIndex evaluates a $search expression against the search index table
+func (s *SQLiteStorage) searchArticlesIndex(ctx context.Context, expr *odata.SearchExpression, topicID int) ([]string, error) {
+	if expr == nil {
 		return nil, nil
 	}
 
-	// Detect language of search terms
-	searchText := strings.Join(searchTerms, " ")
-	searchLanguage := s.detectLanguage(searchText)
-
-	// Use a more secure approach with individual OR conditions instead of IN clause
-	// This avoids SQL string concatenation while maintaining functionality
-	var articleIDs []string
-
-	// Try language-specific search first
-	for _, term := range searchTerms {
-		query := `
-			SELECT DISTINCT a.article_id 
-			FROM articles a
-			JOIN search_index si ON a.article_id = si.article_id
-			WHERE a.topic_id = ? AND si.language = ? AND si.search_term = ?
-			ORDER BY a.published_at DESC
-		`
-
-		rows, err := s.db.Query(query, topicID, searchLanguage, strings.ToLower(term))
-		if err != nil {
-			return nil, fmt.Errorf("failed to search index: %v", err)
-		}
-
-		for rows.Next() {
-			var articleID string
-			if err := rows.Scan(&articleID); err != nil {
-				if closeErr := rows.Close(); closeErr != nil {
-					log.Printf("Warning: failed to close rows: %v", closeErr)
-				}
-				return nil, fmt.Errorf("failed to scan search result: %v", err)
-			}
-			articleIDs = append(articleIDs, articleID)
-		}
-		if err := rows.Close(); err != nil {
-			log.Printf("Warning: failed to close rows: %v", err)
-		}
-	}
-
-	// If no results found in the same language, try cross-language search
-	if len(articleIDs) == 0 {
-		for _, term := range searchTerms {
-			query := `
-				SELECT DISTINCT a.article_id 
-				FROM articles a
-				JOIN search_index si ON a.article_id = si.article_id
-				WHERE a.topic_id = ? AND si.search_term = ?
-				ORDER BY a.published_at DESC
-			`
-
-			rows, err := s.db.Query(query, topicID, strings.ToLower(term))
-			if err != nil {
-				return nil, fmt.Errorf("failed to search index cross-language: %v", err)
-			}
+	searchClause, searchArgs := buildIndexedSearchClause(expr, "a", "a.article_id")
+	query := `
+		SELECT a.article_id
+		FROM articles a
+		WHERE a.article_id IN (SELECT article_id FROM article_topics WHERE topic_id = ?) AND ` + searchClause + `
+		ORDER BY a.published_at DESC
+	`
+	args := append([]interface{}{topicID}, searchArgs...)
 
-			for rows.Next() {
-				var articleID string
-				if err := rows.Scan(&articleID); err != nil {
-					if closeErr := rows.Close(); closeErr != nil {
-						log.Printf("Warning: failed to close rows: %v", closeErr)
-					}
-					return nil, fmt.Errorf("failed to scan search result: %v", err)
-				}
-				articleIDs = append(articleIDs, articleID)
-			}
-			if err := rows.Close(); err != nil {
-				log.Printf("Warning: failed to close rows: %v", err)
-			}
-		}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search index: %v", err)
 	}
+	defer rows.Close()
 
-	// Remove duplicates while preserving order
-	seen := make(map[string]bool)
-	var uniqueArticleIDs []string
-	for _, id := range articleIDs {
-		if !seen[id] {
-			seen[id] = true
-			uniqueArticleIDs = append(uniqueArticleIDs, id)
+	var articleIDs []string
+	for rows.Next() {
+		var articleID string
+		if err := rows.Scan(&articleID); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %v", err)
 		}
+		articleIDs = append(articleIDs, articleID)
 	}
 
-	return uniqueArticleIDs, nil
+	return articleIDs, rows.Err()
 }
 
 // GetFeedStats returns detailed statistics for each feed
-func (s *SQLiteStorage) GetFeedStats() (map[string]interface{}, error) {
+func (s *SQLiteStorage) GetFeedStats(ctx context.Context) (map[string]interface{}, error) {
 	query := `
 		SELECT 
 			source,
@@ -1818,7 +1608,7 @@ func (s *SQLiteStorage) GetFeedStats() (map[string]interface{}, error) {
 		ORDER BY article_count DESC
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query feed stats: %v", err)
 	}
@@ -1862,30 +1652,51 @@ func (s *SQLiteStorage) GetFeedStats() (map[string]interface{}, error) {
 		feeds = append(feeds, feed)
 	}
 
+	// Article counts per feed URL; sources only tell feeds apart by title
+	feedURLs, err := getSQLFeedURLStats(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
 	return map[string]interface{}{
-		"feeds": feeds,
+		"feeds":     feeds,
+		"feed_urls": feedURLs,
 	}, nil
 }
 
 // SaveArticles saves articles independently of topics (new approach)
-func (s *SQLiteStorage) SaveArticles(articles []models.Article) error {
+func (s *SQLiteStorage) SaveArticles(ctx context.Context, articles []models.Article) error {
 	if len(articles) == 0 {
 		return nil
 	}
 
 	log.Printf("SaveArticles: Starting to save %d articles independently", len(articles))
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
 	defer tx.Rollback()
 
-	// Use INSERT OR REPLACE to handle duplicates gracefully
-	// Set topic_id to 1 temporarily (will be updated later by AssignArticlesToTopic)
-	stmt, err := tx.Prepare(`
-		INSERT OR REPLACE INTO articles (article_id, topic_id, title, link, description, content, author, source, categories, published_at, language)
-		VALUES (?, 1, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	// Upsert so existing rows keep their memberships and pin; saving never unpins
+	// Topic membership is recorded later by AssignArticlesToTopic
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO articles (article_id, title, link, description, content, author, source, categories, published_at, language, pinned, content_hash, content_updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(article_id) DO UPDATE SET
+			title = excluded.title,
+			link = excluded.link,
+			description = excluded.description,
+			content = excluded.content,
+			author = excluded.author,
+			source = excluded.source,
+			categories = excluded.categories,
+			published_at = excluded.published_at,
+			language = excluded.language,
+			pinned = MAX(articles.pinned, excluded.pinned),
+			content_hash = excluded.content_hash,
+			content_updated_at = COALESCE(excluded.content_updated_at, articles.content_updated_at),
+			updated_at = CURRENT_TIMESTAMP
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare insert statement: %v", err)
@@ -1896,19 +1707,30 @@ func (s *SQLiteStorage) SaveArticles(articles []models.Article) error {
 	for _, article := range articles {
 		categoriesJSON, _ := json.Marshal(article.Categories)
 		content := cleanAndOptimizeContent(article.Content)
-		articleLanguage := s.detectLanguage(article.Title + " " + article.Description + " " + article.Content)
+		language := articleLanguage(s.detector, article)
+
+		// Keep the replaced version when the publisher changed the article
+		contentHash, changedAt, err := recordSQLRevision(ctx, tx, article, content)
+		if err != nil {
+			return err
+		}
 
-		_, err = stmt.Exec(article.ID, article.Title, article.Link, article.Description, content, article.Author, article.Source, categoriesJSON, article.PublishedAt, articleLanguage)
+		_, err = stmt.ExecContext(ctx, article.ID, article.Title, article.Link, article.Description, content, article.Author, article.Source, categoriesJSON, article.PublishedAt, language, article.Pinned, contentHash, changedAt)
 		if err != nil {
 			log.Printf("Warning: failed to insert article %s: %v", article.ID, err)
 			continue // Continue with other articles instead of failing completely
 		}
 
 		// Update search index
-		if err := s.updateSearchIndexWithTx(tx, article.ID, article.Title, article.Description, content, article.Author, article.Source); err != nil {
+		if err := s.updateSearchIndexWithTx(ctx, tx, article.ID, article.Title, article.Description, content, article.Author, article.Source, article.Categories); err != nil {
 			log.Printf("Warning: failed to update search index for article %s: %v", article.ID, err)
 		}
 
+		// Assign the article to a story cluster
+		if err := s.assignClusterWithTx(ctx, tx, article, content); err != nil {
+			log.Printf("Warning: failed to cluster article %s: %v", article.ID, err)
+		}
+
 		successCount++
 	}
 
@@ -1921,52 +1743,64 @@ func (s *SQLiteStorage) SaveArticles(articles []models.Article) error {
 }
 
 // AssignArticlesToTopic assigns articles to a topic after they're stored
-func (s *SQLiteStorage) AssignArticlesToTopic(articleIDs []string, topic string) error {
+func (s *SQLiteStorage) AssignArticlesToTopic(ctx context.Context, articleIDs []string, topic string) error {
+	return s.assignArticlesToTopic(ctx, articleIDs, topic, true)
+}
+
+// RestoreArticlesToTopic assigns imported articles to a topic without queuing webhook deliveries
+func (s *SQLiteStorage) RestoreArticlesToTopic(ctx context.Context, articleIDs []string, topic string) error {
+	return s.assignArticlesToTopic(ctx, articleIDs, topic, false)
+}
+
+// assignArticlesToTopic records topic memberships, queuing the webhook
+// deliveries of the new ones when queueWebhooks is set
+func (s *SQLiteStorage) assignArticlesToTopic(ctx context.Context, articleIDs []string, topic string, queueWebhooks bool) error {
 	if len(articleIDs) == 0 {
 		return nil
 	}
 
 	log.Printf("AssignArticlesToTopic: Assigning %d articles to topic '%s'", len(articleIDs), topic)
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
 	defer tx.Rollback()
 
 	// Get or create topic
-	topicID, err := s.getOrCreateTopic(tx, topic)
+	topicID, err := s.getOrCreateTopic(ctx, tx, topic)
 	if err != nil {
 		return err
 	}
 
 	// Use the new article_topics table for many-to-many relationships
-	stmt, err := tx.Prepare("INSERT OR IGNORE INTO article_topics (article_id, topic_id) VALUES (?, ?)")
+	stmt, err := tx.PrepareContext(ctx, "INSERT OR IGNORE INTO article_topics (article_id, topic_id) VALUES (?, ?)")
 	if err != nil {
 		return fmt.Errorf("failed to prepare insert statement: %v", err)
 	}
 	defer stmt.Close()
 
-	// Also update the legacy topic_id column for backward compatibility
-	legacyStmt, err := tx.Prepare("UPDATE articles SET topic_id = ? WHERE article_id = ? AND topic_id = 1")
-	if err != nil {
-		return fmt.Errorf("failed to prepare legacy update statement: %v", err)
-	}
-	defer legacyStmt.Close()
-
 	successCount := 0
+	var newIDs []string
 	for _, articleID := range articleIDs {
 		// Insert into membership table
-		_, err = stmt.Exec(articleID, topicID)
+		result, err := stmt.ExecContext(ctx, articleID, topicID)
 		if err != nil {
 			log.Printf("Warning: failed to assign article %s to topic %s: %v", articleID, topic, err)
 			continue
 		}
 
-		// Update legacy column only if it's still set to the default (1)
-		_, _ = legacyStmt.Exec(topicID, articleID)
-
 		successCount++
+		if inserted, _ := result.RowsAffected(); inserted > 0 {
+			newIDs = append(newIDs, articleID)
+		}
+	}
+
+	// Webhooks only fire for new memberships, with the membership itself
+	if queueWebhooks {
+		if _, err := queueSQLWebhookDeliveries(ctx, tx, newIDs, topic); err != nil {
+			return err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -1978,13 +1812,13 @@ func (s *SQLiteStorage) AssignArticlesToTopic(articleIDs []string, topic string)
 }
 
 // AddArticleToTopic adds a single article to a topic
-func (s *SQLiteStorage) AddArticleToTopic(articleID string, topic string) error {
-	return s.AssignArticlesToTopic([]string{articleID}, topic)
+func (s *SQLiteStorage) AddArticleToTopic(ctx context.Context, articleID string, topic string) error {
+	return s.AssignArticlesToTopic(ctx, []string{articleID}, topic)
 }
 
 // RemoveArticleFromTopic removes an article from a topic
-func (s *SQLiteStorage) RemoveArticleFromTopic(articleID string, topic string) error {
-	tx, err := s.db.Begin()
+func (s *SQLiteStorage) RemoveArticleFromTopic(ctx context.Context, articleID string, topic string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
@@ -1992,13 +1826,13 @@ func (s *SQLiteStorage) RemoveArticleFromTopic(articleID string, topic string) e
 
 	// Get topic ID
 	var topicID int
-	err = tx.QueryRow("SELECT id FROM topics WHERE name = ?", topic).Scan(&topicID)
+	err = tx.QueryRowContext(ctx, "SELECT id FROM topics WHERE name = ?", topic).Scan(&topicID)
 	if err != nil {
 		return fmt.Errorf("topic not found: %v", err)
 	}
 
 	// Remove from membership table
-	_, err = tx.Exec("DELETE FROM article_topics WHERE article_id = ? AND topic_id = ?", articleID, topicID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM article_topics WHERE article_id = ? AND topic_id = ?", articleID, topicID)
 	if err != nil {
 		return fmt.Errorf("failed to remove article from topic: %v", err)
 	}
@@ -2007,8 +1841,8 @@ func (s *SQLiteStorage) RemoveArticleFromTopic(articleID string, topic string) e
 }
 
 // GetArticleTopics returns all topics for an article
-func (s *SQLiteStorage) GetArticleTopics(articleID string) ([]string, error) {
-	rows, err := s.db.Query(`
+func (s *SQLiteStorage) GetArticleTopics(ctx context.Context, articleID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
 		SELECT t.name 
 		FROM topics t 
 		JOIN article_topics at ON t.id = at.topic_id 
@@ -2033,25 +1867,27 @@ func (s *SQLiteStorage) GetArticleTopics(articleID string) ([]string, error) {
 }
 
 // GetTopicArticles returns articles for a topic using the membership table
-func (s *SQLiteStorage) GetTopicArticles(topic string, query *models.ODataQuery) ([]models.Article, int, error) {
-	// This method uses the new article_topics table instead of the legacy topic_id column
+func (s *SQLiteStorage) GetTopicArticles(ctx context.Context, topic string, query *models.ODataQuery) ([]models.Article, int, error) {
 	baseQuery := `
 		SELECT 
 			a.article_id, 
 			a.title, 
 			a.description, 
-			a.content, 
 			a.link, 
 			a.author, 
 			a.source, 
 			a.published_at, 
 			a.categories, 
 			a.language,
-			cc.compressed_content
+			a.pinned,
+			a.content_updated_at,
+			COALESCE(ac.cluster_id, a.article_id) as cluster_id,
+			` + articleContentColumns(query) + `
 		FROM articles a
 		JOIN article_topics at ON a.article_id = at.article_id
 		JOIN topics t ON at.topic_id = t.id
 		LEFT JOIN compressed_content cc ON a.article_id = cc.article_id
+		LEFT JOIN article_clusters ac ON a.article_id = ac.article_id
 		WHERE t.name = ?
 	`
 
@@ -2061,60 +1897,71 @@ func (s *SQLiteStorage) GetTopicArticles(topic string, query *models.ODataQuery)
 		FROM articles a
 		JOIN article_topics at ON a.article_id = at.article_id
 		JOIN topics t ON at.topic_id = t.id
+		LEFT JOIN article_clusters ac ON a.article_id = ac.article_id
 		WHERE t.name = ?
 	`
 
 	args := []interface{}{topic}
 	countArgs := []interface{}{topic}
 
-	// Add search conditions if specified
-	if len(query.Search) > 0 {
-		searchConditions := make([]string, len(query.Search))
-		for i, term := range query.Search {
-			searchConditions[i] = "(a.title LIKE ? OR a.description LIKE ? OR a.content LIKE ?)"
-			searchTerm := "%" + term + "%"
-			args = append(args, searchTerm, searchTerm, searchTerm)
-			countArgs = append(countArgs, searchTerm, searchTerm, searchTerm)
+	// Add search, filter and drill-down conditions
+	conditions, conditionArgs, err := buildArticleConditions(query, "a")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Keep one representative per story cluster within the topic
+	if query.Cluster {
+		clusterCondition, clusterArgs, err := buildClusterCondition(query, topic)
+		if err != nil {
+			return nil, 0, err
 		}
-		searchClause := " AND (" + strings.Join(searchConditions, " OR ") + ")"
-		baseQuery += searchClause
-		countQuery += searchClause
+		conditions += clusterCondition
+		conditionArgs = append(conditionArgs, clusterArgs...)
 	}
+	baseQuery += conditions
+	countQuery += conditions
+	args = append(args, conditionArgs...)
+	countArgs = append(countArgs, conditionArgs...)
 
 	// Get total count
 	var totalCount int
-	err := s.db.QueryRow(countQuery, countArgs...).Scan(&totalCount)
+	err = s.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&totalCount)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count articles: %v", err)
 	}
 
-	// Add ordering
-	if query.OrderBy != "" {
+	// Add keyset pagination and ordering
+	if query.SkipToken != nil {
+		keyset, keysetArgs := buildKeysetCondition(query.SkipToken, "a")
+		baseQuery += keyset + " ORDER BY " + keysetOrder("a")
+		args = append(args, keysetArgs...)
+	} else if query.OrderBy != "" {
 		if strings.Contains(strings.ToLower(query.OrderBy), "desc") {
 			baseQuery += " ORDER BY a.published_at DESC"
 		} else {
 			baseQuery += " ORDER BY a.published_at ASC"
 		}
 	} else {
-		baseQuery += " ORDER BY a.published_at DESC" // Default ordering
+		baseQuery += " ORDER BY " + keysetOrder("a") // Default ordering
 	}
 
 	// Add pagination - LIMIT must come before OFFSET in SQLite
 	if query.Top > 0 {
 		baseQuery += " LIMIT ?"
 		args = append(args, query.Top)
-		if query.Skip > 0 {
+		if query.Skip > 0 && query.SkipToken == nil {
 			baseQuery += " OFFSET ?"
 			args = append(args, query.Skip)
 		}
-	} else if query.Skip > 0 {
+	} else if query.Skip > 0 && query.SkipToken == nil {
 		// If only skip is specified, we need a default limit
 		baseQuery += " LIMIT -1 OFFSET ?"
 		args = append(args, query.Skip)
 	}
 
 	// Execute query
-	rows, err := s.db.Query(baseQuery, args...)
+	rows, err := s.db.QueryContext(ctx, baseQuery, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query topic articles: %v", err)
 	}
@@ -2124,19 +1971,24 @@ func (s *SQLiteStorage) GetTopicArticles(topic string, query *models.ODataQuery)
 	for rows.Next() {
 		var article models.Article
 		var compressedContent []byte
+		var codec sql.NullString
 		var categoriesJSON string
 
 		err := rows.Scan(
 			&article.ID,
 			&article.Title,
 			&article.Description,
-			&article.Content,
 			&article.Link,
 			&article.Author,
 			&article.Source,
 			&article.PublishedAt,
 			&categoriesJSON,
 			&article.Language,
+			&article.Pinned,
+			&article.UpdatedAt,
+			&article.ClusterID,
+			&article.Content,
+			&codec,
 			&compressedContent,
 		)
 		if err != nil {
@@ -2145,7 +1997,7 @@ func (s *SQLiteStorage) GetTopicArticles(topic string, query *models.ODataQuery)
 
 		// Handle compressed content
 		if len(compressedContent) > 0 && article.Content == "" {
-			if decompressed, err := decompressContent(compressedContent); err == nil {
+			if decompressed, err := decompressContent(codec.String, compressedContent); err == nil {
 				article.Content = decompressed
 			}
 		}
@@ -2158,11 +2010,21 @@ func (s *SQLiteStorage) GetTopicArticles(topic string, query *models.ODataQuery)
 		articles = append(articles, article)
 	}
 
+	if err := attachSQLFeedURLs(ctx, s.db, articles); err != nil {
+		return nil, 0, err
+	}
+
+	if query.Cluster {
+		if err := attachClusterSources(ctx, s.db, articles); err != nil {
+			return nil, 0, err
+		}
+	}
+
 	return articles, totalCount, nil
 }
 
 // GetCombinedFilters combines filters from multiple topics
-func (s *SQLiteStorage) GetCombinedFilters(topics []string) ([]string, bool) {
+func (s *SQLiteStorage) GetCombinedFilters(ctx context.Context, topics []string) ([]string, bool) {
 	// This method would ideally read topic configurations from database
 	// For now, we'll implement the logic in the aggregator layer
 	return nil, false
@@ -1,7 +1,10 @@
 package storage
 
 import (
+	"context"
 	"fmt"
+	"math/bits"
+	"reflect"
 	"testing"
 	"time"
 
@@ -64,13 +67,13 @@ func TestSQLiteStorage_BasicOperations(t *testing.T) {
 	}
 
 	// Test SaveFeed
-	err = storage.SaveFeed("test-topic", feed)
+	err = storage.SaveFeed(context.Background(), "test-topic", feed)
 	if err != nil {
 		t.Fatalf("Failed to save feed: %v", err)
 	}
 
 	// Test LoadFeed
-	loadedFeed, err := storage.LoadFeed("test-topic")
+	loadedFeed, err := storage.LoadFeed(context.Background(), "test-topic")
 	if err != nil {
 		t.Fatalf("Failed to load feed: %v", err)
 	}
@@ -84,7 +87,7 @@ func TestSQLiteStorage_BasicOperations(t *testing.T) {
 	}
 
 	// Test ListTopics
-	topics, err := storage.ListTopics()
+	topics, err := storage.ListTopics(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to list topics: %v", err)
 	}
@@ -98,7 +101,7 @@ func TestSQLiteStorage_BasicOperations(t *testing.T) {
 	}
 
 	// Test GetFeedInfo
-	info, err := storage.GetFeedInfo("test-topic")
+	info, err := storage.GetFeedInfo(context.Background(), "test-topic")
 	if err != nil {
 		t.Fatalf("Failed to get feed info: %v", err)
 	}
@@ -163,7 +166,7 @@ func TestSQLiteStorage_ODataQueries(t *testing.T) {
 	}
 
 	// Save feed
-	err = storage.SaveFeed("tech", feed)
+	err = storage.SaveFeed(context.Background(), "tech", feed)
 	if err != nil {
 		t.Fatalf("Failed to save feed: %v", err)
 	}
@@ -173,7 +176,7 @@ func TestSQLiteStorage_ODataQueries(t *testing.T) {
 		Search: []string{"Blockchain"},
 	}
 
-	results, err := storage.QueryArticles("tech", query)
+	results, err := storage.QueryArticles(context.Background(), "tech", query)
 	if err != nil {
 		t.Fatalf("Failed to query articles: %v", err)
 	}
@@ -191,7 +194,7 @@ func TestSQLiteStorage_ODataQueries(t *testing.T) {
 		Top: 1,
 	}
 
-	results, err = storage.QueryArticles("tech", query)
+	results, err = storage.QueryArticles(context.Background(), "tech", query)
 	if err != nil {
 		t.Fatalf("Failed to query articles with top limit: %v", err)
 	}
@@ -223,6 +226,18 @@ func TestSQLiteStorage_StorageFactory(t *testing.T) {
 	}
 }
 
+func TestNewStorage_MemoryDriver(t *testing.T) {
+	storage, err := NewStorage(t.TempDir(), &config.Config{StorageDriver: "memory"})
+	if err != nil {
+		t.Fatalf("Failed to create memory storage via factory: %v", err)
+	}
+	defer storage.Close()
+
+	if _, ok := storage.(*MemoryStorage); !ok {
+		t.Errorf("Expected *MemoryStorage, got %T", storage)
+	}
+}
+
 func TestSQLiteStorage_Compression(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -260,13 +275,13 @@ func TestSQLiteStorage_Compression(t *testing.T) {
 	}
 
 	// Save feed
-	err = storage.SaveFeed("test-compression", feed)
+	err = storage.SaveFeed(context.Background(), "test-compression", feed)
 	if err != nil {
 		t.Fatalf("Failed to save feed: %v", err)
 	}
 
 	// Load feed and verify content is accessible
-	loadedFeed, err := storage.LoadFeed("test-compression")
+	loadedFeed, err := storage.LoadFeed(context.Background(), "test-compression")
 	if err != nil {
 		t.Fatalf("Failed to load feed: %v", err)
 	}
@@ -296,7 +311,7 @@ func TestSQLiteStorage_DatabaseStats(t *testing.T) {
 	defer storage.Close()
 
 	// Test GetDatabaseStats
-	stats, err := storage.GetDatabaseStats()
+	stats, err := storage.GetDatabaseStats(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to get database stats: %v", err)
 	}
@@ -307,7 +322,7 @@ func TestSQLiteStorage_DatabaseStats(t *testing.T) {
 	}
 
 	// Test GetFeedStats
-	feedStats, err := storage.GetFeedStats()
+	feedStats, err := storage.GetFeedStats(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to get feed stats: %v", err)
 	}
@@ -335,19 +350,19 @@ func TestSQLiteStorage_CleanupOperations(t *testing.T) {
 	defer storage.Close()
 
 	// Test CleanupOldArticles
-	err = storage.CleanupOldArticles(24 * time.Hour)
+	err = storage.CleanupOldArticles(context.Background(), RetentionPolicy{MaxAge: 24 * time.Hour})
 	if err != nil {
 		t.Fatalf("Failed to cleanup old articles: %v", err)
 	}
 
 	// Test OptimizeDatabase
-	err = storage.OptimizeDatabase()
+	err = storage.OptimizeDatabase(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to optimize database: %v", err)
 	}
 
 	// Test RemoveDuplicateArticles
-	err = storage.RemoveDuplicateArticles()
+	err = storage.RemoveDuplicateArticles(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to remove duplicate articles: %v", err)
 	}
@@ -392,13 +407,13 @@ func TestSQLiteStorage_DeleteFeed(t *testing.T) {
 	}
 
 	// Save feed
-	err = storage.SaveFeed("test-topic", feed)
+	err = storage.SaveFeed(context.Background(), "test-topic", feed)
 	if err != nil {
 		t.Fatalf("Failed to save feed: %v", err)
 	}
 
 	// Verify feed exists
-	topics, err := storage.ListTopics()
+	topics, err := storage.ListTopics(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to list topics: %v", err)
 	}
@@ -407,13 +422,13 @@ func TestSQLiteStorage_DeleteFeed(t *testing.T) {
 	}
 
 	// Delete feed
-	err = storage.DeleteFeed("test-topic")
+	err = storage.DeleteFeed(context.Background(), "test-topic")
 	if err != nil {
 		t.Fatalf("Failed to delete feed: %v", err)
 	}
 
 	// Verify feed is deleted
-	topics, err = storage.ListTopics()
+	topics, err = storage.ListTopics(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to list topics after deletion: %v", err)
 	}
@@ -422,7 +437,7 @@ func TestSQLiteStorage_DeleteFeed(t *testing.T) {
 	}
 
 	// Test deleting non-existent feed (should not error)
-	err = storage.DeleteFeed("non-existent-topic")
+	err = storage.DeleteFeed(context.Background(), "non-existent-topic")
 	if err != nil {
 		t.Errorf("Expected no error when deleting non-existent feed, got %v", err)
 	}
@@ -479,13 +494,13 @@ func TestSQLiteStorage_LanguageDetection(t *testing.T) {
 	}
 
 	// Save feed
-	err = storage.SaveFeed("multilingual", feed)
+	err = storage.SaveFeed(context.Background(), "multilingual", feed)
 	if err != nil {
 		t.Fatalf("Failed to save multilingual feed: %v", err)
 	}
 
 	// Load feed and verify language detection
-	loadedFeed, err := storage.LoadFeed("multilingual")
+	loadedFeed, err := storage.LoadFeed(context.Background(), "multilingual")
 	if err != nil {
 		t.Fatalf("Failed to load multilingual feed: %v", err)
 	}
@@ -565,7 +580,7 @@ func TestSQLiteStorage_AdvancedODataQueries(t *testing.T) {
 	}
 
 	// Save feed
-	err = storage.SaveFeed("test-advanced", feed)
+	err = storage.SaveFeed(context.Background(), "test-advanced", feed)
 	if err != nil {
 		t.Fatalf("Failed to save feed: %v", err)
 	}
@@ -576,7 +591,7 @@ func TestSQLiteStorage_AdvancedODataQueries(t *testing.T) {
 		Top:  2,
 	}
 
-	results, err := storage.QueryArticles("test-advanced", query)
+	results, err := storage.QueryArticles(context.Background(), "test-advanced", query)
 	if err != nil {
 		t.Fatalf("Failed to query articles with skip and top: %v", err)
 	}
@@ -590,7 +605,7 @@ func TestSQLiteStorage_AdvancedODataQueries(t *testing.T) {
 		OrderBy: "publishedAt desc",
 	}
 
-	results, err = storage.QueryArticles("test-advanced", query)
+	results, err = storage.QueryArticles(context.Background(), "test-advanced", query)
 	if err != nil {
 		t.Fatalf("Failed to query articles with order by: %v", err)
 	}
@@ -609,7 +624,7 @@ func TestSQLiteStorage_AdvancedODataQueries(t *testing.T) {
 		Select: []string{"title", "author"},
 	}
 
-	results, err = storage.QueryArticles("test-advanced", query)
+	results, err = storage.QueryArticles(context.Background(), "test-advanced", query)
 	if err != nil {
 		t.Fatalf("Failed to query articles with select: %v", err)
 	}
@@ -630,6 +645,115 @@ func TestSQLiteStorage_AdvancedODataQueries(t *testing.T) {
 	}
 }
 
+func TestSQLiteStorage_SearchSyntax(t *testing.T) {
+	tempDir := t.TempDir()
+
+	cfg := &config.Config{
+		EnableContentCompression: false,
+		MaxContentLength:         10000,
+		EnableDuplicateRemoval:   true,
+	}
+
+	storage, err := NewSQLiteStorage(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	articles := []models.Article{
+		{
+			ID:          "rust-release",
+			Title:       "Rust compiler release",
+			Link:        "https://example.com/rust",
+			Description: "New language features",
+			Content:     "The Rust team shipped a new compiler",
+			Author:      "Alice",
+			Source:      "Lang News",
+			PublishedAt: time.Now(),
+		},
+		{
+			ID:          "rust-game",
+			Title:       "Rust game update",
+			Link:        "https://example.com/rust-game",
+			Description: "Survival game patch notes",
+			Content:     "The survival game Rust got a new map",
+			Author:      "Bob",
+			Source:      "Game News",
+			PublishedAt: time.Now().Add(-time.Hour),
+		},
+		{
+			ID:          "zero-day",
+			Title:       "Browser zero day exploited",
+			Link:        "https://example.com/zero-day",
+			Description: "Attackers exploit a day zero bug",
+			Content:     "A zero day vulnerability in Kubernetes dashboards",
+			Author:      "Carol",
+			Source:      "Security News",
+			PublishedAt: time.Now().Add(-2 * time.Hour),
+		},
+	}
+
+	if err := storage.SaveArticles(context.Background(), articles); err != nil {
+		t.Fatalf("Failed to save articles: %v", err)
+	}
+	if err := storage.AssignArticlesToTopic(context.Background(), []string{"rust-release", "rust-game", "zero-day"}, "tech"); err != nil {
+		t.Fatalf("Failed to assign articles: %v", err)
+	}
+
+	tests := []struct {
+		search   string
+		expected []string
+	}{
+		{"rust NOT game", []string{"rust-release"}},
+		{`"zero day"`, []string{"zero-day"}},
+		{"title:kubernetes", nil},
+		{"content:kubernetes", []string{"zero-day"}},
+		{"kube*", []string{"zero-day"}},
+		{"(compiler OR survival) AND rust", []string{"rust-release", "rust-game"}},
+		{"compiler, exploited", []string{"rust-release", "zero-day"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.search, func(t *testing.T) {
+			query := &models.ODataQuery{Search: []string{tt.search}}
+
+			results, total, err := storage.GetAllArticles(context.Background(), query)
+			if err != nil {
+				t.Fatalf("GetAllArticles failed: %v", err)
+			}
+			if total != len(tt.expected) || len(results) != len(tt.expected) {
+				t.Fatalf("GetAllArticles: expected %d results, got %d (total %d)", len(tt.expected), len(results), total)
+			}
+			for i, id := range tt.expected {
+				if results[i].ID != id {
+					t.Errorf("GetAllArticles: expected result %d to be %s, got %s", i, id, results[i].ID)
+				}
+			}
+
+			topicResults, topicTotal, err := storage.GetTopicArticles(context.Background(), "tech", query)
+			if err != nil {
+				t.Fatalf("GetTopicArticles failed: %v", err)
+			}
+			if topicTotal != len(tt.expected) || len(topicResults) != len(tt.expected) {
+				t.Errorf("GetTopicArticles: expected %d results, got %d (total %d)", len(tt.expected), len(topicResults), topicTotal)
+			}
+
+			indexed, err := storage.QueryArticles(context.Background(), "tech", query)
+			if err != nil {
+				t.Fatalf("QueryArticles failed: %v", err)
+			}
+			if len(indexed) != len(tt.expected) {
+				t.Errorf("QueryArticles: expected %d results, got %d", len(tt.expected), len(indexed))
+			}
+		})
+	}
+
+	// Invalid expressions are reported instead of silently matching nothing
+	if _, _, err := storage.GetAllArticles(context.Background(), &models.ODataQuery{Search: []string{`"unterminated`}}); err == nil {
+		t.Error("Expected error for invalid search expression")
+	}
+}
+
 func TestSQLiteStorage_ErrorHandling(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -646,19 +770,19 @@ func TestSQLiteStorage_ErrorHandling(t *testing.T) {
 	defer storage.Close()
 
 	// Test loading non-existent feed
-	_, err = storage.LoadFeed("non-existent-topic")
+	_, err = storage.LoadFeed(context.Background(), "non-existent-topic")
 	if err == nil {
 		t.Error("Expected error when loading non-existent feed")
 	}
 
 	// Test getting info for non-existent feed
-	_, err = storage.GetFeedInfo("non-existent-topic")
+	_, err = storage.GetFeedInfo(context.Background(), "non-existent-topic")
 	if err == nil {
 		t.Error("Expected error when getting info for non-existent feed")
 	}
 
 	// Test querying non-existent feed
-	_, err = storage.QueryArticles("non-existent-topic", &models.ODataQuery{})
+	_, err = storage.QueryArticles(context.Background(), "non-existent-topic", &models.ODataQuery{})
 	if err == nil {
 		t.Error("Expected error when querying non-existent feed")
 	}
@@ -715,19 +839,19 @@ func TestSQLiteStorage_CompressOldArticles(t *testing.T) {
 	}
 
 	// Save feed
-	err = storage.SaveFeed("old-articles", feed)
+	err = storage.SaveFeed(context.Background(), "old-articles", feed)
 	if err != nil {
 		t.Fatalf("Failed to save old articles feed: %v", err)
 	}
 
 	// Compress old articles
-	err = storage.CompressOldArticles()
+	err = storage.CompressOldArticles(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to compress old articles: %v", err)
 	}
 
 	// Load feed and verify content is still accessible
-	loadedFeed, err := storage.LoadFeed("old-articles")
+	loadedFeed, err := storage.LoadFeed(context.Background(), "old-articles")
 	if err != nil {
 		t.Fatalf("Failed to load old articles feed: %v", err)
 	}
@@ -792,14 +916,14 @@ func TestSQLiteStorage_ConcurrentAccess(t *testing.T) {
 			}
 
 			// Save feed
-			err := storage.SaveFeed(topicName, feed)
+			err := storage.SaveFeed(context.Background(), topicName, feed)
 			if err != nil {
 				t.Errorf("Failed to save feed for %s: %v", topicName, err)
 				return
 			}
 
 			// Load feed
-			_, err = storage.LoadFeed(topicName)
+			_, err = storage.LoadFeed(context.Background(), topicName)
 			if err != nil {
 				t.Errorf("Failed to load feed for %s: %v", topicName, err)
 				return
@@ -810,7 +934,7 @@ func TestSQLiteStorage_ConcurrentAccess(t *testing.T) {
 	wg.Wait()
 
 	// Verify all topics were created
-	topicsList, err := storage.ListTopics()
+	topicsList, err := storage.ListTopics(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to list topics: %v", err)
 	}
@@ -819,3 +943,303 @@ func TestSQLiteStorage_ConcurrentAccess(t *testing.T) {
 		t.Errorf("Expected 3 topics after concurrent access, got %d", len(topicsList))
 	}
 }
+
+func TestSQLiteStorage_GetArticleFacets(t *testing.T) {
+	storage, err := NewSQLiteStorage(t.TempDir(), &config.Config{MaxContentLength: 10000})
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	day := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	articles := []models.Article{
+		{ID: "a1", Title: "Go release", Link: "https://example.com/1", Author: "Alice", Source: "Go Blog", Categories: []string{"go", "release"}, PublishedAt: day},
+		{ID: "a2", Title: "Go generics", Link: "https://example.com/2", Author: "Bob", Source: "Go Blog", Categories: []string{"go"}, PublishedAt: day.Add(-24 * time.Hour)},
+		{ID: "a3", Title: "Rust release", Link: "https://example.com/3", Author: "Alice", Source: "Rust Blog", Categories: []string{"rust", "release"}, PublishedAt: day},
+	}
+
+	if err := storage.SaveArticles(context.Background(), articles); err != nil {
+		t.Fatalf("Failed to save articles: %v", err)
+	}
+	if err := storage.AssignArticlesToTopic(context.Background(), []string{"a1", "a2", "a3"}, "tech"); err != nil {
+		t.Fatalf("Failed to assign articles: %v", err)
+	}
+
+	// Facets cover the full result set, not just the page
+	facets, err := storage.GetArticleFacets(context.Background(), &models.ODataQuery{
+		Top:    1,
+		Facets: []string{"source", "author", "category", "topic", "published"},
+	})
+	if err != nil {
+		t.Fatalf("GetArticleFacets() error = %v", err)
+	}
+
+	expected := models.Facets{
+		"source":    {{Value: "Go Blog", Count: 2}, {Value: "Rust Blog", Count: 1}},
+		"author":    {{Value: "Alice", Count: 2}, {Value: "Bob", Count: 1}},
+		"category":  {{Value: "go", Count: 2}, {Value: "release", Count: 2}, {Value: "rust", Count: 1}},
+		"topic":     {{Value: "tech", Count: 3}},
+		"published": {{Value: "2024-03-09", Count: 1}, {Value: "2024-03-10", Count: 2}},
+	}
+	for facet, want := range expected {
+		if !reflect.DeepEqual(facets[facet], want) {
+			t.Errorf("facet %s = %v, want %v", facet, facets[facet], want)
+		}
+	}
+
+	// Facets follow the search and drill-down filters of the query
+	facets, err = storage.GetArticleFacets(context.Background(), &models.ODataQuery{
+		Search: []string{"release"},
+		Author: "Alice",
+		Facets: []string{"source"},
+	})
+	if err != nil {
+		t.Fatalf("GetArticleFacets() error = %v", err)
+	}
+	want := []models.FacetCount{{Value: "Go Blog", Count: 1}, {Value: "Rust Blog", Count: 1}}
+	if !reflect.DeepEqual(facets["source"], want) {
+		t.Errorf("filtered source facet = %v, want %v", facets["source"], want)
+	}
+}
+
+func TestSQLiteStorage_SkipTokenPagination(t *testing.T) {
+	storage, err := NewSQLiteStorage(t.TempDir(), &config.Config{MaxContentLength: 10000})
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	base := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	var articles []models.Article
+	var ids []string
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("article-%d", i)
+		// Articles 1 and 2 share a timestamp so the cursor has to break the tie on the ID
+		publishedAt := base.Add(-time.Duration(i) * time.Hour)
+		if i == 2 {
+			publishedAt = base.Add(-time.Hour)
+		}
+		articles = append(articles, models.Article{
+			ID:          id,
+			Title:       fmt.Sprintf("Article %d", i),
+			Link:        fmt.Sprintf("https://example.com/%d", i),
+			PublishedAt: publishedAt,
+		})
+		ids = append(ids, id)
+	}
+
+	if err := storage.SaveArticles(context.Background(), articles); err != nil {
+		t.Fatalf("Failed to save articles: %v", err)
+	}
+	if err := storage.AssignArticlesToTopic(context.Background(), ids, "tech"); err != nil {
+		t.Fatalf("Failed to assign articles: %v", err)
+	}
+
+	listings := map[string]func(ctx context.Context, query *models.ODataQuery) ([]models.Article, int, error){
+		"GetAllArticles": storage.GetAllArticles,
+		"GetTopicArticles": func(ctx context.Context, query *models.ODataQuery) ([]models.Article, int, error) {
+			return storage.GetTopicArticles(ctx, "tech", query)
+		},
+	}
+
+	for name, list := range listings {
+		t.Run(name, func(t *testing.T) {
+			// Snapshot the full listing before paging through it
+			all, _, err := list(context.Background(), &models.ODataQuery{})
+			if err != nil {
+				t.Fatalf("Failed to list articles: %v", err)
+			}
+			var expected []string
+			for _, article := range all {
+				expected = append(expected, article.ID)
+			}
+
+			var seen []string
+			query := &models.ODataQuery{Top: 2}
+			for page := 0; page < 5; page++ {
+				results, _, err := list(context.Background(), query)
+				if err != nil {
+					t.Fatalf("page %d: %v", page, err)
+				}
+				for _, article := range results {
+					seen = append(seen, article.ID)
+				}
+				if len(results) < query.Top {
+					break
+				}
+
+				last := results[len(results)-1]
+				query = &models.ODataQuery{Top: 2, SkipToken: &models.SkipToken{PublishedAt: last.PublishedAt, ID: last.ID}}
+
+				// Articles arriving between pages must not shift the cursor
+				if page == 0 {
+					newer := models.Article{ID: "new-" + name, Title: "Breaking", Link: "https://example.com/new/" + name, PublishedAt: base.Add(time.Hour)}
+					if err := storage.SaveArticles(context.Background(), []models.Article{newer}); err != nil {
+						t.Fatalf("Failed to save new article: %v", err)
+					}
+					if err := storage.AssignArticlesToTopic(context.Background(), []string{newer.ID}, "tech"); err != nil {
+						t.Fatalf("Failed to assign new article: %v", err)
+					}
+				}
+			}
+
+			if fmt.Sprint(expected[len(expected)-5:]) != fmt.Sprint([]string{"article-0", "article-2", "article-1", "article-3", "article-4"}) {
+				t.Errorf("listing order = %v, want ties broken by descending ID", expected)
+			}
+			if fmt.Sprint(seen) != fmt.Sprint(expected) {
+				t.Errorf("paged IDs = %v, want %v", seen, expected)
+			}
+		})
+	}
+}
+
+func TestSQLiteStorage_GetRelatedArticles(t *testing.T) {
+	storage, err := NewSQLiteStorage(t.TempDir(), &config.Config{MaxContentLength: 10000})
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	now := time.Now()
+	articles := []models.Article{
+		{ID: "source", Title: "Kubernetes operators reach stable", Link: "https://a.example.com/1", Content: "Kubernetes operators automate cluster upgrades", Source: "Cloud Weekly", Categories: []string{"Cloud"}, PublishedAt: now},
+		{ID: "close", Title: "Writing Kubernetes operators", Link: "https://b.example.com/1", Content: "A guide to operators that automate cluster upgrades", Source: "Dev Blog", Categories: []string{"cloud"}, PublishedAt: now},
+		{ID: "loose", Title: "Cluster sizing tips", Link: "https://c.example.com/1", Content: "Plan your cluster capacity", Source: "Ops Daily", PublishedAt: now},
+		{ID: "unrelated", Title: "Sourdough baking", Link: "https://d.example.com/1", Content: "Flour, water and patience", Source: "Food Mag", PublishedAt: now},
+	}
+
+	if err := storage.SaveArticles(context.Background(), articles); err != nil {
+		t.Fatalf("Failed to save articles: %v", err)
+	}
+
+	related, err := storage.GetRelatedArticles(context.Background(), "source", 10)
+	if err != nil {
+		t.Fatalf("GetRelatedArticles() error = %v", err)
+	}
+
+	var ids []string
+	for _, article := range related {
+		ids = append(ids, article.ID)
+	}
+	if fmt.Sprint(ids) != "[close loose]" {
+		t.Errorf("related = %v, want [close loose]", ids)
+	}
+	if len(related) > 0 && related[0].Content == "" {
+		t.Errorf("Expected related articles to carry their content")
+	}
+
+	related, err = storage.GetRelatedArticles(context.Background(), "source", 1)
+	if err != nil || len(related) != 1 {
+		t.Errorf("Expected limit to be applied, got %d articles (err %v)", len(related), err)
+	}
+
+	// Categories removed from an article no longer relate it
+	articles[1].Categories = nil
+	if err := storage.SaveArticles(context.Background(), articles[1:2]); err != nil {
+		t.Fatalf("Failed to update article: %v", err)
+	}
+	var categories int
+	if err := storage.db.QueryRow("SELECT COUNT(*) FROM article_categories WHERE article_id = 'close'").Scan(&categories); err != nil || categories != 0 {
+		t.Errorf("Expected the category index of the updated article to be cleared, got %d (err %v)", categories, err)
+	}
+
+	if _, err := storage.GetRelatedArticles(context.Background(), "missing", 10); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for unknown article, got %v", err)
+	}
+}
+
+func TestSimHash(t *testing.T) {
+	story := "Major outage hits cloud provider. A major outage at a large cloud provider took down thousands of websites on Tuesday, engineers said the root cause was a faulty configuration change pushed to its global network."
+	syndicated := "Major outage hits cloud provider - BBC News. A major outage at a large cloud provider took down thousands of websites on Tuesday, engineers said the root cause was a faulty configuration change pushed to its global network."
+	unrelated := "Local team wins championship. The home team secured the title after a dramatic final match that went to penalties."
+
+	if distance := bits.OnesCount64(simHash(story) ^ simHash(syndicated)); distance > clusterMaxDistance {
+		t.Errorf("near-duplicate distance = %d, want <= %d", distance, clusterMaxDistance)
+	}
+	if distance := bits.OnesCount64(simHash(story) ^ simHash(unrelated)); distance <= clusterMaxDistance {
+		t.Errorf("unrelated distance = %d, want > %d", distance, clusterMaxDistance)
+	}
+	if simHash("  ,. ") != 0 {
+		t.Errorf("Expected empty text to hash to 0")
+	}
+}
+
+func TestSQLiteStorage_StoryClusters(t *testing.T) {
+	storage, err := NewSQLiteStorage(t.TempDir(), &config.Config{MaxContentLength: 10000})
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	content := "A major outage at a large cloud provider took down thousands of websites on Tuesday, engineers said the root cause was a faulty configuration change pushed to its global network."
+	now := time.Now()
+	articles := []models.Article{
+		{ID: "bbc", Title: "Major outage hits cloud provider - BBC News", Link: "https://bbc.example.com/outage", Content: content, Source: "BBC", PublishedAt: now.Add(-2 * time.Hour)},
+		{ID: "npr", Title: "Major outage hits cloud provider", Link: "https://npr.example.com/outage", Content: content, Source: "NPR", PublishedAt: now.Add(-time.Hour)},
+		{ID: "sports", Title: "Local team wins championship", Link: "https://sports.example.com/final", Content: "The home team secured the title after a dramatic final match that went to penalties.", Source: "Sports", PublishedAt: now},
+		// Same text but outside the clustering window
+		{ID: "old", Title: "Major outage hits cloud provider", Link: "https://old.example.com/outage", Content: content, Source: "Archive", PublishedAt: now.Add(-30 * 24 * time.Hour)},
+	}
+
+	for _, article := range articles {
+		if err := storage.SaveArticles(context.Background(), []models.Article{article}); err != nil {
+			t.Fatalf("Failed to save article: %v", err)
+		}
+	}
+	if err := storage.AssignArticlesToTopic(context.Background(), []string{"bbc", "npr", "sports", "old"}, "news"); err != nil {
+		t.Fatalf("Failed to assign articles: %v", err)
+	}
+
+	all, _, err := storage.GetAllArticles(context.Background(), &models.ODataQuery{})
+	if err != nil {
+		t.Fatalf("GetAllArticles() error = %v", err)
+	}
+	clusters := make(map[string]string)
+	for _, article := range all {
+		clusters[article.ID] = article.ClusterID
+	}
+	if clusters["npr"] != "bbc" || clusters["bbc"] != "bbc" {
+		t.Errorf("Expected bbc and npr in cluster bbc, got %v", clusters)
+	}
+	if clusters["sports"] != "sports" || clusters["old"] != "old" {
+		t.Errorf("Expected sports and old in their own clusters, got %v", clusters)
+	}
+
+	listings := map[string]func(ctx context.Context, query *models.ODataQuery) ([]models.Article, int, error){
+		"GetAllArticles": storage.GetAllArticles,
+		"GetTopicArticles": func(ctx context.Context, query *models.ODataQuery) ([]models.Article, int, error) {
+			return storage.GetTopicArticles(ctx, "news", query)
+		},
+	}
+
+	for name, list := range listings {
+		t.Run(name, func(t *testing.T) {
+			results, total, err := list(context.Background(), &models.ODataQuery{Cluster: true})
+			if err != nil {
+				t.Fatalf("%s() error = %v", name, err)
+			}
+			if total != 3 || len(results) != 3 {
+				t.Fatalf("Expected 3 clusters, got %d results (total %d)", len(results), total)
+			}
+
+			// The newest article represents the cluster, the others are attached
+			for _, article := range results {
+				if article.ID == "bbc" {
+					t.Errorf("Expected npr to represent the outage cluster")
+				}
+				if article.ID == "npr" && (len(article.ClusterSources) != 1 || article.ClusterSources[0].ID != "bbc") {
+					t.Errorf("Expected bbc attached to npr, got %+v", article.ClusterSources)
+				}
+			}
+
+			// Filters pick the representative among matching articles
+			results, _, err = list(context.Background(), &models.ODataQuery{Cluster: true, Source: "BBC"})
+			if err != nil {
+				t.Fatalf("%s() error = %v", name, err)
+			}
+			if len(results) != 1 || results[0].ID != "bbc" || len(results[0].ClusterSources) != 1 {
+				t.Errorf("Expected bbc with npr attached, got %+v", results)
+			}
+		})
+	}
+}
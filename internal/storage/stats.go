@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gorssag/internal/models"
+)
+
+// statsSnapshotRetention is how long stats snapshots are kept
+const statsSnapshotRetention = 365 * 24 * time.Hour
+
+// maxForecastDays bounds FullAt, slower growth never fills the volume in practice
+const maxForecastDays = 100 * 365
+
+// countSQLTableRows counts the rows of every table
+func countSQLTableRows(ctx context.Context, q queryer, tables []string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		var count int64
+		// Table names come from the schema catalog
+		if err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM "`+strings.ReplaceAll(table, `"`, `""`)+`"`).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count rows of %s: %v", table, err)
+		}
+		counts[table] = count
+	}
+	return counts, nil
+}
+
+// listSQLTables returns the table names returned by a catalog query
+func listSQLTables(ctx context.Context, q queryer, query string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %v", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, fmt.Errorf("failed to scan table name: %v", err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// sqlCompressionRatio returns the original over compressed size of the
+// compressed content recording its original size
+func sqlCompressionRatio(ctx context.Context, q queryer) (float64, error) {
+	var original, compressed int64
+	if err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(original_size), 0), COALESCE(SUM(LENGTH(compressed_content)), 0)
+		FROM compressed_content
+		WHERE original_size > 0
+	`).Scan(&original, &compressed); err != nil {
+		return 0, fmt.Errorf("failed to get compression ratio: %v", err)
+	}
+	if compressed == 0 {
+		return 0, nil
+	}
+	return float64(original) / float64(compressed), nil
+}
+
+// saveSQLStatsSnapshot stores a snapshot and drops the expired ones
+func saveSQLStatsSnapshot(ctx context.Context, q queryer, snapshot *models.StatsSnapshot) error {
+	tableRows, _ := json.Marshal(snapshot.TableRows)
+	indexSizes, _ := json.Marshal(snapshot.IndexSizeBytes)
+	if _, err := q.ExecContext(ctx, `
+		INSERT INTO stats_snapshots (taken_at, table_rows, database_size_bytes, wal_size_bytes, compression_ratio, index_size_bytes)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, snapshot.TakenAt, string(tableRows), snapshot.DatabaseSizeBytes, snapshot.WALSizeBytes, snapshot.CompressionRatio, string(indexSizes)); err != nil {
+		return fmt.Errorf("failed to store stats snapshot: %v", err)
+	}
+	if _, err := q.ExecContext(ctx, "DELETE FROM stats_snapshots WHERE taken_at < ?", snapshot.TakenAt.Add(-statsSnapshotRetention)); err != nil {
+		return fmt.Errorf("failed to delete expired stats snapshots: %v", err)
+	}
+	return nil
+}
+
+// getSQLStatsSnapshots returns the snapshots taken since a time, oldest first
+func getSQLStatsSnapshots(ctx context.Context, q queryer, since time.Time) ([]models.StatsSnapshot, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT taken_at, table_rows, database_size_bytes, wal_size_bytes, compression_ratio, index_size_bytes
+		FROM stats_snapshots
+		WHERE taken_at >= ?
+		ORDER BY taken_at, id
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stats snapshots: %v", err)
+	}
+	defer rows.Close()
+
+	snapshots := []models.StatsSnapshot{}
+	for rows.Next() {
+		var snapshot models.StatsSnapshot
+		var tableRows, indexSizes string
+		if err := rows.Scan(&snapshot.TakenAt, &tableRows, &snapshot.DatabaseSizeBytes, &snapshot.WALSizeBytes, &snapshot.CompressionRatio, &indexSizes); err != nil {
+			return nil, fmt.Errorf("failed to scan stats snapshot: %v", err)
+		}
+		if err := json.Unmarshal([]byte(tableRows), &snapshot.TableRows); err != nil {
+			return nil, fmt.Errorf("failed to decode stats snapshot table rows: %v", err)
+		}
+		if err := json.Unmarshal([]byte(indexSizes), &snapshot.IndexSizeBytes); err != nil {
+			return nil, fmt.Errorf("failed to decode stats snapshot index sizes: %v", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
+
+// sqliteIndexSizes measures every index with the dbstat virtual table, which
+// needs SQLite built with SQLITE_ENABLE_DBSTAT_VTAB
+func sqliteIndexSizes(ctx context.Context, q queryer) (map[string]int64, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT name, SUM(pgsize)
+		FROM dbstat
+		WHERE name IN (SELECT name FROM sqlite_master WHERE type = 'index')
+		GROUP BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dbstat: %v", err)
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]int64)
+	for rows.Next() {
+		var name string
+		var size int64
+		if err := rows.Scan(&name, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan index size: %v", err)
+		}
+		sizes[name] = size
+	}
+	return sizes, rows.Err()
+}
+
+// RecordStatsSnapshot stores the current storage statistics in the history
+func (s *SQLiteStorage) RecordStatsSnapshot(ctx context.Context) (*models.StatsSnapshot, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snapshot := &models.StatsSnapshot{TakenAt: time.Now().UTC()}
+
+	tables, err := listSQLTables(ctx, s.db, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.TableRows, err = countSQLTableRows(ctx, s.db, tables); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.QueryRowContext(ctx, "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&snapshot.DatabaseSizeBytes); err != nil {
+		return nil, fmt.Errorf("failed to get database size: %v", err)
+	}
+	var dbPath string
+	if err := s.db.QueryRowContext(ctx, "SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&dbPath); err != nil {
+		return nil, fmt.Errorf("failed to get database path: %v", err)
+	}
+	if info, err := os.Stat(dbPath + "-wal"); err == nil {
+		snapshot.WALSizeBytes = info.Size()
+	}
+
+	if snapshot.CompressionRatio, err = sqlCompressionRatio(ctx, s.db); err != nil {
+		return nil, err
+	}
+
+	snapshot.IndexSizeBytes, err = sqliteIndexSizes(ctx, s.db)
+	if err != nil {
+		log.Printf("Warning: index sizes unavailable: %v", err)
+		snapshot.IndexSizeBytes = map[string]int64{}
+	}
+
+	if err := saveSQLStatsSnapshot(ctx, s.db, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// GetStatsSnapshots returns the stats snapshots taken since a time, oldest first
+func (s *SQLiteStorage) GetStatsSnapshots(ctx context.Context, since time.Time) ([]models.StatsSnapshot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return getSQLStatsSnapshots(ctx, s.db, since)
+}
+
+// RecordStatsSnapshot stores the current storage statistics in the history
+func (s *PostgresStorage) RecordStatsSnapshot(ctx context.Context) (*models.StatsSnapshot, error) {
+	snapshot := &models.StatsSnapshot{TakenAt: time.Now().UTC()}
+
+	tables, err := listSQLTables(ctx, s.db, "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name")
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.TableRows, err = countSQLTableRows(ctx, s.db, tables); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.QueryRowContext(ctx, "SELECT pg_database_size(current_database())").Scan(&snapshot.DatabaseSizeBytes); err != nil {
+		return nil, fmt.Errorf("failed to get database size: %v", err)
+	}
+	// Listing the WAL directory needs the pg_monitor role
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(SUM(size), 0) FROM pg_ls_waldir()").Scan(&snapshot.WALSizeBytes); err != nil {
+		log.Printf("Warning: WAL size unavailable: %v", err)
+	}
+
+	if snapshot.CompressionRatio, err = sqlCompressionRatio(ctx, s.db); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT indexrelname, pg_relation_size(indexrelid)
+		FROM pg_stat_user_indexes
+		WHERE schemaname = current_schema()
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index sizes: %v", err)
+	}
+	defer rows.Close()
+	snapshot.IndexSizeBytes = make(map[string]int64)
+	for rows.Next() {
+		var name string
+		var size int64
+		if err := rows.Scan(&name, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan index size: %v", err)
+		}
+		snapshot.IndexSizeBytes[name] = size
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %v", err)
+	}
+
+	if err := saveSQLStatsSnapshot(ctx, s.db, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// GetStatsSnapshots returns the stats snapshots taken since a time, oldest first
+func (s *PostgresStorage) GetStatsSnapshots(ctx context.Context, since time.Time) ([]models.StatsSnapshot, error) {
+	return getSQLStatsSnapshots(ctx, s.db, since)
+}
+
+// ForecastDiskUsage fits a least squares line through the disk usage of the
+// snapshots and extrapolates it horizon ahead of the last one. With a
+// capacity, it also tells when usage reaches it at the fitted growth.
+func ForecastDiskUsage(snapshots []models.StatsSnapshot, horizon time.Duration, capacity int64) *models.StorageForecast {
+	forecast := &models.StorageForecast{
+		Samples:       len(snapshots),
+		HorizonDays:   int(horizon / (24 * time.Hour)),
+		CapacityBytes: capacity,
+	}
+	if len(snapshots) == 0 {
+		return forecast
+	}
+
+	sorted := append([]models.StatsSnapshot(nil), snapshots...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].TakenAt.Before(sorted[j].TakenAt) })
+	first, last := sorted[0], sorted[len(sorted)-1]
+	forecast.CurrentBytes = last.DatabaseSizeBytes + last.WALSizeBytes
+
+	// Days since the first snapshot against bytes used
+	var sumX, sumY, sumXY, sumXX float64
+	for _, snapshot := range sorted {
+		x := snapshot.TakenAt.Sub(first.TakenAt).Hours() / 24
+		y := float64(snapshot.DatabaseSizeBytes + snapshot.WALSizeBytes)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(sorted))
+	if denominator := n*sumXX - sumX*sumX; denominator > 0 {
+		forecast.GrowthBytesPerDay = (n*sumXY - sumX*sumY) / denominator
+	}
+
+	days := horizon.Hours() / 24
+	forecast.ForecastBytes = int64(math.Max(0, math.Round(float64(forecast.CurrentBytes)+forecast.GrowthBytesPerDay*days)))
+
+	if capacity > 0 {
+		switch {
+		case forecast.CurrentBytes >= capacity:
+			fullAt := last.TakenAt
+			forecast.FullAt = &fullAt
+		case forecast.GrowthBytesPerDay > 0:
+			remaining := float64(capacity-forecast.CurrentBytes) / forecast.GrowthBytesPerDay
+			if remaining > maxForecastDays {
+				break
+			}
+			fullAt := last.TakenAt.Add(time.Duration(remaining * float64(24*time.Hour)))
+			forecast.FullAt = &fullAt
+		}
+	}
+	return forecast
+}
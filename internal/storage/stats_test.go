@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"gorssag/internal/models"
+)
+
+func TestForecastDiskUsage(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := func(day int, size int64) models.StatsSnapshot {
+		return models.StatsSnapshot{TakenAt: start.AddDate(0, 0, day), DatabaseSizeBytes: size - 100, WALSizeBytes: 100}
+	}
+
+	// 1000 bytes a day, reported out of order
+	snapshots := []models.StatsSnapshot{snapshot(2, 3000), snapshot(0, 1000), snapshot(1, 2000)}
+	forecast := ForecastDiskUsage(snapshots, 10*24*time.Hour, 8000)
+	if forecast.Samples != 3 || forecast.CurrentBytes != 3000 || forecast.HorizonDays != 10 {
+		t.Errorf("ForecastDiskUsage() = %+v, want 3 samples at 3000 bytes over 10 days", forecast)
+	}
+	if forecast.GrowthBytesPerDay != 1000 || forecast.ForecastBytes != 13000 {
+		t.Errorf("ForecastDiskUsage() growth = %v, forecast = %d, want 1000/day and 13000", forecast.GrowthBytesPerDay, forecast.ForecastBytes)
+	}
+	if forecast.FullAt == nil || !forecast.FullAt.Equal(start.AddDate(0, 0, 7)) {
+		t.Errorf("ForecastDiskUsage() full at %v, want %v", forecast.FullAt, start.AddDate(0, 0, 7))
+	}
+
+	// Shrinking or single samples never fill the volume
+	if forecast := ForecastDiskUsage([]models.StatsSnapshot{snapshot(0, 3000), snapshot(1, 2000)}, 24*time.Hour, 8000); forecast.FullAt != nil || forecast.ForecastBytes != 1000 {
+		t.Errorf("ForecastDiskUsage(shrinking) = %+v, want 1000 bytes and no full date", forecast)
+	}
+	if forecast := ForecastDiskUsage([]models.StatsSnapshot{snapshot(0, 3000)}, 24*time.Hour, 0); forecast.GrowthBytesPerDay != 0 || forecast.ForecastBytes != 3000 {
+		t.Errorf("ForecastDiskUsage(single) = %+v, want no growth", forecast)
+	}
+	if forecast := ForecastDiskUsage(nil, 24*time.Hour, 0); forecast.Samples != 0 || forecast.ForecastBytes != 0 {
+		t.Errorf("ForecastDiskUsage(nil) = %+v, want an empty forecast", forecast)
+	}
+}
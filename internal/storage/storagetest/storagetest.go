@@ -0,0 +1,1497 @@
+// Package storagetest provides a conformance suite checking that a
+// storage.Storage implementation follows the contract shared by the backends.
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorssag/internal/config"
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+	"gorssag/internal/storage"
+)
+
+// Config returns the configuration the suite creates storages with
+func Config() *config.Config {
+	return &config.Config{
+		EnableContentCompression: true,
+		MaxContentLength:         10000,
+		EnableDuplicateRemoval:   true,
+	}
+}
+
+// Run checks the storage.Storage contract against a backend. Every subtest
+// calls newStorage for an empty storage and closes it when done.
+func Run(t *testing.T, newStorage func(t *testing.T, cfg *config.Config) storage.Storage) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	searchArticles := []models.Article{
+		{ID: "rust-release", Title: "Rust compiler release", Link: "https://example.com/rust", Description: "New language features", Content: "The Rust team shipped a new compiler", Author: "Alice", Source: "Lang News", Categories: []string{"programming"}, PublishedAt: now},
+		{ID: "rust-game", Title: "Rust game update", Link: "https://example.com/rust-game", Description: "Survival game patch notes", Content: "The survival game Rust got a new map", Author: "Bob", Source: "Game News", Categories: []string{"gaming"}, PublishedAt: now.Add(-time.Hour)},
+		{ID: "zero-day", Title: "Browser zero day exploited", Link: "https://example.com/zero-day", Description: "Attackers exploit a day zero bug", Content: "A zero day vulnerability in Kubernetes dashboards", Author: "Carol", Source: "Security News", Categories: []string{"security", "programming"}, PublishedAt: now.Add(-2 * time.Hour)},
+	}
+
+	ids := func(articles []models.Article) []string {
+		result := []string{}
+		for _, article := range articles {
+			result = append(result, article.ID)
+		}
+		return result
+	}
+
+	t.Run("SaveAndLoadFeed", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveFeed(ctx, "tech", &models.AggregatedFeed{Topic: "tech", Articles: searchArticles}); err != nil {
+			t.Fatalf("SaveFeed() error = %v", err)
+		}
+
+		feed, err := store.LoadFeed(ctx, "tech")
+		if err != nil {
+			t.Fatalf("LoadFeed() error = %v", err)
+		}
+		if got := ids(feed.Articles); !reflect.DeepEqual(got, []string{"rust-release", "rust-game", "zero-day"}) {
+			t.Errorf("LoadFeed() = %v, want newest first", got)
+		}
+		loaded := feed.Articles[2]
+		if loaded.Title != searchArticles[2].Title || loaded.Content != searchArticles[2].Content || !loaded.PublishedAt.Equal(searchArticles[2].PublishedAt) {
+			t.Errorf("LoadFeed() returned %+v, want %+v", loaded, searchArticles[2])
+		}
+		if !reflect.DeepEqual(loaded.Categories, searchArticles[2].Categories) {
+			t.Errorf("Categories = %v, want %v", loaded.Categories, searchArticles[2].Categories)
+		}
+
+		info, err := store.GetFeedInfo(ctx, "tech")
+		if err != nil {
+			t.Fatalf("GetFeedInfo() error = %v", err)
+		}
+		if info.ArticleCount != 3 {
+			t.Errorf("GetFeedInfo().ArticleCount = %d, want 3", info.ArticleCount)
+		}
+
+		topics, err := store.ListTopics(ctx)
+		if err != nil {
+			t.Fatalf("ListTopics() error = %v", err)
+		}
+		if !reflect.DeepEqual(topics, []string{"tech"}) {
+			t.Errorf("ListTopics() = %v, want [tech]", topics)
+		}
+
+		if err := store.DeleteFeed(ctx, "tech"); err != nil {
+			t.Fatalf("DeleteFeed() error = %v", err)
+		}
+		if _, err := store.LoadFeed(ctx, "tech"); err == nil {
+			t.Error("LoadFeed() after DeleteFeed expected error, got nil")
+		}
+		if _, err := store.GetFeedInfo(ctx, "missing"); err == nil {
+			t.Error("GetFeedInfo() for unknown topic expected error, got nil")
+		}
+	})
+
+	t.Run("SaveFeedReplacesTopicArticles", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveFeed(ctx, "tech", &models.AggregatedFeed{Topic: "tech", Articles: searchArticles[:2]}); err != nil {
+			t.Fatalf("SaveFeed() error = %v", err)
+		}
+		if err := store.AddArticleToTopic(ctx, "rust-release", "news"); err != nil {
+			t.Fatalf("AddArticleToTopic() error = %v", err)
+		}
+		if err := store.SaveFeed(ctx, "tech", &models.AggregatedFeed{Topic: "tech", Articles: searchArticles[2:]}); err != nil {
+			t.Fatalf("SaveFeed() error = %v", err)
+		}
+
+		feed, err := store.LoadFeed(ctx, "tech")
+		if err != nil {
+			t.Fatalf("LoadFeed() error = %v", err)
+		}
+		if got := ids(feed.Articles); !reflect.DeepEqual(got, []string{"zero-day"}) {
+			t.Errorf("LoadFeed(tech) = %v, want [zero-day]", got)
+		}
+
+		// Articles still in another topic survive
+		news, err := store.LoadFeed(ctx, "news")
+		if err != nil {
+			t.Fatalf("LoadFeed(news) error = %v", err)
+		}
+		if got := ids(news.Articles); !reflect.DeepEqual(got, []string{"rust-release"}) {
+			t.Errorf("LoadFeed(news) = %v, want [rust-release]", got)
+		}
+	})
+
+	t.Run("TopicMembership", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveArticles(ctx, searchArticles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+
+		// Saved articles belong to no topic until assigned
+		if _, total, err := store.GetTopicArticles(ctx, "tech", &models.ODataQuery{}); err != nil || total != 0 {
+			t.Errorf("GetTopicArticles() before assignment = %d, %v, want 0", total, err)
+		}
+
+		if err := store.AssignArticlesToTopic(ctx, []string{"rust-release", "zero-day"}, "tech"); err != nil {
+			t.Fatalf("AssignArticlesToTopic() error = %v", err)
+		}
+		// Assigning twice is a no-op and unknown articles are skipped
+		if err := store.AssignArticlesToTopic(ctx, []string{"rust-release", "missing"}, "tech"); err != nil {
+			t.Fatalf("AssignArticlesToTopic() error = %v", err)
+		}
+		if err := store.AddArticleToTopic(ctx, "rust-release", "news"); err != nil {
+			t.Fatalf("AddArticleToTopic() error = %v", err)
+		}
+
+		articles, total, err := store.GetTopicArticles(ctx, "tech", &models.ODataQuery{})
+		if err != nil {
+			t.Fatalf("GetTopicArticles() error = %v", err)
+		}
+		if total != 2 || !reflect.DeepEqual(ids(articles), []string{"rust-release", "zero-day"}) {
+			t.Errorf("GetTopicArticles(tech) = %v (total %d), want [rust-release zero-day]", ids(articles), total)
+		}
+
+		topics, err := store.GetArticleTopics(ctx, "rust-release")
+		if err != nil {
+			t.Fatalf("GetArticleTopics() error = %v", err)
+		}
+		if !reflect.DeepEqual(topics, []string{"news", "tech"}) {
+			t.Errorf("GetArticleTopics() = %v, want [news tech]", topics)
+		}
+		if topics, err := store.GetArticleTopics(ctx, "missing"); err != nil || len(topics) != 0 {
+			t.Errorf("GetArticleTopics(missing) = %v, %v, want no topics", topics, err)
+		}
+		if articles, total, err := store.GetTopicArticles(ctx, "unknown", &models.ODataQuery{}); err != nil || total != 0 || len(articles) != 0 {
+			t.Errorf("GetTopicArticles(unknown) = %d articles (total %d), %v, want none", len(articles), total, err)
+		}
+
+		if err := store.RemoveArticleFromTopic(ctx, "rust-release", "tech"); err != nil {
+			t.Fatalf("RemoveArticleFromTopic() error = %v", err)
+		}
+		if _, total, _ := store.GetTopicArticles(ctx, "tech", &models.ODataQuery{}); total != 1 {
+			t.Errorf("GetTopicArticles(tech) after removal total = %d, want 1", total)
+		}
+
+		// Removing from a topic does not delete the article
+		all, total, err := store.GetAllArticles(ctx, &models.ODataQuery{})
+		if err != nil {
+			t.Fatalf("GetAllArticles() error = %v", err)
+		}
+		if total != 3 || len(all) != 3 {
+			t.Errorf("GetAllArticles() = %v (total %d), want 3 articles", ids(all), total)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveFeed(ctx, "tech", &models.AggregatedFeed{Topic: "tech", Articles: searchArticles}); err != nil {
+			t.Fatalf("SaveFeed() error = %v", err)
+		}
+
+		tests := []struct {
+			search   string
+			expected []string
+		}{
+			{"rust NOT game", []string{"rust-release"}},
+			{`"zero day"`, []string{"zero-day"}},
+			{"title:kubernetes", []string{}},
+			{"content:kubernetes", []string{"zero-day"}},
+			{"kube*", []string{"zero-day"}},
+			{"ubernetes*", []string{}},
+			{"author:car*", []string{"zero-day"}},
+			{"author:arol*", []string{}},
+			{"author:carol", []string{"zero-day"}},
+			{"(compiler OR survival) AND rust", []string{"rust-release", "rust-game"}},
+			{"compiler, exploited", []string{"rust-release", "zero-day"}},
+		}
+
+		for _, tt := range tests {
+			query := &models.ODataQuery{Search: []string{tt.search}}
+
+			articles, total, err := store.GetAllArticles(ctx, query)
+			if err != nil {
+				t.Fatalf("GetAllArticles(%q) error = %v", tt.search, err)
+			}
+			if got := ids(articles); total != len(tt.expected) || !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("GetAllArticles(%q) = %v (total %d), want %v", tt.search, got, total, tt.expected)
+			}
+
+			topicArticles, _, err := store.GetTopicArticles(ctx, "tech", query)
+			if err != nil {
+				t.Fatalf("GetTopicArticles(%q) error = %v", tt.search, err)
+			}
+			if got := ids(topicArticles); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("GetTopicArticles(%q) = %v, want %v", tt.search, got, tt.expected)
+			}
+
+			queried, err := store.QueryArticles(ctx, "tech", query)
+			if err != nil {
+				t.Fatalf("QueryArticles(%q) error = %v", tt.search, err)
+			}
+			if len(queried) != len(tt.expected) {
+				t.Errorf("QueryArticles(%q) returned %d articles, want %d", tt.search, len(queried), len(tt.expected))
+			}
+		}
+
+		if _, _, err := store.GetAllArticles(ctx, &models.ODataQuery{Search: []string{`"unterminated`}}); err == nil {
+			t.Error("Expected error for invalid search expression")
+		}
+	})
+
+	t.Run("FiltersAndPagination", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveFeed(ctx, "tech", &models.AggregatedFeed{Topic: "tech", Articles: searchArticles}); err != nil {
+			t.Fatalf("SaveFeed() error = %v", err)
+		}
+
+		dateFrom := now.Add(-90 * time.Minute)
+		tests := []struct {
+			name     string
+			query    models.ODataQuery
+			expected []string
+		}{
+			{"source", models.ODataQuery{Source: "game"}, []string{"rust-game"}},
+			{"author", models.ODataQuery{Author: "ALICE"}, []string{"rust-release"}},
+			{"category", models.ODataQuery{Category: "security"}, []string{"zero-day"}},
+			{"datefrom", models.ODataQuery{DateFrom: &dateFrom}, []string{"rust-release", "rust-game"}},
+			{"orderby", models.ODataQuery{OrderBy: "title asc"}, []string{"zero-day", "rust-release", "rust-game"}},
+			{"top", models.ODataQuery{Top: 2}, []string{"rust-release", "rust-game"}},
+			{"skip", models.ODataQuery{Top: 2, Skip: 2}, []string{"zero-day"}},
+		}
+
+		for _, tt := range tests {
+			query := tt.query
+			articles, total, err := store.GetAllArticles(ctx, &query)
+			if err != nil {
+				t.Fatalf("%s: GetAllArticles() error = %v", tt.name, err)
+			}
+			if got := ids(articles); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("%s: GetAllArticles() = %v, want %v", tt.name, got, tt.expected)
+			}
+			if query.Top == 0 && total != len(tt.expected) {
+				t.Errorf("%s: total = %d, want %d", tt.name, total, len(tt.expected))
+			}
+		}
+
+		// $skiptoken pages through the listing without gaps or repeats
+		var paged []string
+		query := &models.ODataQuery{Top: 1}
+		for i := 0; i < 5; i++ {
+			page, _, err := store.GetAllArticles(ctx, query)
+			if err != nil {
+				t.Fatalf("GetAllArticles() with skiptoken error = %v", err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			paged = append(paged, page[0].ID)
+			token, err := odata.DecodeSkipToken(odata.EncodeSkipToken(page[0]))
+			if err != nil {
+				t.Fatalf("DecodeSkipToken() error = %v", err)
+			}
+			query = &models.ODataQuery{Top: 1, SkipToken: token}
+		}
+		if !reflect.DeepEqual(paged, []string{"rust-release", "rust-game", "zero-day"}) {
+			t.Errorf("Keyset pages = %v, want every article once", paged)
+		}
+	})
+
+	t.Run("Facets", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveFeed(ctx, "tech", &models.AggregatedFeed{Topic: "tech", Articles: searchArticles}); err != nil {
+			t.Fatalf("SaveFeed() error = %v", err)
+		}
+
+		facets, err := store.GetArticleFacets(ctx, &models.ODataQuery{Facets: []string{"category", "topic"}})
+		if err != nil {
+			t.Fatalf("GetArticleFacets() error = %v", err)
+		}
+		expected := models.Facets{
+			"category": {{Value: "programming", Count: 2}, {Value: "gaming", Count: 1}, {Value: "security", Count: 1}},
+			"topic":    {{Value: "tech", Count: 3}},
+		}
+		if !reflect.DeepEqual(facets, expected) {
+			t.Errorf("GetArticleFacets() = %v, want %v", facets, expected)
+		}
+	})
+
+	t.Run("RelatedArticles", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveArticles(ctx, searchArticles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+
+		if _, err := store.GetRelatedArticles(ctx, "missing", 5); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetRelatedArticles(missing) error = %v, want storage.ErrNotFound", err)
+		}
+
+		related, err := store.GetRelatedArticles(ctx, "rust-release", 5)
+		if err != nil {
+			t.Fatalf("GetRelatedArticles() error = %v", err)
+		}
+		if len(related) == 0 || related[0].ID == "rust-release" {
+			t.Fatalf("GetRelatedArticles() = %v, want other articles", ids(related))
+		}
+		for _, article := range related {
+			if article.ID == "rust-release" {
+				t.Errorf("GetRelatedArticles() returned the source article")
+			}
+		}
+	})
+
+	t.Run("StoryClusters", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		content := "A configuration change at a major cloud provider took down storage and compute services across several regions for about three hours on Tuesday."
+		articles := []models.Article{
+			{ID: "first", Title: "Major outage hits cloud provider", Link: "https://a.example.com/outage", Content: content, Source: "A", PublishedAt: now.Add(-time.Hour)},
+			{ID: "second", Title: "Major outage hits cloud provider - Live", Link: "https://b.example.com/outage", Content: content, Source: "B", PublishedAt: now},
+			{ID: "other", Title: "Local team wins championship", Link: "https://c.example.com/final", Content: "The home team secured the title after a dramatic final match.", Source: "C", PublishedAt: now},
+		}
+		for _, article := range articles {
+			if err := store.SaveArticles(ctx, []models.Article{article}); err != nil {
+				t.Fatalf("SaveArticles() error = %v", err)
+			}
+		}
+
+		clustered, total, err := store.GetAllArticles(ctx, &models.ODataQuery{Cluster: true})
+		if err != nil {
+			t.Fatalf("GetAllArticles() error = %v", err)
+		}
+		if total != 2 || !reflect.DeepEqual(ids(clustered), []string{"second", "other"}) {
+			t.Fatalf("Clustered listing = %v (total %d), want [second other]", ids(clustered), total)
+		}
+		if clustered[0].ClusterID != "first" || len(clustered[0].ClusterSources) != 1 || clustered[0].ClusterSources[0].ID != "first" {
+			t.Errorf("Expected second to carry first as cluster source, got %+v", clustered[0])
+		}
+	})
+
+	t.Run("CompressedContent", func(t *testing.T) {
+		for _, codec := range []string{"gzip", "zstd"} {
+			t.Run(codec, func(t *testing.T) {
+				cfg := Config()
+				cfg.ContentCompressionCodec = codec
+				store := newStorage(t, cfg)
+				defer store.Close()
+
+				old := models.Article{ID: "old", Title: "Old news", Link: "https://example.com/old", Content: "The glacier report was published long ago", Source: "Archive", PublishedAt: now.Add(-10 * 24 * time.Hour)}
+				if err := store.SaveFeed(ctx, "tech", &models.AggregatedFeed{Topic: "tech", Articles: append([]models.Article{old}, searchArticles...)}); err != nil {
+					t.Fatalf("SaveFeed() error = %v", err)
+				}
+				if err := store.CompressOldArticles(ctx); err != nil {
+					t.Fatalf("CompressOldArticles() error = %v", err)
+				}
+
+				feed, err := store.LoadFeed(ctx, "tech")
+				if err != nil {
+					t.Fatalf("LoadFeed() error = %v", err)
+				}
+				for _, article := range feed.Articles {
+					if article.ID == "old" && article.Content != old.Content {
+						t.Errorf("LoadFeed() content = %q, want the original", article.Content)
+					}
+				}
+
+				for name, query := range map[string]*models.ODataQuery{
+					"search": {Search: []string{`"glacier report"`}},
+					"filter": {Filter: "glacier report"},
+				} {
+					articles, total, err := store.GetAllArticles(ctx, query)
+					if err != nil {
+						t.Fatalf("GetAllArticles(%s) error = %v", name, err)
+					}
+					if total != 1 || len(articles) != 1 || articles[0].Content != old.Content {
+						t.Errorf("GetAllArticles(%s) = %v (total %d), want the old article with its content", name, ids(articles), total)
+					}
+
+					articles, total, err = store.GetTopicArticles(ctx, "tech", query)
+					if err != nil {
+						t.Fatalf("GetTopicArticles(%s) error = %v", name, err)
+					}
+					if total != 1 || len(articles) != 1 || articles[0].Content != old.Content {
+						t.Errorf("GetTopicArticles(%s) = %v (total %d), want the old article with its content", name, ids(articles), total)
+					}
+				}
+
+				// Content left out of $select is not loaded
+				articles, _, err := store.GetAllArticles(ctx, &models.ODataQuery{Select: []string{"title"}, Filter: "glacier"})
+				if err != nil {
+					t.Fatalf("GetAllArticles($select) error = %v", err)
+				}
+				if len(articles) != 1 || articles[0].Content != "" || articles[0].Title != old.Title {
+					t.Errorf("GetAllArticles($select=title) = %+v, want the old article without content", articles)
+				}
+			})
+		}
+	})
+
+	t.Run("Maintenance", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		old := models.Article{ID: "old", Title: "Old news", Link: "https://example.com/old", Content: strings.Repeat("archived content ", 20), Source: "Archive", PublishedAt: now.Add(-10 * 24 * time.Hour)}
+		duplicate := searchArticles[0]
+		duplicate.ID = "rust-release-copy"
+		if err := store.SaveFeed(ctx, "tech", &models.AggregatedFeed{Topic: "tech", Articles: append([]models.Article{old, duplicate}, searchArticles...)}); err != nil {
+			t.Fatalf("SaveFeed() error = %v", err)
+		}
+
+		if err := store.CompressOldArticles(ctx); err != nil {
+			t.Fatalf("CompressOldArticles() error = %v", err)
+		}
+		feed, err := store.LoadFeed(ctx, "tech")
+		if err != nil {
+			t.Fatalf("LoadFeed() error = %v", err)
+		}
+		for _, article := range feed.Articles {
+			if article.ID == "old" && article.Content != strings.TrimSpace(old.Content) {
+				t.Errorf("Compressed content = %q, want the original", article.Content)
+			}
+		}
+
+		if err := store.RemoveDuplicateArticles(ctx); err != nil {
+			t.Fatalf("RemoveDuplicateArticles() error = %v", err)
+		}
+		if err := store.CleanupOldArticles(ctx, storage.RetentionPolicy{MaxAge: 7 * 24 * time.Hour}); err != nil {
+			t.Fatalf("CleanupOldArticles() error = %v", err)
+		}
+		if err := store.OptimizeDatabase(ctx); err != nil {
+			t.Fatalf("OptimizeDatabase() error = %v", err)
+		}
+
+		stats, err := store.GetDatabaseStats(ctx)
+		if err != nil {
+			t.Fatalf("GetDatabaseStats() error = %v", err)
+		}
+		if stats["total_articles"] != 3 {
+			t.Errorf("total_articles = %v, want 3 after cleanup and deduplication", stats["total_articles"])
+		}
+
+		feedStats, err := store.GetFeedStats(ctx)
+		if err != nil {
+			t.Fatalf("GetFeedStats() error = %v", err)
+		}
+		if feeds, _ := feedStats["feeds"].([]map[string]interface{}); len(feeds) != 3 {
+			t.Errorf("GetFeedStats() returned %d sources, want 3", len(feeds))
+		}
+	})
+
+	t.Run("Retention", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		day := 24 * time.Hour
+		article := func(id, source string, age time.Duration) models.Article {
+			return models.Article{ID: id, Title: "Retention " + id, Link: "https://example.com/" + id, Source: source, PublishedAt: now.Add(-age)}
+		}
+		articles := []models.Article{
+			article("sec-old", "Security Feed", 200*day),
+			article("news-old", "News Feed", 10*day),
+			article("news-1", "Wire", time.Hour),
+			article("news-2", "Wire", 2*time.Hour),
+			article("news-3", "Tech Feed", 3*time.Hour),
+			article("news-4", "News Feed", 4*time.Hour),
+			article("orphan-old", "Other Feed", 40*day),
+			article("orphan-new", "Other Feed", day),
+		}
+		if err := store.SaveArticles(ctx, articles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		memberships := map[string][]string{
+			"security": {"sec-old"},
+			"news":     {"sec-old", "news-old", "news-1", "news-2", "news-3", "news-4"},
+			"tech":     {"news-3"},
+		}
+		for topic, articleIDs := range memberships {
+			if err := store.AssignArticlesToTopic(ctx, articleIDs, topic); err != nil {
+				t.Fatalf("AssignArticlesToTopic(%s) error = %v", topic, err)
+			}
+		}
+
+		remaining := func() string {
+			t.Helper()
+			articles, _, err := store.GetAllArticles(ctx, &models.ODataQuery{})
+			if err != nil {
+				t.Fatalf("GetAllArticles() error = %v", err)
+			}
+			return strings.Join(ids(articles), ",")
+		}
+
+		// Everything was just ingested
+		if err := store.CleanupOldArticles(ctx, storage.RetentionPolicy{MaxAge: 30 * day, ByIngestion: true}); err != nil {
+			t.Fatalf("CleanupOldArticles() error = %v", err)
+		}
+		if got, want := remaining(), "news-1,news-2,news-3,news-4,orphan-new,news-old,orphan-old,sec-old"; got != want {
+			t.Errorf("After ingestion retention: %s, want %s", got, want)
+		}
+
+		// Shared articles stay while one topic retains them
+		policy := storage.RetentionPolicy{
+			MaxAge: 30 * day,
+			Topics: map[string]config.TopicRetention{
+				"security": {MaxAge: 365 * day},
+				"news":     {MaxAge: 7 * day, MaxCount: 2},
+			},
+		}
+		if err := store.CleanupOldArticles(ctx, policy); err != nil {
+			t.Fatalf("CleanupOldArticles() error = %v", err)
+		}
+		if got, want := remaining(), "news-1,news-2,news-3,orphan-new,sec-old"; got != want {
+			t.Errorf("After topic retention: %s, want %s", got, want)
+		}
+		if topics, _ := store.GetArticleTopics(ctx, "sec-old"); strings.Join(topics, ",") != "news,security" {
+			t.Errorf("Retained article lost memberships: %v", topics)
+		}
+
+		policy.MaxPerFeed = 1
+		if err := store.CleanupOldArticles(ctx, policy); err != nil {
+			t.Fatalf("CleanupOldArticles() error = %v", err)
+		}
+		if got, want := remaining(), "news-1,news-3,orphan-new,sec-old"; got != want {
+			t.Errorf("After per-feed retention: %s, want %s", got, want)
+		}
+	})
+
+	t.Run("RetentionPerFeed", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		// Two feeds share a title, the last article is linked to no feed
+		var articles []models.Article
+		for i, id := range []string{"a-1", "a-2", "b-1", "b-2", "unlinked"} {
+			articles = append(articles, models.Article{ID: id, Title: "Per feed " + id, Link: "https://example.com/" + id, Source: "Same Title", PublishedAt: now.Add(-time.Duration(i+1) * time.Hour)})
+		}
+		articles[4].Source = "Former Title"
+		if err := store.SaveArticles(ctx, articles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.AssignArticlesToFeed(ctx, []string{"a-1", "a-2"}, "https://a.example.com/feed", "Same Title"); err != nil {
+			t.Fatalf("AssignArticlesToFeed() error = %v", err)
+		}
+		if err := store.AssignArticlesToFeed(ctx, []string{"b-1", "b-2"}, "https://b.example.com/feed", "Same Title"); err != nil {
+			t.Fatalf("AssignArticlesToFeed() error = %v", err)
+		}
+
+		if err := store.CleanupOldArticles(ctx, storage.RetentionPolicy{MaxAge: 30 * 24 * time.Hour, MaxPerFeed: 1}); err != nil {
+			t.Fatalf("CleanupOldArticles() error = %v", err)
+		}
+		remaining, _, err := store.GetAllArticles(ctx, &models.ODataQuery{})
+		if err != nil {
+			t.Fatalf("GetAllArticles() error = %v", err)
+		}
+		if got, want := strings.Join(ids(remaining), ","), "a-1,b-1,unlinked"; got != want {
+			t.Errorf("After per-feed retention: %s, want %s", got, want)
+		}
+	})
+
+	t.Run("RetentionTimeZones", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		// Ages follow the instant, not the wall clock of the feed
+		west := time.FixedZone("UTC-5", -5*60*60)
+		east := time.FixedZone("UTC+9", 9*60*60)
+		articles := []models.Article{
+			{ID: "west-recent", Title: "West recent", Link: "https://example.com/west", Source: "West", PublishedAt: now.Add(-30 * time.Minute).In(west)},
+			{ID: "east-old", Title: "East old", Link: "https://example.com/east", Source: "East", PublishedAt: now.Add(-90 * time.Minute).In(east)},
+		}
+		if err := store.SaveArticles(ctx, articles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+
+		if err := store.CleanupOldArticles(ctx, storage.RetentionPolicy{MaxAge: time.Hour}); err != nil {
+			t.Fatalf("CleanupOldArticles() error = %v", err)
+		}
+		remaining, _, err := store.GetAllArticles(ctx, &models.ODataQuery{})
+		if err != nil {
+			t.Fatalf("GetAllArticles() error = %v", err)
+		}
+		if got := strings.Join(ids(remaining), ","); got != "west-recent" {
+			t.Errorf("After retention: %s, want west-recent", got)
+		}
+	})
+
+	t.Run("Pins", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		old := models.Article{ID: "old", Title: "Old news", Link: "https://example.com/old", Content: strings.Repeat("archived content ", 20), Source: "Archive", PublishedAt: now.Add(-10 * 24 * time.Hour)}
+		duplicate := searchArticles[0]
+		duplicate.ID = "rust-release-copy"
+		if err := store.SaveArticles(ctx, append(append([]models.Article{}, searchArticles...), old)); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.SaveArticles(ctx, []models.Article{duplicate}); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+
+		for _, id := range []string{"old", "rust-release-copy"} {
+			if err := store.SetArticlePinned(ctx, id, true); err != nil {
+				t.Fatalf("SetArticlePinned(%s) error = %v", id, err)
+			}
+		}
+		if err := store.SetArticlePinned(ctx, "missing", true); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("SetArticlePinned(missing) error = %v, want storage.ErrNotFound", err)
+		}
+
+		// Polling the article again must not unpin it
+		if err := store.SaveArticles(ctx, []models.Article{old}); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+
+		if err := store.CompressOldArticles(ctx); err != nil {
+			t.Fatalf("CompressOldArticles() error = %v", err)
+		}
+		if err := store.RemoveDuplicateArticles(ctx); err != nil {
+			t.Fatalf("RemoveDuplicateArticles() error = %v", err)
+		}
+		if err := store.CleanupOldArticles(ctx, storage.RetentionPolicy{MaxAge: 7 * 24 * time.Hour}); err != nil {
+			t.Fatalf("CleanupOldArticles() error = %v", err)
+		}
+
+		pinned := true
+		articles, total, err := store.GetAllArticles(ctx, &models.ODataQuery{Pinned: &pinned})
+		if err != nil {
+			t.Fatalf("GetAllArticles(pinned) error = %v", err)
+		}
+		if got := strings.Join(ids(articles), ","); got != "rust-release-copy,old" || total != 2 {
+			t.Errorf("Pinned articles = %s (%d), want rust-release-copy,old", got, total)
+		}
+		for _, article := range articles {
+			if !article.Pinned {
+				t.Errorf("Article %s not reported as pinned", article.ID)
+			}
+			if article.ID == "old" && article.Content != strings.TrimSpace(old.Content) {
+				t.Errorf("Pinned article content = %q, want the original", article.Content)
+			}
+		}
+
+		if err := store.SetArticlePinned(ctx, "old", false); err != nil {
+			t.Fatalf("SetArticlePinned(old, false) error = %v", err)
+		}
+		if err := store.CleanupOldArticles(ctx, storage.RetentionPolicy{MaxAge: 7 * 24 * time.Hour}); err != nil {
+			t.Fatalf("CleanupOldArticles() error = %v", err)
+		}
+		unpinned := false
+		articles, _, err = store.GetAllArticles(ctx, &models.ODataQuery{Pinned: &unpinned})
+		if err != nil {
+			t.Fatalf("GetAllArticles(unpinned) error = %v", err)
+		}
+		if got, want := strings.Join(ids(articles), ","), "rust-release,rust-game,zero-day"; got != want {
+			t.Errorf("Unpinned articles after cleanup = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("Archive", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		day := 24 * time.Hour
+		lastMonth := time.Date(now.Year(), now.Month(), 1, 12, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+		articles := []models.Article{
+			{ID: "recent", Title: "Recent rust news", Link: "https://example.com/recent", Content: "Still hot", Source: "Wire", PublishedAt: now},
+			{ID: "expired-1", Title: "Rust archive one", Link: "https://example.com/expired-1", Content: "Archived rust content", Source: "Wire", Categories: []string{"programming"}, PublishedAt: lastMonth.Add(-40 * day)},
+			{ID: "expired-2", Title: "Rust archive two", Link: "https://example.com/expired-2", Content: "More archived content", Source: "Lang News", PublishedAt: lastMonth},
+			{ID: "expired-3", Title: "Go archive", Link: "https://example.com/expired-3", Content: "Gophers", Source: "Wire", PublishedAt: lastMonth.Add(-time.Hour)},
+		}
+		if err := store.SaveArticles(ctx, articles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.AssignArticlesToTopic(ctx, []string{"recent", "expired-1", "expired-2"}, "tech"); err != nil {
+			t.Fatalf("AssignArticlesToTopic() error = %v", err)
+		}
+
+		archive := storage.NewArchive(t.TempDir())
+		policy := storage.RetentionPolicy{MaxAge: now.Sub(lastMonth) - day, Archive: archive}
+		if err := store.CleanupOldArticles(ctx, policy); err != nil {
+			t.Fatalf("CleanupOldArticles() error = %v", err)
+		}
+
+		hot, _, err := store.GetAllArticles(ctx, &models.ODataQuery{})
+		if err != nil {
+			t.Fatalf("GetAllArticles() error = %v", err)
+		}
+		if got := strings.Join(ids(hot), ","); got != "recent" {
+			t.Errorf("Hot articles after archiving = %s, want recent", got)
+		}
+
+		tests := []struct {
+			name  string
+			topic string
+			query *models.ODataQuery
+			want  string
+			total int
+		}{
+			{"all", "", &models.ODataQuery{}, "expired-2,expired-3,expired-1", 3},
+			{"search", "", &models.ODataQuery{Search: []string{"rust"}}, "expired-2,expired-1", 2},
+			{"topic", "tech", &models.ODataQuery{}, "expired-2,expired-1", 2},
+			{"across months", "", &models.ODataQuery{Top: 2, Skip: 1}, "expired-3,expired-1", 3},
+			{"category", "", &models.ODataQuery{Category: "programming"}, "expired-1", 1},
+		}
+		for _, tt := range tests {
+			archived, total, err := archive.Query(ctx, tt.topic, tt.query)
+			if err != nil {
+				t.Fatalf("%s: Query() error = %v", tt.name, err)
+			}
+			if got := strings.Join(ids(archived), ","); got != tt.want || total != tt.total {
+				t.Errorf("%s: archived = %s (%d), want %s (%d)", tt.name, got, total, tt.want, tt.total)
+			}
+		}
+
+		archived, _, err := archive.Query(ctx, "", &models.ODataQuery{Search: []string{"archive one"}})
+		if err != nil || len(archived) != 1 {
+			t.Fatalf("Query(archive one) = %v, %v", archived, err)
+		}
+		if archived[0].Content != "Archived rust content" || archived[0].Topic != "tech" || !archived[0].PublishedAt.Equal(articles[1].PublishedAt) {
+			t.Errorf("Archived article = %+v, want the original content, topic and date", archived[0])
+		}
+	})
+
+	t.Run("GetArticles", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveArticles(ctx, searchArticles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+
+		articles, err := store.GetArticles(ctx, []string{"zero-day", "missing", "rust-release", "zero-day"})
+		if err != nil {
+			t.Fatalf("GetArticles() error = %v", err)
+		}
+		if got := ids(articles); !reflect.DeepEqual(got, []string{"zero-day", "rust-release"}) {
+			t.Fatalf("GetArticles() = %v, want [zero-day rust-release]", got)
+		}
+		if articles[0].Content != searchArticles[2].Content || articles[0].Language == "" {
+			t.Errorf("GetArticles() article = %+v, want full content and language", articles[0])
+		}
+
+		if articles, err := store.GetArticles(ctx, []string{"missing"}); err != nil || len(articles) != 0 {
+			t.Errorf("GetArticles(missing) = %v, %v, want no articles", ids(articles), err)
+		}
+	})
+
+	t.Run("GetArticleIDsByLink", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		duplicate := searchArticles[0]
+		duplicate.ID = "rust-release-copy"
+		if err := store.SaveArticles(ctx, append([]models.Article{duplicate}, searchArticles...)); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+
+		got, err := store.GetArticleIDsByLink(ctx, []string{"https://example.com/rust", "https://example.com/zero-day", "https://example.com/missing"})
+		if err != nil {
+			t.Fatalf("GetArticleIDsByLink() error = %v", err)
+		}
+		want := map[string]string{"https://example.com/rust": "rust-release", "https://example.com/zero-day": "zero-day"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetArticleIDsByLink() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("ArticleFeeds", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveArticles(ctx, searchArticles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.AssignArticlesToFeed(ctx, []string{"rust-release", "zero-day", "missing"}, "https://a.example.com/rss", "Feed A"); err != nil {
+			t.Fatalf("AssignArticlesToFeed(a) error = %v", err)
+		}
+		if err := store.AssignArticlesToFeed(ctx, []string{"zero-day"}, "https://b.example.com/rss", "Feed B"); err != nil {
+			t.Fatalf("AssignArticlesToFeed(b) error = %v", err)
+		}
+		// Re-polling a feed must neither duplicate nor drop its articles
+		if err := store.SaveArticles(ctx, searchArticles[2:]); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.AssignArticlesToFeed(ctx, []string{"zero-day"}, "https://a.example.com/rss", "Feed A"); err != nil {
+			t.Fatalf("AssignArticlesToFeed(a) error = %v", err)
+		}
+
+		articles, err := store.GetArticles(ctx, []string{"zero-day", "rust-game"})
+		if err != nil {
+			t.Fatalf("GetArticles() error = %v", err)
+		}
+		if got := articles[0].FeedURLs; !reflect.DeepEqual(got, []string{"https://a.example.com/rss", "https://b.example.com/rss"}) {
+			t.Errorf("zero-day FeedURLs = %v, want both feeds", got)
+		}
+		if got := articles[1].FeedURLs; len(got) != 0 {
+			t.Errorf("rust-game FeedURLs = %v, want none", got)
+		}
+
+		articles, total, err := store.GetAllArticles(ctx, &models.ODataQuery{FeedURL: "https://a.example.com/rss"})
+		if err != nil {
+			t.Fatalf("GetAllArticles(feed_url) error = %v", err)
+		}
+		if got := strings.Join(ids(articles), ","); got != "rust-release,zero-day" || total != 2 {
+			t.Errorf("Feed A articles = %s (%d), want rust-release,zero-day", got, total)
+		}
+
+		stats, err := store.GetFeedStats(ctx)
+		if err != nil {
+			t.Fatalf("GetFeedStats() error = %v", err)
+		}
+		feedURLs, ok := stats["feed_urls"].([]map[string]interface{})
+		if !ok || len(feedURLs) != 2 {
+			t.Fatalf("GetFeedStats() feed_urls = %v, want two feeds", stats["feed_urls"])
+		}
+		if feedURLs[0]["url"] != "https://a.example.com/rss" || feedURLs[0]["title"] != "Feed A" || feedURLs[0]["article_count"] != 2 {
+			t.Errorf("GetFeedStats() first feed = %v, want Feed A with 2 articles", feedURLs[0])
+		}
+		if feedURLs[1]["article_count"] != 1 {
+			t.Errorf("GetFeedStats() second feed = %v, want 1 article", feedURLs[1])
+		}
+	})
+
+	t.Run("DeleteSourceFeed", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveArticles(ctx, searchArticles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.AssignArticlesToFeed(ctx, ids(searchArticles), "https://a.example.com/rss", "Feed A"); err != nil {
+			t.Fatalf("AssignArticlesToFeed(a) error = %v", err)
+		}
+		if err := store.AssignArticlesToFeed(ctx, []string{"zero-day"}, "https://b.example.com/rss", "Feed B"); err != nil {
+			t.Fatalf("AssignArticlesToFeed(b) error = %v", err)
+		}
+		if err := store.SetArticlePinned(ctx, "rust-game", true); err != nil {
+			t.Fatalf("SetArticlePinned() error = %v", err)
+		}
+
+		stats, err := store.GetFeedStats(ctx)
+		if err != nil {
+			t.Fatalf("GetFeedStats() error = %v", err)
+		}
+		feedIDs := make(map[string]int64)
+		for _, feed := range stats["feed_urls"].([]map[string]interface{}) {
+			feedIDs[feed["url"].(string)] = feed["id"].(int64)
+		}
+
+		// Only the article no other feed or pin references goes
+		deletion, err := store.DeleteSourceFeed(ctx, feedIDs["https://a.example.com/rss"], true)
+		if err != nil {
+			t.Fatalf("DeleteSourceFeed(a) error = %v", err)
+		}
+		if deletion.URL != "https://a.example.com/rss" || deletion.LinkedArticles != 3 || deletion.DeletedArticles != 1 || deletion.KeptArticles != 2 {
+			t.Errorf("DeleteSourceFeed(a) = %+v, want 3 linked, 1 deleted and 2 kept", deletion)
+		}
+		if !reflect.DeepEqual(deletion.DeletedArticleIDs, []string{"rust-release"}) || deletion.DeletedSearchTerms == 0 {
+			t.Errorf("DeleteSourceFeed(a) deleted %v with %d search terms, want rust-release and its terms", deletion.DeletedArticleIDs, deletion.DeletedSearchTerms)
+		}
+
+		articles, err := store.GetArticles(ctx, []string{"rust-release", "rust-game", "zero-day"})
+		if err != nil {
+			t.Fatalf("GetArticles() error = %v", err)
+		}
+		if got := ids(articles); !reflect.DeepEqual(got, []string{"rust-game", "zero-day"}) {
+			t.Fatalf("GetArticles() after purge = %v, want [rust-game zero-day]", got)
+		}
+		if len(articles[0].FeedURLs) != 0 || !reflect.DeepEqual(articles[1].FeedURLs, []string{"https://b.example.com/rss"}) {
+			t.Errorf("FeedURLs after purge = %v and %v, want none and feed B", articles[0].FeedURLs, articles[1].FeedURLs)
+		}
+		if found, _, err := store.GetAllArticles(ctx, &models.ODataQuery{Search: []string{"compiler"}}); err != nil || len(found) != 0 {
+			t.Errorf("Search(compiler) after purge = %v, %v, want no articles", ids(found), err)
+		}
+
+		if _, err := store.DeleteSourceFeed(ctx, feedIDs["https://a.example.com/rss"], true); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("DeleteSourceFeed(a) again error = %v, want storage.ErrNotFound", err)
+		}
+
+		// Without purge the articles stay
+		deletion, err = store.DeleteSourceFeed(ctx, feedIDs["https://b.example.com/rss"], false)
+		if err != nil {
+			t.Fatalf("DeleteSourceFeed(b) error = %v", err)
+		}
+		if deletion.DeletedArticles != 0 || deletion.KeptArticles != 1 {
+			t.Errorf("DeleteSourceFeed(b) = %+v, want 1 kept article", deletion)
+		}
+		if articles, err := store.GetArticles(ctx, []string{"zero-day"}); err != nil || len(articles) != 1 || len(articles[0].FeedURLs) != 0 {
+			t.Errorf("GetArticles(zero-day) = %+v, %v, want the article without feeds", articles, err)
+		}
+	})
+
+	t.Run("DeleteSourceFeedLegacyArticles", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		// Stored before feeds were tracked, so linked to no feed
+		legacy := []models.Article{
+			{ID: "legacy-1", Title: "Legacy one", Link: "https://legacy.example.com/1", Source: "Legacy Feed", PublishedAt: now.Add(-48 * time.Hour)},
+			{ID: "legacy-2", Title: "Legacy two", Link: "https://legacy.example.com/2", Source: "Legacy Feed", PublishedAt: now.Add(-24 * time.Hour)},
+			{ID: "other", Title: "Other", Link: "https://other.example.com/1", Source: "Other Feed", PublishedAt: now.Add(-24 * time.Hour)},
+		}
+		current := models.Article{ID: "current", Title: "Current", Link: "https://legacy.example.com/3", Source: "Legacy Feed", PublishedAt: now}
+		if err := store.SaveArticles(ctx, append(legacy, current)); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+
+		// The first poll only carries the current article
+		if err := store.AssignArticlesToFeed(ctx, []string{"current"}, "https://legacy.example.com/rss", "Legacy Feed"); err != nil {
+			t.Fatalf("AssignArticlesToFeed() error = %v", err)
+		}
+		stats, err := store.GetFeedStats(ctx)
+		if err != nil {
+			t.Fatalf("GetFeedStats() error = %v", err)
+		}
+		feeds := stats["feed_urls"].([]map[string]interface{})
+		if len(feeds) != 1 {
+			t.Fatalf("GetFeedStats() returned %d feeds, want 1", len(feeds))
+		}
+
+		deletion, err := store.DeleteSourceFeed(ctx, feeds[0]["id"].(int64), true)
+		if err != nil {
+			t.Fatalf("DeleteSourceFeed() error = %v", err)
+		}
+		if !reflect.DeepEqual(deletion.DeletedArticleIDs, []string{"current", "legacy-1", "legacy-2"}) {
+			t.Errorf("DeleteSourceFeed() deleted %v, want the current and legacy articles", deletion.DeletedArticleIDs)
+		}
+		if remaining, err := store.GetArticles(ctx, []string{"legacy-1", "legacy-2", "other", "current"}); err != nil || !reflect.DeepEqual(ids(remaining), []string{"other"}) {
+			t.Errorf("GetArticles() after purge = %v, %v, want [other]", ids(remaining), err)
+		}
+	})
+
+	t.Run("StatsSnapshots", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		old := models.Article{ID: "old", Title: "Old news", Link: "https://example.com/old", Content: strings.Repeat("archived content ", 20), Source: "Archive", PublishedAt: now.Add(-10 * 24 * time.Hour)}
+		if err := store.SaveArticles(ctx, append(append([]models.Article{}, searchArticles...), old)); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.CompressOldArticles(ctx); err != nil {
+			t.Fatalf("CompressOldArticles() error = %v", err)
+		}
+
+		first, err := store.RecordStatsSnapshot(ctx)
+		if err != nil {
+			t.Fatalf("RecordStatsSnapshot() error = %v", err)
+		}
+		if first.TableRows["articles"] != 4 || first.TableRows["compressed_content"] != 1 {
+			t.Errorf("Snapshot table rows = %v, want 4 articles and 1 compressed content", first.TableRows)
+		}
+		if first.CompressionRatio <= 1 {
+			t.Errorf("Snapshot compression ratio = %v, want above 1", first.CompressionRatio)
+		}
+		if _, err := store.RecordStatsSnapshot(ctx); err != nil {
+			t.Fatalf("RecordStatsSnapshot() error = %v", err)
+		}
+
+		snapshots, err := store.GetStatsSnapshots(ctx, now.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("GetStatsSnapshots() error = %v", err)
+		}
+		if len(snapshots) != 2 || snapshots[1].TakenAt.Before(snapshots[0].TakenAt) {
+			t.Fatalf("GetStatsSnapshots() = %d snapshots, want 2 oldest first", len(snapshots))
+		}
+		if snapshots[0].TableRows["articles"] != 4 || snapshots[0].DatabaseSizeBytes != first.DatabaseSizeBytes {
+			t.Errorf("Stored snapshot = %+v, want the recorded one", snapshots[0])
+		}
+		if snapshots, err := store.GetStatsSnapshots(ctx, now.Add(time.Hour)); err != nil || len(snapshots) != 0 {
+			t.Errorf("GetStatsSnapshots(future) = %d snapshots, %v, want none", len(snapshots), err)
+		}
+	})
+
+	t.Run("Webhooks", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		all := &models.Webhook{URL: "https://hooks.example.com/all", Topic: "tech", Secret: "s1"}
+		filtered := &models.Webhook{URL: "https://hooks.example.com/rust", Topic: "tech", Filter: "contains(title, 'Rust')", Secret: "s2"}
+		other := &models.Webhook{URL: "https://hooks.example.com/news", Topic: "news", Secret: "s3"}
+		for _, webhook := range []*models.Webhook{all, filtered, other} {
+			if err := store.CreateWebhook(ctx, webhook); err != nil {
+				t.Fatalf("CreateWebhook() error = %v", err)
+			}
+		}
+		if all.ID == 0 || filtered.ID == all.ID {
+			t.Fatalf("CreateWebhook() IDs = %d, %d, want distinct IDs", all.ID, filtered.ID)
+		}
+
+		webhooks, err := store.ListWebhooks(ctx)
+		if err != nil || len(webhooks) != 3 {
+			t.Fatalf("ListWebhooks() = %d webhooks, %v, want 3", len(webhooks), err)
+		}
+		got, err := store.GetWebhook(ctx, filtered.ID)
+		if err != nil || got.Filter != filtered.Filter || got.Secret != "s2" {
+			t.Fatalf("GetWebhook() = %+v, %v, want the filtered webhook", got, err)
+		}
+		if _, err := store.GetWebhook(ctx, 9999); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetWebhook(unknown) error = %v, want ErrNotFound", err)
+		}
+
+		if err := store.SaveArticles(ctx, searchArticles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.AssignArticlesToTopic(ctx, []string{"rust-release", "zero-day"}, "tech"); err != nil {
+			t.Fatalf("AssignArticlesToTopic() error = %v", err)
+		}
+		// Existing memberships do not fire again
+		if err := store.AssignArticlesToTopic(ctx, []string{"rust-release", "rust-game"}, "tech"); err != nil {
+			t.Fatalf("AssignArticlesToTopic() error = %v", err)
+		}
+		// Restored memberships do not fire at all
+		if err := store.RestoreArticlesToTopic(ctx, []string{"zero-day"}, "news"); err != nil {
+			t.Fatalf("RestoreArticlesToTopic() error = %v", err)
+		}
+		if topics, err := store.GetArticleTopics(ctx, "zero-day"); err != nil || !reflect.DeepEqual(topics, []string{"news", "tech"}) {
+			t.Errorf("GetArticleTopics(zero-day) = %v, %v, want news and tech", topics, err)
+		}
+
+		due, err := store.GetDueWebhookDeliveries(ctx, time.Now().Add(time.Minute), 100)
+		if err != nil {
+			t.Fatalf("GetDueWebhookDeliveries() error = %v", err)
+		}
+		perWebhook := map[int64][]string{}
+		for _, delivery := range due {
+			perWebhook[delivery.WebhookID] = append(perWebhook[delivery.WebhookID], delivery.ArticleID)
+		}
+		if got := perWebhook[all.ID]; !reflect.DeepEqual(got, []string{"rust-release", "zero-day", "rust-game"}) {
+			t.Errorf("Deliveries of the unfiltered webhook = %v, want each new membership once", got)
+		}
+		if got := perWebhook[filtered.ID]; !reflect.DeepEqual(got, []string{"rust-release", "rust-game"}) {
+			t.Errorf("Deliveries of the filtered webhook = %v, want the Rust articles", got)
+		}
+		if got := perWebhook[other.ID]; len(got) != 0 {
+			t.Errorf("Deliveries of the other topic webhook = %v, want none", got)
+		}
+
+		var payload models.WebhookPayload
+		if err := json.Unmarshal(due[0].Payload, &payload); err != nil {
+			t.Fatalf("Payload is not JSON: %v", err)
+		}
+		if payload.Event != "article.assigned" || payload.Topic != "tech" || payload.Article.ID != due[0].ArticleID || payload.Article.Content == "" {
+			t.Errorf("Payload = %+v, want the assigned article with its content", payload)
+		}
+
+		delivered := time.Now().UTC().Truncate(time.Second)
+		delivery := due[0]
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.Attempts = 1
+		delivery.ResponseStatus = 204
+		delivery.DeliveredAt = &delivered
+		if err := store.UpdateWebhookDelivery(ctx, &delivery); err != nil {
+			t.Fatalf("UpdateWebhookDelivery() error = %v", err)
+		}
+		retry := due[1]
+		retry.Attempts = 1
+		retry.LastError = "connection refused"
+		retry.NextAttemptAt = time.Now().Add(time.Hour)
+		if err := store.UpdateWebhookDelivery(ctx, &retry); err != nil {
+			t.Fatalf("UpdateWebhookDelivery() error = %v", err)
+		}
+
+		if due, err := store.GetDueWebhookDeliveries(ctx, time.Now().Add(time.Minute), 100); err != nil || len(due) != 3 {
+			t.Errorf("GetDueWebhookDeliveries() after updates = %d deliveries, %v, want 3", len(due), err)
+		}
+		stored, err := store.GetWebhookDelivery(ctx, delivery.ID)
+		if err != nil || stored.Status != models.WebhookDeliveryDelivered || stored.ResponseStatus != 204 || stored.DeliveredAt == nil {
+			t.Errorf("GetWebhookDelivery() = %+v, %v, want the delivered outcome", stored, err)
+		}
+		if _, err := store.GetWebhookDelivery(ctx, 9999); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetWebhookDelivery(unknown) error = %v, want ErrNotFound", err)
+		}
+
+		deliveries, err := store.GetWebhookDeliveries(ctx, all.ID, "", 10)
+		if err != nil || len(deliveries) != 3 || deliveries[0].ID < deliveries[2].ID {
+			t.Errorf("GetWebhookDeliveries() = %d deliveries, %v, want 3 newest first", len(deliveries), err)
+		}
+		if deliveries, err := store.GetWebhookDeliveries(ctx, delivery.WebhookID, models.WebhookDeliveryDelivered, 10); err != nil || len(deliveries) != 1 {
+			t.Errorf("GetWebhookDeliveries(delivered) = %d deliveries, %v, want 1", len(deliveries), err)
+		}
+		if _, err := store.GetWebhookDeliveries(ctx, 9999, "", 10); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetWebhookDeliveries(unknown) error = %v, want ErrNotFound", err)
+		}
+
+		if err := store.DeleteWebhook(ctx, all.ID); err != nil {
+			t.Fatalf("DeleteWebhook() error = %v", err)
+		}
+		if err := store.DeleteWebhook(ctx, all.ID); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("DeleteWebhook(deleted) error = %v, want ErrNotFound", err)
+		}
+		if due, err := store.GetDueWebhookDeliveries(ctx, time.Now().Add(time.Minute), 100); err != nil || len(due) != 2 {
+			t.Errorf("GetDueWebhookDeliveries() after delete = %d deliveries, %v, want the filtered webhook's 2", len(due), err)
+		}
+	})
+
+	t.Run("Digests", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveArticles(ctx, searchArticles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.AssignArticlesToTopic(ctx, []string{"zero-day"}, "tech"); err != nil {
+			t.Fatalf("AssignArticlesToTopic() error = %v", err)
+		}
+		_, start, err := store.GetTopicArrivals(ctx, "tech", -1)
+		if err != nil || start <= 0 {
+			t.Fatalf("GetTopicArrivals(-1) cursor = %d, %v, want the current cursor", start, err)
+		}
+
+		if err := store.AssignArticlesToTopic(ctx, []string{"rust-game", "rust-release"}, "tech"); err != nil {
+			t.Fatalf("AssignArticlesToTopic() error = %v", err)
+		}
+		if err := store.AssignArticlesToTopic(ctx, []string{"rust-release"}, "news"); err != nil {
+			t.Fatalf("AssignArticlesToTopic() error = %v", err)
+		}
+		arrived, cursor, err := store.GetTopicArrivals(ctx, "tech", start)
+		if err != nil || !reflect.DeepEqual(arrived, []string{"rust-game", "rust-release"}) || cursor <= start {
+			t.Fatalf("GetTopicArrivals() = %v, %d, %v, want the articles assigned after the cursor in order", arrived, cursor, err)
+		}
+		if arrived, next, err := store.GetTopicArrivals(ctx, "tech", cursor); err != nil || len(arrived) != 0 || next != cursor {
+			t.Errorf("GetTopicArrivals(last cursor) = %v, %d, %v, want nothing new", arrived, next, err)
+		}
+		if arrived, _, err := store.GetTopicArrivals(ctx, "unknown", 0); err != nil || len(arrived) != 0 {
+			t.Errorf("GetTopicArrivals(unknown) = %v, %v, want none", arrived, err)
+		}
+
+		due := now.Add(time.Hour)
+		digest := &models.Digest{Recipient: "team@example.com", Topic: "tech", Filter: "contains(title, 'Rust')", Schedule: "daily 08:00", MaxArticles: 10, Cursor: start, NextSendAt: due}
+		if err := store.CreateDigest(ctx, digest); err != nil {
+			t.Fatalf("CreateDigest() error = %v", err)
+		}
+		got, err := store.GetDigest(ctx, digest.ID)
+		if err != nil || got.Recipient != digest.Recipient || got.Filter != digest.Filter || got.Cursor != start || !got.NextSendAt.Equal(due) || got.LastSentAt != nil {
+			t.Fatalf("GetDigest() = %+v, %v, want the created digest", got, err)
+		}
+		if _, err := store.GetDigest(ctx, 9999); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetDigest(unknown) error = %v, want ErrNotFound", err)
+		}
+
+		if digests, err := store.GetDueDigests(ctx, now); err != nil || len(digests) != 0 {
+			t.Errorf("GetDueDigests(before) = %d digests, %v, want none", len(digests), err)
+		}
+		if digests, err := store.GetDueDigests(ctx, due); err != nil || len(digests) != 1 {
+			t.Errorf("GetDueDigests(due) = %d digests, %v, want 1", len(digests), err)
+		}
+
+		sent := now.Add(2 * time.Hour).UTC()
+		got.Cursor = cursor
+		got.LastSentAt = &sent
+		got.NextSendAt = sent.Add(24 * time.Hour)
+		got.LastError = "previous failure"
+		if err := store.UpdateDigestState(ctx, got); err != nil {
+			t.Fatalf("UpdateDigestState() error = %v", err)
+		}
+		digests, err := store.ListDigests(ctx)
+		if err != nil || len(digests) != 1 {
+			t.Fatalf("ListDigests() = %d digests, %v, want 1", len(digests), err)
+		}
+		if updated := digests[0]; updated.Cursor != cursor || updated.LastSentAt == nil || !updated.LastSentAt.Equal(sent) || !updated.NextSendAt.Equal(sent.Add(24*time.Hour)) || updated.LastError != "previous failure" {
+			t.Errorf("Digest after UpdateDigestState() = %+v, want the new state", updated)
+		}
+
+		if err := store.DeleteDigest(ctx, digest.ID); err != nil {
+			t.Fatalf("DeleteDigest() error = %v", err)
+		}
+		if err := store.DeleteDigest(ctx, digest.ID); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("DeleteDigest(deleted) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("ReaderItems", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveArticles(ctx, searchArticles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.AssignArticlesToTopic(ctx, []string{"rust-release"}, "tech"); err != nil {
+			t.Fatalf("AssignArticlesToTopic() error = %v", err)
+		}
+		if err := store.AssignArticlesToTopic(ctx, []string{"zero-day", "rust-release"}, "security"); err != nil {
+			t.Fatalf("AssignArticlesToTopic() error = %v", err)
+		}
+		if err := store.AssignArticlesToFeed(ctx, []string{"rust-release", "rust-game"}, "https://lang.example.com/feed", "Lang News"); err != nil {
+			t.Fatalf("AssignArticlesToFeed() error = %v", err)
+		}
+
+		// Item IDs follow the storage order, newest first by default
+		stream := func(query models.ReaderQuery) []string {
+			t.Helper()
+			if query.Limit == 0 {
+				query.Limit = 100
+			}
+			refs, err := store.GetReaderItemRefs(ctx, &query)
+			if err != nil {
+				t.Fatalf("GetReaderItemRefs(%+v) error = %v", query, err)
+			}
+			itemIDs := make([]int64, len(refs))
+			for i, ref := range refs {
+				itemIDs[i] = ref.ID
+				if i > 0 && (ref.ID < refs[i-1].ID) == query.Oldest {
+					t.Errorf("GetReaderItemRefs(%+v) = %v, not in storage order", query, refs)
+				}
+			}
+			items, err := store.GetReaderItems(ctx, itemIDs)
+			if err != nil || len(items) != len(refs) {
+				t.Fatalf("GetReaderItems(%v) = %d items, %v, want %d", itemIDs, len(items), err, len(refs))
+			}
+			articleIDs := []string{}
+			for _, item := range items {
+				articleIDs = append(articleIDs, item.Article.ID)
+			}
+			return articleIDs
+		}
+		if got := stream(models.ReaderQuery{}); !reflect.DeepEqual(got, []string{"zero-day", "rust-game", "rust-release"}) {
+			t.Errorf("Reading list = %v, want the articles newest stored first", got)
+		}
+		if got := stream(models.ReaderQuery{Oldest: true, Limit: 1, Offset: 1}); !reflect.DeepEqual(got, []string{"rust-game"}) {
+			t.Errorf("Second oldest item = %v, want rust-game", got)
+		}
+		if got := stream(models.ReaderQuery{Topic: "tech"}); !reflect.DeepEqual(got, []string{"rust-release"}) {
+			t.Errorf("Topic stream = %v, want rust-release", got)
+		}
+		if got := stream(models.ReaderQuery{FeedURL: "https://lang.example.com/feed"}); !reflect.DeepEqual(got, []string{"rust-game", "rust-release"}) {
+			t.Errorf("Feed stream = %v, want the articles of the feed", got)
+		}
+		if got := stream(models.ReaderQuery{Since: now.Add(-time.Minute), Until: now.Add(time.Hour)}); len(got) != 3 {
+			t.Errorf("Items stored within the last minute = %v, want all 3", got)
+		}
+		if got := stream(models.ReaderQuery{Since: now.Add(time.Hour)}); len(got) != 0 {
+			t.Errorf("Items stored in an hour = %v, want none", got)
+		}
+
+		refs, err := store.GetReaderItemRefs(ctx, &models.ReaderQuery{Limit: 10})
+		if err != nil || len(refs) != 3 {
+			t.Fatalf("GetReaderItemRefs() = %v, %v", refs, err)
+		}
+		zeroDay, rustRelease := refs[0].ID, refs[2].ID
+		items, err := store.GetReaderItems(ctx, []int64{9999, rustRelease, rustRelease})
+		if err != nil || len(items) != 1 {
+			t.Fatalf("GetReaderItems(unknown, repeated) = %d items, %v, want 1", len(items), err)
+		}
+		if item := items[0]; item.Article.Title != "Rust compiler release" || item.Article.Content == "" || !reflect.DeepEqual(item.Topics, []string{"tech", "security"}) ||
+			!reflect.DeepEqual(item.Article.FeedURLs, []string{"https://lang.example.com/feed"}) || item.CrawledAt.IsZero() || item.Read || item.Starred {
+			t.Errorf("GetReaderItems() = %+v, want the article with its topics and no state", item)
+		}
+
+		// States survive polling the articles again
+		if err := store.SetReaderItemState(ctx, []int64{zeroDay, 9999}, models.ReaderStateRead, true); err != nil {
+			t.Fatalf("SetReaderItemState(read) error = %v", err)
+		}
+		if err := store.SetReaderItemState(ctx, []int64{rustRelease}, models.ReaderStateStarred, true); err != nil {
+			t.Fatalf("SetReaderItemState(starred) error = %v", err)
+		}
+		if err := store.SaveArticles(ctx, searchArticles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if got := stream(models.ReaderQuery{Unread: true}); !reflect.DeepEqual(got, []string{"rust-game", "rust-release"}) {
+			t.Errorf("Unread items = %v, want all but zero-day", got)
+		}
+		if got := stream(models.ReaderQuery{Read: true}); !reflect.DeepEqual(got, []string{"zero-day"}) {
+			t.Errorf("Read items = %v, want zero-day", got)
+		}
+		if got := stream(models.ReaderQuery{Starred: true, Unread: true}); !reflect.DeepEqual(got, []string{"rust-release"}) {
+			t.Errorf("Unread starred items = %v, want rust-release", got)
+		}
+		if items, err := store.GetReaderItems(ctx, []int64{zeroDay, rustRelease}); err != nil || len(items) != 2 || !items[0].Read || items[0].Starred || items[1].Read || !items[1].Starred {
+			t.Errorf("GetReaderItems() = %+v, %v, want zero-day read and rust-release starred", items, err)
+		}
+
+		if err := store.SetReaderItemState(ctx, []int64{zeroDay}, models.ReaderStateRead, false); err != nil {
+			t.Fatalf("SetReaderItemState(unread) error = %v", err)
+		}
+		if got := stream(models.ReaderQuery{Unread: true}); len(got) != 3 {
+			t.Errorf("Unread items after marking unread = %v, want all 3", got)
+		}
+		if err := store.SetReaderItemState(ctx, []int64{zeroDay}, "liked", true); err == nil {
+			t.Error("SetReaderItemState(unknown state) succeeded, want an error")
+		}
+	})
+
+	t.Run("StarredKept", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		old := models.Article{ID: "old", Title: "Old news", Link: "https://example.com/old", Content: strings.Repeat("archived content ", 20), Source: "Archive", PublishedAt: now.Add(-10 * 24 * time.Hour)}
+		duplicate := searchArticles[0]
+		duplicate.ID = "rust-release-copy"
+		if err := store.SaveArticles(ctx, append(append([]models.Article{}, searchArticles...), old)); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.SaveArticles(ctx, []models.Article{duplicate}); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.AssignArticlesToFeed(ctx, []string{"old", "rust-release-copy", "rust-game"}, "https://a.example.com/rss", "Feed A"); err != nil {
+			t.Fatalf("AssignArticlesToFeed() error = %v", err)
+		}
+
+		// Star every article but rust-game through the Google Reader states
+		refs, err := store.GetReaderItemRefs(ctx, &models.ReaderQuery{Limit: 100})
+		if err != nil {
+			t.Fatalf("GetReaderItemRefs() error = %v", err)
+		}
+		itemIDs := make([]int64, len(refs))
+		for i, ref := range refs {
+			itemIDs[i] = ref.ID
+		}
+		items, err := store.GetReaderItems(ctx, itemIDs)
+		if err != nil {
+			t.Fatalf("GetReaderItems() error = %v", err)
+		}
+		var starred []int64
+		for _, item := range items {
+			if item.Article.ID != "rust-game" {
+				starred = append(starred, item.ID)
+			}
+		}
+		if err := store.SetReaderItemState(ctx, starred, models.ReaderStateStarred, true); err != nil {
+			t.Fatalf("SetReaderItemState() error = %v", err)
+		}
+
+		if err := store.CompressOldArticles(ctx); err != nil {
+			t.Fatalf("CompressOldArticles() error = %v", err)
+		}
+		if err := store.RemoveDuplicateArticles(ctx); err != nil {
+			t.Fatalf("RemoveDuplicateArticles() error = %v", err)
+		}
+		if err := store.CleanupOldArticles(ctx, storage.RetentionPolicy{MaxAge: 7 * 24 * time.Hour}); err != nil {
+			t.Fatalf("CleanupOldArticles() error = %v", err)
+		}
+		stats, err := store.GetFeedStats(ctx)
+		if err != nil {
+			t.Fatalf("GetFeedStats() error = %v", err)
+		}
+		feeds := stats["feed_urls"].([]map[string]interface{})
+		if len(feeds) != 1 {
+			t.Fatalf("GetFeedStats() feeds = %v, want 1", feeds)
+		}
+		deletion, err := store.DeleteSourceFeed(ctx, feeds[0]["id"].(int64), true)
+		if err != nil {
+			t.Fatalf("DeleteSourceFeed() error = %v", err)
+		}
+		if got := strings.Join(deletion.DeletedArticleIDs, ","); got != "rust-game" {
+			t.Errorf("Purged articles = %s, want rust-game", got)
+		}
+
+		articles, err := store.GetArticles(ctx, []string{"old", "rust-release-copy"})
+		if err != nil {
+			t.Fatalf("GetArticles() error = %v", err)
+		}
+		if len(articles) != 2 {
+			t.Fatalf("Starred articles = %v, want old and rust-release-copy", ids(articles))
+		}
+		if articles[0].Content != strings.TrimSpace(old.Content) {
+			t.Errorf("Starred article content = %q, want the original", articles[0].Content)
+		}
+
+		// Unstarred articles are no longer kept
+		if err := store.SetReaderItemState(ctx, starred, models.ReaderStateStarred, false); err != nil {
+			t.Fatalf("SetReaderItemState() error = %v", err)
+		}
+		if err := store.CleanupOldArticles(ctx, storage.RetentionPolicy{MaxAge: 7 * 24 * time.Hour}); err != nil {
+			t.Fatalf("CleanupOldArticles() error = %v", err)
+		}
+		if articles, err := store.GetArticles(ctx, []string{"old"}); err != nil || len(articles) != 0 {
+			t.Errorf("GetArticles(old) = %v, %v, want the unstarred article cleaned up", ids(articles), err)
+		}
+	})
+
+	t.Run("Revisions", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		original := models.Article{ID: "story", Title: "Storm heads north", Link: "https://example.com/story", Description: "Forecast", Content: "First paragraph\nSecond paragraph", Source: "Wire", PublishedAt: now}
+		if err := store.SaveArticles(ctx, []models.Article{original}); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		if err := store.AssignArticlesToTopic(ctx, []string{"story"}, "news"); err != nil {
+			t.Fatalf("AssignArticlesToTopic() error = %v", err)
+		}
+
+		// Polling the same version again is not a revision
+		if err := store.SaveArticles(ctx, []models.Article{original}); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+		articles, _, err := store.GetAllArticles(ctx, &models.ODataQuery{})
+		if err != nil || len(articles) != 1 {
+			t.Fatalf("GetAllArticles() = %v, %v", articles, err)
+		}
+		if articles[0].UpdatedAt != nil {
+			t.Errorf("Unchanged article has updated_at %v", articles[0].UpdatedAt)
+		}
+
+		// Article IDs do not depend on the title, so a corrected headline keeps the ID
+		corrected := original
+		corrected.Title = "Storm heads south"
+		corrected.Content = "First paragraph\nCorrected second paragraph"
+		if err := store.SaveArticles(ctx, []models.Article{corrected}); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+
+		revisions, err := store.GetArticleRevisions(ctx, "story")
+		if err != nil {
+			t.Fatalf("GetArticleRevisions() error = %v", err)
+		}
+		if len(revisions) != 1 {
+			t.Fatalf("GetArticleRevisions() returned %d revisions, want 1", len(revisions))
+		}
+		revision := revisions[0]
+		if revision.Revision != 1 || revision.Title != "Storm heads north" || len(revision.ContentHash) != 64 {
+			t.Errorf("Revision = %+v, want revision 1 of the original title", revision)
+		}
+		wantDiff := "@@ title\n-Storm heads north\n+Storm heads south\n@@ content\n First paragraph\n-Second paragraph\n+Corrected second paragraph\n"
+		if revision.Diff != wantDiff {
+			t.Errorf("Diff = %q, want %q", revision.Diff, wantDiff)
+		}
+
+		articles, _, err = store.GetAllArticles(ctx, &models.ODataQuery{})
+		if err != nil || len(articles) != 1 {
+			t.Fatalf("GetAllArticles() = %v, %v", articles, err)
+		}
+		if articles[0].Title != "Storm heads south" || articles[0].UpdatedAt == nil {
+			t.Errorf("Updated article = %+v, want the corrected title and an updated_at", articles[0])
+		}
+		if topics, _ := store.GetArticleTopics(ctx, "story"); len(topics) != 1 {
+			t.Errorf("Updated article lost its topics: %v", topics)
+		}
+
+		if _, err := store.GetArticleRevisions(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetArticleRevisions(missing) error = %v, want storage.ErrNotFound", err)
+		}
+	})
+
+	t.Run("CancelledContext", func(t *testing.T) {
+		store := newStorage(t, Config())
+		defer store.Close()
+
+		if err := store.SaveArticles(ctx, searchArticles); err != nil {
+			t.Fatalf("SaveArticles() error = %v", err)
+		}
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		if _, _, err := store.GetAllArticles(cancelled, &models.ODataQuery{}); err == nil {
+			t.Error("GetAllArticles() with a cancelled context succeeded, want error")
+		}
+		if _, err := store.GetRelatedArticles(cancelled, "rust-release", 5); err == nil {
+			t.Error("GetRelatedArticles() with a cancelled context succeeded, want error")
+		}
+	})
+}
@@ -0,0 +1,311 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"gorssag/internal/models"
+	"gorssag/internal/odata"
+)
+
+// webhookEvent is the event name of the payloads posted to webhooks
+const webhookEvent = "article.assigned"
+
+// webhookDeliveries builds the deliveries of articles newly assigned to a
+// topic, for the topic's webhooks whose filter matches them
+func webhookDeliveries(webhooks []models.Webhook, articles []models.Article, topic string, now time.Time) []models.WebhookDelivery {
+	filterParser := odata.NewFilterParser()
+
+	var deliveries []models.WebhookDelivery
+	for _, webhook := range webhooks {
+		var filter *odata.FilterExpression
+		if webhook.Filter != "" {
+			expr, err := filterParser.Parse(webhook.Filter)
+			if err != nil {
+				log.Printf("Warning: skipping webhook %d with invalid filter: %v", webhook.ID, err)
+				continue
+			}
+			filter = expr
+		}
+
+		for _, article := range articles {
+			if filter != nil {
+				matches, err := filterParser.Evaluate(filter, article)
+				if err != nil || !matches {
+					continue
+				}
+			}
+
+			payload, err := json.Marshal(models.WebhookPayload{
+				Event:     webhookEvent,
+				WebhookID: webhook.ID,
+				Topic:     topic,
+				Article:   article,
+				CreatedAt: now,
+			})
+			if err != nil {
+				log.Printf("Warning: failed to encode webhook payload for article %s: %v", article.ID, err)
+				continue
+			}
+			deliveries = append(deliveries, models.WebhookDelivery{
+				WebhookID:     webhook.ID,
+				ArticleID:     article.ID,
+				Topic:         topic,
+				Payload:       payload,
+				Status:        models.WebhookDeliveryPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			})
+		}
+	}
+	return deliveries
+}
+
+// queueSQLWebhookDeliveries queues the deliveries of articles newly assigned
+// to a topic, in the transaction assigning them
+func queueSQLWebhookDeliveries(ctx context.Context, q queryer, articleIDs []string, topic string) (int, error) {
+	if len(articleIDs) == 0 {
+		return 0, nil
+	}
+
+	webhooks, err := listSQLWebhooks(ctx, q, "WHERE topic = ?", topic)
+	if err != nil || len(webhooks) == 0 {
+		return 0, err
+	}
+
+	articles, err := getSQLArticles(ctx, q, articleIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	deliveries := webhookDeliveries(webhooks, articles, topic, time.Now().UTC())
+	for _, delivery := range deliveries {
+		if _, err := q.ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (webhook_id, article_id, topic, payload, status, next_attempt_at, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, delivery.WebhookID, delivery.ArticleID, delivery.Topic, string(delivery.Payload), delivery.Status, delivery.NextAttemptAt, delivery.CreatedAt); err != nil {
+			return 0, fmt.Errorf("failed to queue webhook delivery: %v", err)
+		}
+	}
+	return len(deliveries), nil
+}
+
+// createSQLWebhook stores a webhook, setting its ID and creation time
+func createSQLWebhook(ctx context.Context, q queryer, webhook *models.Webhook) error {
+	webhook.CreatedAt = time.Now().UTC()
+	if err := q.QueryRowContext(ctx, `
+		INSERT INTO webhooks (url, topic, filter, secret, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`, webhook.URL, webhook.Topic, webhook.Filter, webhook.Secret, webhook.CreatedAt).Scan(&webhook.ID); err != nil {
+		return fmt.Errorf("failed to create webhook: %v", err)
+	}
+	return nil
+}
+
+// listSQLWebhooks returns the webhooks matching a WHERE clause, oldest first
+func listSQLWebhooks(ctx context.Context, q queryer, where string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, url, topic, filter, secret, created_at FROM webhooks "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %v", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := rows.Scan(&webhook.ID, &webhook.URL, &webhook.Topic, &webhook.Filter, &webhook.Secret, &webhook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %v", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// getSQLWebhook returns a webhook by ID
+func getSQLWebhook(ctx context.Context, q queryer, id int64) (*models.Webhook, error) {
+	webhooks, err := listSQLWebhooks(ctx, q, "WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, ErrNotFound
+	}
+	return &webhooks[0], nil
+}
+
+// deleteSQLWebhook deletes a webhook, its deliveries cascade
+func deleteSQLWebhook(ctx context.Context, q queryer, id int64) error {
+	result, err := q.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// listSQLWebhookDeliveries returns the deliveries matching a WHERE clause
+func listSQLWebhookDeliveries(ctx context.Context, q queryer, where string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, webhook_id, article_id, topic, payload, status, attempts, next_attempt_at, last_error, response_status, created_at, delivered_at
+		FROM webhook_deliveries `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var payload string
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.ArticleID, &delivery.Topic, &payload, &delivery.Status, &delivery.Attempts,
+			&delivery.NextAttemptAt, &delivery.LastError, &delivery.ResponseStatus, &delivery.CreatedAt, &deliveredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %v", err)
+		}
+		delivery.Payload = []byte(payload)
+		if deliveredAt.Valid {
+			delivery.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// getSQLWebhookDeliveries returns the deliveries of a webhook, newest first,
+// optionally limited to a status
+func getSQLWebhookDeliveries(ctx context.Context, q queryer, webhookID int64, status string, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := getSQLWebhook(ctx, q, webhookID); err != nil {
+		return nil, err
+	}
+	if status == "" {
+		return listSQLWebhookDeliveries(ctx, q, "WHERE webhook_id = ? ORDER BY id DESC LIMIT ?", webhookID, limit)
+	}
+	return listSQLWebhookDeliveries(ctx, q, "WHERE webhook_id = ? AND status = ? ORDER BY id DESC LIMIT ?", webhookID, status, limit)
+}
+
+// getSQLWebhookDelivery returns a delivery by ID
+func getSQLWebhookDelivery(ctx context.Context, q queryer, id int64) (*models.WebhookDelivery, error) {
+	deliveries, err := listSQLWebhookDeliveries(ctx, q, "WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, ErrNotFound
+	}
+	return &deliveries[0], nil
+}
+
+// updateSQLWebhookDelivery stores the outcome of a delivery attempt
+func updateSQLWebhookDelivery(ctx context.Context, q queryer, delivery *models.WebhookDelivery) error {
+	var deliveredAt interface{}
+	if delivery.DeliveredAt != nil {
+		deliveredAt = *delivery.DeliveredAt
+	}
+	result, err := q.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, response_status = ?, delivered_at = ?
+		WHERE id = ?
+	`, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastError, delivery.ResponseStatus, deliveredAt, delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateWebhook stores a webhook, setting its ID and creation time
+func (s *SQLiteStorage) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	return createSQLWebhook(ctx, s.db, webhook)
+}
+
+// ListWebhooks returns all webhooks, oldest first
+func (s *SQLiteStorage) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	return listSQLWebhooks(ctx, s.db, "")
+}
+
+// GetWebhook returns a webhook by ID
+func (s *SQLiteStorage) GetWebhook(ctx context.Context, id int64) (*models.Webhook, error) {
+	return getSQLWebhook(ctx, s.db, id)
+}
+
+// DeleteWebhook deletes a webhook with its deliveries
+func (s *SQLiteStorage) DeleteWebhook(ctx context.Context, id int64) error {
+	return deleteSQLWebhook(ctx, s.db, id)
+}
+
+// GetDueWebhookDeliveries returns the pending deliveries due by now, oldest first
+func (s *SQLiteStorage) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return listSQLWebhookDeliveries(ctx, s.db, "WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?", models.WebhookDeliveryPending, now.UTC(), limit)
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook, newest first
+func (s *SQLiteStorage) GetWebhookDeliveries(ctx context.Context, webhookID int64, status string, limit int) ([]models.WebhookDelivery, error) {
+	return getSQLWebhookDeliveries(ctx, s.db, webhookID, status, limit)
+}
+
+// GetWebhookDelivery returns a delivery by ID
+func (s *SQLiteStorage) GetWebhookDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	return getSQLWebhookDelivery(ctx, s.db, id)
+}
+
+// UpdateWebhookDelivery stores the outcome of a delivery attempt
+func (s *SQLiteStorage) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return updateSQLWebhookDelivery(ctx, s.db, delivery)
+}
+
+// CreateWebhook stores a webhook, setting its ID and creation time
+func (s *PostgresStorage) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	return createSQLWebhook(ctx, s.db, webhook)
+}
+
+// ListWebhooks returns all webhooks, oldest first
+func (s *PostgresStorage) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	return listSQLWebhooks(ctx, s.db, "")
+}
+
+// GetWebhook returns a webhook by ID
+func (s *PostgresStorage) GetWebhook(ctx context.Context, id int64) (*models.Webhook, error) {
+	return getSQLWebhook(ctx, s.db, id)
+}
+
+// DeleteWebhook deletes a webhook with its deliveries
+func (s *PostgresStorage) DeleteWebhook(ctx context.Context, id int64) error {
+	return deleteSQLWebhook(ctx, s.db, id)
+}
+
+// GetDueWebhookDeliveries returns the pending deliveries due by now, oldest first
+func (s *PostgresStorage) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return listSQLWebhookDeliveries(ctx, s.db, "WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?", models.WebhookDeliveryPending, now.UTC(), limit)
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook, newest first
+func (s *PostgresStorage) GetWebhookDeliveries(ctx context.Context, webhookID int64, status string, limit int) ([]models.WebhookDelivery, error) {
+	return getSQLWebhookDeliveries(ctx, s.db, webhookID, status, limit)
+}
+
+// GetWebhookDelivery returns a delivery by ID
+func (s *PostgresStorage) GetWebhookDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	return getSQLWebhookDelivery(ctx, s.db, id)
+}
+
+// UpdateWebhookDelivery stores the outcome of a delivery attempt
+func (s *PostgresStorage) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return updateSQLWebhookDelivery(ctx, s.db, delivery)
+}
@@ -0,0 +1,233 @@
+// Package webhooks delivers the webhook payloads queued by storage when
+// articles are newly assigned to a topic.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorssag/internal/config"
+	"gorssag/internal/models"
+	"gorssag/internal/storage"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Gorssag-Signature" // "sha256=" and the hex HMAC-SHA256 of the body
+	EventHeader     = "X-Gorssag-Event"
+	DeliveryHeader  = "X-Gorssag-Delivery" // Delivery ID, the same across retries
+)
+
+const (
+	defaultMaxAttempts = 6
+	defaultTimeout     = 10 * time.Second
+	dispatchInterval   = 10 * time.Second // How often due deliveries are sent
+	dispatchBatch      = 50               // Deliveries sent per storage read
+	baseBackoff        = 30 * time.Second // Delay before the first retry, doubled after each failure
+	maxBackoff         = time.Hour
+	maxErrorLength     = 512 // Bytes of the response body kept as the error
+)
+
+// Dispatcher sends the due webhook deliveries in the background, retrying
+// failures with exponential backoff until they are delivered or dead
+type Dispatcher struct {
+	storage     storage.Storage
+	client      *http.Client
+	maxAttempts int
+	now         func() time.Time
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewDispatcher creates a dispatcher for the deliveries queued in storage
+func NewDispatcher(store storage.Storage, cfg *config.Config) *Dispatcher {
+	maxAttempts, timeout := defaultMaxAttempts, defaultTimeout
+	if cfg != nil && cfg.WebhookMaxAttempts > 0 {
+		maxAttempts = cfg.WebhookMaxAttempts
+	}
+	if cfg != nil && cfg.WebhookTimeout > 0 {
+		timeout = cfg.WebhookTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		storage:     store,
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: maxAttempts,
+		now:         time.Now,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start sends the due deliveries every dispatchInterval until Stop
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	if d.running {
+		d.mu.Unlock()
+		return
+	}
+	d.running = true
+	d.mu.Unlock()
+
+	log.Printf("Starting webhook dispatcher with up to %d attempts per delivery", d.maxAttempts)
+
+	d.wg.Add(1)
+	go d.dispatchLoop()
+}
+
+// Stop waits for the delivery in progress and stops the dispatcher
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	if !d.running {
+		d.mu.Unlock()
+		return
+	}
+	d.running = false
+	d.mu.Unlock()
+
+	d.cancel()
+	d.wg.Wait()
+	log.Println("Webhook dispatcher stopped")
+}
+
+func (d *Dispatcher) dispatchLoop() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DeliverDue(d.ctx); err != nil && d.ctx.Err() == nil {
+			log.Printf("Warning: webhook dispatch failed: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// DeliverDue sends every delivery due now and returns how many were attempted
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	webhooks := make(map[int64]*models.Webhook)
+	attempted := 0
+	for {
+		due, err := d.storage.GetDueWebhookDeliveries(ctx, d.now(), dispatchBatch)
+		if err != nil {
+			return attempted, fmt.Errorf("failed to read due webhook deliveries: %v", err)
+		}
+
+		for i := range due {
+			delivery := &due[i]
+			webhook, ok := webhooks[delivery.WebhookID]
+			if !ok {
+				webhook, err = d.storage.GetWebhook(ctx, delivery.WebhookID)
+				if errors.Is(err, storage.ErrNotFound) {
+					continue // Deleted meanwhile, its deliveries went with it
+				}
+				if err != nil {
+					return attempted, fmt.Errorf("failed to read webhook %d: %v", delivery.WebhookID, err)
+				}
+				webhooks[delivery.WebhookID] = webhook
+			}
+
+			d.attempt(ctx, webhook, delivery)
+			attempted++
+			if err := d.storage.UpdateWebhookDelivery(ctx, delivery); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return attempted, err
+			}
+		}
+
+		// Failed deliveries are rescheduled, so a full batch means more are due
+		if len(due) < dispatchBatch || ctx.Err() != nil {
+			return attempted, ctx.Err()
+		}
+	}
+}
+
+// attempt posts a delivery once and records the outcome in it
+func (d *Dispatcher) attempt(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.ResponseStatus = 0
+
+	status, err := d.post(ctx, webhook, delivery)
+	delivery.ResponseStatus = status
+	now := d.now().UTC()
+	if err == nil {
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.maxAttempts {
+		delivery.Status = models.WebhookDeliveryDead
+		log.Printf("Warning: webhook %d delivery %d is dead after %d attempts: %v", webhook.ID, delivery.ID, delivery.Attempts, err)
+		return
+	}
+	delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+}
+
+// post sends the signed payload and returns the response status
+func (d *Dispatcher) post(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gorssag-webhooks")
+	req.Header.Set(EventHeader, "article.assigned")
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value of a payload: "sha256=" and the
+// hex HMAC-SHA256 of the body keyed with the webhook secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay before retrying a delivery that failed attempts times
+func Backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
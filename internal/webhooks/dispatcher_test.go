@@ -0,0 +1,157 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"gorssag/internal/config"
+	"gorssag/internal/models"
+	"gorssag/internal/storage"
+)
+
+// newTestStorage returns a memory storage with a webhook on topic tech and
+// one article newly assigned to it
+func newTestStorage(t *testing.T, url string) (storage.Storage, *models.Webhook) {
+	t.Helper()
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(&config.Config{MaxContentLength: 10000})
+
+	webhook := &models.Webhook{URL: url, Topic: "tech", Secret: "topsecret"}
+	if err := store.CreateWebhook(ctx, webhook); err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	article := models.Article{ID: "a1", Title: "Go release", Link: "https://example.com/a1", Content: "Go shipped", Source: "Example", PublishedAt: time.Now()}
+	if err := store.SaveArticles(ctx, []models.Article{article}); err != nil {
+		t.Fatalf("SaveArticles() error = %v", err)
+	}
+	if err := store.AssignArticlesToTopic(ctx, []string{"a1"}, "tech"); err != nil {
+		t.Fatalf("AssignArticlesToTopic() error = %v", err)
+	}
+	return store, webhook
+}
+
+func TestDispatcher_DeliversSignedPayload(t *testing.T) {
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store, webhook := newTestStorage(t, receiver.URL)
+	dispatcher := NewDispatcher(store, nil)
+
+	attempted, err := dispatcher.DeliverDue(context.Background())
+	if err != nil || attempted != 1 {
+		t.Fatalf("DeliverDue() = %d, %v, want 1 delivery", attempted, err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(received))
+	}
+
+	req := received[0]
+	if got, want := req.Header.Get(SignatureHeader), Sign("topsecret", bodies[0]); got != want {
+		t.Errorf("Signature = %q, want %q", got, want)
+	}
+	if req.Header.Get(EventHeader) != "article.assigned" || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected headers %v", req.Header)
+	}
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(bodies[0], &payload); err != nil {
+		t.Fatalf("Payload is not JSON: %v", err)
+	}
+	if payload.WebhookID != webhook.ID || payload.Topic != "tech" || payload.Article.ID != "a1" {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+
+	deliveryID, _ := strconv.ParseInt(req.Header.Get(DeliveryHeader), 10, 64)
+	delivery, err := store.GetWebhookDelivery(context.Background(), deliveryID)
+	if err != nil {
+		t.Fatalf("GetWebhookDelivery() error = %v", err)
+	}
+	if delivery.Status != models.WebhookDeliveryDelivered || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusNoContent || delivery.DeliveredAt == nil {
+		t.Errorf("Expected a delivered delivery, got %+v", delivery)
+	}
+
+	// Delivered payloads are not sent again
+	if attempted, err := dispatcher.DeliverDue(context.Background()); err != nil || attempted != 0 {
+		t.Errorf("Second DeliverDue() = %d, %v, want nothing due", attempted, err)
+	}
+}
+
+func TestDispatcher_RetriesUntilDead(t *testing.T) {
+	requests := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	store, webhook := newTestStorage(t, receiver.URL)
+	dispatcher := NewDispatcher(store, &config.Config{WebhookMaxAttempts: 3})
+	clock := time.Now()
+	dispatcher.now = func() time.Time { return clock }
+
+	ctx := context.Background()
+	for attempt := 1; attempt <= 3; attempt++ {
+		if attempted, err := dispatcher.DeliverDue(ctx); err != nil || attempted != 1 {
+			t.Fatalf("Attempt %d: DeliverDue() = %d, %v, want 1 delivery", attempt, attempted, err)
+		}
+		deliveries, err := store.GetWebhookDeliveries(ctx, webhook.ID, "", 10)
+		if err != nil || len(deliveries) != 1 {
+			t.Fatalf("GetWebhookDeliveries() = %v, %v", deliveries, err)
+		}
+		delivery := deliveries[0]
+		if delivery.Attempts != attempt || delivery.ResponseStatus != http.StatusServiceUnavailable || delivery.LastError == "" {
+			t.Errorf("Attempt %d: unexpected delivery %+v", attempt, delivery)
+		}
+
+		if attempt < 3 {
+			if delivery.Status != models.WebhookDeliveryPending || !delivery.NextAttemptAt.Equal(clock.UTC().Add(Backoff(attempt))) {
+				t.Errorf("Attempt %d: expected a retry after %v, got %+v", attempt, Backoff(attempt), delivery)
+			}
+			// Nothing is due before the backoff elapsed
+			if attempted, _ := dispatcher.DeliverDue(ctx); attempted != 0 {
+				t.Errorf("Attempt %d: expected no delivery during the backoff, got %d", attempt, attempted)
+			}
+			clock = delivery.NextAttemptAt
+		} else if delivery.Status != models.WebhookDeliveryDead {
+			t.Errorf("Expected the delivery to be dead after 3 attempts, got %+v", delivery)
+		}
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+
+	clock = clock.Add(24 * time.Hour)
+	if attempted, _ := dispatcher.DeliverDue(ctx); attempted != 0 {
+		t.Errorf("Expected dead deliveries to stay dead, got %d attempts", attempted)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		8:  time.Hour,
+		30: time.Hour,
+	} {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -14,14 +14,23 @@ import (
 	"gorssag/internal/api"
 	"gorssag/internal/cache"
 	"gorssag/internal/config"
+	"gorssag/internal/digest"
+	"gorssag/internal/notifier"
 	"gorssag/internal/poller"
 	"gorssag/internal/storage"
+	"gorssag/internal/webhooks"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Run a maintenance command instead of the server when one is given
+	if len(os.Args) > 1 {
+		runCommand(cfg, os.Args[1:])
+		return
+	}
+
 	// Initialize cache for hot data
 	cacheManager := cache.NewManager(cfg.CacheTTL)
 
@@ -31,16 +40,20 @@ func main() {
 		log.Fatal("Failed to initialize storage:", err)
 	}
 
+	// Create a context for graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Clean up old articles based on retention policy
 	log.Printf("Cleaning up articles older than %v", cfg.ArticleRetention)
-	if err := storageManager.CleanupOldArticles(cfg.ArticleRetention); err != nil {
+	if err := storageManager.CleanupOldArticles(ctx, storage.NewRetentionPolicy(cfg)); err != nil {
 		log.Printf("Warning: failed to cleanup old articles: %v", err)
 	}
 
 	// Compress old articles that are still uncompressed (run in background)
 	log.Printf("Starting background compression of old articles for storage optimization")
 	go func() {
-		if err := storageManager.CompressOldArticles(); err != nil {
+		if err := storageManager.CompressOldArticles(ctx); err != nil {
 			log.Printf("Warning: failed to compress old articles: %v", err)
 		} else {
 			log.Printf("Background compression of old articles completed")
@@ -50,9 +63,28 @@ func main() {
 	// Initialize RSS aggregator
 	agg := aggregator.New(cacheManager, storageManager, cfg.Feeds)
 
+	// Deliver the webhook payloads queued as articles are assigned to topics
+	webhookDispatcher := webhooks.NewDispatcher(storageManager, cfg)
+	webhookDispatcher.Start()
+
+	// Send the scheduled email digests, catching up the ones missed while down
+	digestScheduler := digest.NewScheduler(storageManager, digest.NewMailer(cfg.SMTP))
+	if cfg.SMTP.Host != "" {
+		digestScheduler.Start()
+	} else {
+		log.Printf("Email digests are disabled, set SMTP_HOST to send them")
+	}
+
+	// Post the new articles of each topic to its chat channels
+	chatNotifier := notifier.New(cfg)
+	if chatNotifier.Enabled() {
+		agg.SetNotifier(chatNotifier)
+		chatNotifier.Start()
+	}
+
 	// Perform initial centralized feed polling to establish status
 	log.Printf("Starting initial centralized feed polling...")
-	err = agg.PollAllFeeds()
+	err = agg.PollAllFeeds(ctx)
 	if err != nil {
 		log.Printf("Warning: some feeds failed during initial polling: %v", err)
 	}
@@ -77,15 +109,14 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Create a context for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	// Start signal handler in goroutine
 	go func() {
 		<-sigChan
 		log.Println("Received shutdown signal, stopping services...")
 		backgroundPoller.Stop()
+		webhookDispatcher.Stop()
+		digestScheduler.Stop()
+		chatNotifier.Stop()
 		cancel() // Cancel the context to stop the server
 	}()
 
@@ -94,3 +125,23 @@ func main() {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// runCommand runs a maintenance command against the configured storage
+func runCommand(cfg *config.Config, args []string) {
+	switch args[0] {
+	case "restore":
+		// The server must be stopped: the database file is swapped in place
+		if len(args) != 2 {
+			log.Fatal("Usage: gorssag restore <backup-file>")
+		}
+		if cfg.StorageDriver != "sqlite" {
+			log.Fatalf("Restore is only supported by the sqlite storage driver, not %s", cfg.StorageDriver)
+		}
+		if err := storage.RestoreBackup(cfg.DataDir, args[1]); err != nil {
+			log.Fatal("Failed to restore backup:", err)
+		}
+		log.Printf("Restored %s into %s", args[1], cfg.DataDir)
+	default:
+		log.Fatalf("Unknown command: %s", args[0])
+	}
+}